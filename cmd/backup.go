@@ -0,0 +1,480 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/backup"
+	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/manifest"
+	"github.com/yejune/git-multirepo/internal/patch"
+)
+
+var (
+	backupBranch      string
+	backupAt          string
+	backupPruneDryRun bool
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Browse and restore backups of keep files",
+	Long: `Browse the backups in .multirepos/backup: copies of keep files taken
+before they are overwritten (modified/) and copies of their patches
+(patched/), including the monthly archives in archived/. Encrypted backups
+and archives are decrypted with the key from GIT_MULTIREPO_PASSPHRASE or
+GIT_MULTIREPO_KEYFILE.
+
+A file is named <workspace>/<file> (just <file> for the parent repository),
+and a version by the timestamp shown by list (YYYYMMDD_HHMMSS). --at also
+takes a prefix of one, e.g. a day, and then picks the latest matching
+version.
+
+Examples:
+  git multirepo backup list
+  git multirepo backup list apps/api config.json --branch main
+  git multirepo backup show apps/api/config.json --at 20260109
+  git multirepo backup diff apps/api/config.json
+  git multirepo backup restore apps/api/config.json --at 20260109_143022
+  git multirepo backup prune --dry-run`,
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list [workspace] [file]",
+	Short: "List backup versions by timestamp",
+	Long: `List the backup versions of keep files, oldest first, optionally only
+those of a workspace ("." for the parent repository), a file (or directory)
+in it, or a branch. Versions inside the monthly archives are marked
+archived; their branch has / replaced by _.`,
+	Args: cobra.MaximumNArgs(2),
+	RunE: runBackupList,
+}
+
+var backupShowCmd = &cobra.Command{
+	Use:   "show <file>",
+	Short: "Show a backup version and whether it differs from the file",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runBackupShow,
+}
+
+var backupDiffCmd = &cobra.Command{
+	Use:   "diff <file>",
+	Short: "Diff a backup version against the current file",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runBackupDiff,
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <file> --at <timestamp>",
+	Short: "Restore a backup version of a file",
+	Long: `Write a backup version over the current file. The current content is
+backed up first, and the keep patch of the file follows the restored
+content.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBackupRestore,
+}
+
+var backupPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove backups outside the retention limits",
+	Long: `Remove the backups and archives that fall outside the retention
+section of the manifest:
+
+  retention:
+    days: 90            # keep loose backups 90 days
+    archive_months: 12  # keep monthly archives 12 months
+    max_size: 500MB     # then remove the oldest until the total fits
+
+sync prunes automatically with its daily archive check. With --dry-run
+nothing is removed.`,
+	Args: cobra.NoArgs,
+	RunE: runBackupPrune,
+}
+
+func init() {
+	// Command registered in root.go init() in workflow order
+	backupCmd.AddCommand(backupListCmd)
+	backupCmd.AddCommand(backupShowCmd)
+	backupCmd.AddCommand(backupDiffCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+	backupCmd.AddCommand(backupPruneCmd)
+	backupPruneCmd.Flags().BoolVar(&backupPruneDryRun, "dry-run", false, "Only show what would be removed")
+	backupListCmd.Flags().StringVar(&backupBranch, "branch", "", "Only list versions backed up on this branch")
+	for _, c := range []*cobra.Command{backupShowCmd, backupDiffCmd, backupRestoreCmd} {
+		c.Flags().StringVar(&backupAt, "at", "", "Version timestamp, or a prefix of one (default: latest)")
+	}
+	backupRestoreCmd.MarkFlagRequired("at")
+}
+
+// backupFileID names the file of a backup version like a patch: <workspace>/<file>
+func backupFileID(v *backup.Version) string {
+	if v.Workspace == "" {
+		return v.File
+	}
+	return v.Workspace + "/" + v.File
+}
+
+// loadBackups lists every backup version of the workspace
+// Archives that cannot be read are reported and skipped.
+func loadBackups(ctx *common.WorkspaceContext) ([]backup.Version, error) {
+	backupDir := filepath.Join(ctx.RepoRoot, ".multirepos", "backup")
+	versions, unreadable, err := backup.List(backupDir, ctx.Manifest.Paths())
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range unreadable {
+		rel, _ := filepath.Rel(ctx.RepoRoot, p)
+		colorYellow.Fprintf(os.Stderr, "⚠ Skipped %s: cannot be read (encrypted without a key?)\n", rel)
+	}
+	return versions, nil
+}
+
+func runBackupList(cmd *cobra.Command, args []string) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	workspace, file := "", ""
+	if len(args) > 0 {
+		target, err := resolveKeepTarget(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		workspace = target.backupPath()
+	}
+	if len(args) > 1 {
+		file = strings.TrimSuffix(filepath.ToSlash(filepath.Clean(args[1])), "/")
+	}
+
+	all, err := loadBackups(ctx)
+	if err != nil {
+		return err
+	}
+	var versions []backup.Version
+	for _, v := range all {
+		if len(args) > 0 && v.Workspace != workspace {
+			continue
+		}
+		if file != "" && v.File != file && !strings.HasPrefix(v.File, file+"/") {
+			continue
+		}
+		if backupBranch != "" && v.Branch != backupBranch && !(v.Archived() && v.Branch == strings.ReplaceAll(backupBranch, "/", "_")) {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	if len(versions) == 0 {
+		fmt.Println("No backups")
+		return nil
+	}
+
+	fileWidth, branchWidth := len("FILE"), len("BRANCH")
+	for _, v := range versions {
+		fileWidth = max(fileWidth, len(backupFileID(&v)))
+		branchWidth = max(branchWidth, len(v.Branch))
+	}
+
+	printFaint("%-*s  %-*s  %s\n", fileWidth, "FILE", branchWidth, "BRANCH", "VERSION")
+	for _, v := range versions {
+		fmt.Printf("%-*s  %-*s  %s", fileWidth, backupFileID(&v), branchWidth, v.Branch, v.Timestamp)
+		var notes []string
+		if v.Kind == backup.KindPatched {
+			notes = append(notes, "patch")
+		}
+		if v.Archived() {
+			notes = append(notes, "archived")
+		}
+		if len(notes) > 0 {
+			printFaint(" (%s)", strings.Join(notes, ", "))
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// resolveBackupFile splits <workspace>/<file> into its keep target and the
+// file relative to it, using the longest matching workspace path
+func resolveBackupFile(ctx *common.WorkspaceContext, name string) (*keepTarget, string, error) {
+	name = filepath.ToSlash(filepath.Clean(name))
+	path := manifest.ParentPath
+	for _, ws := range ctx.Manifest.Paths() {
+		if strings.HasPrefix(name, ws+"/") && (path == manifest.ParentPath || len(ws) > len(path)) {
+			path = ws
+		}
+	}
+	target, err := resolveKeepTarget(ctx, path)
+	if err != nil {
+		return nil, "", err
+	}
+	if path == manifest.ParentPath {
+		return target, name, nil
+	}
+	return target, strings.TrimPrefix(name, path+"/"), nil
+}
+
+// selectBackupVersion returns the latest full-file backup of a file whose
+// timestamp starts with at (any version if at is empty)
+func selectBackupVersion(ctx *common.WorkspaceContext, target *keepTarget, file, at string) (*backup.Version, error) {
+	versions, err := loadBackups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var found *backup.Version
+	for i := range versions {
+		v := &versions[i]
+		if v.Kind == backup.KindModified && v.Workspace == target.backupPath() && v.File == file && strings.HasPrefix(v.Timestamp, at) {
+			found = v // Sorted oldest first
+		}
+	}
+	if found == nil {
+		if at != "" {
+			return nil, fmt.Errorf("no backup of %s at %s (see 'git multirepo backup list')", file, at)
+		}
+		return nil, fmt.Errorf("no backup of %s", file)
+	}
+	return found, nil
+}
+
+func runBackupShow(cmd *cobra.Command, args []string) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+	target, file, err := resolveBackupFile(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	v, err := selectBackupVersion(ctx, target, file, backupAt)
+	if err != nil {
+		return err
+	}
+	content, err := v.Read()
+	if err != nil {
+		return fmt.Errorf("%s: %w", v.Timestamp, err)
+	}
+
+	stored, _ := filepath.Rel(ctx.RepoRoot, v.Path)
+	if v.Archived() {
+		stored += " (" + v.Member + ")"
+	}
+	printCyan("%s\n", backupFileID(v))
+	printFaint("Version:   %s\n", v.Timestamp)
+	printFaint("Branch:    %s\n", v.Branch)
+	printFaint("Stored in: %s\n", stored)
+
+	current, err := os.ReadFile(filepath.Join(target.fullPath, file))
+	switch {
+	case os.IsNotExist(err):
+		colorYellow.Fprintf(os.Stdout, "Current:   file missing\n")
+	case err != nil:
+		return err
+	case bytes.Equal(current, content):
+		printFaint("Current:   identical\n")
+	default:
+		colorYellow.Fprintf(os.Stdout, "Current:   differs (see 'git multirepo backup diff')\n")
+	}
+	fmt.Println()
+	_, err = os.Stdout.Write(content)
+	return err
+}
+
+func runBackupDiff(cmd *cobra.Command, args []string) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+	target, file, err := resolveBackupFile(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	v, err := selectBackupVersion(ctx, target, file, backupAt)
+	if err != nil {
+		return err
+	}
+	content, err := v.Read()
+	if err != nil {
+		return fmt.Errorf("%s: %w", v.Timestamp, err)
+	}
+
+	// Diff copies named <version>/<file> and current/<file>, so the headers
+	// say which side is which. The copies may hold decrypted content.
+	tmp, err := os.MkdirTemp("", "git-multirepo-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	old := filepath.Join(v.Timestamp, filepath.Base(file))
+	if err := writeDiffSide(filepath.Join(tmp, old), content); err != nil {
+		return err
+	}
+	cur := "/dev/null"
+	if data, err := os.ReadFile(filepath.Join(target.fullPath, file)); err == nil {
+		cur = filepath.Join("current", filepath.Base(file))
+		if err := writeDiffSide(filepath.Join(tmp, cur), data); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	err = git.Exec(commandContext(cmd), git.Cmd{
+		Dir:    tmp,
+		Args:   []string{"diff", "--no-index", "--no-prefix", "--", old, cur},
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+	var exitErr *git.ExitError
+	if errors.As(err, &exitErr) && exitErr.Code == 1 {
+		return nil // Differences found
+	}
+	if err == nil {
+		printFaint("%s %s is identical to the current file\n", backupFileID(v), v.Timestamp)
+	}
+	return err
+}
+
+// writeDiffSide writes one side of a backup diff, readable only by the user
+func writeDiffSide(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0600)
+}
+
+func runBackupRestore(cmd *cobra.Command, args []string) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+	target, file, err := resolveBackupFile(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	v, err := selectBackupVersion(ctx, target, file, backupAt)
+	if err != nil {
+		return err
+	}
+	content, err := v.Read()
+	if err != nil {
+		return fmt.Errorf("%s: %w", v.Timestamp, err)
+	}
+
+	fullPath := filepath.Join(target.fullPath, file)
+	current, err := os.ReadFile(fullPath)
+	if err == nil && bytes.Equal(current, content) {
+		printFaint("  - %s: already at %s\n", backupFileID(v), v.Timestamp)
+		return nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	key, err := sensitiveKey(ctx.Manifest, target.path, file)
+	if err != nil {
+		return err
+	}
+	branch, _ := git.GetCurrentBranch(target.fullPath)
+
+	// 1. Back up the current content
+	backupDir := filepath.Join(ctx.RepoRoot, ".multirepos", "backup")
+	if err := backup.CreateFileBackupWithKey(fullPath, backupDir, ctx.RepoRoot, target.backupPath(), branch, key); err != nil {
+		return fmt.Errorf("failed to backup: %w", err)
+	}
+
+	// 2. Write the version
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(fullPath); err == nil {
+		perm = info.Mode().Perm()
+	} else if key != nil {
+		perm = 0600
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(fullPath, content, perm); err != nil {
+		return err
+	}
+
+	// 3. Keep the patch store in line with the restored content
+	if git.IsRepo(target.fullPath) && containsString(expandKeep(target.fullPath, *target.keep), file) {
+		head, _ := git.GetCurrentCommit(target.fullPath)
+		err = git.WithSkipWorktreeTransaction(target.fullPath, []string{file}, func() error {
+			if modified, err := keepFileDiffers(target.fullPath, "HEAD", file); err != nil {
+				return err
+			} else if !modified {
+				return patch.Drop(ctx.RepoRoot, target.path, file)
+			}
+			return storeKeepPatch(commandContext(cmd), ctx.RepoRoot, target, file, branch, head, key)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	printGreen("  ✓ %s: restored %s\n", backupFileID(v), v.Timestamp)
+	return nil
+}
+
+func runBackupPrune(cmd *cobra.Command, args []string) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+	retention, err := backupRetention(ctx.Manifest)
+	if err != nil {
+		return err
+	}
+	if retention.IsZero() {
+		return fmt.Errorf("no retention configured (add a retention section to %s)", manifest.FileName)
+	}
+	return pruneBackups(ctx.RepoRoot, retention, backupPruneDryRun)
+}
+
+// backupRetention returns the retention limits of the manifest
+func backupRetention(m *manifest.Manifest) (backup.Retention, error) {
+	if m.Retention == nil {
+		return backup.Retention{}, nil
+	}
+	r := backup.Retention{Days: m.Retention.Days, ArchiveMonths: m.Retention.ArchiveMonths}
+	if m.Retention.MaxSize != "" {
+		size, err := backup.ParseSize(m.Retention.MaxSize)
+		if err != nil {
+			return r, fmt.Errorf("retention.max_size: %w", err)
+		}
+		r.MaxSize = size
+	}
+	return r, nil
+}
+
+// pruneBackups removes (or with dryRun lists) the backups outside retention
+func pruneBackups(repoRoot string, retention backup.Retention, dryRun bool) error {
+	backupDir := filepath.Join(repoRoot, ".multirepos", "backup")
+	pruned, err := backup.Prune(backupDir, retention, time.Now(), dryRun)
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	for _, p := range pruned {
+		size += p.Size
+		printFaint("  - %s (%s)\n", filepath.ToSlash(p.Path), p.Reason)
+	}
+	switch {
+	case len(pruned) == 0:
+		fmt.Println("No backups to prune")
+	case dryRun:
+		fmt.Printf("Would remove %d backup(s), %s\n", len(pruned), backup.FormatSize(size))
+	default:
+		printGreen("✓ Removed %d backup(s), %s\n", len(pruned), backup.FormatSize(size))
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yejune/git-multirepo/internal/backup"
+	"github.com/yejune/git-multirepo/internal/manifest"
+	"github.com/yejune/git-multirepo/internal/patch"
+)
+
+func resetBackupFlags() {
+	backupBranch = ""
+	backupAt = ""
+}
+
+func TestRunBackup(t *testing.T) {
+	dir, _, _ := setupBranchWorkspaces(t)
+	defer resetBackupFlags()
+	resetBackupFlags()
+	api := filepath.Join(dir, "apps/api")
+	backupDir := filepath.Join(dir, ".multirepos", "backup")
+
+	write := func(rel, content string) {
+		p := filepath.Join(backupDir, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte(content), 0644)
+	}
+	write("modified/multirepo/apps/api/feature/x/2025/11/03/config.20251103_101500.json", "{\"november\": true}\n")
+	captureOutput(func() {
+		if err := backup.ArchiveOldBackupsFor(backupDir, []string{"apps/api", "apps/web"}, nil); err != nil {
+			t.Fatalf("ArchiveOldBackupsFor failed: %v", err)
+		}
+	})
+	write("modified/multirepo/apps/api/main/2026/01/09/config.20260109_143022.json", "{\"january\": true}\n")
+	write("patched/multirepo/apps/api/main/2026/01/09/config.json.20260109_143022.patch", "diff\n")
+	write("modified/workspace/main/2026/01/10/.env.20260110_080000", "PARENT=1\n")
+
+	t.Run("list", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := runBackupList(backupListCmd, nil); err != nil {
+				t.Errorf("runBackupList failed: %v", err)
+			}
+		})
+		for _, want := range []string{
+			"apps/api/config.json  feature_x  20251103_101500 (archived)",
+			"apps/api/config.json  main       20260109_143022\n",
+			"apps/api/config.json  main       20260109_143022 (patch)",
+			".env                  main       20260110_080000",
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("list should contain %q, got:\n%s", want, output)
+			}
+		}
+
+		backupBranch = "feature/x"
+		defer resetBackupFlags()
+		output = captureOutput(func() {
+			runBackupList(backupListCmd, []string{"apps/api", "config.json"})
+		})
+		if !strings.Contains(output, "20251103_101500") || strings.Contains(output, "20260109_143022") {
+			t.Errorf("list should filter by branch, got:\n%s", output)
+		}
+
+		backupBranch = ""
+		output = captureOutput(func() {
+			runBackupList(backupListCmd, []string{"."})
+		})
+		if !strings.Contains(output, ".env") || strings.Contains(output, "config.json") {
+			t.Errorf("list should filter by workspace, got:\n%s", output)
+		}
+	})
+
+	t.Run("show", func(t *testing.T) {
+		backupAt = "202511"
+		defer resetBackupFlags()
+		output := captureOutput(func() {
+			if err := runBackupShow(backupShowCmd, []string{"apps/api/config.json"}); err != nil {
+				t.Errorf("runBackupShow failed: %v", err)
+			}
+		})
+		if !strings.Contains(output, "Version:   20251103_101500") || !strings.Contains(output, "2025-11-feature_x.tar.gz (03/config.20251103_101500.json)") ||
+			!strings.Contains(output, "Current:   differs") || !strings.Contains(output, "{\"november\": true}") {
+			t.Errorf("unexpected output:\n%s", output)
+		}
+
+		backupAt = "2024"
+		if err := runBackupShow(backupShowCmd, []string{"apps/api/config.json"}); err == nil {
+			t.Error("expected error for an unknown version")
+		}
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := runBackupDiff(backupDiffCmd, []string{"apps/api/config.json"}); err != nil {
+				t.Errorf("runBackupDiff failed: %v", err)
+			}
+		})
+		if !strings.Contains(output, "--- 20260109_143022/config.json") || !strings.Contains(output, "+++ current/config.json") ||
+			!strings.Contains(output, "-{\"january\": true}") || !strings.Contains(output, "+{\"local\": true}") {
+			t.Errorf("unexpected diff:\n%s", output)
+		}
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		backupAt = "20251103_101500"
+		defer resetBackupFlags()
+		output := captureOutput(func() {
+			if err := runBackupRestore(backupRestoreCmd, []string{"apps/api/config.json"}); err != nil {
+				t.Errorf("runBackupRestore failed: %v", err)
+			}
+		})
+		if !strings.Contains(output, "✓ apps/api/config.json: restored 20251103_101500") {
+			t.Errorf("unexpected output: %s", output)
+		}
+		if content, _ := os.ReadFile(filepath.Join(api, "config.json")); string(content) != "{\"november\": true}\n" {
+			t.Errorf("file should be restored, got %q", content)
+		}
+
+		// The replaced content is backed up, and the keep patch follows the file
+		var last backup.Version
+		versions, _, _ := backup.List(backupDir, []string{"apps/api"})
+		for _, v := range versions {
+			if v.Kind == backup.KindModified {
+				last = v
+			}
+		}
+		if content, _ := last.Read(); string(content) != "{\"local\": true}\n" {
+			t.Errorf("previous content should be backed up, latest version is %+v (%q)", last, content)
+		}
+		content, _ := patch.Read(patch.StorePath(dir, "apps/api", "config.json"))
+		if !strings.Contains(string(content), "+{\"november\": true}") {
+			t.Errorf("patch should follow the restored content, got %q", content)
+		}
+
+		output = captureOutput(func() {
+			runBackupRestore(backupRestoreCmd, []string{"apps/api/config.json"})
+		})
+		if !strings.Contains(output, "already at 20251103_101500") {
+			t.Errorf("second restore should be skipped, got: %s", output)
+		}
+	})
+}
+
+func TestRunBackupPrune(t *testing.T) {
+	dir, _, _ := setupBranchWorkspaces(t)
+	defer func() { backupPruneDryRun = false }()
+	backupDir := filepath.Join(dir, ".multirepos", "backup")
+	old := filepath.Join(backupDir, "modified", "workspace", "main", "2020", "01", "02", ".env.20200102_100000")
+	os.MkdirAll(filepath.Dir(old), 0755)
+	os.WriteFile(old, []byte("OLD=1\n"), 0644)
+
+	if err := runBackupPrune(backupPruneCmd, nil); err == nil || !strings.Contains(err.Error(), "no retention configured") {
+		t.Errorf("expected error without retention, got %v", err)
+	}
+
+	m, _ := manifest.Load(dir)
+	m.Retention = &manifest.RetentionConfig{Days: 30, MaxSize: "1GB"}
+	manifest.Save(dir, m)
+
+	backupPruneDryRun = true
+	output := captureOutput(func() {
+		if err := runBackupPrune(backupPruneCmd, nil); err != nil {
+			t.Errorf("runBackupPrune failed: %v", err)
+		}
+	})
+	if !strings.Contains(output, "- modified/workspace/main/2020/01/02/.env.20200102_100000 (older than 30 day(s))") || !strings.Contains(output, "Would remove 1 backup(s), 6 B") {
+		t.Errorf("unexpected output: %s", output)
+	}
+	if _, err := os.Stat(old); err != nil {
+		t.Error("dry run should not remove backups")
+	}
+
+	backupPruneDryRun = false
+	output = captureOutput(func() {
+		if err := runBackupPrune(backupPruneCmd, nil); err != nil {
+			t.Errorf("runBackupPrune failed: %v", err)
+		}
+	})
+	if !strings.Contains(output, "✓ Removed 1 backup(s)") {
+		t.Errorf("unexpected output: %s", output)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("old backup should be removed")
+	}
+
+	m.Retention.MaxSize = "lots"
+	manifest.Save(dir, m)
+	if err := runBackupPrune(backupPruneCmd, nil); err == nil || !strings.Contains(err.Error(), "retention.max_size") {
+		t.Errorf("expected invalid size error, got %v", err)
+	}
+}
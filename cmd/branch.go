@@ -2,9 +2,7 @@ package cmd
 
 import (
 	"fmt"
-	"os/exec"
 	"path/filepath"
-	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/yejune/git-multirepo/internal/git"
@@ -21,15 +19,24 @@ Shows:
   - Repository URL
   - Current branch
 
+Subcommands manage a feature branch across workspaces:
+  create   Create a branch from each workspace's base branch
+  switch   Check out a branch where it exists, the base branch elsewhere
+  delete   Delete a branch locally and on the remote
+
 Examples:
   git-multirepo branch                 # Show all repositories
-  git-multirepo branch packages/lib    # Show specific repository`,
+  git-multirepo branch packages/lib    # Show specific repository
+  git-multirepo branch create feat/x   # Start a cross-repo feature`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runBranch,
 }
 
 func init() {
 	// Command registered in root.go init() in workflow order
+	branchCmd.AddCommand(branchCreateCmd)
+	branchCmd.AddCommand(branchSwitchCmd)
+	branchCmd.AddCommand(branchDeleteCmd)
 }
 
 func runBranch(cmd *cobra.Command, args []string) error {
@@ -79,22 +86,14 @@ func showBranchInfo(repoRoot string, ws *manifest.WorkspaceEntry) error {
 	}
 
 	// Get current branch
-	cmdBranch := exec.Command("git", "-C", fullPath, "rev-parse", "--abbrev-ref", "HEAD")
-	output, err := cmdBranch.Output()
+	branch, err := git.GetCurrentBranch(fullPath)
 	if err != nil {
 		fmt.Printf("  %s: failed to get branch\n", ws.Path)
 		return nil
 	}
 
-	branch := strings.TrimSpace(string(output))
-
 	// Get remote tracking branch
-	cmdTracking := exec.Command("git", "-C", fullPath, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
-	trackingOutput, err := cmdTracking.Output()
-	tracking := ""
-	if err == nil {
-		tracking = strings.TrimSpace(string(trackingOutput))
-	}
+	tracking, _ := git.GetUpstream(fullPath)
 
 	fmt.Printf("  %s\n", ws.Path)
 	fmt.Printf("    Repo:   %s\n", ws.Repo)
@@ -0,0 +1,332 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/interactive"
+	"github.com/yejune/git-multirepo/internal/manifest"
+)
+
+var (
+	branchGroups    []string
+	branchStash     bool
+	branchAutostash bool
+	branchFrom      string
+	branchForce     bool
+	branchLocalOnly bool
+	branchYes       bool
+)
+
+var branchCreateCmd = &cobra.Command{
+	Use:   "create <branch> [path...]",
+	Short: "Create and check out a branch in workspaces",
+	Long: `Create a branch in every workspace and check it out.
+
+The branch starts from the workspace's manifest branch, or the remote
+default branch (origin/HEAD) when none is configured. Use --from to pick
+another base. Workspaces that already have the branch just switch to it.
+
+Keep files keep their local content across the checkout. Workspaces with
+uncommitted changes are refused unless --stash (leave the changes in a
+stash) or --autostash (carry them over to the new branch) is given.
+'autostash: true' in the manifest makes --autostash the default.
+
+Examples:
+  git multirepo branch create feat/x
+  git multirepo branch create feat/x apps/api apps/web
+  git multirepo branch create feat/x --group backend --from develop`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runBranchCreate,
+}
+
+var branchSwitchCmd = &cobra.Command{
+	Use:   "switch <branch> [path...]",
+	Short: "Switch workspaces to a branch",
+	Long: `Check out a branch in every workspace where it exists (locally or on
+origin). Other workspaces switch to their base branch, so the whole tree
+is consistent for the feature.
+
+Keep files keep their local content across the checkout. Workspaces with
+uncommitted changes are refused unless --stash (leave the changes in a
+stash) or --autostash (carry them over to the new branch) is given.
+'autostash: true' in the manifest makes --autostash the default.
+
+Examples:
+  git multirepo branch switch feat/x
+  git multirepo branch switch main --stash`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runBranchSwitch,
+}
+
+var branchDeleteCmd = &cobra.Command{
+	Use:   "delete <branch> [path...]",
+	Short: "Delete a branch locally and on the remote",
+	Long: `Delete a branch in every workspace, locally and on origin.
+
+Workspaces currently on the branch switch to their base branch first.
+Unmerged branches are refused unless --force is given.
+
+Examples:
+  git multirepo branch delete feat/x
+  git multirepo branch delete feat/x --local-only
+  git multirepo branch delete feat/x --force -y`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runBranchDelete,
+}
+
+func init() {
+	for _, c := range []*cobra.Command{branchCreateCmd, branchSwitchCmd, branchDeleteCmd} {
+		c.Flags().StringSliceVarP(&branchGroups, "group", "g", nil, "Only include workspaces in group (repeatable)")
+		c.Flags().BoolVar(&branchStash, "stash", false, "Stash uncommitted changes and leave them in the stash")
+		c.Flags().BoolVar(&branchAutostash, "autostash", false, "Stash uncommitted changes and reapply them after switching")
+	}
+	branchCreateCmd.Flags().StringVar(&branchFrom, "from", "", "Base branch (default: manifest branch or origin/HEAD)")
+	branchDeleteCmd.Flags().BoolVarP(&branchForce, "force", "f", false, "Delete even if the branch is not merged")
+	branchDeleteCmd.Flags().BoolVar(&branchLocalOnly, "local-only", false, "Keep the branch on the remote")
+	branchDeleteCmd.Flags().BoolVarP(&branchYes, "yes", "y", false, "Skip confirmation")
+}
+
+// branchResult is the outcome of a branch operation in one workspace
+type branchResult struct {
+	path   string
+	action string // What happened, e.g. "created from main"
+	err    error
+}
+
+func runBranchCreate(cmd *cobra.Command, args []string) error {
+	branch := args[0]
+	return forEachBranchWorkspace(commandContext(cmd), args[1:], func(ws manifest.WorkspaceEntry, fullPath string, autostash bool) (string, error) {
+		if git.BranchExists(fullPath, branch) {
+			if err := switchWorkspaceBranch(ws, fullPath, branch, "", autostash); err != nil {
+				return "", err
+			}
+			return "already exists, switched", nil
+		}
+
+		base := branchFrom
+		if base == "" {
+			var err error
+			if base, err = workspaceBaseBranch(ws, fullPath); err != nil {
+				return "", err
+			}
+		}
+		startPoint := base
+		if !git.BranchExists(fullPath, base) {
+			if !git.RemoteBranchExists(fullPath, base) {
+				return "", fmt.Errorf("base branch not found: %s", base)
+			}
+			startPoint = "origin/" + base
+		}
+
+		if err := switchWorkspaceBranch(ws, fullPath, branch, startPoint, autostash); err != nil {
+			return "", err
+		}
+		return "created from " + startPoint, nil
+	})
+}
+
+func runBranchSwitch(cmd *cobra.Command, args []string) error {
+	branch := args[0]
+	return forEachBranchWorkspace(commandContext(cmd), args[1:], func(ws manifest.WorkspaceEntry, fullPath string, autostash bool) (string, error) {
+		target := branch
+		action := "switched"
+		if !git.BranchExists(fullPath, branch) && !git.RemoteBranchExists(fullPath, branch) {
+			base, err := workspaceBaseBranch(ws, fullPath)
+			if err != nil {
+				return "", fmt.Errorf("%s not found and %w", branch, err)
+			}
+			target = base
+			action = fmt.Sprintf("%s not found, switched to %s", branch, base)
+		}
+
+		if current, _ := git.GetCurrentBranch(fullPath); current == target {
+			if target == branch {
+				return "already on " + target, nil
+			}
+			return fmt.Sprintf("%s not found, stays on %s", branch, target), nil
+		}
+
+		if err := switchWorkspaceBranch(ws, fullPath, target, "", autostash); err != nil {
+			return "", err
+		}
+		return action, nil
+	})
+}
+
+func runBranchDelete(cmd *cobra.Command, args []string) error {
+	branch := args[0]
+
+	if !branchYes {
+		where := "locally and on origin"
+		if branchLocalOnly {
+			where = "locally"
+		}
+		confirmed, err := interactive.ConfirmYesNo(fmt.Sprintf("Delete branch %s %s in all selected workspaces?", branch, where))
+		if err != nil {
+			return fmt.Errorf("failed to read confirmation: %w", err)
+		}
+		if !confirmed {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	runCtx := commandContext(cmd)
+	return forEachBranchWorkspace(runCtx, args[1:], func(ws manifest.WorkspaceEntry, fullPath string, autostash bool) (string, error) {
+		local := git.BranchExists(fullPath, branch)
+		remote := !branchLocalOnly && git.RemoteBranchExists(fullPath, branch)
+		if !local && !remote {
+			return "", nil // Nothing to delete
+		}
+
+		base, err := workspaceBaseBranch(ws, fullPath)
+		if err == nil && base == branch {
+			return "", fmt.Errorf("refusing to delete the base branch %s", branch)
+		}
+
+		if local {
+			// git cannot delete the checked-out branch
+			if current, _ := git.GetCurrentBranch(fullPath); current == branch {
+				if err != nil {
+					return "", fmt.Errorf("cannot switch away from %s: %w", branch, err)
+				}
+				if err := switchWorkspaceBranch(ws, fullPath, base, "", autostash); err != nil {
+					return "", err
+				}
+			}
+			if err := git.DeleteBranch(fullPath, branch, branchForce); err != nil {
+				return "", err
+			}
+		}
+
+		if remote {
+			if err := git.DeleteRemoteBranch(runCtx, fullPath, branch); err != nil {
+				return "", err
+			}
+		}
+
+		switch {
+		case local && remote:
+			return "deleted locally and on origin", nil
+		case local:
+			return "deleted locally", nil
+		default:
+			return "deleted on origin", nil
+		}
+	})
+}
+
+// forEachBranchWorkspace runs op in every selected, cloned workspace and prints the results
+// op returns an empty action when there was nothing to do
+// Cancelling runCtx stops before the next workspace
+func forEachBranchWorkspace(runCtx context.Context, paths []string, op func(ws manifest.WorkspaceEntry, fullPath string, autostash bool) (string, error)) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	// --stash explicitly leaves changes behind, overriding the manifest default
+	autostash := branchAutostash || (ctx.Manifest.Autostash && !branchStash)
+
+	workspaces, err := ctx.SelectWorkspaces(paths, branchGroups)
+	if err != nil {
+		return err
+	}
+
+	var results []branchResult
+	skipped := 0
+	for i, ws := range workspaces {
+		if runCtx.Err() != nil {
+			skipped = len(workspaces) - i
+			break
+		}
+		fullPath := filepath.Join(ctx.RepoRoot, ws.Path)
+		if !git.IsRepo(fullPath) {
+			continue
+		}
+		action, err := op(ws, fullPath, autostash)
+		results = append(results, branchResult{path: ws.Path, action: action, err: err})
+	}
+
+	failed := 0
+	for _, r := range results {
+		switch {
+		case r.err != nil:
+			failed++
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: %v\n", r.path, r.err)
+		case r.action == "":
+			printFaint("  - %s: nothing to do\n", r.path)
+		default:
+			printGreen("  ✓ %s: %s\n", r.path, r.action)
+		}
+	}
+
+	if skipped > 0 {
+		return interruptedError(skipped)
+	}
+	if failed > 0 {
+		return fmt.Errorf("branch operation failed in %d workspace(s)", failed)
+	}
+	return nil
+}
+
+// switchWorkspaceBranch checks out branch (creating it from startPoint if set),
+// preserving keep files. Uncommitted changes are refused, stashed (--stash)
+// or carried over to the new branch (autostash)
+func switchWorkspaceBranch(ws manifest.WorkspaceEntry, fullPath, branch, startPoint string, autostash bool) error {
+	// Keep files are skip-worktree here, so only real changes are reported
+	dirty, err := git.HasTrackedChanges(fullPath)
+	if err != nil {
+		return fmt.Errorf("failed to check changes: %w", err)
+	}
+
+	stash := ""
+	if dirty {
+		if !branchStash && !autostash {
+			return fmt.Errorf("uncommitted changes (commit them, or use --stash or --autostash)")
+		}
+		current, _ := git.GetCurrentBranch(fullPath)
+		stash, err = git.StashSave(fullPath, fmt.Sprintf("switching %s -> %s", current, branch))
+		if err != nil {
+			return err
+		}
+		if branchStash {
+			colorYellow.Fprintf(os.Stdout, "  → %s: stashed uncommitted changes\n", ws.Path)
+			stash = "" // Left in the stash on purpose
+		}
+	}
+
+	keepFiles := expandKeep(fullPath, ws.Keep)
+	err = git.WithSkipWorktreeTransaction(fullPath, keepFiles, func() error {
+		return git.CheckoutBranch(fullPath, branch, startPoint, keepFiles)
+	})
+
+	// Reapply autostashed changes on the new branch, or on the old one if the checkout failed
+	if stash != "" {
+		if popErr := git.StashPopCommit(fullPath, stash); popErr != nil {
+			if err != nil {
+				return fmt.Errorf("%w; restoring changes also failed: %v", err, popErr)
+			}
+			return fmt.Errorf("switched to %s, but reapplying changes conflicted (resolve, then 'git stash drop'): %w", branch, popErr)
+		}
+	}
+	return err
+}
+
+// workspaceBaseBranch returns the manifest branch, or the remote default branch
+func workspaceBaseBranch(ws manifest.WorkspaceEntry, fullPath string) (string, error) {
+	if ws.Branch != "" {
+		return ws.Branch, nil
+	}
+	branch, err := git.GetDefaultBranch(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("no base branch (set branch in manifest or --from): %w", err)
+	}
+	return branch, nil
+}
@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/manifest"
+)
+
+// resetBranchFlags restores branch subcommand flags to their defaults (confirmation skipped)
+func resetBranchFlags() {
+	branchGroups = nil
+	branchStash = false
+	branchAutostash = false
+	branchFrom = ""
+	branchForce = false
+	branchLocalOnly = false
+	branchYes = true
+}
+
+// setupBranchWorkspaces creates two workspaces sharing a remote, each with a locally modified keep file
+func setupBranchWorkspaces(t *testing.T) (dir, remote string, paths []string) {
+	t.Helper()
+	dir, cleanup := setupTestEnv(t)
+	t.Cleanup(cleanup)
+
+	remote = setupRemoteRepo(t)
+	os.WriteFile(filepath.Join(remote, "config.json"), []byte("{}\n"), 0644)
+	exec.Command("git", "-C", remote, "add", ".").Run()
+	exec.Command("git", "-C", remote, "commit", "-m", "Add config").Run()
+
+	cloneBranch = ""
+	paths = []string{"apps/api", "apps/web"}
+	for _, p := range paths {
+		if err := runClone(cloneCmd, []string{remote, p}); err != nil {
+			t.Fatalf("clone failed: %v", err)
+		}
+		wsPath := filepath.Join(dir, p)
+		exec.Command("git", "-C", wsPath, "config", "user.email", "test@test.com").Run()
+		exec.Command("git", "-C", wsPath, "config", "user.name", "Test User").Run()
+	}
+
+	m, _ := manifest.Load(dir)
+	for _, p := range paths {
+		m.Find(p).Keep = []string{"config.json"}
+		wsPath := filepath.Join(dir, p)
+		os.WriteFile(filepath.Join(wsPath, "config.json"), []byte("{\"local\": true}\n"), 0644)
+		git.ApplySkipWorktree(wsPath, []string{"config.json"})
+	}
+	manifest.Save(dir, m)
+
+	return dir, remote, paths
+}
+
+func currentBranch(t *testing.T, path string) string {
+	t.Helper()
+	branch, err := git.GetCurrentBranch(path)
+	if err != nil {
+		t.Fatalf("failed to get branch: %v", err)
+	}
+	return branch
+}
+
+func TestRunBranchCreate(t *testing.T) {
+	dir, remote, paths := setupBranchWorkspaces(t)
+	defer resetBranchFlags()
+	resetBranchFlags()
+	base := currentBranch(t, remote)
+
+	output := captureOutput(func() {
+		if err := runBranchCreate(branchCreateCmd, []string{"feat/x"}); err != nil {
+			t.Errorf("runBranchCreate failed: %v", err)
+		}
+	})
+
+	for _, p := range paths {
+		wsPath := filepath.Join(dir, p)
+		if branch := currentBranch(t, wsPath); branch != "feat/x" {
+			t.Errorf("%s: expected feat/x, got %s", p, branch)
+		}
+		if content, _ := os.ReadFile(filepath.Join(wsPath, "config.json")); !strings.Contains(string(content), "local") {
+			t.Errorf("%s: keep file content lost: %q", p, content)
+		}
+		if skipped, _ := git.ListSkipWorktree(wsPath); len(skipped) != 1 {
+			t.Errorf("%s: keep file should stay skip-worktree, got %v", p, skipped)
+		}
+	}
+	if !strings.Contains(output, "created from "+base) {
+		t.Errorf("output should show base branch, got: %s", output)
+	}
+
+	t.Run("existing branch switches", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := runBranchCreate(branchCreateCmd, []string{"feat/x", "apps/api"}); err != nil {
+				t.Errorf("runBranchCreate failed: %v", err)
+			}
+		})
+		if !strings.Contains(output, "already exists") {
+			t.Errorf("expected already exists, got: %s", output)
+		}
+	})
+}
+
+func TestRunBranchSwitch_FallsBackToBase(t *testing.T) {
+	dir, remote, _ := setupBranchWorkspaces(t)
+	defer resetBranchFlags()
+	resetBranchFlags()
+	base := currentBranch(t, remote)
+	api, web := filepath.Join(dir, "apps/api"), filepath.Join(dir, "apps/web")
+
+	captureOutput(func() {
+		runBranchCreate(branchCreateCmd, []string{"feat/x", "apps/api"})
+	})
+	exec.Command("git", "-C", web, "checkout", "-q", "-b", "other").Run()
+
+	output := captureOutput(func() {
+		if err := runBranchSwitch(branchSwitchCmd, []string{"feat/x"}); err != nil {
+			t.Errorf("runBranchSwitch failed: %v", err)
+		}
+	})
+
+	if branch := currentBranch(t, api); branch != "feat/x" {
+		t.Errorf("apps/api: expected feat/x, got %s", branch)
+	}
+	if branch := currentBranch(t, web); branch != base {
+		t.Errorf("apps/web: expected fallback to %s, got %s", base, branch)
+	}
+	if !strings.Contains(output, "feat/x not found, switched to "+base) {
+		t.Errorf("output should explain fallback, got: %s", output)
+	}
+}
+
+func TestRunBranchSwitch_DirtyWorkspace(t *testing.T) {
+	dir, remote, _ := setupBranchWorkspaces(t)
+	defer resetBranchFlags()
+	resetBranchFlags()
+	base := currentBranch(t, remote)
+	api := filepath.Join(dir, "apps/api")
+
+	captureOutput(func() {
+		runBranchCreate(branchCreateCmd, []string{"feat/x", "apps/api"})
+	})
+	os.WriteFile(filepath.Join(api, "README.md"), []byte("# Dirty\n"), 0644)
+
+	t.Run("refused without --stash", func(t *testing.T) {
+		var err error
+		output := captureOutput(func() {
+			err = runBranchSwitch(branchSwitchCmd, []string{base, "apps/api"})
+		})
+		if err == nil {
+			t.Error("expected error for dirty workspace")
+		}
+		if !strings.Contains(output, "uncommitted changes") {
+			t.Errorf("output should explain refusal, got: %s", output)
+		}
+		if branch := currentBranch(t, api); branch != "feat/x" {
+			t.Errorf("branch should not change, got %s", branch)
+		}
+	})
+
+	t.Run("stashed with --stash", func(t *testing.T) {
+		branchStash = true
+		defer func() { branchStash = false }()
+
+		output := captureOutput(func() {
+			if err := runBranchSwitch(branchSwitchCmd, []string{base, "apps/api"}); err != nil {
+				t.Errorf("runBranchSwitch failed: %v", err)
+			}
+		})
+		if branch := currentBranch(t, api); branch != base {
+			t.Errorf("expected %s, got %s", base, branch)
+		}
+		if !strings.Contains(output, "stashed") {
+			t.Errorf("output should mention stash, got: %s", output)
+		}
+		out, _ := exec.Command("git", "-C", api, "stash", "list").Output()
+		if !strings.Contains(string(out), "feat/x -> "+base) {
+			t.Errorf("stash should be labeled, got: %s", out)
+		}
+		if content, _ := os.ReadFile(filepath.Join(api, "config.json")); !strings.Contains(string(content), "local") {
+			t.Errorf("keep file must not be stashed: %q", content)
+		}
+	})
+}
+
+func TestRunBranchDelete(t *testing.T) {
+	dir, remote, _ := setupBranchWorkspaces(t)
+	defer resetBranchFlags()
+	resetBranchFlags()
+	base := currentBranch(t, remote)
+	api, web := filepath.Join(dir, "apps/api"), filepath.Join(dir, "apps/web")
+
+	captureOutput(func() {
+		runBranchCreate(branchCreateCmd, []string{"feat/x"})
+	})
+	if err := exec.Command("git", "-C", api, "push", "-q", "-u", "origin", "feat/x").Run(); err != nil {
+		t.Fatalf("failed to push branch: %v", err)
+	}
+	exec.Command("git", "-C", web, "fetch", "-q").Run()
+
+	output := captureOutput(func() {
+		if err := runBranchDelete(branchDeleteCmd, []string{"feat/x"}); err != nil {
+			t.Errorf("runBranchDelete failed: %v", err)
+		}
+	})
+
+	for _, wsPath := range []string{api, web} {
+		if git.BranchExists(wsPath, "feat/x") {
+			t.Errorf("%s: branch should be deleted", wsPath)
+		}
+		if branch := currentBranch(t, wsPath); branch != base {
+			t.Errorf("%s: expected switch to %s, got %s", wsPath, base, branch)
+		}
+	}
+	if err := exec.Command("git", "-C", remote, "rev-parse", "--verify", "--quiet", "refs/heads/feat/x").Run(); err == nil {
+		t.Error("remote branch should be deleted")
+	}
+	if !strings.Contains(output, "deleted locally and on origin") {
+		t.Errorf("output should report remote deletion, got: %s", output)
+	}
+
+	t.Run("base branch is refused", func(t *testing.T) {
+		var err error
+		captureOutput(func() {
+			err = runBranchDelete(branchDeleteCmd, []string{base, "apps/api"})
+		})
+		if err == nil {
+			t.Error("expected refusal to delete base branch")
+		}
+	})
+}
+
+func TestRunBranchSwitch_Autostash(t *testing.T) {
+	dir, remote, _ := setupBranchWorkspaces(t)
+	defer resetBranchFlags()
+	resetBranchFlags()
+	base := currentBranch(t, remote)
+	api := filepath.Join(dir, "apps/api")
+
+	captureOutput(func() {
+		runBranchCreate(branchCreateCmd, []string{"feat/x", "apps/api"})
+	})
+	os.WriteFile(filepath.Join(api, "README.md"), []byte("# Work in progress\n"), 0644)
+
+	branchAutostash = true
+	output := captureOutput(func() {
+		if err := runBranchSwitch(branchSwitchCmd, []string{base, "apps/api"}); err != nil {
+			t.Errorf("runBranchSwitch failed: %v", err)
+		}
+	})
+
+	if branch := currentBranch(t, api); branch != base {
+		t.Errorf("expected %s, got %s (output: %s)", base, branch, output)
+	}
+	if content, _ := os.ReadFile(filepath.Join(api, "README.md")); string(content) != "# Work in progress\n" {
+		t.Errorf("changes should be carried over, got %q", content)
+	}
+	if entries, _ := git.ListStashes(api); len(entries) != 0 {
+		t.Errorf("autostash entry should be popped, got %+v", entries)
+	}
+}
+
+func TestRunBranchCreate_Interrupted(t *testing.T) {
+	dir, _, _ := setupBranchWorkspaces(t)
+	defer resetBranchFlags()
+	resetBranchFlags()
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cmd := &cobra.Command{}
+	cmd.SetContext(runCtx)
+
+	var err error
+	captureOutput(func() {
+		err = runBranchCreate(cmd, []string{"feat/x"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "interrupted, 2 workspace(s) not processed") {
+		t.Errorf("expected interrupted error, got %v", err)
+	}
+	for _, path := range []string{"apps/api", "apps/web"} {
+		if git.BranchExists(filepath.Join(dir, path), "feat/x") {
+			t.Errorf("%s: branch should not be created after interrupt", path)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/yejune/git-multirepo/internal/git"
 	"github.com/yejune/git-multirepo/internal/manifest"
 )
 
@@ -257,3 +258,34 @@ func TestRunBranch_CorruptedGitRepo(t *testing.T) {
 		}
 	})
 }
+
+func TestShowBranchInfo_FakeRunner(t *testing.T) {
+	fake := git.NewFakeRunner()
+	defer git.SetRunner(fake)()
+
+	// Only the .git directory is needed; git itself is answered by the fake
+	root := t.TempDir()
+	wsPath := filepath.Join(root, "apps/api")
+	os.MkdirAll(filepath.Join(wsPath, ".git"), 0755)
+
+	fake.On(wsPath, "rev-parse", "--abbrev-ref", "HEAD").Return("feat/x\n")
+	fake.On(wsPath, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}").Return("origin/feat/x\n")
+
+	ws := &manifest.WorkspaceEntry{Path: "apps/api", Repo: "https://example.com/api.git"}
+	output := captureOutput(func() {
+		showBranchInfo(root, ws)
+	})
+	if !strings.Contains(output, "Branch: feat/x → origin/feat/x") {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	t.Run("no upstream", func(t *testing.T) {
+		fake.On(wsPath, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}").Fail(128, "fatal: no upstream configured")
+		output := captureOutput(func() {
+			showBranchInfo(root, ws)
+		})
+		if !strings.Contains(output, "Branch: feat/x\n") {
+			t.Errorf("unexpected output: %s", output)
+		}
+	})
+}
@@ -85,7 +85,7 @@ func runClone(cmd *cobra.Command, args []string) error {
 
 	// Clone the repository
 	fmt.Printf("Cloning %s into %s...\n", repo, path)
-	if err := git.Clone(repo, fullPath, cloneBranch); err != nil {
+	if err := git.Clone(commandContext(cmd), repo, fullPath, cloneBranch); err != nil {
 		return fmt.Errorf("failed to clone: %w", err)
 	}
 
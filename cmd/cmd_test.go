@@ -49,6 +49,16 @@ func setupTestEnv(t *testing.T) (string, func()) {
 	return dir, cleanup
 }
 
+// loadManifest loads the manifest of dir, failing the test if it cannot
+func loadManifest(t *testing.T, dir string) *manifest.Manifest {
+	t.Helper()
+	m, err := manifest.Load(dir)
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	return m
+}
+
 // setupRemoteRepo creates a "remote" repo that can be cloned
 func setupRemoteRepo(t *testing.T) string {
 	t.Helper()
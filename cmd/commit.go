@@ -0,0 +1,294 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/interactive"
+	"github.com/yejune/git-multirepo/internal/manifest"
+)
+
+var (
+	commitMessage    string
+	commitAll        bool
+	commitTrailers   []string
+	commitGroups     []string
+	commitYes        bool
+	commitNoRollback bool
+)
+
+var commitCmd = &cobra.Command{
+	Use:   "commit -m <message> [path...]",
+	Short: "Commit changes in all workspaces with one message",
+	Long: `Commit staged changes in every workspace with the same message.
+
+Shows a summary of the changes per workspace and asks for confirmation
+before committing. Keep files are never committed, even with --all.
+
+If a commit fails (e.g., a commit hook rejects it), commits already made
+by this run are rolled back with 'git reset --soft', leaving the changes
+staged. Use --no-rollback to keep them.
+
+Examples:
+  git multirepo commit -m "Bump API version"
+  git multirepo commit -a -m "Rename config key" apps/api apps/web
+  git multirepo commit -m "Add feature" --trailer "Multirepo-Topic: feat-x"
+  git multirepo commit -a -y -m "Fix lint" --group backend`,
+	RunE: runCommit,
+}
+
+func init() {
+	commitCmd.Flags().StringVarP(&commitMessage, "message", "m", "", "Commit message")
+	commitCmd.Flags().BoolVarP(&commitAll, "all", "a", false, "Stage modified and deleted tracked files (except keep files)")
+	commitCmd.Flags().StringArrayVar(&commitTrailers, "trailer", nil, "Add a trailer to the message, e.g. \"Change-Id: I123\" (repeatable)")
+	commitCmd.Flags().StringSliceVarP(&commitGroups, "group", "g", nil, "Only commit workspaces in group (repeatable)")
+	commitCmd.Flags().BoolVarP(&commitYes, "yes", "y", false, "Skip confirmation")
+	commitCmd.Flags().BoolVar(&commitNoRollback, "no-rollback", false, "Keep successful commits when another workspace fails")
+}
+
+// commitPlan holds the changes to commit in one workspace
+type commitPlan struct {
+	ws       manifest.WorkspaceEntry
+	fullPath string
+	branch   string
+	files    []string
+	prevHead string // HEAD before committing ("" for repositories without commits)
+	done     bool
+	reverted bool // Committed, then rolled back
+	err      error
+}
+
+func runCommit(cmd *cobra.Command, args []string) error {
+	if strings.TrimSpace(commitMessage) == "" {
+		return fmt.Errorf("commit message is required (-m)")
+	}
+
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	workspaces, err := ctx.SelectWorkspaces(args, commitGroups)
+	if err != nil {
+		return err
+	}
+
+	// 1. Collect changes per workspace
+	var plans []*commitPlan
+	for _, ws := range workspaces {
+		fullPath := filepath.Join(ctx.RepoRoot, ws.Path)
+		if !git.IsRepo(fullPath) {
+			continue
+		}
+
+		files, err := commitCandidates(fullPath, expandKeep(fullPath, ws.Keep), commitAll)
+		if err != nil {
+			fmt.Printf("⚠ %s: %v\n", ws.Path, err)
+			continue
+		}
+		if len(files) == 0 {
+			continue
+		}
+
+		branch, err := git.GetCurrentBranch(fullPath)
+		if err != nil {
+			branch = "unknown"
+		}
+		plans = append(plans, &commitPlan{ws: ws, fullPath: fullPath, branch: branch, files: files})
+	}
+
+	if len(plans) == 0 {
+		fmt.Println("Nothing to commit")
+		if !commitAll {
+			printFaint("  (use --all to include modified tracked files)\n")
+		}
+		return nil
+	}
+
+	// 2. Show summary and confirm
+	printCyan("Changes to commit:\n")
+	for _, p := range plans {
+		fmt.Printf("\n  %s (%s) - %d file(s)\n", p.ws.Path, p.branch, len(p.files))
+		for _, f := range p.files {
+			printFaint("      %s\n", f)
+		}
+	}
+	message := buildCommitMessage(commitMessage, commitTrailers)
+	fmt.Println()
+	printFaint("Message:\n")
+	for _, line := range strings.Split(message, "\n") {
+		printFaint("  %s\n", line)
+	}
+	fmt.Println()
+
+	if !commitYes {
+		confirmed, err := interactive.ConfirmYesNo(fmt.Sprintf("Commit %d workspace(s)?", len(plans)))
+		if err != nil {
+			return fmt.Errorf("failed to read confirmation: %w", err)
+		}
+		if !confirmed {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	// 3. Commit sequentially so a failure can roll back earlier commits
+	runCtx := commandContext(cmd)
+	var failed *commitPlan
+	for _, p := range plans {
+		if err := commitWorkspace(runCtx, p, commitAll); err != nil {
+			p.err = err
+			failed = p
+			break
+		}
+		p.done = true
+	}
+
+	rolledBack := false
+	if failed != nil && !commitNoRollback {
+		rolledBack = true
+		for i := len(plans) - 1; i >= 0; i-- {
+			p := plans[i]
+			if !p.done {
+				continue
+			}
+			if err := git.ResetSoft(p.fullPath, p.prevHead); err != nil {
+				p.err = fmt.Errorf("rollback failed: %w", err)
+				continue
+			}
+			p.done = false
+			p.reverted = true
+		}
+	}
+
+	// 4. Report
+	fmt.Println()
+	printCyan("Results:\n")
+	for _, p := range plans {
+		switch {
+		case p.done:
+			commit, _ := git.GetCurrentCommit(p.fullPath)
+			printGreen("  ✓ %s %s\n", p.ws.Path, shortHash(commit))
+		case p == failed:
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s\n", p.ws.Path)
+			for _, line := range strings.Split(p.err.Error(), "\n") {
+				printFaint("      %s\n", line)
+			}
+		case p.err != nil:
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s (%v)\n", p.ws.Path, p.err)
+		case p.reverted:
+			colorYellow.Fprintf(os.Stdout, "  ↺ %s (rolled back)\n", p.ws.Path)
+		default:
+			printFaint("  - %s (not attempted)\n", p.ws.Path)
+		}
+	}
+
+	if failed != nil {
+		if rolledBack {
+			return fmt.Errorf("commit failed in %s; all commits rolled back (changes remain staged)", failed.ws.Path)
+		}
+		return fmt.Errorf("commit failed in %s", failed.ws.Path)
+	}
+	return nil
+}
+
+// commitCandidates returns the files that would be committed in a workspace, excluding keep files
+func commitCandidates(fullPath string, keepFiles []string, all bool) ([]string, error) {
+	var files []string
+	var err error
+	if all {
+		// Staged and unstaged changes to tracked files (skip-worktree files are hidden)
+		files, err = git.GetModifiedFiles(fullPath)
+	} else {
+		files, err = git.GetStagedFiles(fullPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return excludeFiles(files, keepFiles), nil
+}
+
+// commitWorkspace stages (with --all), drops keep files from the index and commits
+func commitWorkspace(runCtx context.Context, p *commitPlan, all bool) error {
+	prevHead, err := git.GetCurrentCommit(p.fullPath)
+	if err != nil {
+		prevHead = ""
+	}
+	p.prevHead = prevHead
+
+	if all {
+		if err := git.StageTracked(p.fullPath); err != nil {
+			return err
+		}
+	}
+
+	// Keep files must never be committed
+	staged, err := git.GetStagedFiles(p.fullPath)
+	if err != nil {
+		return err
+	}
+	if keepStaged := intersectFiles(staged, expandKeep(p.fullPath, p.ws.Keep)); len(keepStaged) > 0 {
+		if err := git.UnstageFiles(p.fullPath, keepStaged); err != nil {
+			return fmt.Errorf("failed to unstage keep files: %w", err)
+		}
+		colorYellow.Fprintf(os.Stdout, "  → %s: unstaged %d keep file(s)\n", p.ws.Path, len(keepStaged))
+	}
+
+	return git.CreateCommit(runCtx, p.fullPath, buildCommitMessage(commitMessage, commitTrailers))
+}
+
+// buildCommitMessage appends trailers to the message
+// Trailers may be written as "Key: Value" or "Key=Value"
+func buildCommitMessage(message string, trailers []string) string {
+	message = strings.TrimRight(message, "\n")
+	if len(trailers) == 0 {
+		return message
+	}
+
+	var lines []string
+	for _, t := range trailers {
+		if !strings.Contains(t, ":") {
+			if key, value, ok := strings.Cut(t, "="); ok {
+				t = strings.TrimSpace(key) + ": " + strings.TrimSpace(value)
+			}
+		}
+		lines = append(lines, t)
+	}
+	return message + "\n\n" + strings.Join(lines, "\n")
+}
+
+// excludeFiles returns files that are not in exclude
+func excludeFiles(files, exclude []string) []string {
+	excluded := make(map[string]bool, len(exclude))
+	for _, f := range exclude {
+		excluded[f] = true
+	}
+	var result []string
+	for _, f := range files {
+		if !excluded[f] {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// intersectFiles returns files present in both lists
+func intersectFiles(files, other []string) []string {
+	set := make(map[string]bool, len(other))
+	for _, f := range other {
+		set[f] = true
+	}
+	var result []string
+	for _, f := range files {
+		if set[f] {
+			result = append(result, f)
+		}
+	}
+	return result
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/manifest"
+)
+
+// resetCommitFlags restores commit command flags to their defaults (confirmation skipped)
+func resetCommitFlags() {
+	commitMessage = ""
+	commitAll = false
+	commitTrailers = nil
+	commitGroups = nil
+	commitYes = true
+	commitNoRollback = false
+}
+
+// setupCommitWorkspaces creates two workspaces with a modified tracked file and a modified keep file
+func setupCommitWorkspaces(t *testing.T) (string, []string) {
+	t.Helper()
+	dir, cleanup := setupTestEnv(t)
+	t.Cleanup(cleanup)
+
+	remoteRepo := setupRemoteRepo(t)
+	os.WriteFile(filepath.Join(remoteRepo, "config.json"), []byte("{}\n"), 0644)
+	exec.Command("git", "-C", remoteRepo, "add", ".").Run()
+	exec.Command("git", "-C", remoteRepo, "commit", "-m", "Add config").Run()
+
+	cloneBranch = ""
+	paths := []string{"apps/api", "apps/web"}
+	for _, p := range paths {
+		runClone(cloneCmd, []string{remoteRepo, p})
+		wsPath := filepath.Join(dir, p)
+		exec.Command("git", "-C", wsPath, "config", "user.email", "test@test.com").Run()
+		exec.Command("git", "-C", wsPath, "config", "user.name", "Test User").Run()
+		os.WriteFile(filepath.Join(wsPath, "README.md"), []byte("# Changed\n"), 0644)
+		os.WriteFile(filepath.Join(wsPath, "config.json"), []byte("{\"local\": true}\n"), 0644)
+	}
+	m, _ := manifest.Load(dir)
+	for _, p := range paths {
+		m.Find(p).Keep = []string{"config.json"}
+	}
+	manifest.Save(dir, m)
+
+	return dir, paths
+}
+
+// lastCommitMessage returns the full message of HEAD
+func lastCommitMessage(t *testing.T, repoPath string) string {
+	t.Helper()
+	out, _ := exec.Command("git", "-C", repoPath, "log", "-1", "--format=%B").Output()
+	return strings.TrimSpace(string(out))
+}
+
+func TestRunCommit_AllExcludesKeepFiles(t *testing.T) {
+	dir, paths := setupCommitWorkspaces(t)
+	defer resetCommitFlags()
+
+	resetCommitFlags()
+	commitMessage = "Shared change"
+	commitAll = true
+	commitTrailers = []string{"Multirepo-Topic=feat-x"}
+
+	output := captureOutput(func() {
+		if err := runCommit(commitCmd, []string{}); err != nil {
+			t.Errorf("runCommit failed: %v", err)
+		}
+	})
+
+	for _, p := range paths {
+		wsPath := filepath.Join(dir, p)
+		if msg := lastCommitMessage(t, wsPath); msg != "Shared change\n\nMultirepo-Topic: feat-x" {
+			t.Errorf("%s: unexpected message %q", p, msg)
+		}
+
+		// Keep file must remain an uncommitted local change
+		modified, _ := git.GetModifiedFiles(wsPath)
+		if len(modified) != 1 || modified[0] != "config.json" {
+			t.Errorf("%s: keep file should stay uncommitted, got %v", p, modified)
+		}
+		if !strings.Contains(output, "✓ "+p) {
+			t.Errorf("output should report success for %s, got: %s", p, output)
+		}
+	}
+}
+
+func TestRunCommit_StagedKeepFileIsUnstaged(t *testing.T) {
+	dir, _ := setupCommitWorkspaces(t)
+	defer resetCommitFlags()
+
+	wsPath := filepath.Join(dir, "apps/api")
+	exec.Command("git", "-C", wsPath, "add", "README.md", "config.json").Run()
+
+	resetCommitFlags()
+	commitMessage = "Only readme"
+
+	captureOutput(func() {
+		if err := runCommit(commitCmd, []string{"apps/api"}); err != nil {
+			t.Errorf("runCommit failed: %v", err)
+		}
+	})
+
+	out, _ := exec.Command("git", "-C", wsPath, "show", "--name-only", "--format=", "HEAD").Output()
+	if strings.TrimSpace(string(out)) != "README.md" {
+		t.Errorf("commit should only contain README.md, got %q", out)
+	}
+}
+
+func TestRunCommit_RollbackOnHookFailure(t *testing.T) {
+	dir, paths := setupCommitWorkspaces(t)
+	defer resetCommitFlags()
+
+	// Reject commits in the second workspace
+	hook := filepath.Join(dir, paths[1], ".git", "hooks", "pre-commit")
+	os.WriteFile(hook, []byte("#!/bin/sh\necho rejected by hook\nexit 1\n"), 0755)
+
+	before, _ := git.GetCurrentCommit(filepath.Join(dir, paths[0]))
+
+	resetCommitFlags()
+	commitMessage = "Will be rolled back"
+	commitAll = true
+
+	var err error
+	output := captureOutput(func() {
+		err = runCommit(commitCmd, []string{})
+	})
+
+	if err == nil {
+		t.Fatal("runCommit should fail when a hook rejects the commit")
+	}
+	after, _ := git.GetCurrentCommit(filepath.Join(dir, paths[0]))
+	if after != before {
+		t.Errorf("first workspace should be rolled back to %s, got %s", before, after)
+	}
+	staged, _ := git.GetStagedFiles(filepath.Join(dir, paths[0]))
+	if len(staged) != 1 || staged[0] != "README.md" {
+		t.Errorf("rolled back changes should remain staged, got %v", staged)
+	}
+	if !strings.Contains(output, "rejected by hook") || !strings.Contains(output, "↺ apps/api") {
+		t.Errorf("output should show hook output and rollback, got: %s", output)
+	}
+}
+
+func TestRunCommit_NothingToCommit(t *testing.T) {
+	setupCommitWorkspaces(t)
+	defer resetCommitFlags()
+
+	resetCommitFlags()
+	commitMessage = "Nothing"
+
+	output := captureOutput(func() {
+		runCommit(commitCmd, []string{})
+	})
+	if !strings.Contains(output, "Nothing to commit") {
+		t.Errorf("expected nothing to commit, got: %s", output)
+	}
+
+	resetCommitFlags()
+	if err := runCommit(commitCmd, []string{}); err == nil {
+		t.Error("runCommit should require a message")
+	}
+}
+
+func TestBuildCommitMessage(t *testing.T) {
+	got := buildCommitMessage("Subject\n", []string{"Change-Id: I1", "Multirepo-Topic=x"})
+	want := "Subject\n\nChange-Id: I1\nMultirepo-Topic: x"
+	if got != want {
+		t.Errorf("buildCommitMessage = %q, want %q", got, want)
+	}
+}
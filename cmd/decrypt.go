@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/backup"
+)
+
+var decryptOutput string
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt <file>",
+	Short: "Print the decrypted content of a backup, patch or archive",
+	Long: `Print the content of an encrypted backup, patch or archive.
+
+Backups and patches of keep files marked sensitive, and archives written
+while a key is configured, are encrypted with the key from
+GIT_MULTIREPO_PASSPHRASE or GIT_MULTIREPO_KEYFILE. Plain files are printed
+as they are. Backups are index entries into .multirepos/backup/objects;
+their content is printed.
+
+Examples:
+  git multirepo decrypt .multirepos/patches/apps/api/.env.patch
+  git multirepo decrypt .multirepos/backup/modified/.../.env.20260109_143022 -o apps/api/.env
+  git multirepo decrypt .multirepos/backup/archived/.../2025-12-main.tar.gz.enc | tar -xz`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDecrypt,
+}
+
+func init() {
+	// Command registered in root.go init() in workflow order
+	decryptCmd.Flags().StringVarP(&decryptOutput, "output", "o", "", "Write to a file instead of stdout")
+}
+
+func runDecrypt(cmd *cobra.Command, args []string) error {
+	content, err := backup.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	if decryptOutput == "" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	if err := os.WriteFile(decryptOutput, content, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", decryptOutput, err)
+	}
+	return nil
+}
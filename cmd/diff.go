@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/interactive"
+)
+
+var (
+	diffStat        bool
+	diffNameOnly    bool
+	diffExcludeKeep bool
+	diffGroups      []string
+	diffNoParent    bool
+	diffNoPager     bool
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [path...]",
+	Short: "Show uncommitted changes across all repositories",
+	Long: `Show uncommitted changes of the parent repository and all workspaces
+in a single pager session. File paths are prefixed with the workspace path.
+
+Keep files are hidden from git by skip-worktree. diff temporarily unskips
+them so local-only changes are shown too. Use --exclude-keep to hide them.
+
+Examples:
+  git multirepo diff                    # All repositories
+  git multirepo diff packages/lib       # Specific workspace
+  git multirepo diff --stat             # Diffstat per workspace
+  git multirepo diff --name-only        # Changed files only
+  git multirepo diff --exclude-keep     # Hide local config changes`,
+	RunE: runDiff,
+}
+
+func init() {
+	diffCmd.Flags().BoolVar(&diffStat, "stat", false, "Show diffstat instead of patch")
+	diffCmd.Flags().BoolVar(&diffNameOnly, "name-only", false, "Show only names of changed files")
+	diffCmd.Flags().BoolVar(&diffExcludeKeep, "exclude-keep", false, "Hide changes to keep files")
+	diffCmd.Flags().StringSliceVarP(&diffGroups, "group", "g", nil, "Only include workspaces in group (repeatable)")
+	diffCmd.Flags().BoolVar(&diffNoParent, "no-parent", false, "Exclude the parent repository")
+	diffCmd.Flags().BoolVar(&diffNoPager, "no-pager", false, "Print to stdout instead of a pager")
+}
+
+// diffTarget is a repository to collect changes from
+type diffTarget struct {
+	label string
+	path  string
+	keep  []string
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	workspaces, err := ctx.SelectWorkspaces(args, diffGroups)
+	if err != nil {
+		return err
+	}
+
+	// Parent is included only when no path/group filter narrows the selection
+	var targets []diffTarget
+	if !diffNoParent && len(args) == 0 && len(diffGroups) == 0 {
+		targets = append(targets, diffTarget{label: ".", path: ctx.RepoRoot, keep: expandKeep(ctx.RepoRoot, ctx.Manifest.Keep)})
+	}
+	for _, ws := range workspaces {
+		fullPath := filepath.Join(ctx.RepoRoot, ws.Path)
+		if !git.IsRepo(fullPath) {
+			continue
+		}
+		targets = append(targets, diffTarget{label: ws.Path, path: fullPath, keep: expandKeep(fullPath, ws.Keep)})
+	}
+
+	usePager := !diffNoPager && interactive.IsTerminal(os.Stdout)
+	output := collectDiffs(targets, usePager && !color.NoColor)
+
+	if output == "" {
+		fmt.Println("No uncommitted changes")
+		return nil
+	}
+
+	if usePager {
+		return interactive.ShowDiff(output)
+	}
+	fmt.Print(output)
+	return nil
+}
+
+// collectDiffs builds the combined diff output for all targets
+func collectDiffs(targets []diffTarget, useColor bool) string {
+	var buf strings.Builder
+
+	for _, t := range targets {
+		out, err := diffTargetOutput(t, useColor)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ %s: %v\n", t.label, err)
+			continue
+		}
+		if strings.TrimSpace(out) == "" {
+			continue
+		}
+
+		switch {
+		case diffNameOnly:
+			// Prefix each file with the workspace path
+			for _, file := range strings.Split(strings.TrimSpace(out), "\n") {
+				buf.WriteString(joinWorkspacePath(t.label, file) + "\n")
+			}
+		case diffStat:
+			header := fmt.Sprintf("%s:\n", t.label)
+			if useColor {
+				header = colorCyan.Sprint(header)
+			}
+			buf.WriteString(header)
+			buf.WriteString(out)
+			buf.WriteString("\n")
+		default:
+			buf.WriteString(out)
+		}
+	}
+
+	return buf.String()
+}
+
+// diffTargetOutput returns the diff for a single target, respecting keep file handling
+func diffTargetOutput(t diffTarget, useColor bool) (string, error) {
+	opts := git.DiffOptions{
+		Stat:     diffStat,
+		NameOnly: diffNameOnly,
+		Color:    useColor,
+	}
+	if t.label != "." {
+		opts.Prefix = t.label
+	}
+
+	// Keep files are excluded entirely - no need to unskip them
+	if diffExcludeKeep {
+		opts.Exclude = t.keep
+		return git.Diff(t.path, opts)
+	}
+
+	var out string
+	err := git.WithSkipWorktreeTransaction(t.path, t.keep, func() error {
+		var err error
+		out, err = git.Diff(t.path, opts)
+		return err
+	})
+	return out, err
+}
+
+// joinWorkspacePath joins a workspace label and a repository-relative file path
+func joinWorkspacePath(label, file string) string {
+	if label == "." || label == "" {
+		return file
+	}
+	return label + "/" + file
+}
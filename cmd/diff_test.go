@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/manifest"
+)
+
+// resetDiffFlags restores diff command flags to their defaults (pager disabled)
+func resetDiffFlags() {
+	diffStat = false
+	diffNameOnly = false
+	diffExcludeKeep = false
+	diffGroups = nil
+	diffNoParent = false
+	diffNoPager = true
+}
+
+// setupDiffWorkspace creates a workspace with one modified regular file and one modified keep file
+func setupDiffWorkspace(t *testing.T) (string, string) {
+	t.Helper()
+	dir, cleanup := setupTestEnv(t)
+	t.Cleanup(cleanup)
+
+	remoteRepo := setupRemoteRepo(t)
+	os.WriteFile(filepath.Join(remoteRepo, "config.json"), []byte("{}\n"), 0644)
+	exec.Command("git", "-C", remoteRepo, "add", ".").Run()
+	exec.Command("git", "-C", remoteRepo, "commit", "-m", "Add config").Run()
+
+	cloneBranch = ""
+	runClone(cloneCmd, []string{remoteRepo, "packages/lib"})
+	wsPath := filepath.Join(dir, "packages/lib")
+
+	m, _ := manifest.Load(dir)
+	m.Find("packages/lib").Keep = []string{"config.json"}
+	manifest.Save(dir, m)
+
+	os.WriteFile(filepath.Join(wsPath, "config.json"), []byte("{\"local\": true}\n"), 0644)
+	os.WriteFile(filepath.Join(wsPath, "README.md"), []byte("# Changed\n"), 0644)
+	git.ApplySkipWorktree(wsPath, []string{"config.json"})
+
+	return dir, wsPath
+}
+
+func TestRunDiff_IncludesKeepFiles(t *testing.T) {
+	_, wsPath := setupDiffWorkspace(t)
+	defer resetDiffFlags()
+
+	t.Run("full diff with workspace prefix", func(t *testing.T) {
+		resetDiffFlags()
+
+		output := captureOutput(func() {
+			if err := runDiff(diffCmd, []string{"packages/lib"}); err != nil {
+				t.Errorf("runDiff failed: %v", err)
+			}
+		})
+
+		if !strings.Contains(output, "a/packages/lib/README.md") {
+			t.Errorf("diff paths should be prefixed with workspace, got: %s", output)
+		}
+		if !strings.Contains(output, "a/packages/lib/config.json") {
+			t.Errorf("diff should include keep file, got: %s", output)
+		}
+	})
+
+	t.Run("keep files stay skipped afterwards", func(t *testing.T) {
+		skipped, _ := git.ListSkipWorktree(wsPath)
+		if len(skipped) != 1 || skipped[0] != "config.json" {
+			t.Errorf("skip-worktree should be re-applied, got %v", skipped)
+		}
+	})
+
+	t.Run("exclude keep", func(t *testing.T) {
+		resetDiffFlags()
+		diffExcludeKeep = true
+
+		output := captureOutput(func() {
+			runDiff(diffCmd, []string{"packages/lib"})
+		})
+
+		if strings.Contains(output, "config.json") {
+			t.Errorf("keep file should be hidden, got: %s", output)
+		}
+		if !strings.Contains(output, "README.md") {
+			t.Errorf("regular change should be shown, got: %s", output)
+		}
+	})
+
+	t.Run("name only", func(t *testing.T) {
+		resetDiffFlags()
+		diffNameOnly = true
+
+		output := captureOutput(func() {
+			runDiff(diffCmd, []string{"packages/lib"})
+		})
+
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 files, got: %s", output)
+		}
+		for _, line := range lines {
+			if !strings.HasPrefix(line, "packages/lib/") {
+				t.Errorf("file should be prefixed with workspace, got %q", line)
+			}
+		}
+	})
+
+	t.Run("stat", func(t *testing.T) {
+		resetDiffFlags()
+		diffStat = true
+
+		output := captureOutput(func() {
+			runDiff(diffCmd, []string{"packages/lib"})
+		})
+
+		if !strings.Contains(output, "packages/lib:") || !strings.Contains(output, "2 files changed") {
+			t.Errorf("stat should show header and summary, got: %s", output)
+		}
+	})
+}
+
+func TestRunDiff_NoChanges(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer resetDiffFlags()
+
+	resetDiffFlags()
+	output := captureOutput(func() {
+		if err := runDiff(diffCmd, []string{}); err != nil {
+			t.Errorf("runDiff failed: %v", err)
+		}
+	})
+
+	if !strings.Contains(output, "No uncommitted changes") {
+		t.Errorf("expected no changes message, got: %s", output)
+	}
+}
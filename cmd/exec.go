@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/manifest"
+)
+
+var (
+	execParallel int
+	execGroups   []string
+	execFailFast bool
+)
+
+var execCmd = &cobra.Command{
+	Use:     "exec [path...] -- <command> [args...]",
+	Aliases: []string{"foreach"},
+	Short:   "Run a command in every workspace",
+	Long: `Run a command in the directory of every workspace.
+
+The command is executed directly (not through a shell). Use 'sh -c' for
+pipes, redirects or variable expansion.
+
+Environment variables set for each run:
+  MULTIREPO_ROOT    Absolute path of the parent repository
+  MULTIREPO_PATH    Workspace path relative to the parent
+  MULTIREPO_REPO    Workspace repository URL
+  MULTIREPO_BRANCH  Current workspace branch
+
+With --parallel 1 (default) output is streamed as it happens. With higher
+values output is buffered and printed per workspace when it finishes.
+
+Examples:
+  git multirepo exec -- git fetch
+  git multirepo exec --parallel 4 --group backend -- make test
+  git multirepo exec apps/api apps/web -- npm ci
+  git multirepo exec -- sh -c 'echo "$MULTIREPO_PATH: $(git rev-parse HEAD)"'`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runExec,
+}
+
+func init() {
+	execCmd.Flags().IntVarP(&execParallel, "parallel", "j", 1, "Number of workspaces to run concurrently")
+	execCmd.Flags().StringSliceVarP(&execGroups, "group", "g", nil, "Only run in workspaces in group (repeatable)")
+	execCmd.Flags().BoolVar(&execFailFast, "fail-fast", false, "Stop starting new workspaces after the first failure")
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	// Split "[path...] -- command" into filters and the command itself
+	var paths, command []string
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		paths, command = args[:dash], args[dash:]
+	} else {
+		command = args
+	}
+	if len(command) == 0 {
+		return fmt.Errorf("no command specified (usage: git multirepo exec [path...] -- <command>)")
+	}
+
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	workspaces, err := ctx.SelectWorkspaces(paths, execGroups)
+	if err != nil {
+		return err
+	}
+
+	// Skip workspaces that are not cloned yet
+	var runnable []manifest.WorkspaceEntry
+	notCloned := 0
+	for _, ws := range workspaces {
+		if !git.IsRepo(filepath.Join(ctx.RepoRoot, ws.Path)) {
+			colorYellow.Fprintf(os.Stdout, "→ Skipping %s (not cloned)\n", ws.Path)
+			notCloned++
+			continue
+		}
+		runnable = append(runnable, ws)
+	}
+
+	if len(runnable) == 0 {
+		fmt.Println("No workspaces to run in")
+		return nil
+	}
+
+	buffered := execParallel > 1
+	var outMu sync.Mutex
+	var durationsMu sync.Mutex
+	durations := make(map[string]time.Duration)
+
+	runCtx := commandContext(cmd)
+	results := ctx.ForEachWorkspaceParallel(runnable, common.ParallelOptions{
+		Workers:  execParallel,
+		FailFast: execFailFast,
+		Context:  runCtx,
+	}, func(ws *manifest.WorkspaceEntry, fullPath string) error {
+		var buf bytes.Buffer
+		var stdout, stderr io.Writer = os.Stdout, os.Stderr
+		if buffered {
+			stdout, stderr = &buf, &buf
+		} else {
+			printCyan("\n%s\n", ws.Path)
+		}
+
+		start := time.Now()
+		runErr := runInWorkspace(runCtx, ctx.RepoRoot, ws, fullPath, command, stdout, stderr)
+		elapsed := time.Since(start)
+
+		if buffered {
+			outMu.Lock()
+			printCyan("\n%s\n", ws.Path)
+			os.Stdout.Write(buf.Bytes())
+			outMu.Unlock()
+		}
+
+		durationsMu.Lock()
+		durations[ws.Path] = elapsed
+		durationsMu.Unlock()
+
+		return runErr
+	})
+
+	// Summary
+	fmt.Println()
+	printFaint("Summary:\n")
+	failed, skipped := 0, 0
+	for _, r := range results {
+		d := durations[r.Workspace.Path].Round(time.Millisecond)
+		switch {
+		case r.Skipped:
+			skipped++
+			colorFaint.Fprintf(os.Stdout, "  - %s (skipped)\n", r.Workspace.Path)
+		case r.Err != nil:
+			failed++
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s (%v, %s)\n", r.Workspace.Path, r.Err, d)
+		default:
+			printGreen("  ✓ %s (%s)\n", r.Workspace.Path, d)
+		}
+	}
+
+	fmt.Println()
+	passed := len(results) - failed - skipped
+	fmt.Printf("%d passed, %d failed, %d skipped\n", passed, failed, skipped+notCloned)
+
+	if failed > 0 {
+		return fmt.Errorf("command failed in %d workspace(s)", failed)
+	}
+	return nil
+}
+
+// runInWorkspace runs the command in a workspace directory with MULTIREPO_* variables set
+// Cancelling runCtx (e.g., Ctrl-C) interrupts the command, and kills it if it
+// does not exit within execWaitDelay.
+func runInWorkspace(runCtx context.Context, repoRoot string, ws *manifest.WorkspaceEntry, fullPath string, command []string, stdout, stderr io.Writer) error {
+	branch, err := git.GetCurrentBranch(fullPath)
+	if err != nil {
+		branch = ws.Branch
+	}
+
+	c := exec.CommandContext(runCtx, command[0], command[1:]...)
+	c.Cancel = func() error {
+		if err := c.Process.Signal(os.Interrupt); err != nil {
+			return c.Process.Kill()
+		}
+		return nil
+	}
+	c.WaitDelay = execWaitDelay
+	c.Dir = fullPath
+	c.Stdout = stdout
+	c.Stderr = stderr
+	c.Env = append(os.Environ(),
+		"MULTIREPO_ROOT="+repoRoot,
+		"MULTIREPO_PATH="+ws.Path,
+		"MULTIREPO_REPO="+ws.Repo,
+		"MULTIREPO_BRANCH="+branch,
+	)
+
+	return c.Run()
+}
+
+// execWaitDelay is how long an interrupted command may take to exit before it is killed
+const execWaitDelay = 5 * time.Second
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yejune/git-multirepo/internal/manifest"
+)
+
+// resetExecFlags restores exec command flags to their defaults
+func resetExecFlags() {
+	execParallel = 1
+	execGroups = nil
+	execFailFast = false
+}
+
+// setupExecWorkspaces creates a parent repository with two cloned workspaces
+func setupExecWorkspaces(t *testing.T) string {
+	t.Helper()
+	dir, cleanup := setupTestEnv(t)
+	t.Cleanup(cleanup)
+
+	remoteRepo := setupRemoteRepo(t)
+	cloneBranch = ""
+	runClone(cloneCmd, []string{remoteRepo, "apps/api"})
+	runClone(cloneCmd, []string{remoteRepo, "apps/web"})
+
+	return dir
+}
+
+func TestRunExec_SetsEnvironment(t *testing.T) {
+	dir := setupExecWorkspaces(t)
+	defer resetExecFlags()
+
+	for _, parallel := range []int{1, 2} {
+		resetExecFlags()
+		execParallel = parallel
+		execCmd.Flags().Parse([]string{"--", "sh", "-c", "echo path=$MULTIREPO_PATH root=$MULTIREPO_ROOT > out.txt"})
+
+		output := captureOutput(func() {
+			if err := runExec(execCmd, execCmd.Flags().Args()); err != nil {
+				t.Errorf("runExec failed: %v", err)
+			}
+		})
+
+		if !strings.Contains(output, "2 passed, 0 failed") {
+			t.Errorf("parallel=%d: expected summary, got: %s", parallel, output)
+		}
+
+		for _, ws := range []string{"apps/api", "apps/web"} {
+			data, err := os.ReadFile(filepath.Join(dir, ws, "out.txt"))
+			if err != nil {
+				t.Fatalf("command did not run in %s: %v", ws, err)
+			}
+			want := "path=" + ws + " root=" + dir
+			if strings.TrimSpace(string(data)) != want {
+				t.Errorf("got %q, want %q", strings.TrimSpace(string(data)), want)
+			}
+		}
+	}
+}
+
+func TestRunExec_PathFilterAndFailure(t *testing.T) {
+	setupExecWorkspaces(t)
+	defer resetExecFlags()
+
+	t.Run("failure is reported", func(t *testing.T) {
+		resetExecFlags()
+		execCmd.Flags().Parse([]string{"apps/api", "--", "false"})
+
+		var err error
+		output := captureOutput(func() {
+			err = runExec(execCmd, execCmd.Flags().Args())
+		})
+
+		if err == nil {
+			t.Error("runExec should return error when command fails")
+		}
+		if !strings.Contains(output, "✗ apps/api") || strings.Contains(output, "apps/web") {
+			t.Errorf("expected only apps/api failure, got: %s", output)
+		}
+	})
+
+	t.Run("fail fast skips remaining", func(t *testing.T) {
+		resetExecFlags()
+		execFailFast = true
+		execCmd.Flags().Parse([]string{"--", "false"})
+
+		output := captureOutput(func() {
+			runExec(execCmd, execCmd.Flags().Args())
+		})
+
+		if !strings.Contains(output, "0 passed, 1 failed, 1 skipped") {
+			t.Errorf("expected second workspace to be skipped, got: %s", output)
+		}
+	})
+
+	t.Run("missing command", func(t *testing.T) {
+		resetExecFlags()
+		execCmd.Flags().Parse([]string{"apps/api", "--"})
+
+		if err := runExec(execCmd, execCmd.Flags().Args()); err == nil {
+			t.Error("runExec should fail without a command")
+		}
+	})
+}
+
+func TestRunInWorkspace_Cancelled(t *testing.T) {
+	dir := t.TempDir()
+	runCtx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	ws := &manifest.WorkspaceEntry{Path: "apps/api"}
+	err := runInWorkspace(runCtx, dir, ws, dir, []string{"sleep", "10"}, io.Discard, io.Discard)
+	if err == nil {
+		t.Error("expected error for a cancelled command")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("command should stop on cancellation, ran %v", elapsed)
+	}
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/manifest"
+)
+
+var (
+	grepIgnoreCase bool
+	grepFilesOnly  bool
+	grepJSON       bool
+	grepRef        string
+	grepGroups     []string
+	grepNoParent   bool
+)
+
+var grepCmd = &cobra.Command{
+	Use:   "grep <pattern> [path...] [-- pathspec...]",
+	Short: "Search tracked files across all repositories",
+	Long: `Search tracked files of the parent repository and all workspaces
+with git grep. Repositories are searched in parallel and results are shown
+with paths relative to the parent repository.
+
+Only tracked files are searched, so ignored build output is skipped.
+
+Positional paths select workspaces. Pathspecs after -- are passed to
+git grep in every repository.
+
+Examples:
+  git multirepo grep TODO
+  git multirepo grep -i "api key" apps/api
+  git multirepo grep -l NewClient -- '*.go'
+  git multirepo grep --ref main FeatureFlag
+  git multirepo grep --json deprecated`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runGrep,
+}
+
+func init() {
+	grepCmd.Flags().BoolVarP(&grepIgnoreCase, "ignore-case", "i", false, "Case-insensitive matching")
+	grepCmd.Flags().BoolVarP(&grepFilesOnly, "files-with-matches", "l", false, "Only show names of matching files")
+	grepCmd.Flags().BoolVar(&grepJSON, "json", false, "Output matches as JSON")
+	grepCmd.Flags().StringVar(&grepRef, "ref", "", "Search the given branch or revision in each repository")
+	grepCmd.Flags().StringSliceVarP(&grepGroups, "group", "g", nil, "Only search workspaces in group (repeatable)")
+	grepCmd.Flags().BoolVar(&grepNoParent, "no-parent", false, "Exclude the parent repository")
+}
+
+// grepEntry is a match labeled with the repository it belongs to
+type grepEntry struct {
+	Workspace string `json:"workspace"`
+	File      string `json:"file"` // Relative to the parent repository
+	Line      int    `json:"line,omitempty"`
+	Text      string `json:"text,omitempty"`
+}
+
+func runGrep(cmd *cobra.Command, args []string) error {
+	// Split "<pattern> [path...] -- pathspec..." into its parts
+	pattern := args[0]
+	paths := args[1:]
+	var pathspecs []string
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		if dash == 0 {
+			return fmt.Errorf("pattern must come before --")
+		}
+		paths, pathspecs = args[1:dash], args[dash:]
+	}
+
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	workspaces, err := ctx.SelectWorkspaces(paths, grepGroups)
+	if err != nil {
+		return err
+	}
+
+	// The parent is searched as a pseudo-workspace at "." so it runs in the same pool
+	var targets []manifest.WorkspaceEntry
+	if !grepNoParent && len(paths) == 0 && len(grepGroups) == 0 {
+		targets = append(targets, manifest.WorkspaceEntry{Path: "."})
+	}
+	for _, ws := range workspaces {
+		if git.IsRepo(filepath.Join(ctx.RepoRoot, ws.Path)) {
+			targets = append(targets, ws)
+		}
+	}
+
+	opts := git.GrepOptions{
+		IgnoreCase: grepIgnoreCase,
+		FilesOnly:  grepFilesOnly,
+		Ref:        grepRef,
+		Pathspecs:  pathspecs,
+	}
+
+	// Each handler writes only its own slot, so no locking is needed
+	matches := make([][]git.GrepMatch, len(targets))
+	index := make(map[string]int, len(targets))
+	for i, t := range targets {
+		index[t.Path] = i
+	}
+
+	results := ctx.ForEachWorkspaceParallel(targets, common.ParallelOptions{
+		Workers: getOptimalWorkerCount(),
+	}, func(ws *manifest.WorkspaceEntry, fullPath string) error {
+		found, err := git.Grep(fullPath, pattern, opts)
+		matches[index[ws.Path]] = found
+		return err
+	})
+
+	// Merge results in target order (parent first, then manifest order)
+	var entries []grepEntry
+	for i, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ %s: %v\n", r.Workspace.Path, r.Err)
+			continue
+		}
+		for _, m := range matches[i] {
+			entries = append(entries, grepEntry{
+				Workspace: r.Workspace.Path,
+				File:      joinWorkspacePath(r.Workspace.Path, m.File),
+				Line:      m.Line,
+				Text:      m.Text,
+			})
+		}
+	}
+
+	if grepJSON {
+		if entries == nil {
+			entries = []grepEntry{}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode matches: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for _, e := range entries {
+		if grepFilesOnly {
+			colorCyan.Fprintf(os.Stdout, "%s\n", e.File)
+			continue
+		}
+		colorCyan.Fprintf(os.Stdout, "%s", e.File)
+		fmt.Printf(":")
+		colorGreen.Fprintf(os.Stdout, "%d", e.Line)
+		fmt.Printf(":%s\n", e.Text)
+	}
+
+	return nil
+}
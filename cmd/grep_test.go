@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// resetGrepFlags restores grep command flags to their defaults
+func resetGrepFlags() {
+	grepIgnoreCase = false
+	grepFilesOnly = false
+	grepJSON = false
+	grepRef = ""
+	grepGroups = nil
+	grepNoParent = false
+}
+
+func TestRunGrep(t *testing.T) {
+	dir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer resetGrepFlags()
+
+	remoteRepo := setupRemoteRepo(t)
+	os.WriteFile(filepath.Join(remoteRepo, "main.go"), []byte("package main\n// TODO: fix\n"), 0644)
+	exec.Command("git", "-C", remoteRepo, "add", ".").Run()
+	exec.Command("git", "-C", remoteRepo, "commit", "-m", "Add main").Run()
+
+	cloneBranch = ""
+	runClone(cloneCmd, []string{remoteRepo, "apps/api"})
+
+	// Parent match and an untracked file that must be ignored
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("todo: parent\n"), 0644)
+	exec.Command("git", "-C", dir, "add", "notes.txt").Run()
+	exec.Command("git", "-C", dir, "commit", "-m", "Add notes").Run()
+	os.WriteFile(filepath.Join(dir, "apps/api/build.log"), []byte("TODO: untracked\n"), 0644)
+
+	t.Run("paths are relative to parent", func(t *testing.T) {
+		resetGrepFlags()
+
+		output := captureOutput(func() {
+			if err := runGrep(grepCmd, []string{"TODO"}); err != nil {
+				t.Errorf("runGrep failed: %v", err)
+			}
+		})
+
+		if !strings.Contains(output, "apps/api/main.go:2:// TODO: fix") {
+			t.Errorf("expected workspace match, got: %s", output)
+		}
+		if strings.Contains(output, "build.log") {
+			t.Errorf("untracked files should not be searched, got: %s", output)
+		}
+		if strings.Contains(output, "notes.txt") {
+			t.Errorf("case-sensitive search should not match parent, got: %s", output)
+		}
+	})
+
+	t.Run("ignore case includes parent", func(t *testing.T) {
+		resetGrepFlags()
+		grepIgnoreCase = true
+		grepFilesOnly = true
+
+		output := captureOutput(func() {
+			runGrep(grepCmd, []string{"todo"})
+		})
+
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		if len(lines) != 2 || lines[0] != "notes.txt" || lines[1] != "apps/api/main.go" {
+			t.Errorf("unexpected files: %q", lines)
+		}
+	})
+
+	t.Run("json with ref", func(t *testing.T) {
+		resetGrepFlags()
+		grepJSON = true
+		grepRef = "HEAD"
+
+		output := captureOutput(func() {
+			runGrep(grepCmd, []string{"TODO", "apps/api"})
+		})
+
+		var entries []grepEntry
+		if err := json.Unmarshal([]byte(output), &entries); err != nil {
+			t.Fatalf("invalid JSON: %v\n%s", err, output)
+		}
+		if len(entries) != 1 || entries[0].File != "apps/api/main.go" || entries[0].Line != 2 {
+			t.Errorf("unexpected entries: %+v", entries)
+		}
+	})
+
+	t.Run("pathspec limits search", func(t *testing.T) {
+		resetGrepFlags()
+		grepCmd.Flags().Parse([]string{"TODO", "--", "*.md"})
+
+		output := captureOutput(func() {
+			runGrep(grepCmd, grepCmd.Flags().Args())
+		})
+
+		if strings.TrimSpace(output) != "" {
+			t.Errorf("expected no matches, got: %s", output)
+		}
+	})
+}
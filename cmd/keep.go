@@ -0,0 +1,511 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/backup"
+	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/crypt"
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/manifest"
+	"github.com/yejune/git-multirepo/internal/patch"
+)
+
+var keepRemoveRestore bool
+
+var keepCmd = &cobra.Command{
+	Use:   "keep",
+	Short: "Manage keep files",
+	Long: `Keep files are tracked files with local-only changes (configuration,
+credentials) that git-multirepo protects with skip-worktree, backs up and
+merges with remote changes on pull.
+
+The workspace "." is the parent repository. Entries may be glob patterns
+("config/*.local.yaml", "env/**") or directories ("secrets/").
+
+Examples:
+  git multirepo keep list
+  git multirepo keep add apps/api config.json .env.local
+  git multirepo keep add . docker-compose.override.yml
+  git multirepo keep remove apps/api .env.local --restore`,
+}
+
+var keepAddCmd = &cobra.Command{
+	Use:   "add <workspace> <file>...",
+	Short: "Add files to a keep list",
+	Long: `Add files or patterns to the keep list of a workspace, apply
+skip-worktree to them, and save a backup and patch of files that already
+have local changes.
+
+Every entry must match tracked files.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runKeepAdd,
+}
+
+var keepRemoveCmd = &cobra.Command{
+	Use:   "remove <workspace> <file>...",
+	Short: "Remove files from a keep list",
+	Long: `Remove entries from the keep list of a workspace and clear
+skip-worktree on their files. Local changes then show up as ordinary
+modifications; --restore discards them instead, after taking a backup.
+
+Files still matched by another entry stay kept.`,
+	Aliases: []string{"rm"},
+	Args:    cobra.MinimumNArgs(2),
+	RunE:    runKeepRemove,
+}
+
+var keepListCmd = &cobra.Command{
+	Use:   "list [workspace...]",
+	Short: "List keep files with their skip-worktree and change state",
+	Long: `List the keep files of the parent repository and every workspace.
+
+For each file: whether skip-worktree is set, whether the local file
+differs from HEAD, and whether it differs from the upstream branch
+(as of the last fetch).`,
+	RunE: runKeepList,
+}
+
+func init() {
+	// Command registered in root.go init() in workflow order
+	keepCmd.AddCommand(keepAddCmd)
+	keepCmd.AddCommand(keepRemoveCmd)
+	keepCmd.AddCommand(keepListCmd)
+	keepRemoveCmd.Flags().BoolVar(&keepRemoveRestore, "restore", false, "Discard local changes (restore the files from HEAD)")
+}
+
+// keepTarget is the keep list of the parent repository or of a workspace
+type keepTarget struct {
+	path     string    // Workspace path, "." for the parent repository
+	fullPath string    // Repository directory
+	keep     *[]string // Keep list in the manifest
+}
+
+// resolveKeepTarget finds the keep list for a workspace argument
+func resolveKeepTarget(ctx *common.WorkspaceContext, path string) (*keepTarget, error) {
+	path = strings.TrimSuffix(filepath.ToSlash(filepath.Clean(path)), "/")
+	if path == manifest.ParentPath {
+		return &keepTarget{path: path, fullPath: ctx.RepoRoot, keep: &ctx.Manifest.Keep}, nil
+	}
+
+	ws := ctx.Manifest.Find(path)
+	if ws == nil {
+		return nil, fmt.Errorf("workspace not found: %s", path)
+	}
+	return &keepTarget{path: ws.Path, fullPath: filepath.Join(ctx.RepoRoot, ws.Path), keep: &ws.Keep}, nil
+}
+
+// backupPath returns the workspace path used for backups ("" for the parent repository)
+func (t *keepTarget) backupPath() string {
+	if t.path == manifest.ParentPath {
+		return ""
+	}
+	return t.path
+}
+
+func runKeepAdd(cmd *cobra.Command, args []string) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+	target, err := resolveKeepTarget(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if !git.IsRepo(target.fullPath) {
+		return fmt.Errorf("%s is not cloned (run 'git multirepo sync' first)", target.path)
+	}
+
+	// 1. Validate every entry before changing anything
+	expansions, err := git.ExpandKeepEntries(target.fullPath, args[1:])
+	if err != nil {
+		return err
+	}
+	tracked, err := git.ListTrackedFiles(target.fullPath)
+	if err != nil {
+		return err
+	}
+	trackedSet := make(map[string]bool, len(tracked))
+	for _, f := range tracked {
+		trackedSet[f] = true
+	}
+
+	var added []git.KeepExpansion
+	for _, e := range expansions {
+		if containsString(*target.keep, e.Entry) {
+			printFaint("  - %s: %s is already kept\n", target.path, e.Entry)
+			continue
+		}
+		if e.Invalid != "" {
+			return fmt.Errorf("invalid keep entry %s: %s", e.Entry, e.Invalid)
+		}
+		if len(e.Files) == 0 {
+			return fmt.Errorf("%s matches no tracked files in %s", e.Entry, target.path)
+		}
+		for _, f := range e.Files {
+			if !trackedSet[f] {
+				return fmt.Errorf("%s is not tracked in %s (keep files must be committed first)", f, target.path)
+			}
+		}
+		added = append(added, e)
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	// 2. Save the local changes, then hide them with skip-worktree
+	branch, _ := git.GetCurrentBranch(target.fullPath)
+	head, _ := git.GetCurrentCommit(target.fullPath)
+	for _, e := range added {
+		saved := 0
+		for _, file := range e.Files {
+			modified, err := keepFileDiffers(target.fullPath, "HEAD", file)
+			if err != nil {
+				return err
+			}
+			if !modified {
+				continue
+			}
+			if err := saveKeepFile(commandContext(cmd), ctx, target, file, branch, head); err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			saved++
+		}
+
+		if err := git.ApplySkipWorktree(target.fullPath, e.Files); err != nil {
+			return err
+		}
+		*target.keep = append(*target.keep, e.Entry)
+
+		detail := ""
+		if len(e.Files) > 1 || e.Files[0] != e.Entry {
+			detail = fmt.Sprintf(" (%d file(s))", len(e.Files))
+		}
+		if saved > 0 {
+			detail += fmt.Sprintf(", backup and patch saved for %d modified file(s)", saved)
+		}
+		printGreen("  ✓ %s: keeping %s%s\n", target.path, e.Entry, detail)
+	}
+
+	return ctx.SaveManifest()
+}
+
+// saveKeepFile backs up a modified keep file and stores its patch, like sync does
+func saveKeepFile(runCtx context.Context, ctx *common.WorkspaceContext, target *keepTarget, file, branch, head string) error {
+	repoRoot := ctx.RepoRoot
+	key, err := sensitiveKey(ctx.Manifest, target.path, file)
+	if err != nil {
+		return err
+	}
+
+	backupDir := filepath.Join(repoRoot, ".multirepos", "backup")
+	if err := backup.CreateFileBackupWithKey(filepath.Join(target.fullPath, file), backupDir, repoRoot, target.backupPath(), branch, key); err != nil {
+		return fmt.Errorf("failed to backup: %w", err)
+	}
+
+	return storeKeepPatch(runCtx, repoRoot, target, file, branch, head, key)
+}
+
+// storeKeepPatch stores the patch of a modified keep file in the patch store
+// and backs the patch up; a deleted file is recorded as a tombstone
+func storeKeepPatch(runCtx context.Context, repoRoot string, target *keepTarget, file, branch, head string, key *crypt.Key) error {
+	patchPath := patch.StorePath(repoRoot, target.path, file)
+	if err := patch.CreateWithKey(runCtx, target.fullPath, file, patchPath, key); err != nil {
+		return fmt.Errorf("failed to create patch: %w", err)
+	}
+	record := patch.Record
+	if _, err := os.Stat(filepath.Join(target.fullPath, file)); os.IsNotExist(err) {
+		record = patch.RecordDeletion
+	}
+	if err := record(repoRoot, target.path, file, head, branch); err != nil {
+		return fmt.Errorf("failed to record patch: %w", err)
+	}
+	backupDir := filepath.Join(repoRoot, ".multirepos", "backup")
+	if err := backup.CreatePatchBackup(patchPath, backupDir, target.backupPath(), branch); err != nil {
+		return fmt.Errorf("failed to backup patch: %w", err)
+	}
+	return nil
+}
+
+func runKeepRemove(cmd *cobra.Command, args []string) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+	target, err := resolveKeepTarget(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	// 1. Split the keep list into removed and remaining entries
+	var removed, remaining []string
+	for _, entry := range args[1:] {
+		if !containsString(*target.keep, entry) {
+			return fmt.Errorf("%s is not in the keep list of %s", entry, target.path)
+		}
+	}
+	for _, entry := range *target.keep {
+		if containsString(args[1:], entry) {
+			removed = append(removed, entry)
+		} else {
+			remaining = append(remaining, entry)
+		}
+	}
+
+	// 2. Unskip the files no remaining entry covers
+	if git.IsRepo(target.fullPath) {
+		files := subtractFiles(expandKeep(target.fullPath, removed), expandKeep(target.fullPath, remaining))
+		if err := git.UnapplySkipWorktree(target.fullPath, files); err != nil {
+			return err
+		}
+
+		if keepRemoveRestore {
+			branch, _ := git.GetCurrentBranch(target.fullPath)
+			backupDir := filepath.Join(ctx.RepoRoot, ".multirepos", "backup")
+			for _, file := range files {
+				modified, err := keepFileDiffers(target.fullPath, "HEAD", file)
+				if err != nil {
+					return err
+				}
+				if !modified {
+					continue
+				}
+				key, err := sensitiveKey(ctx.Manifest, target.path, file)
+				if err != nil {
+					return err
+				}
+				if err := backup.CreateFileBackupWithKey(filepath.Join(target.fullPath, file), backupDir, ctx.RepoRoot, target.backupPath(), branch, key); err != nil {
+					return fmt.Errorf("failed to backup %s: %w", file, err)
+				}
+				if err := git.RestoreFileToHEAD(target.fullPath, file); err != nil {
+					return err
+				}
+				printFaint("  - %s: restored %s from HEAD (backup in .multirepos/backup/modified/)\n", target.path, file)
+			}
+		} else if changed, _ := git.GetModifiedFiles(target.fullPath); len(intersectFiles(changed, files)) > 0 {
+			modified := intersectFiles(changed, files)
+			colorYellow.Fprintf(os.Stdout, "  ⚠ %s: local changes now show as modified: %s (--restore discards them)\n", target.path, strings.Join(modified, ", "))
+		}
+	}
+
+	// 3. Update the manifest
+	if remaining == nil {
+		remaining = []string{}
+	}
+	*target.keep = remaining
+	if err := ctx.SaveManifest(); err != nil {
+		return err
+	}
+	for _, entry := range removed {
+		printGreen("  ✓ %s: no longer keeping %s\n", target.path, entry)
+	}
+	return nil
+}
+
+func runKeepList(cmd *cobra.Command, args []string) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	var targets []*keepTarget
+	if len(args) == 0 {
+		targets = append(targets, &keepTarget{path: manifest.ParentPath, fullPath: ctx.RepoRoot, keep: &ctx.Manifest.Keep})
+		for i := range ctx.Manifest.Workspaces {
+			ws := &ctx.Manifest.Workspaces[i]
+			targets = append(targets, &keepTarget{path: ws.Path, fullPath: filepath.Join(ctx.RepoRoot, ws.Path), keep: &ws.Keep})
+		}
+	} else {
+		for _, arg := range args {
+			target, err := resolveKeepTarget(ctx, arg)
+			if err != nil {
+				return err
+			}
+			targets = append(targets, target)
+		}
+	}
+
+	found := 0
+	for _, t := range targets {
+		if len(*t.keep) == 0 {
+			continue
+		}
+		if found > 0 {
+			fmt.Println()
+		}
+		found++
+
+		printCyan("%s\n", t.path)
+		if !git.IsRepo(t.fullPath) {
+			colorYellow.Fprintf(os.Stdout, "  ⚠ not cloned (run 'git multirepo sync' first)\n")
+			continue
+		}
+		printKeepFileStates(t.fullPath, expandKeep(t.fullPath, *t.keep))
+	}
+
+	if found == 0 {
+		fmt.Println("No keep files")
+	}
+	return nil
+}
+
+// printKeepFileStates prints a table of skip-worktree, HEAD and upstream state per keep file
+func printKeepFileStates(repoPath string, files []string) {
+	skipped, _ := git.ListSkipWorktree(repoPath)
+	branch, _ := git.GetCurrentBranch(repoPath)
+	upstream := ""
+	if branch != "" && branch != "HEAD" && git.HasUpstream(repoPath, branch) {
+		upstream = branch + "@{upstream}"
+	}
+
+	width := len("FILE")
+	for _, f := range files {
+		width = max(width, len(f))
+	}
+
+	printFaint("  %-*s  %-13s  %-9s  %s\n", width, "FILE", "SKIP-WORKTREE", "HEAD", "UPSTREAM")
+	for _, file := range files {
+		skip := "no"
+		if containsString(skipped, file) {
+			skip = "yes"
+		}
+		fmt.Printf("  %-*s  %-13s  %-9s  %s\n", width, file, skip, keepFileState(repoPath, "HEAD", file), keepFileState(repoPath, upstream, file))
+	}
+}
+
+// keepFileState describes how a keep file compares with its version at rev
+func keepFileState(repoPath, rev, file string) string {
+	if rev == "" {
+		return "-"
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, file)); os.IsNotExist(err) {
+		return "missing"
+	}
+	differs, err := keepFileDiffers(repoPath, rev, file)
+	if err != nil {
+		return "?"
+	}
+	if differs {
+		return "differs"
+	}
+	return "same"
+}
+
+// keepFileDiffers checks if the working tree file differs from its version at rev
+func keepFileDiffers(repoPath, rev, file string) (bool, error) {
+	atRev, err := git.GetFileAtRev(repoPath, rev, file)
+	if err != nil {
+		return false, err
+	}
+	local, err := os.ReadFile(filepath.Join(repoPath, file))
+	if os.IsNotExist(err) {
+		return atRev != nil, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return atRev == nil || !bytes.Equal(local, atRev), nil
+}
+
+// subtractFiles returns files not present in other
+func subtractFiles(files, other []string) []string {
+	set := make(map[string]bool, len(other))
+	for _, f := range other {
+		set[f] = true
+	}
+	var result []string
+	for _, f := range files {
+		if !set[f] {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// containsString checks if list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitiveKey returns the key to encrypt backups and patches of a file with,
+// or nil if the file is not marked sensitive in the manifest
+// workspace is "" or "." for the parent repository. A sensitive file without
+// a key configured is an error, so it is never written in plain text.
+func sensitiveKey(m *manifest.Manifest, workspace, file string) (*crypt.Key, error) {
+	patterns := m.Sensitive
+	if workspace != "" && workspace != manifest.ParentPath {
+		ws := m.Find(workspace)
+		if ws == nil {
+			return nil, nil
+		}
+		patterns = ws.Sensitive
+	}
+
+	file = filepath.ToSlash(file)
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+		if git.MatchKeepPattern(pattern, file) || strings.HasPrefix(file, pattern+"/") {
+			key, err := crypt.LoadKey()
+			if err != nil {
+				return nil, err
+			}
+			if key == nil {
+				return nil, fmt.Errorf("%s is marked sensitive: %w", file, crypt.ErrNoKey)
+			}
+			return key, nil
+		}
+	}
+	return nil, nil
+}
+
+// expandKeep returns the files a keep list stands for in a repository
+// Glob and directory entries are expanded against the tracked files, so files
+// added later are picked up. If the files can't be listed (e.g. the repository
+// is not cloned) the entries are returned as they are.
+func expandKeep(repoPath string, keep []string) []string {
+	files, err := git.ExpandKeep(repoPath, keep)
+	if err != nil {
+		return keep
+	}
+	return files
+}
+
+// printKeepExpansions shows the keep list of a repository, with the files
+// each glob or directory entry currently expands to
+func printKeepExpansions(repoPath string, keep []string) {
+	expansions, err := git.ExpandKeepEntries(repoPath, keep)
+	if err != nil || len(expansions) == 0 {
+		return
+	}
+
+	fmt.Printf("  Keep files:\n")
+	for _, e := range expansions {
+		if len(e.Files) == 1 && e.Files[0] == strings.TrimSuffix(e.Entry, "/") {
+			printFaint("    %s\n", e.Entry)
+			continue
+		}
+		if e.Invalid != "" {
+			colorYellow.Fprintf(os.Stdout, "    %s → ignored (%s)\n", e.Entry, e.Invalid)
+			continue
+		}
+		if len(e.Files) == 0 {
+			printFaint("    %s → no tracked files match\n", e.Entry)
+			continue
+		}
+		printFaint("    %s → %d file(s)\n", e.Entry, len(e.Files))
+		for _, f := range e.Files {
+			printFaint("      - %s\n", f)
+		}
+	}
+}
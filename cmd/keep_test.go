@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/yejune/git-multirepo/internal/backup"
+	"github.com/yejune/git-multirepo/internal/crypt"
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/manifest"
+	"github.com/yejune/git-multirepo/internal/patch"
+)
+
+func TestKeepPatterns(t *testing.T) {
+	dir, _, _ := setupBranchWorkspaces(t)
+	api := filepath.Join(dir, "apps/api")
+
+	m, _ := manifest.Load(dir)
+	m.Find("apps/api").Keep = []string{"config.json", "env/*.local.json"}
+	manifest.Save(dir, m)
+
+	os.MkdirAll(filepath.Join(api, "env"), 0755)
+	os.WriteFile(filepath.Join(api, "env/db.local.json"), []byte("{}\n"), 0644)
+	exec.Command("git", "-C", api, "add", "env").Run()
+	exec.Command("git", "-C", api, "commit", "-q", "-m", "Add env").Run()
+	os.WriteFile(filepath.Join(api, "env/db.local.json"), []byte("{\"password\": \"local\"}\n"), 0644)
+
+	t.Run("sync expands patterns", func(t *testing.T) {
+		captureOutput(func() {
+			if err := runSync(syncCmd, []string{}); err != nil {
+				t.Errorf("runSync failed: %v", err)
+			}
+		})
+		skipped, _ := git.ListSkipWorktree(api)
+		if !reflect.DeepEqual(skipped, []string{"config.json", "env/db.local.json"}) {
+			t.Errorf("skip-worktree files = %v", skipped)
+		}
+		if _, err := os.Stat(patch.StorePath(dir, "apps/api", "env/db.local.json")); err != nil {
+			t.Errorf("patch should be stored for the matched file: %v", err)
+		}
+		if m, _ := manifest.Load(dir); !reflect.DeepEqual(m.Find("apps/api").Keep, []string{"config.json", "env/*.local.json"}) {
+			t.Errorf("keep list should keep the pattern, got %v", m.Find("apps/api").Keep)
+		}
+	})
+
+	t.Run("new matching files are picked up", func(t *testing.T) {
+		os.WriteFile(filepath.Join(api, "env/cache.local.json"), []byte("{}\n"), 0644)
+		exec.Command("git", "-C", api, "add", "env/cache.local.json").Run()
+		exec.Command("git", "-C", api, "commit", "-q", "-m", "Add cache env").Run()
+
+		captureOutput(func() {
+			runSync(syncCmd, []string{})
+		})
+		skipped, _ := git.ListSkipWorktree(api)
+		if !reflect.DeepEqual(skipped, []string{"config.json", "env/cache.local.json", "env/db.local.json"}) {
+			t.Errorf("skip-worktree files = %v", skipped)
+		}
+	})
+
+	t.Run("status shows the expansion", func(t *testing.T) {
+		output := captureOutput(func() {
+			runStatus(statusCmd, []string{"apps/api"})
+		})
+		for _, want := range []string{"Keep files:", "env/*.local.json → 2 file(s)", "- env/cache.local.json", "- env/db.local.json"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("status should contain %q, got: %s", want, output)
+			}
+		}
+	})
+}
+
+func resetKeepFlags() {
+	keepRemoveRestore = false
+}
+
+func TestRunKeep(t *testing.T) {
+	dir, _, _ := setupBranchWorkspaces(t)
+	defer resetKeepFlags()
+	resetKeepFlags()
+	api, web := filepath.Join(dir, "apps/api"), filepath.Join(dir, "apps/web")
+
+	os.WriteFile(filepath.Join(api, "settings.json"), []byte("{}\n"), 0644)
+	exec.Command("git", "-C", api, "add", "settings.json").Run()
+	exec.Command("git", "-C", api, "commit", "-q", "-m", "Add settings").Run()
+	os.WriteFile(filepath.Join(api, "settings.json"), []byte("{\"debug\": true}\n"), 0644)
+
+	t.Run("add", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := runKeepAdd(keepAddCmd, []string{"apps/api", "settings.json", "config.json"}); err != nil {
+				t.Errorf("runKeepAdd failed: %v", err)
+			}
+		})
+		if !strings.Contains(output, "keeping settings.json, backup and patch saved") || !strings.Contains(output, "config.json is already kept") {
+			t.Errorf("unexpected output: %s", output)
+		}
+
+		m, _ := manifest.Load(dir)
+		if !reflect.DeepEqual(m.Find("apps/api").Keep, []string{"config.json", "settings.json"}) {
+			t.Errorf("keep list = %v", m.Find("apps/api").Keep)
+		}
+		if skipped, _ := git.ListSkipWorktree(api); !reflect.DeepEqual(skipped, []string{"config.json", "settings.json"}) {
+			t.Errorf("skip-worktree files = %v", skipped)
+		}
+		if idx, _ := patch.LoadIndex(dir); idx.Find("apps/api", "settings.json") == nil {
+			t.Error("patch should be recorded for the modified file")
+		}
+	})
+
+	t.Run("add refuses untracked files", func(t *testing.T) {
+		os.WriteFile(filepath.Join(api, "local.txt"), []byte("x"), 0644)
+		err := runKeepAdd(keepAddCmd, []string{"apps/api", "local.txt"})
+		if err == nil || !strings.Contains(err.Error(), "not tracked") {
+			t.Errorf("expected not tracked error, got %v", err)
+		}
+		if err := runKeepAdd(keepAddCmd, []string{"apps/unknown", "config.json"}); err == nil {
+			t.Error("expected error for unknown workspace")
+		}
+		if err := runKeepAdd(keepAddCmd, []string{"apps/api", "."}); err == nil || !strings.Contains(err.Error(), "invalid keep entry") {
+			t.Errorf("expected invalid entry error, got %v", err)
+		}
+		if m, _ := manifest.Load(dir); len(m.Find("apps/api").Keep) != 2 {
+			t.Errorf("keep list should be unchanged, got %v", m.Find("apps/api").Keep)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := runKeepList(keepListCmd, nil); err != nil {
+				t.Errorf("runKeepList failed: %v", err)
+			}
+		})
+		for _, want := range []string{"apps/api", "apps/web", "SKIP-WORKTREE"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("list should contain %q, got: %s", want, output)
+			}
+		}
+		if !regexp.MustCompile(`settings\.json\s+yes\s+differs\s+differs`).MatchString(output) {
+			t.Errorf("settings.json should be skipped and differ from HEAD and upstream, got: %s", output)
+		}
+	})
+
+	t.Run("remove with restore", func(t *testing.T) {
+		keepRemoveRestore = true
+		defer resetKeepFlags()
+
+		captureOutput(func() {
+			if err := runKeepRemove(keepRemoveCmd, []string{"apps/api", "settings.json"}); err != nil {
+				t.Errorf("runKeepRemove failed: %v", err)
+			}
+		})
+		if content, _ := os.ReadFile(filepath.Join(api, "settings.json")); string(content) != "{}\n" {
+			t.Errorf("settings.json should be restored from HEAD, got %q", content)
+		}
+		if skipped, _ := git.ListSkipWorktree(api); !reflect.DeepEqual(skipped, []string{"config.json"}) {
+			t.Errorf("skip-worktree files = %v", skipped)
+		}
+		if m, _ := manifest.Load(dir); !reflect.DeepEqual(m.Find("apps/api").Keep, []string{"config.json"}) {
+			t.Errorf("keep list = %v", m.Find("apps/api").Keep)
+		}
+	})
+
+	t.Run("remove keeps local changes", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := runKeepRemove(keepRemoveCmd, []string{"apps/web", "config.json"}); err != nil {
+				t.Errorf("runKeepRemove failed: %v", err)
+			}
+		})
+		if content, _ := os.ReadFile(filepath.Join(web, "config.json")); string(content) != "{\"local\": true}\n" {
+			t.Errorf("local changes should stay, got %q", content)
+		}
+		if !strings.Contains(output, "now show as modified: config.json") {
+			t.Errorf("expected modified warning, got: %s", output)
+		}
+		if err := runKeepRemove(keepRemoveCmd, []string{"apps/web", "config.json"}); err == nil {
+			t.Error("expected error for an entry not in the keep list")
+		}
+	})
+}
+
+func TestSensitiveKeepFiles(t *testing.T) {
+	dir, _, _ := setupBranchWorkspaces(t)
+	api := filepath.Join(dir, "apps/api")
+	patchPath := patch.StorePath(dir, "apps/api", "config.json")
+
+	m, _ := manifest.Load(dir)
+	m.Find("apps/api").Sensitive = []string{"*.json"}
+	manifest.Save(dir, m)
+
+	t.Run("refuses plain text without a key", func(t *testing.T) {
+		t.Setenv(crypt.EnvPassphrase, "")
+		t.Setenv(crypt.EnvKeyfile, "")
+
+		issues := 0
+		output := captureOutput(func() {
+			processKeepFiles(context.Background(), loadManifest(t, dir), dir, api, []string{"config.json"}, &issues)
+		})
+		if issues != 1 || !strings.Contains(output, "marked sensitive") {
+			t.Errorf("expected one issue about the missing key, got %d: %s", issues, output)
+		}
+		if _, err := os.Stat(patchPath); !os.IsNotExist(err) {
+			t.Error("no patch should be written without a key")
+		}
+	})
+
+	t.Setenv(crypt.EnvPassphrase, "test passphrase")
+	t.Setenv(crypt.EnvKeyfile, "")
+
+	t.Run("sync encrypts backups and patches", func(t *testing.T) {
+		issues := 0
+		captureOutput(func() {
+			processKeepFiles(context.Background(), loadManifest(t, dir), dir, api, []string{"config.json"}, &issues)
+		})
+		if issues != 0 {
+			t.Fatalf("processKeepFiles reported %d issue(s)", issues)
+		}
+
+		raw, _ := os.ReadFile(patchPath)
+		if !crypt.IsEncrypted(raw) {
+			t.Error("patch should be encrypted")
+		}
+		filepath.Walk(filepath.Join(dir, ".multirepos", "backup"), func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				if content, _ := os.ReadFile(path); strings.Contains(string(content), "local") {
+					t.Errorf("backup %s is not encrypted", path)
+				}
+			}
+			return nil
+		})
+	})
+
+	t.Run("patches are decrypted transparently", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := runPatchesShow(patchesShowCmd, []string{"apps/api/config.json"}); err != nil {
+				t.Errorf("runPatchesShow failed: %v", err)
+			}
+		})
+		if !strings.Contains(output, "+{\"local\": true}") {
+			t.Errorf("show should print the decrypted patch, got: %s", output)
+		}
+
+		os.WriteFile(filepath.Join(api, "config.json"), []byte("{}\n"), 0644)
+		captureOutput(func() {
+			if err := runPatchesApply(patchesApplyCmd, []string{"apps/api/config.json"}); err != nil {
+				t.Errorf("runPatchesApply failed: %v", err)
+			}
+		})
+		if content, _ := os.ReadFile(filepath.Join(api, "config.json")); string(content) != "{\"local\": true}\n" {
+			t.Errorf("encrypted patch should apply, got %q", content)
+		}
+
+		output = captureOutput(func() {
+			if err := runDecrypt(decryptCmd, []string{patchPath}); err != nil {
+				t.Errorf("runDecrypt failed: %v", err)
+			}
+		})
+		if !strings.Contains(output, "+{\"local\": true}") {
+			t.Errorf("decrypt should print the patch, got: %s", output)
+		}
+
+		// Backups are resolved through the object store
+		var backupPath string
+		versions, _, _ := backup.List(filepath.Join(dir, ".multirepos", "backup"), []string{"apps/api"})
+		for _, v := range versions {
+			if v.Kind == backup.KindModified {
+				backupPath = v.Path
+			}
+		}
+		output = captureOutput(func() {
+			if err := runDecrypt(decryptCmd, []string{backupPath}); err != nil {
+				t.Errorf("runDecrypt failed: %v", err)
+			}
+		})
+		if output != "{\"local\": true}\n" {
+			t.Errorf("decrypt should print the backup, got: %q", output)
+		}
+	})
+}
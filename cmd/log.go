@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/git"
+)
+
+var (
+	logSince    string
+	logUntil    string
+	logAuthor   string
+	logMaxCount int
+	logOneline  bool
+	logJSON     bool
+	logGroups   []string
+	logNoParent bool
+)
+
+var logCmd = &cobra.Command{
+	Use:   "log [path...]",
+	Short: "Show commits across all repositories",
+	Long: `Show commits of the parent repository and all workspaces as one
+chronological stream (newest first). Each commit is labeled with its
+workspace path ("." for the parent repository).
+
+git log runs concurrently in every repository.
+
+Examples:
+  git multirepo log --since=yesterday
+  git multirepo log --author=alice --oneline
+  git multirepo log --group backend --json
+  git multirepo log packages/lib -n 20`,
+	RunE: runLog,
+}
+
+func init() {
+	logCmd.Flags().StringVar(&logSince, "since", "", "Show commits more recent than a specific date")
+	logCmd.Flags().StringVar(&logUntil, "until", "", "Show commits older than a specific date")
+	logCmd.Flags().StringVar(&logAuthor, "author", "", "Limit to commits by matching author")
+	logCmd.Flags().IntVarP(&logMaxCount, "max-count", "n", 0, "Limit the number of commits shown")
+	logCmd.Flags().BoolVar(&logOneline, "oneline", false, "Show each commit on a single line")
+	logCmd.Flags().BoolVar(&logJSON, "json", false, "Output commits as JSON")
+	logCmd.Flags().StringSliceVarP(&logGroups, "group", "g", nil, "Only include workspaces in group (repeatable)")
+	logCmd.Flags().BoolVar(&logNoParent, "no-parent", false, "Exclude the parent repository")
+}
+
+// logEntry is a commit labeled with the repository it belongs to
+type logEntry struct {
+	Workspace string    `json:"workspace"`
+	Hash      string    `json:"hash"`
+	Author    string    `json:"author"`
+	Email     string    `json:"email"`
+	Date      time.Time `json:"date"`
+	Subject   string    `json:"subject"`
+}
+
+// logTarget is a repository to collect commits from
+type logTarget struct {
+	label string
+	path  string
+}
+
+func runLog(cmd *cobra.Command, args []string) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	workspaces, err := ctx.SelectWorkspaces(args, logGroups)
+	if err != nil {
+		return err
+	}
+
+	// Parent is included only when no path/group filter narrows the selection
+	var targets []logTarget
+	if !logNoParent && len(args) == 0 && len(logGroups) == 0 {
+		targets = append(targets, logTarget{label: ".", path: ctx.RepoRoot})
+	}
+	for _, ws := range workspaces {
+		fullPath := filepath.Join(ctx.RepoRoot, ws.Path)
+		if !git.IsRepo(fullPath) {
+			continue
+		}
+		targets = append(targets, logTarget{label: ws.Path, path: fullPath})
+	}
+
+	opts := git.LogOptions{
+		Since:    logSince,
+		Until:    logUntil,
+		Author:   logAuthor,
+		MaxCount: logMaxCount,
+	}
+	entries := collectLogEntries(targets, opts, getOptimalWorkerCount())
+
+	if logMaxCount > 0 && len(entries) > logMaxCount {
+		entries = entries[:logMaxCount]
+	}
+
+	if logJSON {
+		return printLogJSON(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No commits found")
+		return nil
+	}
+
+	if logOneline {
+		printLogOneline(entries)
+	} else {
+		printLogFull(entries)
+	}
+	return nil
+}
+
+// collectLogEntries runs git log in all targets concurrently and merges the results
+// Results are sorted by commit date, newest first
+func collectLogEntries(targets []logTarget, opts git.LogOptions, numWorkers int) []logEntry {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var entries []logEntry
+
+	// Semaphore for worker pool
+	sem := make(chan struct{}, numWorkers)
+
+	for _, target := range targets {
+		t := target // Capture loop variable
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			commits, err := git.Log(t.path, opts)
+			if err != nil {
+				// Warning only - repositories without commits are common for new workspaces
+				fmt.Fprintf(os.Stderr, "⚠ %s: %v\n", t.label, err)
+				return
+			}
+
+			mu.Lock()
+			for _, c := range commits {
+				entries = append(entries, logEntry{
+					Workspace: t.label,
+					Hash:      c.Hash,
+					Author:    c.Author,
+					Email:     c.Email,
+					Date:      c.Date,
+					Subject:   c.Subject,
+				})
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Date.Equal(entries[j].Date) {
+			return entries[i].Date.After(entries[j].Date)
+		}
+		return entries[i].Workspace < entries[j].Workspace
+	})
+
+	return entries
+}
+
+// printLogOneline prints one commit per line: <short-hash> <workspace> <subject>
+func printLogOneline(entries []logEntry) {
+	width := 0
+	for _, e := range entries {
+		if len(e.Workspace) > width {
+			width = len(e.Workspace)
+		}
+	}
+
+	for _, e := range entries {
+		colorYellow.Fprintf(os.Stdout, "%s", shortHash(e.Hash))
+		fmt.Printf(" ")
+		printCyan("%-*s", width, e.Workspace)
+		fmt.Printf(" %s\n", e.Subject)
+	}
+}
+
+// printLogFull prints commits in a format similar to git log's default
+func printLogFull(entries []logEntry) {
+	for i, e := range entries {
+		if i > 0 {
+			fmt.Println()
+		}
+		colorYellow.Fprintf(os.Stdout, "commit %s", e.Hash)
+		fmt.Printf(" ")
+		printCyan("(%s)\n", e.Workspace)
+		fmt.Printf("Author: %s <%s>\n", e.Author, e.Email)
+		fmt.Printf("Date:   %s\n", e.Date.Format("Mon Jan 2 15:04:05 2006 -0700"))
+		fmt.Println()
+		fmt.Printf("    %s\n", e.Subject)
+	}
+}
+
+// printLogJSON prints commits as a JSON array
+func printLogJSON(entries []logEntry) error {
+	if entries == nil {
+		entries = []logEntry{}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode commits: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// shortHash returns the abbreviated form of a commit hash
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
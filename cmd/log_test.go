@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/manifest"
+)
+
+// resetLogFlags restores log command flags to their defaults
+func resetLogFlags() {
+	logSince = ""
+	logUntil = ""
+	logAuthor = ""
+	logMaxCount = 0
+	logOneline = false
+	logJSON = false
+	logGroups = nil
+	logNoParent = false
+}
+
+// commitFile writes a file and commits it in the given repository
+func commitFile(t *testing.T, repoPath, file, content, message string) {
+	t.Helper()
+	os.WriteFile(filepath.Join(repoPath, file), []byte(content), 0644)
+	exec.Command("git", "-C", repoPath, "add", file).Run()
+	if out, err := exec.Command("git", "-C", repoPath, "commit", "-m", message).CombinedOutput(); err != nil {
+		t.Fatalf("commit failed: %v\n%s", err, out)
+	}
+}
+
+func TestRunLog_MergesParentAndWorkspaces(t *testing.T) {
+	dir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer resetLogFlags()
+
+	remoteRepo := setupRemoteRepo(t)
+	cloneBranch = ""
+	runClone(cloneCmd, []string{remoteRepo, "packages/lib"})
+
+	wsPath := filepath.Join(dir, "packages/lib")
+	exec.Command("git", "-C", wsPath, "config", "user.email", "test@test.com").Run()
+	exec.Command("git", "-C", wsPath, "config", "user.name", "Test User").Run()
+	commitFile(t, wsPath, "lib.txt", "lib", "Workspace change")
+
+	t.Run("oneline labels each commit", func(t *testing.T) {
+		resetLogFlags()
+		logOneline = true
+
+		output := captureOutput(func() {
+			if err := runLog(logCmd, []string{}); err != nil {
+				t.Errorf("runLog failed: %v", err)
+			}
+		})
+
+		if !strings.Contains(output, "packages/lib") || !strings.Contains(output, "Workspace change") {
+			t.Errorf("output should contain workspace commit, got: %s", output)
+		}
+		if !strings.Contains(output, "Initial commit") {
+			t.Errorf("output should contain parent commit, got: %s", output)
+		}
+	})
+
+	t.Run("json output", func(t *testing.T) {
+		resetLogFlags()
+		logJSON = true
+
+		output := captureOutput(func() {
+			if err := runLog(logCmd, []string{"packages/lib"}); err != nil {
+				t.Errorf("runLog failed: %v", err)
+			}
+		})
+
+		var entries []logEntry
+		if err := json.Unmarshal([]byte(output), &entries); err != nil {
+			t.Fatalf("invalid JSON: %v\n%s", err, output)
+		}
+		if len(entries) == 0 {
+			t.Fatal("expected commits in JSON output")
+		}
+		for _, e := range entries {
+			if e.Workspace != "packages/lib" {
+				t.Errorf("path filter should exclude %s", e.Workspace)
+			}
+		}
+		if entries[0].Subject != "Workspace change" {
+			t.Errorf("newest commit should be first, got %q", entries[0].Subject)
+		}
+	})
+
+	t.Run("max count limits merged stream", func(t *testing.T) {
+		resetLogFlags()
+		logOneline = true
+		logMaxCount = 1
+
+		output := captureOutput(func() {
+			runLog(logCmd, []string{})
+		})
+
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		if len(lines) != 1 {
+			t.Errorf("expected 1 line, got %d: %s", len(lines), output)
+		}
+	})
+}
+
+func TestRunLog_GroupFilter(t *testing.T) {
+	dir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer resetLogFlags()
+
+	remoteRepo := setupRemoteRepo(t)
+	cloneBranch = ""
+	runClone(cloneCmd, []string{remoteRepo, "apps/api"})
+	runClone(cloneCmd, []string{remoteRepo, "apps/web"})
+
+	m, _ := manifest.Load(dir)
+	m.Find("apps/api").Groups = []string{"backend"}
+	manifest.Save(dir, m)
+
+	t.Run("only workspaces in group", func(t *testing.T) {
+		resetLogFlags()
+		logOneline = true
+		logGroups = []string{"backend"}
+
+		output := captureOutput(func() {
+			if err := runLog(logCmd, []string{}); err != nil {
+				t.Errorf("runLog failed: %v", err)
+			}
+		})
+
+		if !strings.Contains(output, "apps/api") {
+			t.Errorf("output should contain apps/api, got: %s", output)
+		}
+		if strings.Contains(output, "apps/web") {
+			t.Errorf("output should not contain apps/web, got: %s", output)
+		}
+	})
+
+	t.Run("unknown group", func(t *testing.T) {
+		resetLogFlags()
+		logGroups = []string{"missing"}
+
+		if err := runLog(logCmd, []string{}); err == nil {
+			t.Error("runLog should fail for unknown group")
+		}
+	})
+}
+
+func TestCollectLogEntries_SortsNewestFirst(t *testing.T) {
+	repoA := setupRemoteRepo(t)
+	repoB := setupRemoteRepo(t)
+
+	env := append(os.Environ(), "GIT_AUTHOR_DATE=2030-01-01T00:00:00Z", "GIT_COMMITTER_DATE=2030-01-01T00:00:00Z")
+	os.WriteFile(filepath.Join(repoB, "future.txt"), []byte("x"), 0644)
+	exec.Command("git", "-C", repoB, "add", ".").Run()
+	cmd := exec.Command("git", "-C", repoB, "commit", "-m", "Future commit")
+	cmd.Env = env
+	cmd.Run()
+
+	entries := collectLogEntries([]logTarget{
+		{label: "a", path: repoA},
+		{label: "b", path: repoB},
+	}, git.LogOptions{}, 2)
+
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	if entries[0].Subject != "Future commit" || entries[0].Workspace != "b" {
+		t.Errorf("first entry = %+v, want Future commit in b", entries[0])
+	}
+}
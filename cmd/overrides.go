@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/backup"
+	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/crypt"
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/patch"
+)
+
+var (
+	overridesExportOutput string
+	overridesImportCheck  bool
+)
+
+var overridesCmd = &cobra.Command{
+	Use:   "overrides",
+	Short: "Export and import keep file patches as a bundle",
+	Long: `Package the local changes of keep files (the patches in
+.multirepos/patches) into a tar bundle that can be shared, e.g. with a new
+teammate, and apply such a bundle in another checkout.
+
+Each patch in the bundle records the commit and branch it was made against.
+
+Examples:
+  git multirepo overrides export > overrides.tar
+  git multirepo overrides export apps/api -o api-overrides.tar
+  git multirepo overrides import overrides.tar --check
+  git multirepo overrides import overrides.tar`,
+}
+
+var overridesExportCmd = &cobra.Command{
+	Use:   "export [patch...]",
+	Short: "Write stored patches to a bundle",
+	Long: `Write the stored keep file patches (all, or the selected ones) to a tar
+bundle on stdout, or to the file given with -o.
+
+Patches of sensitive keep files stay encrypted; importing them needs the
+same passphrase or keyfile.`,
+	RunE: runOverridesExport,
+}
+
+var overridesImportCmd = &cobra.Command{
+	Use:   "import [bundle]",
+	Short: "Apply the patches of a bundle to the keep files",
+	Long: `Apply the patches of a bundle (a file, or stdin if omitted or "-") to
+the keep files of the parent repository and workspaces.
+
+Every patch is first checked against HEAD; patches that conflict are
+reported and skipped, as are patches for workspaces that are not cloned or
+files that are not in a keep list. Patches already in the file are skipped.
+Modified keep files are backed up before a patch is applied, and applied
+patches are stored in .multirepos/patches.
+
+With --check nothing is changed. Exits non-zero if any patch is not applied.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runOverridesImport,
+}
+
+func init() {
+	// Command registered in root.go init() in workflow order
+	overridesCmd.AddCommand(overridesExportCmd)
+	overridesCmd.AddCommand(overridesImportCmd)
+	overridesExportCmd.Flags().StringVarP(&overridesExportOutput, "output", "o", "", "Write the bundle to a file instead of stdout")
+	overridesImportCmd.Flags().BoolVar(&overridesImportCheck, "check", false, "Only report which patches apply to HEAD")
+}
+
+func runOverridesExport(cmd *cobra.Command, args []string) error {
+	ctx, entries, err := loadPatches(args)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no patches to export (sync records the changes of keep files)")
+	}
+
+	encrypted := 0
+	content := func(e *patch.Entry) ([]byte, error) {
+		data, err := os.ReadFile(patch.StorePath(ctx.RepoRoot, e.Workspace, e.File))
+		if crypt.IsEncrypted(data) {
+			encrypted++
+		}
+		return data, err
+	}
+
+	// The bundle goes to stdout, so messages go to stderr
+	if overridesExportOutput == "" {
+		if err := patch.WriteBundle(os.Stdout, entries, content); err != nil {
+			return err
+		}
+	} else {
+		var buf bytes.Buffer
+		if err := patch.WriteBundle(&buf, entries, content); err != nil {
+			return err
+		}
+		perm := os.FileMode(0644)
+		if encrypted > 0 {
+			perm = 0600
+		}
+		if err := os.WriteFile(overridesExportOutput, buf.Bytes(), perm); err != nil {
+			return fmt.Errorf("failed to write %s: %w", overridesExportOutput, err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d patch(es)\n", len(entries))
+	if encrypted > 0 {
+		fmt.Fprintf(os.Stderr, "%d patch(es) are encrypted; importing them needs the same %s or %s\n", encrypted, crypt.EnvPassphrase, crypt.EnvKeyfile)
+	}
+	return nil
+}
+
+func runOverridesImport(cmd *cobra.Command, args []string) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if len(args) == 1 && args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	bundle, err := patch.ReadBundle(r)
+	if err != nil {
+		return err
+	}
+	if len(bundle.Patches) == 0 {
+		fmt.Println("No patches in bundle")
+		return nil
+	}
+
+	runCtx := commandContext(cmd)
+	failed := 0
+	for _, e := range bundle.Patches {
+		if err := importOverride(runCtx, ctx, bundle, &e); err != nil {
+			failed++
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: %s\n", e.ID(), firstLine(err.Error()))
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d patch(es) not applied", failed, len(bundle.Patches))
+	}
+	return nil
+}
+
+// importOverride checks one patch of a bundle against HEAD and applies it
+// to the working tree, reporting what was done
+func importOverride(runCtx context.Context, ctx *common.WorkspaceContext, bundle *patch.Bundle, e *patch.Entry) error {
+	target, err := resolveKeepTarget(ctx, e.Workspace)
+	if err != nil {
+		return err
+	}
+	if !git.IsRepo(target.fullPath) {
+		return fmt.Errorf("workspace not cloned")
+	}
+	if !containsString(expandKeep(target.fullPath, *target.keep), e.File) {
+		return fmt.Errorf("not in the keep list of %s", target.path)
+	}
+
+	content, err := crypt.Decrypt(bundle.Content(e))
+	if err != nil {
+		return err
+	}
+	if !patch.TouchesOnly(content, e.File) {
+		return fmt.Errorf("patch changes files other than %s", e.File)
+	}
+
+	tmp, err := os.CreateTemp("", "git-multirepo-import-*.patch")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	tmp.Close()
+
+	// 1. Check against HEAD
+	head, _ := git.GetCurrentCommit(target.fullPath)
+	moved := ""
+	if head != e.Base {
+		moved = fmt.Sprintf(" (made on %s, HEAD is %s)", shortHash(e.Base), shortHash(head))
+	}
+	conflicts, err := patch.Check(runCtx, target.fullPath, tmp.Name())
+	if err != nil {
+		return err
+	}
+	if conflicts {
+		return fmt.Errorf("conflicts with HEAD%s", moved)
+	}
+	if overridesImportCheck {
+		printGreen("  ✓ %s: applies to HEAD%s\n", e.ID(), moved)
+		return nil
+	}
+
+	// 2. Apply to the working tree, unless the file already has the changes
+	if patch.IsApplied(runCtx, target.fullPath, tmp.Name()) {
+		printFaint("  - %s: already applied\n", e.ID())
+		return nil
+	}
+	if !patch.Applies(runCtx, target.fullPath, tmp.Name()) {
+		return fmt.Errorf("conflicts with the local changes of %s", e.File)
+	}
+
+	key, err := sensitiveKey(ctx.Manifest, target.path, e.File)
+	if err != nil {
+		return err
+	}
+	branch, _ := git.GetCurrentBranch(target.fullPath)
+	modified, err := keepFileDiffers(target.fullPath, "HEAD", e.File)
+	if err != nil {
+		return err
+	}
+	if modified {
+		backupDir := filepath.Join(ctx.RepoRoot, ".multirepos", "backup")
+		if err := backup.CreateFileBackupWithKey(filepath.Join(target.fullPath, e.File), backupDir, ctx.RepoRoot, target.backupPath(), branch, key); err != nil {
+			return fmt.Errorf("failed to backup: %w", err)
+		}
+	}
+	if err := patch.Apply(runCtx, target.fullPath, tmp.Name()); err != nil {
+		return err
+	}
+
+	// 3. Store the resulting change like sync does
+	err = git.WithSkipWorktreeTransaction(target.fullPath, []string{e.File}, func() error {
+		return storeKeepPatch(runCtx, ctx.RepoRoot, target, e.File, branch, head, key)
+	})
+	if err != nil {
+		return err
+	}
+	printGreen("  ✓ %s: applied%s\n", e.ID(), moved)
+	return nil
+}
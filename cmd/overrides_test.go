@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yejune/git-multirepo/internal/patch"
+)
+
+func resetOverridesFlags() {
+	overridesExportOutput = ""
+	overridesImportCheck = false
+}
+
+func TestRunOverrides(t *testing.T) {
+	dir, _, paths := setupBranchWorkspaces(t)
+	defer resetOverridesFlags()
+	resetOverridesFlags()
+	api, web := filepath.Join(dir, "apps/api"), filepath.Join(dir, "apps/web")
+	bundlePath := filepath.Join(t.TempDir(), "overrides.tar")
+
+	issues := 0
+	captureOutput(func() {
+		for _, p := range paths {
+			processKeepFiles(context.Background(), loadManifest(t, dir), dir, filepath.Join(dir, p), []string{"config.json"}, &issues)
+		}
+	})
+
+	t.Run("export", func(t *testing.T) {
+		overridesExportOutput = bundlePath
+		defer resetOverridesFlags()
+		if err := runOverridesExport(overridesExportCmd, nil); err != nil {
+			t.Fatalf("runOverridesExport failed: %v", err)
+		}
+
+		f, err := os.Open(bundlePath)
+		if err != nil {
+			t.Fatalf("bundle not written: %v", err)
+		}
+		defer f.Close()
+		bundle, err := patch.ReadBundle(f)
+		if err != nil {
+			t.Fatalf("ReadBundle failed: %v", err)
+		}
+		if len(bundle.Patches) != 2 || bundle.Patches[0].Base == "" {
+			t.Fatalf("unexpected bundle: %+v", bundle.Patches)
+		}
+		if !strings.Contains(string(bundle.Content(&bundle.Patches[0])), "+{\"local\": true}") {
+			t.Errorf("unexpected patch content: %s", bundle.Content(&bundle.Patches[0]))
+		}
+	})
+
+	// A fresh checkout: keep files at HEAD, and apps/api changed upstream
+	os.WriteFile(filepath.Join(web, "config.json"), []byte("{}\n"), 0644)
+	exec.Command("git", "-C", api, "update-index", "--no-skip-worktree", "config.json").Run()
+	os.WriteFile(filepath.Join(api, "config.json"), []byte("{\"upstream\": true}\n"), 0644)
+	exec.Command("git", "-C", api, "commit", "-q", "-am", "Change config").Run()
+	exec.Command("git", "-C", api, "update-index", "--skip-worktree", "config.json").Run()
+
+	t.Run("import --check", func(t *testing.T) {
+		overridesImportCheck = true
+		defer resetOverridesFlags()
+
+		var err error
+		output := captureOutput(func() {
+			err = runOverridesImport(overridesImportCmd, []string{bundlePath})
+		})
+		if err == nil || !strings.Contains(err.Error(), "1 of 2 patch(es) not applied") {
+			t.Errorf("expected conflict error, got %v", err)
+		}
+		if !strings.Contains(output, "✗ apps/api/config.json: conflicts with HEAD (made on") || !strings.Contains(output, "✓ apps/web/config.json: applies to HEAD") {
+			t.Errorf("unexpected output: %s", output)
+		}
+		if content, _ := os.ReadFile(filepath.Join(web, "config.json")); string(content) != "{}\n" {
+			t.Errorf("--check should not change files, got %q", content)
+		}
+	})
+
+	t.Run("import", func(t *testing.T) {
+		os.RemoveAll(patch.Dir(dir))
+
+		var err error
+		output := captureOutput(func() {
+			err = runOverridesImport(overridesImportCmd, []string{bundlePath})
+		})
+		if err == nil {
+			t.Error("expected error for the conflicting patch")
+		}
+		if content, _ := os.ReadFile(filepath.Join(web, "config.json")); string(content) != "{\"local\": true}\n" {
+			t.Errorf("patch should be applied, got %q\noutput: %s", content, output)
+		}
+		if content, _ := os.ReadFile(filepath.Join(api, "config.json")); string(content) != "{\"upstream\": true}\n" {
+			t.Errorf("conflicting patch should be skipped, got %q", content)
+		}
+		idx, _ := patch.LoadIndex(dir)
+		if idx.Find("apps/web", "config.json") == nil {
+			t.Errorf("applied patch should be stored: %+v", idx.Patches)
+		}
+
+		output = captureOutput(func() {
+			runOverridesImport(overridesImportCmd, []string{bundlePath})
+		})
+		if !strings.Contains(output, "- apps/web/config.json: already applied") {
+			t.Errorf("second import should skip, got: %s", output)
+		}
+	})
+
+	t.Run("import rejects patches touching other files", func(t *testing.T) {
+		var buf bytes.Buffer
+		evil := "diff --git a/config.json b/config.json\n--- a/config.json\n+++ b/other.sh\n@@ -1 +1 @@\n-{}\n+x\n"
+		err := patch.WriteBundle(&buf, []patch.Entry{{Workspace: "apps/web", File: "config.json"}}, func(e *patch.Entry) ([]byte, error) {
+			return []byte(evil), nil
+		})
+		if err != nil {
+			t.Fatalf("WriteBundle failed: %v", err)
+		}
+		evilPath := filepath.Join(t.TempDir(), "evil.tar")
+		os.WriteFile(evilPath, buf.Bytes(), 0644)
+
+		output := captureOutput(func() {
+			err = runOverridesImport(overridesImportCmd, []string{evilPath})
+		})
+		if err == nil || !strings.Contains(output, "patch changes files other than config.json") {
+			t.Errorf("expected rejection, got %v\noutput: %s", err, output)
+		}
+	})
+
+	t.Run("import rejects tampered bundles", func(t *testing.T) {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		index := `{"version": 1, "patches": [{"workspace": "../x", "file": "config.json", "hash": ""}]}`
+		tw.WriteHeader(&tar.Header{Name: "overrides.json", Mode: 0644, Size: int64(len(index))})
+		tw.Write([]byte(index))
+		tw.Close()
+
+		if _, err := patch.ReadBundle(&buf); err == nil || !strings.Contains(err.Error(), "invalid patch path") {
+			t.Errorf("expected invalid path error, got %v", err)
+		}
+		if _, err := patch.ReadBundle(strings.NewReader("not a tar")); err == nil {
+			t.Error("expected error for non-bundle input")
+		}
+	})
+}
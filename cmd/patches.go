@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/manifest"
+	"github.com/yejune/git-multirepo/internal/patch"
+)
+
+var patchesCmd = &cobra.Command{
+	Use:   "patches",
+	Short: "Inspect and manage stored keep file patches",
+	Long: `Patches record the local changes of keep files. sync stores one per
+modified keep file, and pull keeps one when merging a keep file conflicts.
+
+Patches live in .multirepos/patches/<workspace>/<file>.patch and are
+listed in .multirepos/patches/index.json with the commit and branch they
+were made against. A patch is named <workspace>/<file> (just <file> for
+the parent repository); a workspace path selects all of its patches.
+
+Examples:
+  git multirepo patches list
+  git multirepo patches check
+  git multirepo patches show apps/api/config.json
+  git multirepo patches apply apps/api/config.json
+  git multirepo patches drop apps/api`,
+}
+
+var patchesListCmd = &cobra.Command{
+	Use:   "list [patch...]",
+	Short: "List stored patches",
+	RunE:  runPatchesList,
+}
+
+var patchesShowCmd = &cobra.Command{
+	Use:   "show <patch>...",
+	Short: "Show patch metadata and content",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runPatchesShow,
+}
+
+var patchesCheckCmd = &cobra.Command{
+	Use:   "check [patch...]",
+	Short: "Check that patches still apply to HEAD",
+	Long: `Check every patch against the files at the current HEAD of its
+workspace. A patch that no longer applies is stale: its keep file was
+changed upstream since the patch was made, and reapplying it will need
+manual work. Run this before a pull to see which keep files need care.
+
+Exits non-zero if any patch is stale.`,
+	RunE: runPatchesCheck,
+}
+
+var patchesApplyCmd = &cobra.Command{
+	Use:   "apply <patch>...",
+	Short: "Apply patches to the keep files in the working tree",
+	Long: `Apply stored patches to the working tree, e.g. after a keep file was
+reset or taken from the remote. Patches whose changes are already in the
+file are skipped. The patches stay in the store; drop them when done.
+
+Hunks that moved are found nearby, ignoring up to two context lines at each
+end if needed. Hunks that still do not match are skipped and written to
+<file>.rej next to the file; the other hunks are applied. Rejects of
+encrypted (sensitive) patches are only reported, not written.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runPatchesApply,
+}
+
+var patchesDropCmd = &cobra.Command{
+	Use:   "drop <patch>...",
+	Short: "Delete stored patches",
+	Long: `Delete patches and their index entries. Copies made by sync and pull
+remain in .multirepos/backup/patched/.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runPatchesDrop,
+}
+
+func init() {
+	// Command registered in root.go init() in workflow order
+	patchesCmd.AddCommand(patchesListCmd)
+	patchesCmd.AddCommand(patchesShowCmd)
+	patchesCmd.AddCommand(patchesCheckCmd)
+	patchesCmd.AddCommand(patchesApplyCmd)
+	patchesCmd.AddCommand(patchesDropCmd)
+}
+
+// loadPatches returns the workspace context and the patches selected by args
+func loadPatches(args []string) (*common.WorkspaceContext, []patch.Entry, error) {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return nil, nil, err
+	}
+	idx, err := patch.LoadIndex(ctx.RepoRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, err := idx.Select(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ctx, entries, nil
+}
+
+// patchRepoPath returns the repository a patch applies to
+func patchRepoPath(repoRoot string, e *patch.Entry) string {
+	if e.Workspace == manifest.ParentPath {
+		return repoRoot
+	}
+	return filepath.Join(repoRoot, e.Workspace)
+}
+
+// patchModified checks if a patch file no longer matches the hash in the index
+func patchModified(patchPath string, e *patch.Entry) bool {
+	hash, err := patch.Hash(patchPath)
+	return err == nil && hash != e.Hash
+}
+
+func runPatchesList(cmd *cobra.Command, args []string) error {
+	ctx, entries, err := loadPatches(args)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No patches")
+		return nil
+	}
+
+	idWidth, branchWidth := len("PATCH"), len("BRANCH")
+	for _, e := range entries {
+		idWidth = max(idWidth, len(e.ID()))
+		branchWidth = max(branchWidth, len(e.Branch))
+	}
+
+	printFaint("%-*s  %-*s  %-7s  %s\n", idWidth, "PATCH", branchWidth, "BRANCH", "BASE", "CREATED")
+	for _, e := range entries {
+		fmt.Printf("%-*s  %-*s  %-7s  %s", idWidth, e.ID(), branchWidth, e.Branch, shortHash(e.Base), e.Created.Local().Format("2006-01-02 15:04"))
+		if e.Deleted {
+			printFaint(" (deleted)")
+		}
+		patchPath := patch.StorePath(ctx.RepoRoot, e.Workspace, e.File)
+		if _, err := os.Stat(patchPath); err != nil {
+			colorYellow.Fprintf(os.Stdout, " (file missing)")
+		} else if patchModified(patchPath, &e) {
+			colorYellow.Fprintf(os.Stdout, " (edited)")
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func runPatchesShow(cmd *cobra.Command, args []string) error {
+	ctx, entries, err := loadPatches(args)
+	if err != nil {
+		return err
+	}
+
+	for i, e := range entries {
+		if i > 0 {
+			fmt.Println()
+		}
+		patchPath := patch.StorePath(ctx.RepoRoot, e.Workspace, e.File)
+		relPath, _ := filepath.Rel(ctx.RepoRoot, patchPath)
+
+		printCyan("%s\n", e.ID())
+		printFaint("Workspace: %s\n", e.Workspace)
+		printFaint("File:      %s\n", e.File)
+		printFaint("Branch:    %s\n", e.Branch)
+		printFaint("Base:      %s\n", e.Base)
+		printFaint("Created:   %s\n", e.Created.Local().Format("2006-01-02 15:04:05"))
+		if e.Deleted {
+			printFaint("Deleted:   the keep file is deleted locally\n")
+		}
+		printFaint("Stored in: %s\n", relPath)
+
+		content, err := patch.Read(patchPath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.ID(), err)
+		}
+		if patchModified(patchPath, &e) {
+			colorYellow.Fprintf(os.Stdout, "⚠ Edited since it was recorded\n")
+		}
+		fmt.Println()
+		fmt.Print(string(content))
+	}
+	return nil
+}
+
+func runPatchesCheck(cmd *cobra.Command, args []string) error {
+	ctx, entries, err := loadPatches(args)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No patches")
+		return nil
+	}
+
+	runCtx := commandContext(cmd)
+	stale := 0
+	for _, e := range entries {
+		repoPath := patchRepoPath(ctx.RepoRoot, &e)
+		patchPath := patch.StorePath(ctx.RepoRoot, e.Workspace, e.File)
+
+		if !git.IsRepo(repoPath) {
+			stale++
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: workspace not cloned\n", e.ID())
+			continue
+		}
+		if _, err := os.Stat(patchPath); err != nil {
+			stale++
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: patch file missing\n", e.ID())
+			continue
+		}
+
+		conflicts, err := patch.Check(runCtx, repoPath, patchPath)
+		if err != nil {
+			stale++
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: %v\n", e.ID(), err)
+			continue
+		}
+
+		head, _ := git.GetCurrentCommit(repoPath)
+		moved := ""
+		if head != e.Base {
+			moved = fmt.Sprintf(" (made on %s, HEAD is %s)", shortHash(e.Base), shortHash(head))
+		}
+		if conflicts {
+			stale++
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: stale, does not apply to HEAD%s\n", e.ID(), moved)
+			continue
+		}
+		printGreen("  ✓ %s: applies to HEAD%s\n", e.ID(), moved)
+	}
+
+	if stale > 0 {
+		return fmt.Errorf("%d of %d patch(es) do not apply", stale, len(entries))
+	}
+	return nil
+}
+
+func runPatchesApply(cmd *cobra.Command, args []string) error {
+	ctx, entries, err := loadPatches(args)
+	if err != nil {
+		return err
+	}
+
+	runCtx := commandContext(cmd)
+	failed := 0
+	for _, e := range entries {
+		repoPath := patchRepoPath(ctx.RepoRoot, &e)
+		patchPath := patch.StorePath(ctx.RepoRoot, e.Workspace, e.File)
+
+		if patch.IsApplied(runCtx, repoPath, patchPath) {
+			printFaint("  - %s: already applied\n", e.ID())
+			continue
+		}
+		result, err := patch.ApplyWithResult(runCtx, repoPath, patchPath)
+		if err != nil {
+			failed++
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: %s\n", e.ID(), firstLine(err.Error()))
+			continue
+		}
+		if result.Failed() > 0 {
+			failed++
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: %d of %d hunk(s) failed\n", e.ID(), result.Failed(), result.Hunks())
+		} else {
+			printGreen("  ✓ %s: applied\n", e.ID())
+		}
+		printApplyResult(result)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d patch(es) failed to apply (see 'git multirepo patches check')", failed)
+	}
+	return nil
+}
+
+// printApplyResult prints the hunks that did not apply cleanly and the reject files
+func printApplyResult(result *patch.Result) {
+	for _, f := range result.Files {
+		for _, h := range f.Hunks {
+			switch {
+			case !h.Applied:
+				colorYellow.Fprintf(os.Stdout, "      hunk #%d failed at line %d\n", h.Index, h.OldStart)
+			case h.Offset != 0 || h.Fuzz > 0:
+				printFaint("      hunk #%d applied at line %d (offset %d, fuzz %d)\n", h.Index, h.Line, h.Offset, h.Fuzz)
+			}
+		}
+		if f.Reject != "" {
+			colorYellow.Fprintf(os.Stdout, "      rejected hunks saved to %s\n", f.Reject)
+		} else if len(f.Rejected) > 0 {
+			colorYellow.Fprintf(os.Stdout, "      rejected hunks not saved (encrypted patch)\n")
+		}
+	}
+}
+
+func runPatchesDrop(cmd *cobra.Command, args []string) error {
+	ctx, entries, err := loadPatches(args)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if err := patch.Drop(ctx.RepoRoot, e.Workspace, e.File); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", e.ID(), err)
+		}
+		printGreen("  ✓ %s: dropped\n", e.ID())
+	}
+	return nil
+}
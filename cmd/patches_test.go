@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yejune/git-multirepo/internal/patch"
+)
+
+func TestRunPatches(t *testing.T) {
+	dir, _, paths := setupBranchWorkspaces(t)
+	api, web := filepath.Join(dir, "apps/api"), filepath.Join(dir, "apps/web")
+
+	// sync stores a patch per modified keep file
+	issues := 0
+	captureOutput(func() {
+		for _, p := range paths {
+			processKeepFiles(context.Background(), loadManifest(t, dir), dir, filepath.Join(dir, p), []string{"config.json"}, &issues)
+		}
+	})
+	if issues != 0 {
+		t.Fatalf("processKeepFiles reported %d issue(s)", issues)
+	}
+
+	t.Run("index records base and branch", func(t *testing.T) {
+		idx, err := patch.LoadIndex(dir)
+		if err != nil {
+			t.Fatalf("LoadIndex failed: %v", err)
+		}
+		e := idx.Find("apps/api", "config.json")
+		if e == nil {
+			t.Fatalf("patch not recorded: %+v", idx.Patches)
+		}
+		head, _ := exec.Command("git", "-C", api, "rev-parse", "HEAD").Output()
+		if e.Base != strings.TrimSpace(string(head)) || e.Branch != currentBranch(t, api) || len(e.Hash) != 64 {
+			t.Errorf("unexpected entry: %+v", e)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := runPatchesList(patchesListCmd, nil); err != nil {
+				t.Errorf("runPatchesList failed: %v", err)
+			}
+		})
+		if !strings.Contains(output, "apps/api/config.json") || !strings.Contains(output, "apps/web/config.json") {
+			t.Errorf("list should show both patches, got: %s", output)
+		}
+	})
+
+	t.Run("show", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := runPatchesShow(patchesShowCmd, []string{"apps/api/config.json"}); err != nil {
+				t.Errorf("runPatchesShow failed: %v", err)
+			}
+		})
+		if !strings.Contains(output, "Base:") || !strings.Contains(output, "+{\"local\": true}") {
+			t.Errorf("show should print metadata and diff, got: %s", output)
+		}
+	})
+
+	t.Run("check flags stale patches", func(t *testing.T) {
+		// Change config.json upstream of the patch in apps/api
+		exec.Command("git", "-C", api, "update-index", "--no-skip-worktree", "config.json").Run()
+		os.WriteFile(filepath.Join(api, "config.json"), []byte("{\"upstream\": true}\n"), 0644)
+		exec.Command("git", "-C", api, "commit", "-q", "-am", "Change config").Run()
+		os.WriteFile(filepath.Join(api, "config.json"), []byte("{\"local\": true}\n"), 0644)
+		exec.Command("git", "-C", api, "update-index", "--skip-worktree", "config.json").Run()
+
+		var err error
+		output := captureOutput(func() {
+			err = runPatchesCheck(patchesCheckCmd, nil)
+		})
+		if err == nil || !strings.Contains(err.Error(), "1 of 2 patch(es) do not apply") {
+			t.Errorf("expected stale patch error, got %v", err)
+		}
+		if !strings.Contains(output, "✗ apps/api/config.json: stale") || !strings.Contains(output, "✓ apps/web/config.json: applies to HEAD") {
+			t.Errorf("unexpected output: %s", output)
+		}
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		os.WriteFile(filepath.Join(web, "config.json"), []byte("{}\n"), 0644)
+
+		output := captureOutput(func() {
+			if err := runPatchesApply(patchesApplyCmd, []string{"apps/web"}); err != nil {
+				t.Errorf("runPatchesApply failed: %v", err)
+			}
+		})
+		if content, _ := os.ReadFile(filepath.Join(web, "config.json")); string(content) != "{\"local\": true}\n" {
+			t.Errorf("patch should be applied, got %q\noutput: %s", content, output)
+		}
+
+		output = captureOutput(func() {
+			runPatchesApply(patchesApplyCmd, []string{"apps/web/config.json"})
+		})
+		if !strings.Contains(output, "already applied") {
+			t.Errorf("second apply should be skipped, got: %s", output)
+		}
+	})
+
+	t.Run("apply writes rejects", func(t *testing.T) {
+		// The patch of apps/api is stale: config.json changed upstream
+		os.WriteFile(filepath.Join(api, "config.json"), []byte("{\"upstream\": true}\n"), 0644)
+
+		var err error
+		output := captureOutput(func() {
+			err = runPatchesApply(patchesApplyCmd, []string{"apps/api"})
+		})
+		if err == nil {
+			t.Error("expected error for the stale patch")
+		}
+		if !strings.Contains(output, "✗ apps/api/config.json: 1 of 1 hunk(s) failed") || !strings.Contains(output, "hunk #1 failed at line 1") {
+			t.Errorf("unexpected output: %s", output)
+		}
+		reject, err := os.ReadFile(filepath.Join(api, "config.json.rej"))
+		if err != nil || !strings.Contains(string(reject), "+{\"local\": true}") {
+			t.Errorf("reject file should hold the hunk, got %q (%v)", reject, err)
+		}
+		if content, _ := os.ReadFile(filepath.Join(api, "config.json")); string(content) != "{\"upstream\": true}\n" {
+			t.Errorf("file should be left alone, got %q", content)
+		}
+		os.Remove(filepath.Join(api, "config.json.rej"))
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		captureOutput(func() {
+			if err := runPatchesDrop(patchesDropCmd, []string{"apps/web"}); err != nil {
+				t.Errorf("runPatchesDrop failed: %v", err)
+			}
+		})
+		if _, err := os.Stat(patch.StorePath(dir, "apps/web", "config.json")); !os.IsNotExist(err) {
+			t.Error("patch file should be removed")
+		}
+		idx, _ := patch.LoadIndex(dir)
+		if idx.Find("apps/web", "config.json") != nil || idx.Find("apps/api", "config.json") == nil {
+			t.Errorf("only apps/web should be dropped: %+v", idx.Patches)
+		}
+
+		if err := runPatchesDrop(patchesDropCmd, []string{"apps/unknown"}); err == nil {
+			t.Error("expected error for unknown patch")
+		}
+	})
+}
+
+func TestRunPatches_BinaryAndDeletedKeepFiles(t *testing.T) {
+	dir, _, _ := setupBranchWorkspaces(t)
+	api, web := filepath.Join(dir, "apps/api"), filepath.Join(dir, "apps/web")
+	binary := []byte("\x89PNG\x00local")
+
+	// A binary keep file in apps/api and a deleted one in apps/web
+	os.WriteFile(filepath.Join(api, "config.json"), binary, 0644)
+	os.Remove(filepath.Join(web, "config.json"))
+
+	issues := 0
+	captureOutput(func() {
+		processKeepFiles(context.Background(), loadManifest(t, dir), dir, api, []string{"config.json"}, &issues)
+		processKeepFiles(context.Background(), loadManifest(t, dir), dir, web, []string{"config.json"}, &issues)
+	})
+	if issues != 0 {
+		t.Fatalf("processKeepFiles reported %d issue(s)", issues)
+	}
+	if content, _ := patch.Read(patch.StorePath(dir, "apps/api", "config.json")); !strings.Contains(string(content), "GIT binary patch") {
+		t.Errorf("binary keep file should get a binary patch, got: %s", content)
+	}
+
+	output := captureOutput(func() {
+		runPatchesList(patchesListCmd, nil)
+	})
+	if !strings.Contains(output, "(deleted)") {
+		t.Errorf("list should mark the tombstone, got: %s", output)
+	}
+
+	// Both patches apply again after the files were reset
+	exec.Command("git", "-C", api, "checkout", "HEAD", "--", "config.json").Run()
+	exec.Command("git", "-C", web, "checkout", "HEAD", "--", "config.json").Run()
+	output = captureOutput(func() {
+		if err := runPatchesApply(patchesApplyCmd, []string{"apps/api", "apps/web"}); err != nil {
+			t.Errorf("runPatchesApply failed: %v", err)
+		}
+	})
+	if content, _ := os.ReadFile(filepath.Join(api, "config.json")); string(content) != string(binary) {
+		t.Errorf("binary patch should be applied, got %q\noutput: %s", content, output)
+	}
+	if _, err := os.Stat(filepath.Join(web, "config.json")); !os.IsNotExist(err) {
+		t.Errorf("tombstone should delete the file again\noutput: %s", output)
+	}
+}
@@ -0,0 +1,438 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/backup"
+	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/crypt"
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/manifest"
+	"github.com/yejune/git-multirepo/internal/patch"
+	"github.com/yejune/git-multirepo/internal/profile"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Switch between named sets of keep file overrides",
+	Long: `A keep profile is a named set of local overrides for keep files, e.g. one
+for local development and one for running against staging.
+
+The files each profile overrides are listed in the manifest under
+keep_profiles; their content and patch are stored locally in
+.multirepos/profiles/<name>/<workspace>/<file>.
+
+Examples:
+  git multirepo profile save dev
+  git multirepo profile save staging apps/api
+  git multirepo profile use staging
+  git multirepo profile list`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List keep profiles",
+	Args:  cobra.NoArgs,
+	RunE:  runProfileList,
+}
+
+var profileSaveCmd = &cobra.Command{
+	Use:   "save <name> [workspace...]",
+	Short: "Save the current keep file overrides as a profile",
+	Long: `Save every keep file that differs from HEAD into a profile, in the
+parent repository (".") and all workspaces, or only in the given ones.
+Saving again replaces the files stored for those repositories.
+
+The saved profile becomes the active profile.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runProfileSave,
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Swap in the keep file overrides of a profile",
+	Long: `Replace the keep files with the overrides of a profile. Each file is
+reset to HEAD and the profile's patch applied on top, so upstream changes
+to the file are kept; if the patch no longer applies, the saved content is
+used. Keep files overridden by the previously active profile but not by
+this one are restored from HEAD.
+
+Modified keep files are backed up before they are replaced, and the keep
+patches in .multirepos/patches are updated to the new content. Edits made
+since the active profile was saved are not written back to it: run
+'profile save' first to keep them in the profile.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runProfileUse,
+}
+
+var profileDropCmd = &cobra.Command{
+	Use:   "drop <name>",
+	Short: "Delete a profile",
+	Long: `Delete a profile from the manifest and its stored files. The keep files
+in the working tree are not changed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runProfileDrop,
+}
+
+func init() {
+	// Command registered in root.go init() in workflow order
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileSaveCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileDropCmd)
+}
+
+// profileNames returns the profiles in the manifest, sorted
+func profileNames(m *manifest.Manifest) []string {
+	names := make([]string, 0, len(m.KeepProfiles))
+	for name := range m.KeepProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// profileWorkspaces returns the workspaces of profiles, sorted, without duplicates
+// The parent repository (".") sorts first.
+func profileWorkspaces(profiles ...manifest.KeepProfile) []string {
+	var paths []string
+	for _, p := range profiles {
+		for path := range p {
+			if !containsString(paths, path) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func runProfileList(cmd *cobra.Command, args []string) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+	if len(ctx.Manifest.KeepProfiles) == 0 {
+		fmt.Println("No keep profiles")
+		return nil
+	}
+
+	active := profile.Active(ctx.RepoRoot)
+	for _, name := range profileNames(ctx.Manifest) {
+		if name == active {
+			printCyan("* %s (active)\n", name)
+		} else {
+			fmt.Printf("  %s\n", name)
+		}
+		prof := ctx.Manifest.KeepProfiles[name]
+		for _, path := range profileWorkspaces(prof) {
+			var missing []string
+			for _, file := range prof[path] {
+				if _, err := os.Stat(profile.FilePath(ctx.RepoRoot, name, path, file)); err != nil {
+					missing = append(missing, file)
+				}
+			}
+			printFaint("    %s: %s", path, strings.Join(prof[path], ", "))
+			if len(missing) > 0 {
+				colorYellow.Fprintf(os.Stdout, " (not saved here: %s)", strings.Join(missing, ", "))
+			}
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+func runProfileSave(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := profile.ValidateName(name); err != nil {
+		return err
+	}
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	paths := args[1:]
+	if len(paths) == 0 {
+		paths = []string{manifest.ParentPath}
+		for _, ws := range ctx.Manifest.Workspaces {
+			paths = append(paths, ws.Path)
+		}
+	}
+	var targets []*keepTarget
+	for _, path := range paths {
+		target, err := resolveKeepTarget(ctx, path)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, target)
+	}
+
+	prof := ctx.Manifest.KeepProfiles[name]
+	if prof == nil {
+		prof = manifest.KeepProfile{}
+	}
+
+	for _, target := range targets {
+		if !git.IsRepo(target.fullPath) {
+			printFaint("  - %s: not cloned, skipped\n", target.path)
+			continue
+		}
+		if err := profile.RemoveFiles(ctx.RepoRoot, name, target.path, prof[target.path]); err != nil {
+			return err
+		}
+		delete(prof, target.path)
+
+		files := expandKeep(target.fullPath, *target.keep)
+		var saved []string
+		err := git.WithSkipWorktreeTransaction(target.fullPath, files, func() error {
+			for _, file := range files {
+				modified, err := keepFileDiffers(target.fullPath, "HEAD", file)
+				if err != nil {
+					return err
+				}
+				if !modified {
+					continue
+				}
+				if err := saveProfileFile(commandContext(cmd), ctx, name, target, file); err != nil {
+					return fmt.Errorf("%s: %w", file, err)
+				}
+				saved = append(saved, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(saved) == 0 {
+			printFaint("  - %s: no modified keep files\n", target.path)
+			continue
+		}
+		prof[target.path] = saved
+		printGreen("  ✓ %s: saved %s\n", target.path, strings.Join(saved, ", "))
+	}
+
+	if len(prof) == 0 {
+		return fmt.Errorf("no keep files differ from HEAD, nothing to save in profile %s", name)
+	}
+	if ctx.Manifest.KeepProfiles == nil {
+		ctx.Manifest.KeepProfiles = make(map[string]manifest.KeepProfile)
+	}
+	ctx.Manifest.KeepProfiles[name] = prof
+	if err := ctx.SaveManifest(); err != nil {
+		return err
+	}
+	if err := profile.SetActive(ctx.RepoRoot, name); err != nil {
+		return err
+	}
+	fmt.Printf("Saved profile %s (active)\n", name)
+	return nil
+}
+
+// saveProfileFile stores the content and patch of a keep file in a profile
+// Must run with skip-worktree cleared, so the patch sees the local changes.
+func saveProfileFile(runCtx context.Context, ctx *common.WorkspaceContext, name string, target *keepTarget, file string) error {
+	key, err := sensitiveKey(ctx.Manifest, target.path, file)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(filepath.Join(target.fullPath, file))
+	if err != nil {
+		return err
+	}
+	perm := os.FileMode(0644)
+	if key != nil {
+		if content, err = key.Encrypt(content); err != nil {
+			return fmt.Errorf("failed to encrypt: %w", err)
+		}
+		perm = 0600
+	}
+	filePath := profile.FilePath(ctx.RepoRoot, name, target.path, file)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filePath, content, perm); err != nil {
+		return err
+	}
+
+	patchPath := profile.PatchPath(ctx.RepoRoot, name, target.path, file)
+	if err := patch.CreateWithKey(runCtx, target.fullPath, file, patchPath, key); err != nil {
+		return fmt.Errorf("failed to create patch: %w", err)
+	}
+	return nil
+}
+
+func runProfileUse(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+	prof, ok := ctx.Manifest.KeepProfiles[name]
+	if !ok {
+		return fmt.Errorf("profile not found: %s", name)
+	}
+
+	// Files of the previously active profile that this one leaves alone go back to HEAD
+	var previous manifest.KeepProfile
+	if active := profile.Active(ctx.RepoRoot); active != "" && active != name {
+		previous = ctx.Manifest.KeepProfiles[active]
+	}
+
+	failed, switched := 0, 0
+	for _, path := range profileWorkspaces(prof, previous) {
+		target, err := resolveKeepTarget(ctx, path)
+		if err != nil {
+			failed++
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: %v\n", path, err)
+			continue
+		}
+		if !git.IsRepo(target.fullPath) {
+			failed++
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: not cloned\n", path)
+			continue
+		}
+
+		apply := prof[path]
+		restore := subtractFiles(previous[path], apply)
+		branch, _ := git.GetCurrentBranch(target.fullPath)
+		head, _ := git.GetCurrentCommit(target.fullPath)
+
+		files := append(append([]string(nil), apply...), restore...)
+		err = git.WithSkipWorktreeTransaction(target.fullPath, files, func() error {
+			for _, file := range files {
+				action, err := switchProfileFile(commandContext(cmd), ctx, name, target, file, containsString(apply, file), branch, head)
+				if err != nil {
+					failed++
+					colorYellow.Fprintf(os.Stdout, "  ✗ %s: %s: %s\n", path, file, firstLine(err.Error()))
+					continue
+				}
+				switched++
+				printGreen("  ✓ %s: %s: %s\n", path, file, action)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if switched == 0 && failed > 0 {
+		return fmt.Errorf("profile %s is not active: %d file(s) could not be switched", name, failed)
+	}
+	if err := profile.SetActive(ctx.RepoRoot, name); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("profile %s is active, but %d file(s) could not be switched", name, failed)
+	}
+	fmt.Printf("Using profile %s\n", name)
+	return nil
+}
+
+// switchProfileFile replaces a keep file with its profile version, or with
+// its HEAD version if apply is false, and describes what was done
+// The profile version is read before anything changes, as profile files are
+// not shared with the manifest. The current content is backed up first and
+// the keep patch store updated after. Must run with skip-worktree cleared.
+func switchProfileFile(runCtx context.Context, ctx *common.WorkspaceContext, name string, target *keepTarget, file string, apply bool, branch, head string) (string, error) {
+	key, err := sensitiveKey(ctx.Manifest, target.path, file)
+	if err != nil {
+		return "", err
+	}
+
+	// 0. Make sure the profile version is saved here and readable
+	var saved []byte
+	patchPath := profile.PatchPath(ctx.RepoRoot, name, target.path, file)
+	if apply {
+		if saved, err = crypt.ReadFile(profile.FilePath(ctx.RepoRoot, name, target.path, file)); err != nil {
+			return "", fmt.Errorf("not saved in profile %s here: %w", name, err)
+		}
+		if _, err := os.Stat(patchPath); err == nil {
+			if _, err := patch.Read(patchPath); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	// 1. Back up the current content
+	fullPath := filepath.Join(target.fullPath, file)
+	modified, err := keepFileDiffers(target.fullPath, "HEAD", file)
+	if err != nil {
+		return "", err
+	}
+	if modified {
+		backupDir := filepath.Join(ctx.RepoRoot, ".multirepos", "backup")
+		if err := backup.CreateFileBackupWithKey(fullPath, backupDir, ctx.RepoRoot, target.backupPath(), branch, key); err != nil {
+			return "", fmt.Errorf("failed to backup: %w", err)
+		}
+	}
+
+	// 2. Start from HEAD, then apply the profile patch (or its saved content)
+	if err := git.RestoreFileToHEAD(target.fullPath, file); err != nil {
+		return "", err
+	}
+	action := "restored from HEAD"
+	if apply {
+		if patch.Applies(runCtx, target.fullPath, patchPath) {
+			if err := patch.Apply(runCtx, target.fullPath, patchPath); err != nil {
+				return "", err
+			}
+			action = "applied " + name
+		} else {
+			perm := os.FileMode(0644)
+			if info, err := os.Stat(fullPath); err == nil {
+				perm = info.Mode().Perm()
+			}
+			if err := os.WriteFile(fullPath, saved, perm); err != nil {
+				return "", err
+			}
+			action = "applied " + name + " (saved content; the patch no longer applies to HEAD)"
+		}
+	}
+
+	// 3. Keep the patch store in line with the new content
+	if modified, err := keepFileDiffers(target.fullPath, "HEAD", file); err != nil {
+		return "", err
+	} else if !modified {
+		return action, patch.Drop(ctx.RepoRoot, target.path, file)
+	}
+	if err := storeKeepPatch(runCtx, ctx.RepoRoot, target, file, branch, head, key); err != nil {
+		return "", err
+	}
+	return action, nil
+}
+
+func runProfileDrop(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+	if _, ok := ctx.Manifest.KeepProfiles[name]; !ok {
+		return fmt.Errorf("profile not found: %s", name)
+	}
+
+	if err := profile.Remove(ctx.RepoRoot, name); err != nil {
+		return err
+	}
+	delete(ctx.Manifest.KeepProfiles, name)
+	if err := ctx.SaveManifest(); err != nil {
+		return err
+	}
+	if profile.Active(ctx.RepoRoot) == name {
+		if err := profile.SetActive(ctx.RepoRoot, ""); err != nil {
+			return err
+		}
+	}
+	printGreen("  ✓ dropped profile %s (keep files unchanged)\n", name)
+	return nil
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yejune/git-multirepo/internal/backup"
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/manifest"
+	"github.com/yejune/git-multirepo/internal/patch"
+	"github.com/yejune/git-multirepo/internal/profile"
+)
+
+func TestRunProfile(t *testing.T) {
+	dir, _, _ := setupBranchWorkspaces(t)
+	api, web := filepath.Join(dir, "apps/api"), filepath.Join(dir, "apps/web")
+	readConfig := func(path string) string {
+		content, _ := os.ReadFile(filepath.Join(path, "config.json"))
+		return string(content)
+	}
+
+	t.Run("save", func(t *testing.T) {
+		captureOutput(func() {
+			if err := runProfileSave(profileSaveCmd, []string{"dev"}); err != nil {
+				t.Fatalf("runProfileSave failed: %v", err)
+			}
+		})
+
+		os.WriteFile(filepath.Join(api, "config.json"), []byte("{\"staging\": true}\n"), 0644)
+		captureOutput(func() {
+			if err := runProfileSave(profileSaveCmd, []string{"staging", "apps/api"}); err != nil {
+				t.Fatalf("runProfileSave failed: %v", err)
+			}
+		})
+
+		m, _ := manifest.Load(dir)
+		dev, staging := m.KeepProfiles["dev"], m.KeepProfiles["staging"]
+		if len(dev["apps/api"]) != 1 || len(dev["apps/web"]) != 1 || len(staging) != 1 || staging["apps/api"][0] != "config.json" {
+			t.Fatalf("unexpected profiles: %+v", m.KeepProfiles)
+		}
+		if content, _ := os.ReadFile(profile.FilePath(dir, "dev", "apps/api", "config.json")); string(content) != "{\"local\": true}\n" {
+			t.Errorf("dev content not stored: %q", content)
+		}
+		if _, err := os.Stat(profile.PatchPath(dir, "staging", "apps/api", "config.json")); err != nil {
+			t.Errorf("staging patch not stored: %v", err)
+		}
+		if active := profile.Active(dir); active != "staging" {
+			t.Errorf("saved profile should be active, got %q", active)
+		}
+
+		if err := runProfileSave(profileSaveCmd, []string{"../x"}); err == nil {
+			t.Error("expected error for invalid name")
+		}
+	})
+
+	t.Run("use swaps overrides", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := runProfileUse(profileUseCmd, []string{"dev"}); err != nil {
+				t.Errorf("runProfileUse failed: %v", err)
+			}
+		})
+		if readConfig(api) != "{\"local\": true}\n" || readConfig(web) != "{\"local\": true}\n" {
+			t.Errorf("dev overrides not applied: %q, %q\noutput: %s", readConfig(api), readConfig(web), output)
+		}
+		if skipped, _ := git.ListSkipWorktree(api); len(skipped) != 1 {
+			t.Errorf("skip-worktree should be kept, got %v", skipped)
+		}
+
+		// The staging content was backed up, and the keep patch follows the new content
+		backedUp := false
+		filepath.Walk(filepath.Join(dir, ".multirepos", "backup", "modified", "multirepo", "apps", "api"), func(path string, info os.FileInfo, err error) error {
+			if content, _ := backup.ReadFile(path); err == nil && !info.IsDir() && string(content) == "{\"staging\": true}\n" {
+				backedUp = true
+			}
+			return nil
+		})
+		if !backedUp {
+			t.Error("replaced keep file should be backed up")
+		}
+		if content, _ := patch.Read(patch.StorePath(dir, "apps/api", "config.json")); !strings.Contains(string(content), "+{\"local\": true}") {
+			t.Errorf("keep patch not updated: %s", content)
+		}
+
+		// apps/web is only in dev, so it goes back to HEAD with staging
+		captureOutput(func() {
+			if err := runProfileUse(profileUseCmd, []string{"staging"}); err != nil {
+				t.Errorf("runProfileUse failed: %v", err)
+			}
+		})
+		if readConfig(api) != "{\"staging\": true}\n" || readConfig(web) != "{}\n" {
+			t.Errorf("staging overrides not applied: %q, %q", readConfig(api), readConfig(web))
+		}
+		if patch.IsApplied(context.Background(), web, patch.StorePath(dir, "apps/web", "config.json")) {
+			t.Error("keep patch of the restored file should be dropped")
+		}
+
+		if err := runProfileUse(profileUseCmd, []string{"missing"}); err == nil {
+			t.Error("expected error for unknown profile")
+		}
+	})
+
+	t.Run("status shows active profile", func(t *testing.T) {
+		output := captureOutput(func() {
+			runStatus(statusCmd, nil)
+		})
+		if !strings.Contains(output, "Keep profile: staging") {
+			t.Errorf("status should show the active profile, got: %s", output)
+		}
+	})
+
+	t.Run("use falls back to saved content", func(t *testing.T) {
+		// Change config.json upstream so the dev patch no longer applies
+		exec.Command("git", "-C", api, "update-index", "--no-skip-worktree", "config.json").Run()
+		os.WriteFile(filepath.Join(api, "config.json"), []byte("{\"upstream\": true}\n"), 0644)
+		exec.Command("git", "-C", api, "commit", "-q", "-am", "Change config").Run()
+		exec.Command("git", "-C", api, "update-index", "--skip-worktree", "config.json").Run()
+
+		output := captureOutput(func() {
+			if err := runProfileUse(profileUseCmd, []string{"dev"}); err != nil {
+				t.Errorf("runProfileUse failed: %v", err)
+			}
+		})
+		if readConfig(api) != "{\"local\": true}\n" || !strings.Contains(output, "saved content") {
+			t.Errorf("saved content should be used, got %q\noutput: %s", readConfig(api), output)
+		}
+	})
+
+	t.Run("use leaves files alone when the profile is not saved here", func(t *testing.T) {
+		// The manifest is shared, the profile files are not
+		m, _ := manifest.Load(dir)
+		m.KeepProfiles["shared"] = manifest.KeepProfile{"apps/api": {"config.json"}, "apps/web": {"config.json"}}
+		manifest.Save(dir, m)
+		defer func() {
+			delete(m.KeepProfiles, "shared")
+			manifest.Save(dir, m)
+		}()
+
+		output := captureOutput(func() {
+			if err := runProfileUse(profileUseCmd, []string{"shared"}); err == nil || !strings.Contains(err.Error(), "not active") {
+				t.Errorf("expected error, got %v", err)
+			}
+		})
+		if !strings.Contains(output, "not saved in profile shared here") {
+			t.Errorf("unexpected output: %s", output)
+		}
+		if readConfig(api) != "{\"local\": true}\n" {
+			t.Errorf("local override should be kept, got %q", readConfig(api))
+		}
+		if content, _ := patch.Read(patch.StorePath(dir, "apps/api", "config.json")); !strings.Contains(string(content), "+{\"local\": true}") {
+			t.Errorf("keep patch should be kept: %s", content)
+		}
+		if active := profile.Active(dir); active != "dev" {
+			t.Errorf("active profile should not change, got %q", active)
+		}
+	})
+
+	t.Run("list and drop", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := runProfileList(profileListCmd, nil); err != nil {
+				t.Errorf("runProfileList failed: %v", err)
+			}
+		})
+		if !strings.Contains(output, "* dev (active)") || !strings.Contains(output, "  staging") || !strings.Contains(output, "apps/web: config.json") {
+			t.Errorf("unexpected list output: %s", output)
+		}
+
+		captureOutput(func() {
+			if err := runProfileDrop(profileDropCmd, []string{"dev"}); err != nil {
+				t.Errorf("runProfileDrop failed: %v", err)
+			}
+		})
+		m, _ := manifest.Load(dir)
+		if _, ok := m.KeepProfiles["dev"]; ok || profile.Active(dir) != "" {
+			t.Errorf("dev should be dropped and inactive: %+v", m.KeepProfiles)
+		}
+		if _, err := os.Stat(filepath.Join(profile.Dir(dir), "dev")); !os.IsNotExist(err) {
+			t.Error("stored files should be removed")
+		}
+		if readConfig(api) != "{\"local\": true}\n" {
+			t.Error("drop should leave keep files unchanged")
+		}
+	})
+}
@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/interactive"
+	"github.com/yejune/git-multirepo/internal/manifest"
+)
+
+var (
+	publishGroups []string
+	publishYes    bool
+	publishDryRun bool
+)
+
+var publishCmd = &cobra.Command{
+	Use:   "publish [path...]",
+	Short: "Push all workspaces with unpushed commits",
+	Long: `Push every workspace whose current branch is ahead of its upstream.
+
+Shows the workspaces to push and asks for confirmation, then pushes them
+in parallel. Branches that do not exist on the remote yet are pushed with
+an upstream (-u) so later pulls work.
+
+Rejected pushes (non-fast-forward, protected branches, remote hooks) are
+reported together in one table at the end.
+
+Examples:
+  git multirepo publish                 # All workspaces
+  git multirepo publish apps/api        # Specific workspace
+  git multirepo publish --group backend
+  git multirepo publish --dry-run       # Show what would be pushed`,
+	RunE: runPublish,
+}
+
+func init() {
+	publishCmd.Flags().StringSliceVarP(&publishGroups, "group", "g", nil, "Only push workspaces in group (repeatable)")
+	publishCmd.Flags().BoolVarP(&publishYes, "yes", "y", false, "Skip confirmation")
+	publishCmd.Flags().BoolVar(&publishDryRun, "dry-run", false, "Only show what would be pushed")
+}
+
+// publishPlan holds what to push in one workspace and the outcome
+type publishPlan struct {
+	ws        manifest.WorkspaceEntry
+	branch    string
+	ahead     int
+	newBranch bool // No upstream and no remote branch yet
+	skipped   bool // Not pushed because of Ctrl-C
+	result    git.PushResult
+	err       error
+}
+
+func runPublish(cmd *cobra.Command, args []string) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	workspaces, err := ctx.SelectWorkspaces(args, publishGroups)
+	if err != nil {
+		return err
+	}
+
+	// 1. Find workspaces ahead of their upstream
+	var plans []*publishPlan
+	for _, ws := range workspaces {
+		fullPath := filepath.Join(ctx.RepoRoot, ws.Path)
+		if !git.IsRepo(fullPath) {
+			continue
+		}
+
+		plan, err := planPublish(ws, fullPath)
+		if err != nil {
+			colorYellow.Fprintf(os.Stdout, "⚠ %s: %v\n", ws.Path, err)
+			continue
+		}
+		if plan != nil {
+			plans = append(plans, plan)
+		}
+	}
+
+	if len(plans) == 0 {
+		fmt.Println("Nothing to push")
+		return nil
+	}
+
+	// 2. Show summary and confirm
+	printCyan("Workspaces to push:\n")
+	for _, p := range plans {
+		if p.newBranch {
+			fmt.Printf("  %s (%s) new branch\n", p.ws.Path, p.branch)
+		} else {
+			fmt.Printf("  %s (%s) ↑%d\n", p.ws.Path, p.branch, p.ahead)
+		}
+	}
+	fmt.Println()
+
+	if publishDryRun {
+		printFaint("Dry run - nothing pushed\n")
+		return nil
+	}
+
+	if !publishYes {
+		confirmed, err := interactive.ConfirmYesNo(fmt.Sprintf("Push %d workspace(s)?", len(plans)))
+		if err != nil {
+			return fmt.Errorf("failed to read confirmation: %w", err)
+		}
+		if !confirmed {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	// 3. Push in parallel; each handler writes only its own plan
+	index := make(map[string]*publishPlan, len(plans))
+	targets := make([]manifest.WorkspaceEntry, len(plans))
+	for i, p := range plans {
+		index[p.ws.Path] = p
+		targets[i] = p.ws
+	}
+
+	runCtx := commandContext(cmd)
+	results := ctx.ForEachWorkspaceParallel(targets, common.ParallelOptions{
+		Workers: getOptimalWorkerCount(),
+		Context: runCtx,
+	}, func(ws *manifest.WorkspaceEntry, fullPath string) error {
+		p := index[ws.Path]
+		setUpstream := !git.HasUpstream(fullPath, p.branch)
+		p.result, p.err = git.PushBranch(runCtx, fullPath, p.branch, setUpstream)
+		return p.err
+	})
+	skipped := 0
+	for _, r := range results {
+		if r.Skipped {
+			index[r.Workspace.Path].skipped = true
+			skipped++
+		}
+	}
+
+	// 4. Report
+	failed := printPublishResults(plans)
+	if skipped > 0 {
+		return interruptedError(skipped)
+	}
+	if failed > 0 {
+		return fmt.Errorf("push failed in %d workspace(s)", failed)
+	}
+	return nil
+}
+
+// planPublish returns what to push for a workspace, or nil if it is up to date
+func planPublish(ws manifest.WorkspaceEntry, fullPath string) (*publishPlan, error) {
+	branch, err := git.GetCurrentBranch(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get branch: %w", err)
+	}
+	if branch == "HEAD" {
+		return nil, nil // Detached HEAD - nothing to publish
+	}
+	if _, err := git.GetRemoteURL(fullPath); err != nil {
+		return nil, fmt.Errorf("no origin remote")
+	}
+
+	unpushed, err := git.HasUnpushedCommits(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check unpushed commits: %w", err)
+	}
+	if !unpushed {
+		return nil, nil
+	}
+
+	plan := &publishPlan{ws: ws, branch: branch}
+	if !git.HasUpstream(fullPath, branch) && !git.RemoteBranchExists(fullPath, branch) {
+		plan.newBranch = true
+		return plan, nil
+	}
+
+	ahead, err := git.GetAheadCount(fullPath, branch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count commits: %w", err)
+	}
+	if ahead == 0 {
+		return nil, nil
+	}
+	plan.ahead = ahead
+	return plan, nil
+}
+
+// printPublishResults prints one table for all pushes and returns the failure count
+func printPublishResults(plans []*publishPlan) int {
+	type row struct {
+		mark, path, branch, status, detail string
+		ok                                 bool
+	}
+
+	var rows []row
+	failed, skipped := 0, 0
+	for _, p := range plans {
+		r := row{mark: "✓", path: p.ws.Path, branch: p.branch, ok: true}
+		switch {
+		case p.skipped:
+			r.mark, r.status, r.ok = "-", "skipped", false
+			r.detail = "interrupted"
+			skipped++
+		case p.err != nil:
+			r.mark, r.status, r.ok = "✗", "error", false
+			r.detail = firstLine(p.err.Error())
+		case p.result.Rejected:
+			r.mark, r.status, r.ok = "✗", p.result.Status, false
+			r.detail = describeRejection(p.result.Reason)
+		case p.result.NewBranch:
+			r.status, r.detail = "pushed", "new branch, upstream set"
+		default:
+			r.status, r.detail = "pushed", fmt.Sprintf("%d commit(s)", p.ahead)
+		}
+		if !r.ok && !p.skipped {
+			failed++
+		}
+		rows = append(rows, r)
+	}
+
+	pathWidth, branchWidth, statusWidth := len("WORKSPACE"), len("BRANCH"), len("RESULT")
+	for _, r := range rows {
+		pathWidth = max(pathWidth, len(r.path))
+		branchWidth = max(branchWidth, len(r.branch))
+		statusWidth = max(statusWidth, len(r.status))
+	}
+
+	fmt.Println()
+	printFaint("    %-*s  %-*s  %-*s  %s\n", pathWidth, "WORKSPACE", branchWidth, "BRANCH", statusWidth, "RESULT", "DETAIL")
+	for _, r := range rows {
+		line := fmt.Sprintf("  %s %-*s  %-*s  %-*s  %s\n", r.mark, pathWidth, r.path, branchWidth, r.branch, statusWidth, r.status, r.detail)
+		if r.ok {
+			printGreen("%s", line)
+		} else {
+			colorYellow.Fprint(os.Stdout, line)
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%d pushed, %d failed\n", len(rows)-failed-skipped, failed)
+	return failed
+}
+
+// describeRejection adds a hint to the reason reported by git
+func describeRejection(reason string) string {
+	switch {
+	case reason == "non-fast-forward" || reason == "fetch first":
+		return reason + " (pull first)"
+	case strings.Contains(reason, "protected"):
+		return reason + " (open a pull request instead)"
+	case reason == "":
+		return "rejected by remote"
+	}
+	return reason
+}
+
+// firstLine returns the first non-empty line of s
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yejune/git-multirepo/internal/git"
+)
+
+// resetPublishFlags restores publish command flags to their defaults (confirmation skipped)
+func resetPublishFlags() {
+	publishGroups = nil
+	publishYes = true
+	publishDryRun = false
+}
+
+// setupPublishWorkspace clones remote into path and configures the commit author
+func setupPublishWorkspace(t *testing.T, dir, remote, path string) string {
+	t.Helper()
+	cloneBranch = ""
+	if err := runClone(cloneCmd, []string{remote, path}); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	wsPath := filepath.Join(dir, path)
+	exec.Command("git", "-C", wsPath, "config", "user.email", "test@test.com").Run()
+	exec.Command("git", "-C", wsPath, "config", "user.name", "Test User").Run()
+	return wsPath
+}
+
+func TestRunPublish_NothingToPush(t *testing.T) {
+	dir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer resetPublishFlags()
+	resetPublishFlags()
+
+	setupPublishWorkspace(t, dir, setupRemoteRepo(t), "apps/api")
+
+	output := captureOutput(func() {
+		if err := runPublish(publishCmd, []string{}); err != nil {
+			t.Errorf("runPublish failed: %v", err)
+		}
+	})
+	if !strings.Contains(output, "Nothing to push") {
+		t.Errorf("expected nothing to push, got: %s", output)
+	}
+}
+
+func TestRunPublish_PushesAheadAndNewBranches(t *testing.T) {
+	dir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer resetPublishFlags()
+	resetPublishFlags()
+
+	// Non-bare remotes must accept pushes to their checked-out branch
+	apiRemote := setupRemoteRepo(t)
+	exec.Command("git", "-C", apiRemote, "config", "receive.denyCurrentBranch", "ignore").Run()
+	webRemote := setupRemoteRepo(t)
+
+	api := setupPublishWorkspace(t, dir, apiRemote, "apps/api")
+	web := setupPublishWorkspace(t, dir, webRemote, "apps/web")
+	setupPublishWorkspace(t, dir, setupRemoteRepo(t), "apps/clean")
+
+	commitFile(t, api, "api.txt", "api", "API change")
+	exec.Command("git", "-C", web, "checkout", "-q", "-b", "feature").Run()
+	commitFile(t, web, "web.txt", "web", "Web feature")
+
+	t.Run("dry run pushes nothing", func(t *testing.T) {
+		publishDryRun = true
+		defer func() { publishDryRun = false }()
+
+		output := captureOutput(func() {
+			if err := runPublish(publishCmd, []string{}); err != nil {
+				t.Errorf("runPublish failed: %v", err)
+			}
+		})
+		if !strings.Contains(output, "apps/api (") || !strings.Contains(output, "↑1") {
+			t.Errorf("summary should list apps/api ahead by 1, got: %s", output)
+		}
+		if !strings.Contains(output, "apps/web (feature) new branch") {
+			t.Errorf("summary should list the new branch, got: %s", output)
+		}
+		if strings.Contains(output, "apps/clean") {
+			t.Errorf("up-to-date workspace should not be listed, got: %s", output)
+		}
+		if git.HasUpstream(web, "feature") {
+			t.Error("dry run should not push")
+		}
+	})
+
+	t.Run("push", func(t *testing.T) {
+		output := captureOutput(func() {
+			if err := runPublish(publishCmd, []string{}); err != nil {
+				t.Errorf("runPublish failed: %v", err)
+			}
+		})
+		if !strings.Contains(output, "new branch, upstream set") || !strings.Contains(output, "1 commit(s)") {
+			t.Errorf("unexpected results, got: %s", output)
+		}
+		if !strings.Contains(output, "2 pushed, 0 failed") {
+			t.Errorf("expected 2 pushed, got: %s", output)
+		}
+		if !git.HasUpstream(web, "feature") {
+			t.Error("upstream should be set for the new branch")
+		}
+		if err := exec.Command("git", "-C", webRemote, "rev-parse", "--verify", "feature").Run(); err != nil {
+			t.Error("feature branch should exist on the remote")
+		}
+		if unpushed, _ := git.HasUnpushedCommits(api); unpushed {
+			t.Error("apps/api should have no unpushed commits")
+		}
+	})
+}
+
+func TestRunPublish_ReportsRejections(t *testing.T) {
+	dir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer resetPublishFlags()
+	resetPublishFlags()
+
+	// A non-bare remote refuses updates to its checked-out branch, like a protected branch
+	api := setupPublishWorkspace(t, dir, setupRemoteRepo(t), "apps/api")
+	commitFile(t, api, "api.txt", "api", "API change")
+
+	var err error
+	output := captureOutput(func() {
+		err = runPublish(publishCmd, []string{})
+	})
+	if err == nil {
+		t.Error("expected error for rejected push")
+	}
+	if !strings.Contains(output, "✗ apps/api") || !strings.Contains(output, "remote rejected") {
+		t.Errorf("rejection should be reported in the table, got: %s", output)
+	}
+	if !strings.Contains(output, "0 pushed, 1 failed") {
+		t.Errorf("expected 1 failure, got: %s", output)
+	}
+}
+
+func TestDescribeRejection(t *testing.T) {
+	tests := map[string]string{
+		"non-fast-forward":               "non-fast-forward (pull first)",
+		"fetch first":                    "fetch first (pull first)",
+		"protected branch hook declined": "protected branch hook declined (open a pull request instead)",
+		"":                               "rejected by remote",
+		"pre-receive hook declined":      "pre-receive hook declined",
+	}
+	for reason, want := range tests {
+		if got := describeRejection(reason); got != want {
+			t.Errorf("describeRejection(%q) = %q, want %q", reason, got, want)
+		}
+	}
+}
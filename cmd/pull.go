@@ -2,19 +2,30 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/yejune/git-multirepo/internal/backup"
 	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/crypt"
 	"github.com/yejune/git-multirepo/internal/git"
 	"github.com/yejune/git-multirepo/internal/i18n"
 	"github.com/yejune/git-multirepo/internal/interactive"
+	"github.com/yejune/git-multirepo/internal/manifest"
 	"github.com/yejune/git-multirepo/internal/patch"
 )
 
+var (
+	pullYes          bool
+	pullStrategy     string
+	pullKeepStrategy string
+	pullAutostash    bool
+)
+
 var pullCmd = &cobra.Command{
 	Use:   "pull [path]",
 	Short: "Pull latest changes for repositories",
@@ -23,26 +34,86 @@ var pullCmd = &cobra.Command{
 Examples:
   git multirepo pull              # Pull all repositories with confirmation
   git multirepo pull apps/admin   # Pull specific repository only
+  git multirepo pull --yes --strategy=ff-only --keep-strategy=fail   # CI
 
 For each repository:
   1. Shows current branch and uncommitted files
   2. Asks for confirmation (Y/n)
   3. Pulls from remote
-  4. Shows result (✓ Updated / ✗ Failed)`,
+  4. Shows result (✓ Updated / ✗ Failed)
+
+Strategies (--strategy):
+  merge     Merge remote changes (git pull --no-rebase)
+  rebase    Rebase local commits (git pull --rebase)
+  ff-only   Only fast-forward (git pull --ff-only)
+  Default: git's configured pull behavior
+
+Keep files with remote changes (--keep-strategy):
+  reapply   Update to remote and three-way merge local changes.
+            Overlapping changes are written as conflict markers; the
+            file needs resolution before the next pull.
+  theirs    Take the remote version (local changes are backed up)
+  ours      Keep the local version
+  fail      Stop pulling the workspace
+  Default: ask interactively
+
+When stdin is not a terminal (scripts, hooks, CI), pull runs without
+prompts as if --yes was given, and keep files default to reapply.
+
+With --autostash (or 'autostash: true' in the manifest) uncommitted
+changes other than keep files are stashed before pulling and reapplied
+afterwards. If reapplying conflicts, the stash entry is kept; find it
+with 'git multirepo stash list'.`,
 	RunE: runPull,
 }
 
 func init() {
 	// Command registered in root.go init() in workflow order
+	pullCmd.Flags().BoolVarP(&pullYes, "yes", "y", false, "Pull without confirmation")
+	pullCmd.Flags().StringVar(&pullStrategy, "strategy", "", "Pull strategy: merge, rebase or ff-only")
+	pullCmd.Flags().StringVar(&pullKeepStrategy, "keep-strategy", "", "Keep file conflicts: reapply, theirs, ours or fail")
+	pullCmd.Flags().BoolVar(&pullAutostash, "autostash", false, "Stash uncommitted changes before pulling and reapply them after")
 }
 
+// Keep file strategies for remote changes
+const (
+	keepStrategyReapply = "reapply"
+	keepStrategyTheirs  = "theirs"
+	keepStrategyOurs    = "ours"
+	keepStrategyFail    = "fail"
+)
+
 func runPull(cmd *cobra.Command, args []string) error {
+	switch pullStrategy {
+	case "", "merge", "rebase", "ff-only":
+	default:
+		return fmt.Errorf("invalid --strategy %q (use merge, rebase or ff-only)", pullStrategy)
+	}
+	switch pullKeepStrategy {
+	case "", keepStrategyReapply, keepStrategyTheirs, keepStrategyOurs, keepStrategyFail:
+	default:
+		return fmt.Errorf("invalid --keep-strategy %q (use reapply, theirs, ours or fail)", pullKeepStrategy)
+	}
+
+	// Prompts cannot be answered without a terminal
+	nonInteractive := pullYes || !interactive.IsTerminal(os.Stdin)
+	keepStrategy := pullKeepStrategy
+	if keepStrategy == "" && nonInteractive {
+		keepStrategy = keepStrategyReapply
+	}
+
 	// Load workspace context
 	ctx, err := common.LoadWorkspaceContext()
 	if err != nil {
 		return err
 	}
 
+	// An explicit --autostash=false overrides the manifest default
+	autostash := pullAutostash || ctx.Manifest.Autostash
+	if cmd.Flags().Changed("autostash") {
+		autostash = pullAutostash
+	}
+
 	if len(ctx.Manifest.Workspaces) == 0 {
 		fmt.Println(i18n.T("no_subs_registered"))
 		return nil
@@ -54,7 +125,13 @@ func runPull(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("%s", i18n.T("sub_not_found", args[0]))
 	}
 
-	for _, workspace := range workspacesToProcess {
+	runCtx := commandContext(cmd)
+	failed, skipped := 0, 0
+	for i, workspace := range workspacesToProcess {
+		if runCtx.Err() != nil {
+			skipped = len(workspacesToProcess) - i
+			break
+		}
 		fullPath := filepath.Join(ctx.RepoRoot, workspace.Path)
 
 		// Check if directory exists and is a git repo
@@ -75,7 +152,8 @@ func runPull(cmd *cobra.Command, args []string) error {
 		}
 
 		// Get workspace status using unified pattern
-		status, err := git.GetWorkspaceStatus(fullPath, workspace.Keep)
+		keepFiles := expandKeep(fullPath, workspace.Keep)
+		status, err := git.GetWorkspaceStatus(fullPath, keepFiles)
 		if err != nil {
 			fmt.Printf("%s:\n", workspace.Path)
 			fmt.Printf("  Failed to get status: %v\n", err)
@@ -92,43 +170,96 @@ func runPull(cmd *cobra.Command, args []string) error {
 		}
 
 		// Ask for confirmation using unified prompt
-		confirmed, err := interactive.ConfirmYesNo("  " + i18n.T("pull_confirm"))
-		if err != nil {
-			fmt.Printf("  %s\n", i18n.T("failed_read_input", err))
-			fmt.Println()
-			continue
+		if !nonInteractive {
+			confirmed, err := interactive.ConfirmYesNo("  " + i18n.T("pull_confirm"))
+			if err != nil {
+				fmt.Printf("  %s\n", i18n.T("failed_read_input", err))
+				fmt.Println()
+				continue
+			}
+
+			if !confirmed {
+				fmt.Printf("  %s\n", i18n.T("pull_skipped"))
+				fmt.Println()
+				continue
+			}
 		}
 
-		if !confirmed {
-			fmt.Printf("  %s\n", i18n.T("pull_skipped"))
+		// Fetch remote changes first
+		if err := git.Fetch(runCtx, fullPath); err != nil {
+			fmt.Printf("  %s\n", i18n.T("fetch_failed"))
 			fmt.Println()
+			failed++
 			continue
 		}
 
-		// Fetch remote changes first
-		if err := git.Fetch(fullPath); err != nil {
-			fmt.Printf("  %s\n", i18n.T("fetch_failed"))
+		// Stash uncommitted changes (except keep files) so the pull is not refused
+		stash := ""
+		if autostash {
+			stash, err = autostashForPull(fullPath, keepFiles, branch)
+			if err != nil {
+				fmt.Printf("  Autostash failed: %v\n", err)
+				fmt.Println()
+				failed++
+				continue
+			}
+		}
+
+		// Keep files renamed upstream move to their new path after the pull
+		renames, err := prepareKeepRenames(fullPath, branch, keepFiles)
+		if err != nil {
+			popAutostash(fullPath, stash)
+			fmt.Printf("  Keep file handling failed: %v\n", err)
 			fmt.Println()
+			failed++
 			continue
 		}
+		for _, r := range renames {
+			keepFiles = subtractFiles(keepFiles, []string{r.from})
+		}
 
 		// Handle keep files before pulling
-		keepFiles := workspace.Keep
+		var kept map[string]*keptFile
 		if len(keepFiles) > 0 {
-			if err := handleKeepFiles(fullPath, branch, keepFiles, ctx.RepoRoot, workspace.Path); err != nil {
+			kept, err = handleKeepFiles(runCtx, ctx.Manifest, fullPath, branch, keepFiles, ctx.RepoRoot, workspace.Path, keepStrategy)
+			if err != nil {
+				restoreKeptFiles(fullPath, kept)
+				undoKeepRenames(fullPath, renames)
+				popAutostash(fullPath, stash)
 				fmt.Printf("  Keep file handling failed: %v\n", err)
 				fmt.Println()
+				failed++
 				continue
 			}
 		}
 
 		// Pull from remote
-		if err := git.Pull(fullPath); err != nil {
+		pullErr := git.PullWithStrategy(runCtx, fullPath, pullStrategy)
+		restoreKeptFiles(fullPath, kept)
+		if pullErr != nil {
+			undoKeepRenames(fullPath, renames)
+			popAutostash(fullPath, stash)
 			fmt.Printf("  %s\n", i18n.T("pull_failed"))
 			fmt.Printf("  %s\n", i18n.T("run_status", workspace.Path))
 			fmt.Println()
+			failed++
 			continue
 		}
+		if !popAutostash(fullPath, stash) {
+			failed++
+		}
+		if len(renames) > 0 {
+			moved, err := finishKeepRenames(runCtx, ctx, workspace.Path, renames)
+			if err != nil {
+				fmt.Printf("  ✗ Following renamed keep files failed: %v\n", err)
+				failed++
+			}
+			keepFiles = append(keepFiles, moved...)
+		}
+		if unresolved := git.FilesWithConflictMarkers(fullPath, keepFiles); len(unresolved) > 0 {
+			fmt.Printf("  ✗ Keep files need conflict resolution: %s\n", strings.Join(unresolved, ", "))
+			failed++
+		}
 
 		// Count changed files
 		changedCount := 0
@@ -144,19 +275,99 @@ func runPull(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
+	if skipped > 0 {
+		return interruptedError(skipped)
+	}
+	if failed > 0 {
+		return fmt.Errorf("pull failed in %d workspace(s)", failed)
+	}
 	return nil
 }
 
-// handleKeepFiles handles keep files with remote changes interactively
-func handleKeepFiles(wsPath, branch string, keepFiles []string, repoRoot string, workspacePath string) error {
+// autostashForPull stashes uncommitted changes except keep files
+// Returns the stash commit, or "" if there was nothing to stash
+func autostashForPull(wsPath string, keepFiles []string, branch string) (string, error) {
+	// Keep files must be skip-worktree so the stash leaves them alone
+	if err := git.ApplySkipWorktree(wsPath, keepFiles); err != nil {
+		return "", err
+	}
+	stash, err := git.StashSave(wsPath, "autostash before pull on "+branch)
+	if err != nil {
+		return "", err
+	}
+	if stash != "" {
+		fmt.Printf("  → Stashed uncommitted changes\n")
+	}
+	return stash, nil
+}
+
+// popAutostash reapplies changes stashed by autostashForPull
+// Returns false (and reports the kept stash entry) if reapplying conflicts
+func popAutostash(wsPath, stash string) bool {
+	if stash == "" {
+		return true
+	}
+	if err := git.StashPopCommit(wsPath, stash); err != nil {
+		fmt.Printf("  ✗ Reapplying stashed changes conflicted\n")
+		fmt.Printf("  ℹ Changes are kept in the stash (%s); resolve conflicts, then run 'git stash drop'\n", shortHash(stash))
+		fmt.Printf("  ℹ Run 'git multirepo stash list' to see all stashes left by git-multirepo\n")
+		return false
+	}
+	fmt.Printf("  ✓ Reapplied stashed changes\n")
+	return true
+}
+
+// keptFile is the local version of a keep file set aside during a pull
+type keptFile struct {
+	content []byte
+	perm    os.FileMode
+}
+
+// handleKeepFiles handles keep files with remote changes
+// An empty keepStrategy asks interactively for each file.
+// Files whose local version is kept are reset to HEAD so the pull is not
+// refused; their content is returned for restoreKeptFiles after the pull.
+// m is the manifest of repoRoot, as loaded by the caller.
+func handleKeepFiles(runCtx context.Context, m *manifest.Manifest, wsPath, branch string, keepFiles []string, repoRoot string, workspacePath string, keepStrategy string) (map[string]*keptFile, error) {
+	kept := make(map[string]*keptFile)
 	// Use transaction pattern for skip-worktree handling
-	return git.WithSkipWorktreeTransaction(wsPath, keepFiles, func() error {
-		return handleKeepFilesWork(wsPath, branch, keepFiles, repoRoot, workspacePath)
+	err := git.WithSkipWorktreeTransaction(wsPath, keepFiles, func() error {
+		return handleKeepFilesWork(runCtx, m, wsPath, branch, keepFiles, repoRoot, workspacePath, keepStrategy, kept)
 	})
+	return kept, err
+}
+
+// restoreKeptFiles writes back keep files set aside by handleKeepFiles
+// A nil entry is a deleted keep file, which is deleted again.
+func restoreKeptFiles(wsPath string, kept map[string]*keptFile) {
+	for file, k := range kept {
+		if k == nil {
+			if err := os.Remove(filepath.Join(wsPath, file)); err != nil && !os.IsNotExist(err) {
+				fmt.Printf("  ⚠ Failed to delete %s: %v\n", file, err)
+			}
+			continue
+		}
+		if err := git.WriteFileMode(filepath.Join(wsPath, file), k.content, k.perm); err != nil {
+			fmt.Printf("  ⚠ Failed to restore %s: %v\n", file, err)
+		}
+	}
+}
+
+// keepFileUpdate describes a keep file with remote changes
+type keepFileUpdate struct {
+	wsPath        string
+	file          string
+	branch        string // Branch to take the remote version from
+	currentBranch string // Branch used in backup paths
+	repoRoot      string
+	workspacePath string
+	patchPath     string
+	key           *crypt.Key           // Encrypts backups and the patch of a sensitive file; nil otherwise
+	kept          map[string]*keptFile // Local versions to restore after the pull
 }
 
 // handleKeepFilesWork contains the actual work logic (extracted for transaction)
-func handleKeepFilesWork(wsPath, branch string, keepFiles []string, repoRoot string, workspacePath string) error {
+func handleKeepFilesWork(runCtx context.Context, m *manifest.Manifest, wsPath, branch string, keepFiles []string, repoRoot string, workspacePath string, keepStrategy string, kept map[string]*keptFile) error {
 	// Get current branch for this workspace
 	currentBranch, branchErr := git.GetCurrentBranch(wsPath)
 	if branchErr != nil {
@@ -175,105 +386,429 @@ func handleKeepFilesWork(wsPath, branch string, keepFiles []string, repoRoot str
 			continue // No remote changes, skip
 		}
 
-		// Create patch directory in .multirepos/patches/{workspace-path}/
-		patchDir := filepath.Join(repoRoot, ".multirepos", "patches", workspacePath)
-		if err := os.MkdirAll(patchDir, 0755); err != nil {
-			return fmt.Errorf("failed to create patch directory: %w", err)
+		// Merging again would nest conflict markers
+		if git.HasConflictMarkers(wsPath, file) {
+			return fmt.Errorf("%s has unresolved conflict markers from a previous pull; resolve them first", file)
+		}
+
+		// Sensitive files are backed up encrypted, or not at all
+		key, err := sensitiveKey(m, workspacePath, file)
+		if err != nil {
+			return err
+		}
+
+		u := keepFileUpdate{
+			wsPath:        wsPath,
+			file:          file,
+			branch:        branch,
+			currentBranch: currentBranch,
+			repoRoot:      repoRoot,
+			workspacePath: workspacePath,
+			patchPath:     patch.StorePath(repoRoot, workspacePath, file),
+			key:           key,
+			kept:          kept,
+		}
+
+		// A keep file deleted locally stays deleted unless the remote version is wanted
+		if _, statErr := os.Stat(filepath.Join(wsPath, file)); os.IsNotExist(statErr) && keepStrategy != keepStrategyTheirs {
+			if keepStrategy == keepStrategyFail {
+				return fmt.Errorf("%s has remote changes (--keep-strategy=fail)", file)
+			}
+			if err := keepDeletedKeepFile(u); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if keepStrategy != "" {
+			err = resolveKeepFile(runCtx, u, keepStrategy)
+		} else {
+			err = resolveKeepFileInteractive(runCtx, u)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveKeepFile applies a keep strategy without prompting
+func resolveKeepFile(runCtx context.Context, u keepFileUpdate, keepStrategy string) error {
+	switch keepStrategy {
+	case keepStrategyReapply:
+		if isBinaryKeepFile(u) {
+			fmt.Printf("  ⚠ %s is binary and cannot be merged; keeping the local version (--keep-strategy=theirs takes the remote one)\n", u.file)
+			return keepLocalKeepFile(u)
+		}
+
+		// Restore the local version if the merge fails
+		original, err := readKeptFile(u)
+		if err != nil {
+			return err
 		}
+		conflicts, err := reapplyKeepFile(runCtx, u)
+		if err != nil {
+			if writeErr := git.WriteFileMode(filepath.Join(u.wsPath, u.file), original.content, original.perm); writeErr != nil {
+				return fmt.Errorf("failed to restore %s: %w", u.file, writeErr)
+			}
+			return err
+		}
+		if conflicts > 0 {
+			reportKeepFileConflicts(u, conflicts)
+		}
+		return nil
+
+	case keepStrategyTheirs:
+		return takeRemoteKeepFile(u)
 
-		patchPath := filepath.Join(patchDir, filepath.Base(file)+".patch")
+	case keepStrategyOurs:
+		return keepLocalKeepFile(u)
 
-		// Interactive loop for this file
-		for {
-			choice, err := interactive.ResolveConflict(file, []string{
-				"Update origin and reapply patch (recommended)",
-				"Update origin only (discard patch)",
-				"Skip (keep current state)",
-				"Show diff",
-			})
+	case keepStrategyFail:
+		return fmt.Errorf("%s has remote changes (--keep-strategy=fail)", u.file)
+	}
+	return fmt.Errorf("unknown keep strategy: %s", keepStrategy)
+}
+
+// resolveKeepFileInteractive asks how to handle a keep file until an action succeeds
+func resolveKeepFileInteractive(runCtx context.Context, u keepFileUpdate) error {
+	for {
+		choice, err := interactive.ResolveConflict(u.file, []string{
+			"Update origin and merge local changes (recommended)",
+			"Update origin only (discard patch)",
+			"Skip (keep current state)",
+			"Show diff",
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get user choice: %w", err)
+		}
+
+		switch choice {
+		case 0: // Update origin and merge local changes (recommended)
+			conflicts, err := reapplyKeepFile(runCtx, u)
 			if err != nil {
-				return fmt.Errorf("failed to get user choice: %w", err)
+				fmt.Printf("  ⚠ %v\n", err)
+				continue
+			}
+			if conflicts > 0 {
+				reportKeepFileConflicts(u, conflicts)
 			}
+			return nil
 
-			switch choice {
-			case 0: // Update origin and reapply patch (recommended)
-				// Backup original file
-				backupDir := filepath.Join(repoRoot, ".multirepos", "backup")
-				if err := backup.CreateFileBackup(filepath.Join(wsPath, file), backupDir, repoRoot, workspacePath, currentBranch); err != nil {
-					return fmt.Errorf("backup failed for %s: %w", file, err)
-				}
+		case 1: // Update origin only (discard patch)
+			if err := takeRemoteKeepFile(u); err != nil {
+				fmt.Printf("  ⚠ %v\n", err)
+				continue
+			}
+			return nil
 
-				// Create patch from current local changes
-				if err := patch.Create(wsPath, file, patchPath); err != nil {
-					fmt.Printf("  ⚠ Failed to create patch: %v\n", err)
-					continue
-				}
+		case 2: // Skip (keep current state)
+			return keepLocalKeepFile(u)
 
-				// Backup patch file
-				if err := backup.CreatePatchBackup(patchPath, backupDir, workspacePath, currentBranch); err != nil {
-					fmt.Printf("  ⚠ Patch backup failed: %v\n", err)
-				}
+		case 3: // Show diff
+			diff, err := git.GetFileDiff(u.wsPath, u.file, u.branch)
+			if err != nil {
+				fmt.Printf("  ⚠ Failed to get diff: %v\n", err)
+				continue
+			}
+			if err := interactive.ShowDiff(diff); err != nil {
+				fmt.Printf("  ⚠ Failed to show diff: %v\n", err)
+			}
+			// Continue loop to show menu again
+			continue
 
-				// Reset file to remote version
-				if err := git.ResetFile(wsPath, file, branch); err != nil {
-					fmt.Printf("  ⚠ Failed to reset file: %v\n", err)
-					continue
-				}
+		default:
+			return fmt.Errorf("invalid choice: %d", choice)
+		}
+	}
+}
+
+// reapplyKeepFile backs up the file and three-way merges local changes with the remote version
+// base is HEAD, ours the local file and theirs origin/<branch>. The remote
+// version is staged so the pull is not refused. Returns the number of
+// conflicts, which are left in the file as conflict markers to resolve.
+func reapplyKeepFile(runCtx context.Context, u keepFileUpdate) (int, error) {
+	if isBinaryKeepFile(u) {
+		return 0, fmt.Errorf("%s is binary and cannot be merged", u.file)
+	}
+	fullPath := filepath.Join(u.wsPath, u.file)
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", u.file, err)
+	}
+
+	// Backup original file
+	backupDir := filepath.Join(u.repoRoot, ".multirepos", "backup")
+	if err := backup.CreateFileBackupWithKey(fullPath, backupDir, u.repoRoot, u.workspacePath, u.currentBranch, u.key); err != nil {
+		return 0, fmt.Errorf("backup failed for %s: %w", u.file, err)
+	}
+
+	// Record local changes as a patch, for reference if the merge conflicts
+	if err := patch.CreateWithKey(runCtx, u.wsPath, u.file, u.patchPath, u.key); err != nil {
+		return 0, fmt.Errorf("failed to create patch: %w", err)
+	}
+	base, _ := git.GetCurrentCommit(u.wsPath)
+	if err := patch.Record(u.repoRoot, u.workspacePath, u.file, base, u.currentBranch); err != nil {
+		fmt.Printf("  ⚠ Failed to record patch: %v\n", err)
+	}
+	if err := backup.CreatePatchBackup(u.patchPath, backupDir, u.workspacePath, u.currentBranch); err != nil {
+		fmt.Printf("  ⚠ Patch backup failed: %v\n", err)
+	}
+
+	merged, err := git.MergeFile(u.wsPath, u.file, "HEAD", "origin/"+u.branch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to merge %s: %w", u.file, err)
+	}
+
+	// Reset file to remote version, then write the merge result over it
+	if err := git.ResetFile(u.wsPath, u.file, u.branch); err != nil {
+		return 0, fmt.Errorf("failed to reset file: %w", err)
+	}
+	if err := os.WriteFile(fullPath, merged.Content, info.Mode().Perm()); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", u.file, err)
+	}
+
+	if merged.Conflicts > 0 {
+		return merged.Conflicts, nil
+	}
+
+	fmt.Printf("  ✓ Updated %s and reapplied local changes\n", u.file)
+	// Clean up successful patch
+	if err := patch.Drop(u.repoRoot, u.workspacePath, u.file); err != nil {
+		fmt.Printf("  ⚠ Failed to remove patch: %v\n", err)
+	}
+	return 0, nil
+}
+
+// reportKeepFileConflicts explains how to resolve a keep file merged with conflicts
+func reportKeepFileConflicts(u keepFileUpdate, conflicts int) {
+	fmt.Printf("  ⚠ %s: %d conflict(s) with remote changes, conflict markers written\n", u.file, conflicts)
+	fmt.Printf("  ℹ Edit the file to resolve them; original backed up, patch saved to: %s\n", u.patchPath)
+}
+
+// keepLocalKeepFile sets the local version aside so the pull can update the file
+// The local version is written back by restoreKeptFiles
+func keepLocalKeepFile(u keepFileUpdate) error {
+	local, err := readKeptFile(u)
+	if err != nil {
+		return err
+	}
+	if err := git.RestoreFileToHEAD(u.wsPath, u.file); err != nil {
+		return err
+	}
+	u.kept[u.file] = local
+	fmt.Printf("  ⏭ Skipped %s (keeping current state)\n", u.file)
+	return nil
+}
 
-				// Check patch for conflicts before applying
-				hasConflicts, err := patch.Check(wsPath, patchPath)
+// readKeptFile reads the local version of a keep file with its mode
+func readKeptFile(u keepFileUpdate) (*keptFile, error) {
+	path := filepath.Join(u.wsPath, u.file)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", u.file, err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", u.file, err)
+	}
+	return &keptFile{content: content, perm: info.Mode().Perm()}, nil
+}
+
+// keepDeletedKeepFile keeps a locally deleted keep file deleted through the pull
+// The file is restored from HEAD so the pull can update it, and deleted again
+// by restoreKeptFiles.
+func keepDeletedKeepFile(u keepFileUpdate) error {
+	if err := git.RestoreFileToHEAD(u.wsPath, u.file); err != nil {
+		return err
+	}
+	u.kept[u.file] = nil
+	fmt.Printf("  ⏭ Skipped %s (deleted locally, keeping it deleted)\n", u.file)
+	return nil
+}
+
+// isBinaryKeepFile checks if the local, HEAD or remote version of a keep file is binary
+func isBinaryKeepFile(u keepFileUpdate) bool {
+	if local, err := os.ReadFile(filepath.Join(u.wsPath, u.file)); err == nil && git.IsBinary(local) {
+		return true
+	}
+	for _, rev := range []string{"HEAD", "origin/" + u.branch} {
+		if content, err := git.GetFileAtRev(u.wsPath, rev, u.file); err == nil && git.IsBinary(content) {
+			return true
+		}
+	}
+	return false
+}
+
+// takeRemoteKeepFile replaces the file with the remote version after backing it up
+func takeRemoteKeepFile(u keepFileUpdate) error {
+	backupDir := filepath.Join(u.repoRoot, ".multirepos", "backup")
+	if err := backup.CreateFileBackupWithKey(filepath.Join(u.wsPath, u.file), backupDir, u.repoRoot, u.workspacePath, u.currentBranch, u.key); err != nil {
+		return fmt.Errorf("backup failed for %s: %w", u.file, err)
+	}
+
+	if err := git.ResetFile(u.wsPath, u.file, u.branch); err != nil {
+		return fmt.Errorf("failed to reset file: %w", err)
+	}
+	fmt.Printf("  ✓ Updated %s to remote version (local changes backed up)\n", u.file)
+	return nil
+}
+
+// keepFileRename is a keep file renamed upstream
+// Its local version is set aside before the pull and written to the new path after.
+type keepFileRename struct {
+	from, to  string
+	original  []byte      // Local version at the old path, nil if it was deleted
+	content   []byte      // Version for the new path: local changes merged with the remote version; nil if none
+	perm      os.FileMode // Mode of the local version, carried over to the new path
+	conflicts int
+}
+
+// prepareKeepRenames finds keep files renamed between HEAD and origin/<branch>
+// and sets their local versions aside, restoring the old paths to HEAD so the
+// pull can rename them. Local changes are merged with the renamed remote file.
+func prepareKeepRenames(wsPath, branch string, keepFiles []string) ([]keepFileRename, error) {
+	if len(keepFiles) == 0 || !git.RemoteBranchExists(wsPath, branch) {
+		return nil, nil
+	}
+	renamed, err := git.RenamedFiles(wsPath, "HEAD", "origin/"+branch)
+	if err != nil || len(renamed) == 0 {
+		return nil, err
+	}
+
+	var renames []keepFileRename
+	for _, from := range keepFiles {
+		to, ok := renamed[from]
+		if !ok {
+			continue
+		}
+		r := keepFileRename{from: from, to: to}
+
+		modified, err := keepFileDiffers(wsPath, "HEAD", from)
+		if err != nil {
+			return nil, err
+		}
+		if modified {
+			if r.original, err = os.ReadFile(filepath.Join(wsPath, from)); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+		if info, err := os.Stat(filepath.Join(wsPath, from)); err == nil {
+			r.perm = info.Mode().Perm()
+		}
+		if r.original != nil {
+			if git.IsBinary(r.original) {
+				r.content = r.original
+			} else {
+				merged, err := git.MergeRenamedFile(wsPath, from, to, "HEAD", "origin/"+branch)
 				if err != nil {
-					fmt.Printf("  ⚠ Failed to check patch: %v\n", err)
-					fmt.Printf("  ℹ Original backed up, patch saved to: %s\n", patchPath)
-					continue
-				}
-				if hasConflicts {
-					fmt.Printf("  ⚠ Patch has conflicts\n")
-					fmt.Printf("  ℹ Original backed up, patch saved to: %s\n", patchPath)
-					continue
+					return nil, fmt.Errorf("failed to merge %s into %s: %w", from, to, err)
 				}
+				r.content, r.conflicts = merged.Content, merged.Conflicts
+			}
+		}
 
-				// Apply patch
-				if err := patch.Apply(wsPath, patchPath); err != nil {
-					fmt.Printf("  ⚠ Failed to apply patch: %v\n", err)
-					fmt.Printf("  ℹ Original backed up\n")
-				} else {
-					fmt.Printf("  ✓ Updated %s and reapplied local changes\n", file)
-					// Clean up successful patch
-					os.Remove(patchPath)
-				}
-				return nil
+		if err := git.UnapplySkipWorktree(wsPath, []string{from}); err != nil {
+			return nil, err
+		}
+		if err := git.RestoreFileToHEAD(wsPath, from); err != nil {
+			return nil, err
+		}
+		renames = append(renames, r)
+	}
+	return renames, nil
+}
 
-			case 1: // Update origin only (discard patch)
-				// Reset file to remote version
-				if err := git.ResetFile(wsPath, file, branch); err != nil {
-					fmt.Printf("  ⚠ Failed to reset file: %v\n", err)
-					continue
-				}
-				fmt.Printf("  ✓ Updated %s to remote version (local changes discarded)\n", file)
-				return nil
+// undoKeepRenames puts back keep files set aside by prepareKeepRenames when the pull fails
+func undoKeepRenames(wsPath string, renames []keepFileRename) {
+	for _, r := range renames {
+		if r.original != nil {
+			if err := git.WriteFileMode(filepath.Join(wsPath, r.from), r.original, r.perm); err != nil {
+				fmt.Printf("  ⚠ Failed to restore %s: %v\n", r.from, err)
+			}
+		}
+		if err := git.ApplySkipWorktree(wsPath, []string{r.from}); err != nil {
+			fmt.Printf("  ⚠ %v\n", err)
+		}
+	}
+}
 
-			case 2: // Skip (keep current state)
-				fmt.Printf("  ⏭ Skipped %s (keeping current state)\n", file)
-				return nil
+// finishKeepRenames moves keep files renamed by the pull: the local version,
+// keep and sensitive entries, stored patch and skip-worktree flag follow the
+// file to its new path. Returns the new paths.
+func finishKeepRenames(runCtx context.Context, ctx *common.WorkspaceContext, workspacePath string, renames []keepFileRename) ([]string, error) {
+	target, err := resolveKeepTarget(ctx, workspacePath)
+	if err != nil {
+		return nil, err
+	}
+	ws := ctx.Manifest.Find(workspacePath)
+
+	var moved []string
+	for _, r := range renames {
+		*target.keep = renameKeepEntry(*target.keep, r.from, r.to)
+		ws.Sensitive = renameKeepEntry(ws.Sensitive, r.from, r.to)
+		if err := patch.Drop(ctx.RepoRoot, target.path, r.from); err != nil {
+			return moved, err
+		}
 
-			case 3: // Show diff
-				diff, err := git.GetFileDiff(wsPath, file, branch)
+		if r.content != nil {
+			if err := git.WriteFileMode(filepath.Join(target.fullPath, r.to), r.content, r.perm); err != nil {
+				return moved, err
+			}
+			if modified, err := keepFileDiffers(target.fullPath, "HEAD", r.to); err == nil && modified {
+				key, err := sensitiveKey(ctx.Manifest, target.path, r.to)
 				if err != nil {
-					fmt.Printf("  ⚠ Failed to get diff: %v\n", err)
-					continue
+					return moved, err
 				}
-				if err := interactive.ShowDiff(diff); err != nil {
-					fmt.Printf("  ⚠ Failed to show diff: %v\n", err)
+				branch, _ := git.GetCurrentBranch(target.fullPath)
+				head, _ := git.GetCurrentCommit(target.fullPath)
+				if err := storeKeepPatch(runCtx, ctx.RepoRoot, target, r.to, branch, head, key); err != nil {
+					return moved, err
 				}
-				// Continue loop to show menu again
-				continue
-
-			default:
-				return fmt.Errorf("invalid choice: %d", choice)
 			}
 		}
+		if err := git.ApplySkipWorktree(target.fullPath, []string{r.to}); err != nil {
+			return moved, err
+		}
+		moved = append(moved, r.to)
+
+		if r.conflicts > 0 {
+			fmt.Printf("  ⚠ %s: renamed to %s upstream, %d conflict(s) with remote changes, conflict markers written\n", r.from, r.to, r.conflicts)
+		} else {
+			fmt.Printf("  ✓ Followed rename of keep file %s → %s\n", r.from, r.to)
+		}
 	}
+	return moved, ctx.SaveManifest()
+}
 
-	return nil
+// renameKeepEntry updates a keep (or sensitive) list for a renamed file
+// A literal entry is renamed. A file covered by a pattern that no longer
+// matches its new path is added as a literal entry, so it stays covered.
+func renameKeepEntry(entries []string, from, to string) []string {
+	covered := false
+	for i, entry := range entries {
+		switch {
+		case entry == from:
+			entries[i] = to
+			return entries
+		case keepEntryMatches(entry, from):
+			covered = true
+		}
+	}
+	if !covered {
+		return entries
+	}
+	for _, entry := range entries {
+		if keepEntryMatches(entry, to) {
+			return entries
+		}
+	}
+	return append(entries, to)
+}
+
+// keepEntryMatches checks if a keep entry (file, pattern or directory) covers a file
+func keepEntryMatches(entry, file string) bool {
+	entry = strings.TrimSuffix(filepath.ToSlash(entry), "/")
+	return entry == file || git.MatchKeepPattern(entry, file) || strings.HasPrefix(file, entry+"/")
 }
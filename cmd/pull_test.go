@@ -1,13 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/yejune/git-multirepo/internal/crypt"
+	"github.com/yejune/git-multirepo/internal/git"
 	"github.com/yejune/git-multirepo/internal/manifest"
+	"github.com/yejune/git-multirepo/internal/patch"
 )
 
 // ============================================================================
@@ -153,6 +157,14 @@ func mockInteractiveChoice(choice string) func() {
 	}
 }
 
+// resetPullFlags restores pull command flags to their defaults
+func resetPullFlags() {
+	pullYes = false
+	pullStrategy = ""
+	pullKeepStrategy = ""
+	pullAutostash = false
+}
+
 // ============================================================================
 // Test Cases: Basic Flow (4 tests)
 // ============================================================================
@@ -394,18 +406,91 @@ func TestRunPull_KeepFileWithRemoteChanges_UpdateAndReapply(t *testing.T) {
 	remoteRepo := setupRemoteRepoWithCommits(t)
 	setupWorkspaceWithKeepFile(t, dir, remoteRepo, "packages/keep-reapply")
 
-	// Modify keep file locally
+	// Start from a multi-line config so local and remote edits do not overlap
 	wsPath := filepath.Join(dir, "packages/keep-reapply")
-	os.WriteFile(filepath.Join(wsPath, "config.yml"), []byte("version: 1.0\nlocal: true"), 0644)
+	commitToRemote(t, remoteRepo, "config.yml", "version: 1.0\na: 1\nb: 2\nc: 3\nlocal: false\n")
+	exec.Command("git", "-C", wsPath, "pull", "-q").Run()
+
+	// Modify keep file locally
+	os.WriteFile(filepath.Join(wsPath, "config.yml"), []byte("version: 1.0\na: 1\nb: 2\nc: 3\nlocal: true\n"), 0644)
 
 	// Commit change to remote
-	commitToRemote(t, remoteRepo, "config.yml", "version: 2.0\nremote: true")
+	commitToRemote(t, remoteRepo, "config.yml", "version: 2.0\na: 1\nb: 2\nc: 3\nlocal: false\n")
 
 	t.Run("keep file with remote changes can be updated and reapplied", func(t *testing.T) {
-		// Note: This test requires interactive input which is complex to mock
-		// We test the code paths exist but full integration would need survey mock
-		t.Skip("Interactive test - requires survey mocking")
-		_ = dir
+		defer resetPullFlags()
+		pullYes = true
+		pullKeepStrategy = "reapply"
+
+		output := captureOutput(func() {
+			if err := runPull(pullCmd, []string{}); err != nil {
+				t.Errorf("Pull failed: %v", err)
+			}
+		})
+
+		content, _ := os.ReadFile(filepath.Join(wsPath, "config.yml"))
+		if string(content) != "version: 2.0\na: 1\nb: 2\nc: 3\nlocal: true\n" {
+			t.Errorf("expected remote update with local change reapplied, got %q\noutput: %s", content, output)
+		}
+		if !strings.Contains(output, "reapplied local changes") {
+			t.Errorf("Output should report reapply, got: %s", output)
+		}
+	})
+
+	t.Run("overlapping changes leave conflict markers", func(t *testing.T) {
+		defer resetPullFlags()
+		pullYes = true
+		pullKeepStrategy = "reapply"
+
+		os.WriteFile(filepath.Join(wsPath, "config.yml"), []byte("version: 2.5\na: 1\nb: 2\nc: 3\nlocal: true\n"), 0644)
+		commitToRemote(t, remoteRepo, "config.yml", "version: 3.0\na: 1\nb: 2\nc: 3\nlocal: false\n")
+
+		var err error
+		output := captureOutput(func() {
+			err = runPull(pullCmd, []string{})
+		})
+		if err == nil {
+			t.Error("expected error while the keep file needs resolution")
+		}
+
+		content, _ := os.ReadFile(filepath.Join(wsPath, "config.yml"))
+		for _, want := range []string{"<<<<<<< local\nversion: 2.5\n", "=======\nversion: 3.0\n", "local: true\n"} {
+			if !strings.Contains(string(content), want) {
+				t.Errorf("merged file should contain %q, got %q", want, content)
+			}
+		}
+		if head, _ := exec.Command("git", "-C", wsPath, "show", "HEAD:config.yml").Output(); !strings.HasPrefix(string(head), "version: 3.0") {
+			t.Errorf("workspace should have been pulled, HEAD has %q", head)
+		}
+		if !strings.Contains(output, "1 conflict(s) with remote changes") || !strings.Contains(output, "Keep files need conflict resolution: config.yml") {
+			t.Errorf("Output should report the conflict, got: %s", output)
+		}
+		patchPath := filepath.Join(dir, ".multirepos", "patches", "packages/keep-reapply", "config.yml.patch")
+		if _, err := os.Stat(patchPath); err != nil {
+			t.Errorf("patch should be saved: %v", err)
+		}
+	})
+
+	t.Run("unresolved keep file is not merged again", func(t *testing.T) {
+		defer resetPullFlags()
+		pullYes = true
+		pullKeepStrategy = "reapply"
+
+		commitToRemote(t, remoteRepo, "config.yml", "version: 4.0\na: 1\nb: 2\nc: 3\nlocal: false\n")
+
+		var err error
+		output := captureOutput(func() {
+			err = runPull(pullCmd, []string{})
+		})
+		if err == nil {
+			t.Error("expected error for unresolved conflict markers")
+		}
+		if !strings.Contains(output, "unresolved conflict markers") {
+			t.Errorf("Output should ask to resolve first, got: %s", output)
+		}
+		if content, _ := os.ReadFile(filepath.Join(wsPath, "config.yml")); strings.Contains(string(content), "version: 4.0") {
+			t.Errorf("file should not be merged again, got %q", content)
+		}
 	})
 }
 
@@ -424,9 +509,23 @@ func TestRunPull_KeepFileWithRemoteChanges_UpdateOnly(t *testing.T) {
 	commitToRemote(t, remoteRepo, "config.yml", "version: 2.0")
 
 	t.Run("keep file with remote changes can discard local changes", func(t *testing.T) {
-		// Interactive test - would need survey mocking
-		t.Skip("Interactive test - requires survey mocking")
-		_ = dir
+		defer resetPullFlags()
+		pullYes = true
+		pullKeepStrategy = "theirs"
+
+		output := captureOutput(func() {
+			if err := runPull(pullCmd, []string{}); err != nil {
+				t.Errorf("Pull failed: %v", err)
+			}
+		})
+
+		content, _ := os.ReadFile(filepath.Join(wsPath, "config.yml"))
+		if string(content) != "version: 2.0" {
+			t.Errorf("expected remote version, got %q", content)
+		}
+		if !strings.Contains(output, "to remote version") {
+			t.Errorf("Output should report update, got: %s", output)
+		}
 	})
 }
 
@@ -440,14 +539,56 @@ func TestRunPull_KeepFileWithRemoteChanges_Skip(t *testing.T) {
 	// Modify keep file locally
 	wsPath := filepath.Join(dir, "packages/keep-skip")
 	os.WriteFile(filepath.Join(wsPath, "config.yml"), []byte("version: 1.0\nkeep_local: true"), 0644)
+	os.Chmod(filepath.Join(wsPath, "config.yml"), 0600)
 
 	// Commit change to remote
 	commitToRemote(t, remoteRepo, "config.yml", "version: 2.0")
 
 	t.Run("keep file changes can be skipped", func(t *testing.T) {
-		// Interactive test - would need survey mocking
-		t.Skip("Interactive test - requires survey mocking")
-		_ = dir
+		defer resetPullFlags()
+		pullYes = true
+		pullKeepStrategy = "ours"
+
+		output := captureOutput(func() {
+			if err := runPull(pullCmd, []string{}); err != nil {
+				t.Errorf("Pull failed: %v", err)
+			}
+		})
+
+		content, _ := os.ReadFile(filepath.Join(wsPath, "config.yml"))
+		if string(content) != "version: 1.0\nkeep_local: true" {
+			t.Errorf("local version should be kept, got %q", content)
+		}
+		if info, _ := os.Stat(filepath.Join(wsPath, "config.yml")); info.Mode().Perm() != 0600 {
+			t.Errorf("local mode should be kept, got %v", info.Mode().Perm())
+		}
+		if !strings.Contains(output, "Skipped config.yml") {
+			t.Errorf("Output should report skip, got: %s", output)
+		}
+	})
+
+	t.Run("fail strategy stops the workspace", func(t *testing.T) {
+		defer resetPullFlags()
+		pullYes = true
+		pullKeepStrategy = "fail"
+
+		commitToRemote(t, remoteRepo, "config.yml", "version: 3.0")
+		before, _ := exec.Command("git", "-C", wsPath, "rev-parse", "HEAD").Output()
+
+		var err error
+		output := captureOutput(func() {
+			err = runPull(pullCmd, []string{})
+		})
+		if err == nil {
+			t.Error("expected error with --keep-strategy=fail")
+		}
+		if !strings.Contains(output, "--keep-strategy=fail") {
+			t.Errorf("Output should explain failure, got: %s", output)
+		}
+		after, _ := exec.Command("git", "-C", wsPath, "rev-parse", "HEAD").Output()
+		if string(before) != string(after) {
+			t.Error("workspace should not be pulled")
+		}
 	})
 }
 
@@ -466,10 +607,18 @@ func TestRunPull_KeepFileBackupCreated(t *testing.T) {
 	commitToRemote(t, remoteRepo, "config.yml", "version: 2.0")
 
 	t.Run("backup is created for keep files", func(t *testing.T) {
-		// This would need full integration with interactive prompts
-		// Testing backup creation in isolation would require calling handleKeepFiles directly
-		t.Skip("Integration test - requires full workflow")
-		_ = dir
+		defer resetPullFlags()
+		pullYes = true
+		pullKeepStrategy = "theirs"
+
+		captureOutput(func() {
+			runPull(pullCmd, []string{})
+		})
+
+		backupDir := filepath.Join(dir, ".multirepos", "backup", "modified")
+		if !hasBackupInDir(backupDir, "config") {
+			t.Error("backup of the local keep file should be created")
+		}
 	})
 }
 
@@ -610,3 +759,290 @@ func TestRunPull_PullFailed(t *testing.T) {
 		_ = dir
 	})
 }
+
+// ============================================================================
+// Test Cases: Autostash
+// ============================================================================
+
+func TestRunPull_Autostash(t *testing.T) {
+	dir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer resetPullFlags()
+
+	remoteRepo := setupRemoteRepoWithCommits(t)
+	cloneBranch = ""
+	runRoot(rootCmd, []string{remoteRepo, "packages/autostash"})
+	wsPath := filepath.Join(dir, "packages/autostash")
+
+	// Rebase pulls refuse to run with unstaged changes
+	os.WriteFile(filepath.Join(wsPath, "README.md"), []byte("# Local edit"), 0644)
+	commitToRemote(t, remoteRepo, "remote.txt", "remote")
+
+	t.Run("dirty workspace fails without autostash", func(t *testing.T) {
+		resetPullFlags()
+		pullYes = true
+		pullStrategy = "rebase"
+
+		var err error
+		captureOutput(func() {
+			err = runPull(pullCmd, []string{})
+		})
+		if err == nil {
+			t.Error("expected pull to fail with unstaged changes")
+		}
+	})
+
+	t.Run("manifest autostash stashes and reapplies", func(t *testing.T) {
+		resetPullFlags()
+		pullYes = true
+		pullStrategy = "rebase"
+
+		m, _ := manifest.Load(dir)
+		m.Autostash = true
+		manifest.Save(dir, m)
+
+		output := captureOutput(func() {
+			if err := runPull(pullCmd, []string{}); err != nil {
+				t.Errorf("Pull failed: %v", err)
+			}
+		})
+
+		if _, err := os.Stat(filepath.Join(wsPath, "remote.txt")); err != nil {
+			t.Error("remote changes should be pulled")
+		}
+		if content, _ := os.ReadFile(filepath.Join(wsPath, "README.md")); string(content) != "# Local edit" {
+			t.Errorf("local changes should be reapplied, got %q", content)
+		}
+		if !strings.Contains(output, "Stashed uncommitted changes") || !strings.Contains(output, "Reapplied stashed changes") {
+			t.Errorf("output should report autostash, got: %s", output)
+		}
+		if out, _ := exec.Command("git", "-C", wsPath, "stash", "list").Output(); len(out) != 0 {
+			t.Errorf("stash should be empty, got: %s", out)
+		}
+	})
+
+	t.Run("conflicting pop keeps stash entry", func(t *testing.T) {
+		resetPullFlags()
+		pullYes = true
+		pullStrategy = "rebase"
+		pullAutostash = true
+
+		commitToRemote(t, remoteRepo, "README.md", "# Remote edit")
+
+		var err error
+		output := captureOutput(func() {
+			err = runPull(pullCmd, []string{})
+		})
+		if err == nil {
+			t.Error("expected error when reapplying conflicts")
+		}
+		if !strings.Contains(output, "Reapplying stashed changes conflicted") {
+			t.Errorf("output should report the conflict, got: %s", output)
+		}
+
+		listOutput := captureOutput(func() {
+			runStashList(stashListCmd, []string{})
+		})
+		if !strings.Contains(listOutput, "packages/autostash") || !strings.Contains(listOutput, "git-multirepo: autostash before pull") {
+			t.Errorf("stash list should show the kept entry, got: %s", listOutput)
+		}
+	})
+}
+
+func TestRunPull_KeepFileRenamedUpstream(t *testing.T) {
+	dir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer resetPullFlags()
+
+	remoteRepo := setupRemoteRepoWithCommits(t)
+	commitToRemote(t, remoteRepo, "config.yml", "version: 1.0\na: 1\nb: 2\nc: 3\nlocal: false\n")
+	setupWorkspaceWithKeepFile(t, dir, remoteRepo, "packages/keep-rename")
+	wsPath := filepath.Join(dir, "packages/keep-rename")
+
+	m, _ := manifest.Load(dir)
+	m.Find("packages/keep-rename").Sensitive = []string{"config.yml"}
+	manifest.Save(dir, m)
+	t.Setenv("GIT_MULTIREPO_PASSPHRASE", "rename test")
+
+	// Local change, then rename (and change) the file upstream
+	os.WriteFile(filepath.Join(wsPath, "config.yml"), []byte("version: 1.0\na: 1\nb: 2\nc: 3\nlocal: true\n"), 0644)
+	os.Chmod(filepath.Join(wsPath, "config.yml"), 0600)
+	exec.Command("git", "-C", wsPath, "update-index", "--skip-worktree", "config.yml").Run()
+	exec.Command("git", "-C", remoteRepo, "mv", "config.yml", "settings.yml").Run()
+	commitToRemote(t, remoteRepo, "settings.yml", "version: 2.0\na: 1\nb: 2\nc: 3\nlocal: false\n")
+
+	pullYes = true
+	pullKeepStrategy = "reapply"
+	output := captureOutput(func() {
+		if err := runPull(pullCmd, []string{}); err != nil {
+			t.Errorf("Pull failed: %v", err)
+		}
+	})
+
+	if content, _ := os.ReadFile(filepath.Join(wsPath, "settings.yml")); string(content) != "version: 2.0\na: 1\nb: 2\nc: 3\nlocal: true\n" {
+		t.Errorf("local change should follow the rename, got %q\noutput: %s", content, output)
+	}
+	if info, err := os.Stat(filepath.Join(wsPath, "settings.yml")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("mode of the local version should follow the rename, got %v", info)
+	}
+	if _, err := os.Stat(filepath.Join(wsPath, "config.yml")); !os.IsNotExist(err) {
+		t.Error("old path should be gone after the pull")
+	}
+	if !strings.Contains(output, "Followed rename of keep file config.yml → settings.yml") {
+		t.Errorf("output should report the rename, got: %s", output)
+	}
+
+	// Keep and sensitive entries, skip flag and patch move with the file
+	m, _ = manifest.Load(dir)
+	ws := m.Find("packages/keep-rename")
+	if len(ws.Keep) != 1 || ws.Keep[0] != "settings.yml" || len(ws.Sensitive) != 1 || ws.Sensitive[0] != "settings.yml" {
+		t.Errorf("manifest entries should be renamed: keep %v, sensitive %v", ws.Keep, ws.Sensitive)
+	}
+	if out, _ := exec.Command("git", "-C", wsPath, "ls-files", "-v", "settings.yml").Output(); !strings.HasPrefix(string(out), "S ") {
+		t.Errorf("settings.yml should be skip-worktree, got %q", out)
+	}
+	idx, _ := patch.LoadIndex(dir)
+	if idx.Find("packages/keep-rename", "config.yml") != nil || idx.Find("packages/keep-rename", "settings.yml") == nil {
+		t.Errorf("patch should move to the new path: %+v", idx.Patches)
+	}
+	if raw, _ := os.ReadFile(patch.StorePath(dir, "packages/keep-rename", "settings.yml")); !crypt.IsEncrypted(raw) {
+		t.Error("patch of the renamed sensitive file should stay encrypted")
+	}
+}
+
+func TestRunPull_KeepFileDeletedLocally(t *testing.T) {
+	dir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer resetPullFlags()
+
+	remoteRepo := setupRemoteRepoWithCommits(t)
+	setupWorkspaceWithKeepFile(t, dir, remoteRepo, "packages/keep-deleted")
+	wsPath := filepath.Join(dir, "packages/keep-deleted")
+
+	os.Remove(filepath.Join(wsPath, "config.yml"))
+	exec.Command("git", "-C", wsPath, "update-index", "--skip-worktree", "config.yml").Run()
+	commitToRemote(t, remoteRepo, "config.yml", "version: 2.0")
+
+	// sync records the deletion as a tombstone
+	issues := 0
+	captureOutput(func() {
+		processKeepFiles(context.Background(), loadManifest(t, dir), dir, wsPath, []string{"config.yml"}, &issues)
+	})
+	idx, _ := patch.LoadIndex(dir)
+	if e := idx.Find("packages/keep-deleted", "config.yml"); e == nil || !e.Deleted || issues != 0 {
+		t.Fatalf("deletion should be recorded as a tombstone (%d issues): %+v", issues, idx.Patches)
+	}
+
+	pullYes = true
+	pullKeepStrategy = "reapply"
+	output := captureOutput(func() {
+		if err := runPull(pullCmd, []string{}); err != nil {
+			t.Errorf("Pull failed: %v", err)
+		}
+	})
+	if _, err := os.Stat(filepath.Join(wsPath, "config.yml")); !os.IsNotExist(err) {
+		t.Errorf("deleted keep file should stay deleted\noutput: %s", output)
+	}
+	if head, _ := exec.Command("git", "-C", wsPath, "show", "HEAD:config.yml").Output(); string(head) != "version: 2.0" {
+		t.Errorf("workspace should have been pulled, HEAD has %q", head)
+	}
+}
+
+func TestRunPull_BinaryKeepFile(t *testing.T) {
+	dir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer resetPullFlags()
+
+	remoteRepo := setupRemoteRepoWithCommits(t)
+	commitToRemote(t, remoteRepo, "config.yml", "base\x00binary")
+	setupWorkspaceWithKeepFile(t, dir, remoteRepo, "packages/keep-binary")
+	wsPath := filepath.Join(dir, "packages/keep-binary")
+
+	os.WriteFile(filepath.Join(wsPath, "config.yml"), []byte("local\x00binary"), 0644)
+	exec.Command("git", "-C", wsPath, "update-index", "--skip-worktree", "config.yml").Run()
+	commitToRemote(t, remoteRepo, "config.yml", "remote\x00binary")
+
+	pullYes = true
+	pullKeepStrategy = "reapply"
+	output := captureOutput(func() {
+		if err := runPull(pullCmd, []string{}); err != nil {
+			t.Errorf("Pull failed: %v", err)
+		}
+	})
+	if content, _ := os.ReadFile(filepath.Join(wsPath, "config.yml")); string(content) != "local\x00binary" {
+		t.Errorf("binary keep file should keep its local version, got %q", content)
+	}
+	if !strings.Contains(output, "is binary and cannot be merged") {
+		t.Errorf("output should explain the binary file, got: %s", output)
+	}
+}
+
+// TestHandleKeepFiles_FakeRunner checks the decision for each keep file with git
+// answered by a fake: files without remote changes are left alone, ours sets
+// the local version aside and fail stops
+func TestHandleKeepFiles_FakeRunner(t *testing.T) {
+	fake := git.NewFakeRunner()
+	defer git.SetRunner(fake)()
+
+	root := t.TempDir()
+	wsPath := filepath.Join(root, "apps/api")
+	os.MkdirAll(wsPath, 0755)
+	manifest.Save(root, &manifest.Manifest{Workspaces: []manifest.WorkspaceEntry{
+		{Path: "apps/api", Repo: "https://example.com/api.git", Keep: []string{"config.json", "env.json", "gone.json"}},
+	}})
+	os.WriteFile(filepath.Join(wsPath, "config.json"), []byte("{\"local\": true}\n"), 0600)
+	os.WriteFile(filepath.Join(wsPath, "env.json"), []byte("{}\n"), 0644)
+
+	fake.On(wsPath, "rev-parse", "--abbrev-ref", "HEAD").Return("main\n")
+	fake.On(wsPath, "rev-parse", "--verify", "origin/main").Return("abc\n")
+	fake.On(wsPath, "update-index").Return("")
+	fake.On(wsPath, "ls-files", "-v").Return("H config.json\n")
+	fake.On(wsPath, "checkout", "HEAD", "--").Return("")
+	// config.json and gone.json changed upstream, env.json did not
+	fake.On(wsPath, "diff", "--quiet").Fail(1, "")
+	fake.On(wsPath, "diff", "--quiet", "HEAD", "origin/main", "--", "env.json").Return("")
+
+	keepFiles := []string{"config.json", "env.json", "gone.json"}
+
+	t.Run("ours", func(t *testing.T) {
+		var kept map[string]*keptFile
+		var err error
+		output := captureOutput(func() {
+			kept, err = handleKeepFiles(context.Background(), loadManifest(t, root), wsPath, "main", keepFiles, root, "apps/api", keepStrategyOurs)
+		})
+		if err != nil {
+			t.Fatalf("handleKeepFiles failed: %v", err)
+		}
+		if len(kept) != 2 {
+			t.Fatalf("expected config.json and gone.json to be set aside, got %v", kept)
+		}
+		if k := kept["config.json"]; k == nil || string(k.content) != "{\"local\": true}\n" || k.perm != 0600 {
+			t.Errorf("local version of config.json not kept: %+v", k)
+		}
+		if k, ok := kept["gone.json"]; !ok || k != nil {
+			t.Errorf("gone.json should be kept deleted, got %+v", k)
+		}
+		if !fake.Called(wsPath, "checkout", "HEAD", "--", "config.json") {
+			t.Error("config.json should be reset to HEAD for the pull")
+		}
+		if fake.Called(wsPath, "checkout", "HEAD", "--", "env.json") {
+			t.Error("env.json has no remote changes and should be left alone")
+		}
+		if !strings.Contains(output, "Skipped config.json (keeping current state)") || !strings.Contains(output, "deleted locally") {
+			t.Errorf("unexpected output: %s", output)
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		var err error
+		captureOutput(func() {
+			_, err = handleKeepFiles(context.Background(), loadManifest(t, root), wsPath, "main", keepFiles, root, "apps/api", keepStrategyFail)
+		})
+		if err == nil || !strings.Contains(err.Error(), "config.json has remote changes (--keep-strategy=fail)") {
+			t.Errorf("expected fail strategy error, got %v", err)
+		}
+		if !fake.Called(wsPath, "update-index", "--skip-worktree", "config.json") {
+			t.Error("skip-worktree should be restored after a failure")
+		}
+	})
+}
@@ -4,7 +4,6 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
@@ -121,7 +120,7 @@ func runPush(cmd *cobra.Command, args []string) error {
 	orgName := filepath.Base(orgURL)
 	fmt.Printf("\nPushing to %s/%s...\n", orgName, repoName)
 
-	if err := git.Push(workspacePath); err != nil {
+	if err := git.Push(commandContext(cmd), workspacePath); err != nil {
 		return fmt.Errorf("push failed: %w", err)
 	}
 
@@ -202,9 +201,7 @@ func setupRemote(workspacePath, repoURL string) error {
 
 	if err != nil {
 		// No remote - add it
-		cmd := exec.Command("git", "-C", workspacePath,
-			"remote", "add", "origin", repoURL)
-		if err := cmd.Run(); err != nil {
+		if err := git.AddRemote(workspacePath, "origin", repoURL); err != nil {
 			return fmt.Errorf("failed to add remote: %w", err)
 		}
 		return nil
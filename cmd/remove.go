@@ -72,7 +72,7 @@ func runRemove(cmd *cobra.Command, args []string) error {
 
 		// Get workspace status using unified pattern
 		if ws != nil {
-			status, err := git.GetWorkspaceStatus(fullPath, ws.Keep)
+			status, err := git.GetWorkspaceStatus(fullPath, expandKeep(fullPath, ws.Keep))
 			if err == nil && len(status.ModifiedFiles) > 0 {
 				fmt.Printf("⚠️  WARNING: %d modified files will be deleted:\n", len(status.ModifiedFiles))
 				for i, f := range status.ModifiedFiles {
@@ -57,18 +57,23 @@ func runReset(cmd *cobra.Command, args []string) error {
 		}
 
 		// 백업
-		for _, file := range m.Keep {
-			if err := backup.CreateFileBackup(filepath.Join(repoRoot, file), backupDir, repoRoot, "", currentBranch); err != nil {
+		keepFiles := expandKeep(repoRoot, m.Keep)
+		for _, file := range keepFiles {
+			key, err := sensitiveKey(m, "", file)
+			if err != nil {
+				return err
+			}
+			if err := backup.CreateFileBackupWithKey(filepath.Join(repoRoot, file), backupDir, repoRoot, "", currentBranch, key); err != nil {
 				return fmt.Errorf("failed to backup %s: %w", file, err)
 			}
 		}
 
 		// Unskip
-		if err := git.UnapplySkipWorktree(repoRoot, m.Keep); err != nil {
+		if err := git.UnapplySkipWorktree(repoRoot, keepFiles); err != nil {
 			return fmt.Errorf("failed to unapply skip-worktree: %w", err)
 		}
 
-		fmt.Printf("  ✓ Unskipped %d keep files\n", len(m.Keep))
+		fmt.Printf("  ✓ Unskipped %d keep files\n", len(keepFiles))
 
 		// Keep 리스트 제거
 		m.Keep = []string{}
@@ -89,18 +94,23 @@ func runReset(cmd *cobra.Command, args []string) error {
 			}
 
 			// 백업
-			for _, file := range ws.Keep {
-				if err := backup.CreateFileBackup(filepath.Join(fullPath, file), backupDir, repoRoot, ws.Path, currentBranch); err != nil {
+			keepFiles := expandKeep(fullPath, ws.Keep)
+			for _, file := range keepFiles {
+				key, err := sensitiveKey(m, ws.Path, file)
+				if err != nil {
+					return err
+				}
+				if err := backup.CreateFileBackupWithKey(filepath.Join(fullPath, file), backupDir, repoRoot, ws.Path, currentBranch, key); err != nil {
 					return fmt.Errorf("failed to backup %s: %w", file, err)
 				}
 			}
 
 			// Unskip
-			if err := git.UnapplySkipWorktree(fullPath, ws.Keep); err != nil {
+			if err := git.UnapplySkipWorktree(fullPath, keepFiles); err != nil {
 				return fmt.Errorf("failed to unapply skip-worktree in %s: %w", ws.Path, err)
 			}
 
-			fmt.Printf("  ✓ Unskipped %d keep files\n", len(ws.Keep))
+			fmt.Printf("  ✓ Unskipped %d keep files\n", len(keepFiles))
 
 			// Keep 리스트 제거
 			ws.Keep = []string{}
@@ -2,8 +2,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 )
@@ -32,10 +35,25 @@ Commands (workflow order):
   uninstall-hook Remove git hook
   status         Show detailed status of repositories
   pull           Pull latest changes
+  stash          Inspect stashes across workspaces
+  keep           Manage keep files
+  profile        Switch between named sets of keep file overrides
+  patches        Inspect and manage stored keep file patches
+  overrides      Export and import keep file patches as a bundle
+  backup         Browse and restore backups of keep files
+  snapshot       Save and restore the HEAD of every workspace
   branch         Show branch information
+  log            Show commits across all repositories
+  diff           Show uncommitted changes across all repositories
+  exec           Run a command in every workspace
+  grep           Search tracked files across all repositories
+  commit         Commit changes in all workspaces with one message
+  publish        Push all workspaces with unpushed commits
+  tag            Tag a release across workspaces
   list           List all registered workspaces
   remove         Remove a repository
   reset          Reset repository state
+  decrypt        Print the decrypted content of a backup, patch or archive
   selfupdate     Update git-multirepo to latest version`,
 	Version: Version,
 	Args:    cobra.MaximumNArgs(2),
@@ -55,10 +73,25 @@ func init() {
 	rootCmd.AddCommand(uninstallHookCmd)
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(pullCmd)
+	rootCmd.AddCommand(stashCmd)
+	rootCmd.AddCommand(keepCmd)
+	rootCmd.AddCommand(profileCmd)
+	rootCmd.AddCommand(patchesCmd)
+	rootCmd.AddCommand(overridesCmd)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(snapshotCmd)
 	rootCmd.AddCommand(branchCmd)
+	rootCmd.AddCommand(logCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(grepCmd)
+	rootCmd.AddCommand(commitCmd)
+	rootCmd.AddCommand(publishCmd)
+	rootCmd.AddCommand(tagCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(removeCmd)
 	rootCmd.AddCommand(resetCmd)
+	rootCmd.AddCommand(decryptCmd)
 	rootCmd.AddCommand(selfupdateCmd)
 
 	// Set custom usage template to show commands in workflow order
@@ -116,10 +149,56 @@ func runRoot(cmd *cobra.Command, args []string) error {
 // osExit is a variable that can be overridden in tests
 var osExit = os.Exit
 
+// exitInterrupted is the exit code after Ctrl-C or SIGTERM (128 + SIGINT)
+const exitInterrupted = 130
+
 // Execute runs the root command and exits with code 1 on error
+// Ctrl-C or SIGTERM cancels the command context: running git commands are
+// stopped and cleanup (e.g., restoring skip-worktree flags) still runs.
+// A second Ctrl-C exits immediately.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	watcher := make(chan struct{})
+	go func() {
+		defer close(watcher)
+		select {
+		case <-runCtx.Done():
+			fmt.Fprintln(os.Stderr, "\nInterrupted, cleaning up (press Ctrl-C again to force quit)...")
+			stop() // Restore default signal handling
+		case <-done:
+		}
+	}()
+
+	err := rootCmd.ExecuteContext(runCtx)
+	interrupted := runCtx.Err() != nil
+	close(done)
+	<-watcher
+	stop()
+
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
+	}
+	switch {
+	case interrupted:
+		osExit(exitInterrupted)
+	case err != nil:
 		osExit(1)
 	}
 }
+
+// commandContext returns the context of a running command
+// Falls back to context.Background() when the command was not started by
+// Execute (e.g., RunE called directly in tests)
+func commandContext(cmd *cobra.Command) context.Context {
+	if cmd != nil && cmd.Context() != nil {
+		return cmd.Context()
+	}
+	return context.Background()
+}
+
+// interruptedError reports workspaces left untouched after Ctrl-C
+func interruptedError(skipped int) error {
+	return fmt.Errorf("interrupted, %d workspace(s) not processed", skipped)
+}
@@ -0,0 +1,429 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/manifest"
+	"github.com/yejune/git-multirepo/internal/snapshot"
+)
+
+var (
+	snapshotForce bool
+	snapshotStash bool
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save and restore the HEAD of every workspace",
+	Long: `Record the branch and commit of every workspace (and the parent
+repository) before a risky operation, and check them back out later.
+
+Snapshots are stored in .multirepos/snapshots/<name>.yaml.
+
+Examples:
+  git multirepo snapshot save before-upgrade
+  git multirepo snapshot diff before-upgrade
+  git multirepo snapshot restore before-upgrade
+  git multirepo snapshot list`,
+}
+
+var snapshotSaveCmd = &cobra.Command{
+	Use:   "save <name>",
+	Short: "Record branch, HEAD and dirty state of every workspace",
+	Long: `Record the current branch, HEAD commit and whether there are
+uncommitted changes, for the parent repository and every cloned workspace.
+
+Uncommitted changes themselves are not saved; commit or stash them first
+if you need them back. An existing snapshot is only replaced with --force.
+
+Examples:
+  git multirepo snapshot save before-upgrade
+  git multirepo snapshot save nightly --force`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSnapshotSave,
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <name> [path...]",
+	Short: "Check every workspace back out to a snapshot",
+	Long: `Check every workspace back out to the commit recorded in a snapshot.
+
+The recorded branch is checked out when it still points to the recorded
+commit; otherwise the commit is checked out detached, so no branch is
+moved. Missing commits are fetched from origin. Keep files keep their
+local content.
+
+Workspaces with uncommitted changes are refused unless --stash is given,
+which leaves the changes in a stash (see 'git multirepo stash list').
+The parent repository is never checked out; a changed parent HEAD is
+reported.
+
+Examples:
+  git multirepo snapshot restore before-upgrade
+  git multirepo snapshot restore before-upgrade apps/api
+  git multirepo snapshot restore before-upgrade --stash`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSnapshotRestore,
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved snapshots",
+	Args:  cobra.NoArgs,
+	RunE:  runSnapshotList,
+}
+
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff <name> [other]",
+	Short: "Compare a snapshot with the current state or another snapshot",
+	Long: `Show the repositories whose branch, commit or dirty state differ
+between a snapshot and the current state, or between two snapshots.
+
+Examples:
+  git multirepo snapshot diff before-upgrade
+  git multirepo snapshot diff before-upgrade after-upgrade`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runSnapshotDiff,
+}
+
+func init() {
+	// Command registered in root.go init() in workflow order
+	snapshotCmd.AddCommand(snapshotSaveCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotDiffCmd)
+	snapshotSaveCmd.Flags().BoolVarP(&snapshotForce, "force", "f", false, "Replace an existing snapshot")
+	snapshotRestoreCmd.Flags().BoolVar(&snapshotStash, "stash", false, "Stash uncommitted changes and leave them in the stash")
+}
+
+func runSnapshotSave(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := snapshot.ValidateName(name); err != nil {
+		return err
+	}
+
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	if snapshot.Exists(ctx.RepoRoot, name) && !snapshotForce {
+		return fmt.Errorf("snapshot %s already exists (use --force to replace it)", name)
+	}
+
+	s, err := captureSnapshot(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := snapshot.Save(ctx.RepoRoot, s); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	dirty := 0
+	for _, e := range s.Workspaces {
+		if e.Dirty {
+			dirty++
+		}
+	}
+
+	printGreen("✓ Saved snapshot %s (%d workspace(s))\n", name, len(s.Workspaces))
+	if dirty > 0 {
+		colorYellow.Fprintf(os.Stdout, "⚠ %d workspace(s) have uncommitted changes; they are not part of the snapshot\n", dirty)
+	}
+	return nil
+}
+
+// captureSnapshot records the current state of the parent repository and every cloned workspace
+func captureSnapshot(ctx *common.WorkspaceContext, name string) (*snapshot.Snapshot, error) {
+	parent, err := captureSnapshotEntry(ctx.RepoRoot, manifest.ParentPath)
+	if err != nil {
+		return nil, fmt.Errorf("parent repository: %w", err)
+	}
+
+	s := &snapshot.Snapshot{
+		Name:       name,
+		Created:    time.Now().Truncate(time.Second),
+		Parent:     parent,
+		Workspaces: []snapshot.Entry{},
+	}
+
+	for _, ws := range ctx.Manifest.Workspaces {
+		fullPath := filepath.Join(ctx.RepoRoot, ws.Path)
+		if !git.IsRepo(fullPath) {
+			continue
+		}
+		entry, err := captureSnapshotEntry(fullPath, ws.Path)
+		if err != nil {
+			colorYellow.Fprintf(os.Stdout, "⚠ %s: %v\n", ws.Path, err)
+			continue
+		}
+		s.Workspaces = append(s.Workspaces, entry)
+	}
+	return s, nil
+}
+
+// captureSnapshotEntry records the branch, HEAD and dirty state of one repository
+func captureSnapshotEntry(fullPath, path string) (snapshot.Entry, error) {
+	commit, err := git.GetCurrentCommit(fullPath)
+	if err != nil {
+		return snapshot.Entry{}, fmt.Errorf("no HEAD commit")
+	}
+
+	branch, _ := git.GetCurrentBranch(fullPath)
+	if branch == "HEAD" {
+		branch = "" // Detached
+	}
+
+	// Keep files are skip-worktree, so only real changes count
+	dirty, err := git.HasTrackedChanges(fullPath)
+	if err != nil {
+		return snapshot.Entry{}, fmt.Errorf("failed to check changes: %w", err)
+	}
+
+	return snapshot.Entry{Path: path, Branch: branch, Commit: commit, Dirty: dirty}, nil
+}
+
+func runSnapshotRestore(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	s, err := snapshot.Load(ctx.RepoRoot, name)
+	if err != nil {
+		return err
+	}
+
+	workspaces, err := ctx.SelectWorkspaces(args[1:], nil)
+	if err != nil {
+		return err
+	}
+
+	// 1. Match workspaces with snapshot entries
+	type restoreTarget struct {
+		path     string
+		fullPath string
+		keep     []string
+		entry    *snapshot.Entry
+		dirty    bool
+	}
+	var targets []restoreTarget
+	var dirtyPaths []string
+	for _, ws := range workspaces {
+		entry := s.Find(ws.Path)
+		if entry == nil {
+			printFaint("  - %s: not in snapshot\n", ws.Path)
+			continue
+		}
+		fullPath := filepath.Join(ctx.RepoRoot, ws.Path)
+		if !git.IsRepo(fullPath) {
+			colorYellow.Fprintf(os.Stdout, "  ⚠ %s: not cloned (run 'git multirepo sync' first)\n", ws.Path)
+			continue
+		}
+		dirty, err := git.HasTrackedChanges(fullPath)
+		if err != nil {
+			return fmt.Errorf("%s: failed to check changes: %w", ws.Path, err)
+		}
+		if dirty {
+			dirtyPaths = append(dirtyPaths, ws.Path)
+		}
+		targets = append(targets, restoreTarget{path: ws.Path, fullPath: fullPath, keep: expandKeep(fullPath, ws.Keep), entry: entry, dirty: dirty})
+	}
+
+	// 2. Refuse before touching anything, so a snapshot is restored completely or not at all
+	if len(dirtyPaths) > 0 && !snapshotStash {
+		for _, p := range dirtyPaths {
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: uncommitted changes\n", p)
+		}
+		return fmt.Errorf("%d workspace(s) have uncommitted changes (commit them, or use --stash)", len(dirtyPaths))
+	}
+
+	// 3. Check out recorded commits
+	runCtx := commandContext(cmd)
+	failed, skipped := 0, 0
+	for i, t := range targets {
+		if runCtx.Err() != nil {
+			skipped = len(targets) - i
+			break
+		}
+		action, err := restoreSnapshotEntry(runCtx, t.fullPath, t.keep, t.entry, t.dirty, name)
+		if err != nil {
+			failed++
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: %v\n", t.path, err)
+			continue
+		}
+		printGreen("  ✓ %s: %s\n", t.path, action)
+	}
+
+	// 4. The parent repository is only reported
+	if current, err := captureSnapshotEntry(ctx.RepoRoot, manifest.ParentPath); err == nil && current.Commit != s.Parent.Commit {
+		colorYellow.Fprintf(os.Stdout, "⚠ Parent repository is at %s, snapshot recorded %s (not changed)\n",
+			formatSnapshotEntry(&current), formatSnapshotEntry(&s.Parent))
+	}
+
+	if skipped > 0 {
+		return interruptedError(skipped)
+	}
+	if failed > 0 {
+		return fmt.Errorf("snapshot restore failed in %d workspace(s)", failed)
+	}
+	return nil
+}
+
+// restoreSnapshotEntry checks out the recorded branch, or the recorded commit
+// detached if the branch has moved since, and describes what was done
+func restoreSnapshotEntry(runCtx context.Context, fullPath string, keep []string, entry *snapshot.Entry, dirty bool, name string) (string, error) {
+	current, err := captureSnapshotEntry(fullPath, entry.Path)
+	if err != nil {
+		return "", err
+	}
+	if current.Commit == entry.Commit && current.Branch == entry.Branch {
+		return "already at " + formatSnapshotEntry(entry), nil
+	}
+
+	if !git.CommitExists(fullPath, entry.Commit) {
+		if err := git.Fetch(runCtx, fullPath); git.IsInterrupted(err) {
+			return "", fmt.Errorf("fetch: %w", err)
+		}
+		if !git.CommitExists(fullPath, entry.Commit) {
+			return "", fmt.Errorf("commit %s not found, even after fetching origin", shortHash(entry.Commit))
+		}
+	}
+
+	target := entry.Commit
+	action := "detached at " + shortHash(entry.Commit)
+	if entry.Branch != "" {
+		if commit, err := git.GetBranchCommit(fullPath, entry.Branch); err == nil && commit == entry.Commit {
+			target = entry.Branch
+			action = "checked out " + formatSnapshotEntry(entry)
+		} else {
+			action += fmt.Sprintf(" (%s has moved since the snapshot)", entry.Branch)
+		}
+	}
+
+	if dirty {
+		if _, err := git.StashSave(fullPath, "restoring snapshot "+name); err != nil {
+			return "", err
+		}
+		action += ", changes stashed"
+	}
+
+	err = git.WithSkipWorktreeTransaction(fullPath, keep, func() error {
+		return git.CheckoutBranch(fullPath, target, "", keep)
+	})
+	if err != nil {
+		return "", err
+	}
+	return action, nil
+}
+
+func runSnapshotList(cmd *cobra.Command, args []string) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := snapshot.List(ctx.RepoRoot)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		fmt.Println("No snapshots")
+		return nil
+	}
+
+	nameWidth := len("NAME")
+	for _, s := range snapshots {
+		nameWidth = max(nameWidth, len(s.Name))
+	}
+
+	printFaint("%-*s  %-16s  %s\n", nameWidth, "NAME", "CREATED", "WORKSPACES")
+	for _, s := range snapshots {
+		dirty := 0
+		for _, e := range s.Workspaces {
+			if e.Dirty {
+				dirty++
+			}
+		}
+		fmt.Printf("%-*s  %-16s  %d", nameWidth, s.Name, s.Created.Local().Format("2006-01-02 15:04"), len(s.Workspaces))
+		if dirty > 0 {
+			colorYellow.Fprintf(os.Stdout, " (%d dirty)", dirty)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func runSnapshotDiff(cmd *cobra.Command, args []string) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	before, err := snapshot.Load(ctx.RepoRoot, args[0])
+	if err != nil {
+		return err
+	}
+
+	var after *snapshot.Snapshot
+	label := "current state"
+	if len(args) == 2 {
+		if after, err = snapshot.Load(ctx.RepoRoot, args[1]); err != nil {
+			return err
+		}
+		label = "snapshot " + args[1]
+	} else if after, err = captureSnapshot(ctx, ""); err != nil {
+		return err
+	}
+
+	changes := snapshot.Compare(before, after)
+	if len(changes) == 0 {
+		fmt.Printf("No differences between snapshot %s and %s\n", args[0], label)
+		return nil
+	}
+
+	pathWidth := len("(parent)")
+	for _, c := range changes {
+		pathWidth = max(pathWidth, len(c.Path))
+	}
+
+	printCyan("Snapshot %s → %s\n", args[0], label)
+	for _, c := range changes {
+		path := c.Path
+		if path == manifest.ParentPath {
+			path = "(parent)"
+		}
+		switch {
+		case c.Old == nil:
+			printGreen("  + %-*s  %s\n", pathWidth, path, formatSnapshotEntry(c.New))
+		case c.New == nil:
+			colorYellow.Fprintf(os.Stdout, "  - %-*s  %s\n", pathWidth, path, formatSnapshotEntry(c.Old))
+		default:
+			fmt.Printf("  ~ %-*s  %s → %s\n", pathWidth, path, formatSnapshotEntry(c.Old), formatSnapshotEntry(c.New))
+		}
+	}
+	fmt.Printf("\n%d difference(s)\n", len(changes))
+	return nil
+}
+
+// formatSnapshotEntry formats an entry as "branch abc1234", marking detached and dirty state
+func formatSnapshotEntry(e *snapshot.Entry) string {
+	branch := e.Branch
+	if branch == "" {
+		branch = "(detached)"
+	}
+	s := branch + " " + shortHash(e.Commit)
+	if e.Dirty {
+		s += " (dirty)"
+	}
+	return s
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/snapshot"
+)
+
+// resetSnapshotFlags restores snapshot command flags to their defaults
+func resetSnapshotFlags() {
+	snapshotForce = false
+	snapshotStash = false
+}
+
+func TestRunSnapshotSave(t *testing.T) {
+	dir, _, _ := setupBranchWorkspaces(t)
+	defer resetSnapshotFlags()
+	resetSnapshotFlags()
+	api := filepath.Join(dir, "apps/api")
+	os.WriteFile(filepath.Join(api, "README.md"), []byte("# Dirty\n"), 0644)
+
+	output := captureOutput(func() {
+		if err := runSnapshotSave(snapshotSaveCmd, []string{"before"}); err != nil {
+			t.Errorf("runSnapshotSave failed: %v", err)
+		}
+	})
+	if !strings.Contains(output, "Saved snapshot before (2 workspace(s))") || !strings.Contains(output, "1 workspace(s) have uncommitted changes") {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	s, err := snapshot.Load(dir, "before")
+	if err != nil {
+		t.Fatalf("snapshot not saved: %v", err)
+	}
+	head, _ := git.GetCurrentCommit(api)
+	if e := s.Find("apps/api"); e == nil || e.Commit != head || e.Branch != currentBranch(t, api) || !e.Dirty {
+		t.Errorf("apps/api entry differs: %+v", e)
+	}
+	if e := s.Find("apps/web"); e == nil || e.Dirty {
+		t.Errorf("apps/web should be clean (keep files do not count): %+v", e)
+	}
+	if parent, _ := git.GetCurrentCommit(dir); s.Parent.Commit != parent {
+		t.Errorf("parent commit = %s, want %s", s.Parent.Commit, parent)
+	}
+
+	t.Run("existing name needs --force", func(t *testing.T) {
+		if err := runSnapshotSave(snapshotSaveCmd, []string{"before"}); err == nil {
+			t.Error("expected error for existing snapshot")
+		}
+		snapshotForce = true
+		defer func() { snapshotForce = false }()
+		captureOutput(func() {
+			if err := runSnapshotSave(snapshotSaveCmd, []string{"before"}); err != nil {
+				t.Errorf("--force should replace snapshot: %v", err)
+			}
+		})
+	})
+}
+
+func TestRunSnapshotRestore(t *testing.T) {
+	dir, _, _ := setupBranchWorkspaces(t)
+	defer resetSnapshotFlags()
+	defer resetBranchFlags()
+	resetSnapshotFlags()
+	resetBranchFlags()
+	api, web := filepath.Join(dir, "apps/api"), filepath.Join(dir, "apps/web")
+	base := currentBranch(t, api)
+	baseHead, _ := git.GetCurrentCommit(api) // Both workspaces clone the same remote
+
+	captureOutput(func() {
+		runSnapshotSave(snapshotSaveCmd, []string{"before"})
+	})
+
+	// apps/api moves to a feature branch, apps/web gets a new commit on its branch
+	captureOutput(func() {
+		runBranchCreate(branchCreateCmd, []string{"feat/x", "apps/api"})
+	})
+	commitFile(t, web, "new.txt", "new", "Move on")
+
+	t.Run("dirty workspace refuses everything", func(t *testing.T) {
+		os.WriteFile(filepath.Join(api, "README.md"), []byte("# Dirty\n"), 0644)
+		defer exec.Command("git", "-C", api, "checkout", "--", "README.md").Run()
+
+		var err error
+		output := captureOutput(func() {
+			err = runSnapshotRestore(snapshotRestoreCmd, []string{"before"})
+		})
+		if err == nil {
+			t.Error("expected error for dirty workspace")
+		}
+		if !strings.Contains(output, "apps/api: uncommitted changes") {
+			t.Errorf("output should name the dirty workspace, got: %s", output)
+		}
+		if currentBranch(t, api) != "feat/x" {
+			t.Error("no workspace should be touched")
+		}
+	})
+
+	output := captureOutput(func() {
+		if err := runSnapshotRestore(snapshotRestoreCmd, []string{"before"}); err != nil {
+			t.Errorf("runSnapshotRestore failed: %v", err)
+		}
+	})
+
+	if branch := currentBranch(t, api); branch != base {
+		t.Errorf("apps/api: expected %s, got %s", base, branch)
+	}
+	if head, _ := git.GetCurrentCommit(api); head != baseHead {
+		t.Errorf("apps/api: expected %s, got %s", baseHead, head)
+	}
+	if content, _ := os.ReadFile(filepath.Join(api, "config.json")); !strings.Contains(string(content), "local") {
+		t.Errorf("keep file content lost: %q", content)
+	}
+
+	// The branch moved, so the recorded commit is checked out detached
+	if branch := currentBranch(t, web); branch != "HEAD" {
+		t.Errorf("apps/web: expected detached HEAD, got %s", branch)
+	}
+	if head, _ := git.GetCurrentCommit(web); head != baseHead {
+		t.Errorf("apps/web: expected %s, got %s", baseHead, head)
+	}
+	if !strings.Contains(output, "has moved since the snapshot") {
+		t.Errorf("output should explain detached checkout, got: %s", output)
+	}
+
+	t.Run("--stash stashes dirty workspaces", func(t *testing.T) {
+		snapshotStash = true
+		defer func() { snapshotStash = false }()
+		exec.Command("git", "-C", api, "checkout", "-q", "feat/x").Run()
+		os.WriteFile(filepath.Join(api, "README.md"), []byte("# Dirty\n"), 0644)
+
+		output := captureOutput(func() {
+			if err := runSnapshotRestore(snapshotRestoreCmd, []string{"before", "apps/api"}); err != nil {
+				t.Errorf("runSnapshotRestore failed: %v", err)
+			}
+		})
+		if branch := currentBranch(t, api); branch != base {
+			t.Errorf("expected %s, got %s", base, branch)
+		}
+		if !strings.Contains(output, "changes stashed") {
+			t.Errorf("output should mention stash, got: %s", output)
+		}
+		if entries, _ := git.ListStashes(api); len(entries) != 1 || !strings.Contains(entries[0].Subject, "restoring snapshot before") {
+			t.Errorf("expected one labeled stash, got %+v", entries)
+		}
+	})
+}
+
+func TestRunSnapshotListAndDiff(t *testing.T) {
+	dir, _, _ := setupBranchWorkspaces(t)
+	defer resetSnapshotFlags()
+	resetSnapshotFlags()
+	web := filepath.Join(dir, "apps/web")
+
+	output := captureOutput(func() {
+		runSnapshotList(snapshotListCmd, []string{})
+	})
+	if !strings.Contains(output, "No snapshots") {
+		t.Errorf("expected no snapshots, got: %s", output)
+	}
+
+	captureOutput(func() {
+		runSnapshotSave(snapshotSaveCmd, []string{"before"})
+	})
+
+	output = captureOutput(func() {
+		runSnapshotDiff(snapshotDiffCmd, []string{"before"})
+	})
+	if !strings.Contains(output, "No differences") {
+		t.Errorf("expected no differences, got: %s", output)
+	}
+
+	commitFile(t, web, "new.txt", "new", "Move on")
+	captureOutput(func() {
+		runSnapshotSave(snapshotSaveCmd, []string{"after"})
+	})
+
+	output = captureOutput(func() {
+		runSnapshotList(snapshotListCmd, []string{})
+	})
+	if !strings.Contains(output, "before") || !strings.Contains(output, "after") {
+		t.Errorf("list should show both snapshots, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		if err := runSnapshotDiff(snapshotDiffCmd, []string{"before", "after"}); err != nil {
+			t.Errorf("runSnapshotDiff failed: %v", err)
+		}
+	})
+	if !strings.Contains(output, "~ apps/web") || strings.Contains(output, "apps/api") {
+		t.Errorf("only apps/web should differ, got: %s", output)
+	}
+	if !strings.Contains(output, "1 difference(s)") {
+		t.Errorf("expected difference count, got: %s", output)
+	}
+}
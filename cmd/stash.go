@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/git"
+)
+
+var (
+	stashListAll    bool
+	stashListGroups []string
+)
+
+var stashCmd = &cobra.Command{
+	Use:   "stash",
+	Short: "Inspect stashes across workspaces",
+	Long: `Inspect stash entries across workspaces.
+
+pull --autostash and branch switching stash uncommitted changes with a
+"git-multirepo:" message. When reapplying them conflicts, the entry is
+kept so nothing is lost; 'stash list' finds those entries.`,
+}
+
+var stashListCmd = &cobra.Command{
+	Use:   "list [path...]",
+	Short: "List stashes left by git-multirepo",
+	Long: `List stash entries created by git-multirepo in every workspace.
+
+Use --all to include the workspace's own stashes too.
+
+Examples:
+  git multirepo stash list
+  git multirepo stash list --all apps/api`,
+	RunE: runStashList,
+}
+
+func init() {
+	// Command registered in root.go init() in workflow order
+	stashCmd.AddCommand(stashListCmd)
+	stashListCmd.Flags().BoolVar(&stashListAll, "all", false, "Include stashes not created by git-multirepo")
+	stashListCmd.Flags().StringSliceVarP(&stashListGroups, "group", "g", nil, "Only include workspaces in group (repeatable)")
+}
+
+func runStashList(cmd *cobra.Command, args []string) error {
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	workspaces, err := ctx.SelectWorkspaces(args, stashListGroups)
+	if err != nil {
+		return err
+	}
+
+	found := 0
+	for _, ws := range workspaces {
+		fullPath := filepath.Join(ctx.RepoRoot, ws.Path)
+		if !git.IsRepo(fullPath) {
+			continue
+		}
+
+		entries, err := git.ListStashes(fullPath)
+		if err != nil {
+			colorYellow.Fprintf(os.Stdout, "⚠ %s: %v\n", ws.Path, err)
+			continue
+		}
+
+		header := false
+		for _, e := range entries {
+			if !stashListAll && !e.IsMarked() {
+				continue
+			}
+			if !header {
+				printCyan("%s\n", ws.Path)
+				header = true
+			}
+			fmt.Printf("  %-10s ", e.Ref)
+			printFaint("%s ", e.Date.Format("2006-01-02 15:04"))
+			fmt.Printf("%s\n", e.Subject)
+			found++
+		}
+	}
+
+	if found == 0 {
+		if stashListAll {
+			fmt.Println("No stashes")
+		} else {
+			fmt.Println("No stashes left by git-multirepo")
+		}
+	}
+	return nil
+}
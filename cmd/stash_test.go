@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yejune/git-multirepo/internal/git"
+)
+
+// resetStashFlags restores stash command flags to their defaults
+func resetStashFlags() {
+	stashListAll = false
+	stashListGroups = nil
+}
+
+func TestRunStashList(t *testing.T) {
+	dir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer resetStashFlags()
+
+	remoteRepo := setupRemoteRepo(t)
+	cloneBranch = ""
+	runClone(cloneCmd, []string{remoteRepo, "apps/api"})
+	runClone(cloneCmd, []string{remoteRepo, "apps/web"})
+	api := filepath.Join(dir, "apps/api")
+
+	t.Run("no stashes", func(t *testing.T) {
+		resetStashFlags()
+		output := captureOutput(func() {
+			runStashList(stashListCmd, []string{})
+		})
+		if !strings.Contains(output, "No stashes left by git-multirepo") {
+			t.Errorf("unexpected output: %s", output)
+		}
+	})
+
+	os.WriteFile(filepath.Join(api, "README.md"), []byte("user"), 0644)
+	exec.Command("git", "-C", api, "stash", "push", "-q", "-m", "my own stash").Run()
+	os.WriteFile(filepath.Join(api, "README.md"), []byte("marked"), 0644)
+	git.StashSave(api, "autostash before pull on main")
+
+	t.Run("marked stashes only", func(t *testing.T) {
+		resetStashFlags()
+		output := captureOutput(func() {
+			if err := runStashList(stashListCmd, []string{}); err != nil {
+				t.Errorf("runStashList failed: %v", err)
+			}
+		})
+		if !strings.Contains(output, "apps/api") || !strings.Contains(output, "stash@{0}") {
+			t.Errorf("expected marked entry, got: %s", output)
+		}
+		if strings.Contains(output, "my own stash") {
+			t.Errorf("user stash should be hidden, got: %s", output)
+		}
+		if strings.Contains(output, "apps/web") {
+			t.Errorf("workspace without stashes should be hidden, got: %s", output)
+		}
+	})
+
+	t.Run("all stashes", func(t *testing.T) {
+		resetStashFlags()
+		stashListAll = true
+		output := captureOutput(func() {
+			runStashList(stashListCmd, []string{})
+		})
+		if !strings.Contains(output, "my own stash") {
+			t.Errorf("--all should include user stashes, got: %s", output)
+		}
+	})
+}
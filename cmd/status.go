@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,6 +13,7 @@ import (
 	"github.com/yejune/git-multirepo/internal/git"
 	"github.com/yejune/git-multirepo/internal/hooks"
 	"github.com/yejune/git-multirepo/internal/i18n"
+	"github.com/yejune/git-multirepo/internal/profile"
 )
 
 var (
@@ -416,6 +418,11 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	printCyan("Git Multirepo Status\n")
 	printGray("%s\n\n", strings.Repeat("─", 80))
 
+	if active := profile.Active(ctx.RepoRoot); active != "" {
+		fmt.Printf("Keep profile: ")
+		printCyan("%s\n\n", active)
+	}
+
 	// Section 0: Multirepo Integrity Check
 	printGray("%s\n", strings.Repeat("━", 80))
 	printBlue("%s\n", i18n.T("integrity_check"))
@@ -543,7 +550,12 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("%s", i18n.T("sub_not_found", args[0]))
 	}
 
-	for _, ws := range workspacesToProcess {
+	runCtx := commandContext(cmd)
+	for i, ws := range workspacesToProcess {
+		if runCtx.Err() != nil {
+			return interruptedError(len(workspacesToProcess) - i)
+		}
+
 		// Add separator between repositories
 		printGray("%s\n", strings.Repeat("─", 80))
 		fmt.Println()
@@ -572,12 +584,13 @@ func runStatus(cmd *cobra.Command, args []string) error {
 			branch = "unknown"
 		}
 		fmt.Printf("  Branch: %s\n", branch)
+		printKeepExpansions(fullPath, ws.Keep)
 
 		// Section 1: Local Status
 		printBlue("  %s\n", i18n.T("local_status"))
 
 		// Get workspace status using unified pattern
-		status, err := git.GetWorkspaceStatus(fullPath, ws.Keep)
+		status, err := git.GetWorkspaceStatus(fullPath, expandKeep(fullPath, ws.Keep))
 		hasLocalChanges := false
 		if err != nil {
 			printRed("    Failed to get status: %v\n", err)
@@ -606,6 +619,14 @@ func runStatus(cmd *cobra.Command, args []string) error {
 				}
 			}
 
+			if len(status.UnresolvedFiles) > 0 {
+				hasLocalChanges = true
+				printRed("    Keep files with unresolved conflicts (%d):\n", len(status.UnresolvedFiles))
+				for _, file := range status.UnresolvedFiles {
+					printGray("      - %s (edit to remove the conflict markers)\n", file)
+				}
+			}
+
 			if !hasLocalChanges {
 				printGreen("    %s\n", i18n.T("clean_working_tree"))
 			}
@@ -617,9 +638,10 @@ func runStatus(cmd *cobra.Command, args []string) error {
 
 		// Fetch from remote only if --fetch flag is set
 		if statusFetch {
-			if err := git.Fetch(fullPath); err != nil {
-				if err == git.ErrFetchTimeout {
-					printYellow("    ⚠ Fetch timed out, using cached data\n")
+			if err := git.Fetch(runCtx, fullPath); err != nil {
+				var timeoutErr *git.TimeoutError
+				if errors.As(err, &timeoutErr) {
+					printYellow("    ⚠ Fetch timed out after %v, using cached data\n", timeoutErr.After)
 				}
 			}
 		}
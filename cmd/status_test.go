@@ -6,7 +6,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/yejune/git-multirepo/internal/git"
 	"github.com/yejune/git-multirepo/internal/hooks"
+	"github.com/yejune/git-multirepo/internal/manifest"
 )
 
 // TestGetHookStatusStringEdgeCases tests getHookStatus function with various string edge cases
@@ -460,3 +462,49 @@ sync 2
 		}
 	})
 }
+
+// TestRunStatus_FakeRunner checks the local and remote sections of a workspace
+// with git answered by a fake
+func TestRunStatus_FakeRunner(t *testing.T) {
+	fake := git.NewFakeRunner()
+	defer git.SetRunner(fake)()
+
+	// Only the .git directories and the manifest are needed on disk
+	root := t.TempDir()
+	wsPath := filepath.Join(root, "apps/api")
+	os.MkdirAll(filepath.Join(root, ".git"), 0755)
+	os.MkdirAll(filepath.Join(wsPath, ".git"), 0755)
+	manifest.Save(root, &manifest.Manifest{Workspaces: []manifest.WorkspaceEntry{
+		{Path: "apps/api", Repo: "https://example.com/api.git", Keep: []string{"config.json"}},
+	}})
+	originalDir, _ := os.Getwd()
+	os.Chdir(root)
+	defer os.Chdir(originalDir)
+
+	fake.On("", "rev-parse", "--show-toplevel").Return(root + "\n")
+	fake.On("", "rev-parse", "--abbrev-ref", "HEAD").Return("main\n")
+	fake.On("", "diff", "--name-only").Return("")
+	fake.On("", "ls-files", "--others").Return("")
+	fake.On(wsPath, "ls-files", "-z").Return("config.json\x00main.go\x00")
+	fake.On(wsPath, "ls-files", "-v").Return("S config.json\n")
+	fake.On(wsPath, "update-index").Return("")
+	fake.On(wsPath, "diff", "--name-only", "HEAD").Return("main.go\n")
+	fake.On(wsPath, "ls-files", "--others").Return("new.txt\n")
+	fake.On(wsPath, "rev-parse", "--verify", "origin/main").Return("abc\n")
+	fake.On(wsPath, "rev-list", "--count", "main..origin/main").Return("2\n")
+	fake.On(wsPath, "rev-list", "--count", "origin/main..main").Return("0\n")
+
+	output := captureOutput(func() {
+		if err := runStatus(statusCmd, nil); err != nil {
+			t.Errorf("runStatus failed: %v", err)
+		}
+	})
+	for _, want := range []string{"Repository: apps/api", "Branch: main", "- main.go", "- new.txt", "git multirepo pull apps/api"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("status should contain %q, got: %s", want, output)
+		}
+	}
+	if !fake.Called(wsPath, "update-index", "--no-skip-worktree", "config.json") {
+		t.Error("keep file should be unskipped while listing modified files")
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/yejune/git-multirepo/internal/backup"
 	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/crypt"
 	"github.com/yejune/git-multirepo/internal/git"
 	"github.com/yejune/git-multirepo/internal/i18n"
 	"github.com/yejune/git-multirepo/internal/manifest"
@@ -262,6 +263,7 @@ func runSync(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	runCtx := commandContext(cmd)
 
 	fmt.Println(i18n.T("syncing"))
 
@@ -330,7 +332,7 @@ func runSync(cmd *cobra.Command, args []string) error {
 
 	// 3. Process Mother repo keep files
 	issues := 0
-	motherKeepFiles := ctx.Manifest.Keep
+	motherKeepFiles := expandKeep(ctx.RepoRoot, ctx.Manifest.Keep)
 	if len(motherKeepFiles) > 0 {
 		fmt.Println()
 		printCyan("Mother Repository\n")
@@ -338,7 +340,7 @@ func runSync(cmd *cobra.Command, args []string) error {
 		if syncVerbose {
 			printKeepFileList(os.Stdout, motherKeepFiles)
 		}
-		processKeepFiles(ctx.RepoRoot, ctx.RepoRoot, motherKeepFiles, &issues)
+		processKeepFiles(runCtx, ctx.Manifest, ctx.RepoRoot, ctx.RepoRoot, motherKeepFiles, &issues)
 	}
 
 	if len(ctx.Manifest.Workspaces) == 0 {
@@ -349,7 +351,12 @@ func runSync(cmd *cobra.Command, args []string) error {
 	// 4. Process each workspace
 	fmt.Println(i18n.T("processing_subclones"))
 
-	for _, ws := range ctx.Manifest.Workspaces {
+	skipped := 0
+	for i, ws := range ctx.Manifest.Workspaces {
+		if runCtx.Err() != nil {
+			skipped = len(ctx.Manifest.Workspaces) - i
+			break
+		}
 		fullPath := filepath.Join(ctx.RepoRoot, ws.Path)
 		fmt.Println()
 		printCyan("  %s\n", ws.Path)
@@ -362,7 +369,7 @@ func runSync(cmd *cobra.Command, args []string) error {
 				// Directory exists with files - init git in place
 				fmt.Printf("    %s\n", i18n.T("initializing_git"))
 
-				if err := git.InitRepo(fullPath, ws.Repo, ws.Branch); err != nil {
+				if err := git.InitRepo(runCtx, fullPath, ws.Repo, ws.Branch); err != nil {
 					fmt.Printf("    %s\n", i18n.T("failed_initialize", err))
 					issues++
 					continue
@@ -389,7 +396,7 @@ func runSync(cmd *cobra.Command, args []string) error {
 			}
 
 			// Clone the repository
-			if err := git.Clone(ws.Repo, fullPath, ws.Branch); err != nil {
+			if err := git.Clone(runCtx, ws.Repo, fullPath, ws.Branch); err != nil {
 				fmt.Printf("    %s\n", i18n.T("clone_failed", err))
 				issues++
 				continue
@@ -416,13 +423,16 @@ func runSync(cmd *cobra.Command, args []string) error {
 		}
 
 		// Process keep files for this workspace
-		keepFiles := ws.Keep
+		// Patterns are expanded on every sync, so newly tracked files are kept too
+		keepFiles := expandKeep(fullPath, ws.Keep)
 		if len(keepFiles) > 0 {
 			printBlue("    → Processing keep files (%d files)\n", len(keepFiles))
 			if syncVerbose {
 				printKeepFileList(os.Stdout, keepFiles)
 			}
-			processKeepFiles(ctx.RepoRoot, fullPath, keepFiles, &issues)
+			processKeepFiles(runCtx, ctx.Manifest, ctx.RepoRoot, fullPath, keepFiles, &issues)
+		} else if len(ws.Keep) > 0 {
+			printFaint("    - Keep patterns match no tracked files\n")
 		} else {
 			printGreen("    ✓ No keep files - clean workspace\n")
 		}
@@ -432,12 +442,19 @@ func runSync(cmd *cobra.Command, args []string) error {
 	if err := ctx.SaveManifest(); err != nil {
 		return fmt.Errorf("failed to save manifest: %w", err)
 	}
+	if skipped > 0 {
+		return interruptedError(skipped)
+	}
 
 	// 5. Check if archiving should run (24 hours check)
 	multireposDir := filepath.Join(ctx.RepoRoot, ".multirepos")
 	if backup.ShouldRunArchive(multireposDir) {
 		backupDir := filepath.Join(multireposDir, "backup")
-		if err := backup.ArchiveOldBackups(backupDir); err != nil {
+		// Archives are encrypted whenever a key is configured
+		key, err := crypt.LoadKey()
+		if err != nil {
+			fmt.Printf("\n⚠️  Archive skipped: %v\n", err)
+		} else if err := backup.ArchiveOldBackupsFor(backupDir, ctx.Manifest.Paths(), key); err != nil {
 			fmt.Printf("\n⚠️  Archive failed: %v\n", err)
 			// Don't fail the entire sync if archiving fails
 		} else {
@@ -446,6 +463,16 @@ func runSync(cmd *cobra.Command, args []string) error {
 				fmt.Printf("\n⚠️  Failed to update archive check time: %v\n", err)
 			}
 		}
+
+		// Apply the retention limits, if any
+		if retention, err := backupRetention(ctx.Manifest); err != nil {
+			fmt.Printf("\n⚠️  Prune skipped: %v\n", err)
+		} else if !retention.IsZero() {
+			fmt.Println("\n[Prune] Applying backup retention...")
+			if err := pruneBackups(ctx.RepoRoot, retention, false); err != nil {
+				fmt.Printf("\n⚠️  Prune failed: %v\n", err)
+			}
+		}
 	}
 
 	// Summary
@@ -677,9 +704,10 @@ func scanForWorkspaces(scanRoot, manifestRoot string) ([]manifest.WorkspaceEntry
 }
 
 // processKeepFiles handles backup, patch creation, and skip-worktree for keep files
-func processKeepFiles(repoRoot, workspacePath string, keepFiles []string, issues *int) {
+// m is the manifest of repoRoot, as loaded by the caller; sensitive files are
+// looked up in it.
+func processKeepFiles(runCtx context.Context, m *manifest.Manifest, repoRoot, workspacePath string, keepFiles []string, issues *int) {
 	backupDir := filepath.Join(repoRoot, ".multirepos", "backup")
-	patchBaseDir := filepath.Join(repoRoot, ".multirepos", "patches")
 
 	// Determine workspace path for patches and backups
 	relPath, err := filepath.Rel(repoRoot, workspacePath)
@@ -689,13 +717,20 @@ func processKeepFiles(repoRoot, workspacePath string, keepFiles []string, issues
 	if relPath == "." {
 		relPath = ""
 	}
+	patchWorkspace := relPath
+	if patchWorkspace == "" {
+		patchWorkspace = manifest.ParentPath
+	}
 
 	// Clean slate strategy: Remove directories before saving to prevent file leakage
 
-	// 1. Clean patches directory (complete workspace patch dir) - 최신 상태만 유지
-	patchDir := filepath.Join(patchBaseDir, relPath)
-	os.RemoveAll(patchDir)
-	os.MkdirAll(patchDir, 0755)
+	// 1. Clean patches of this workspace (and their index entries) - 최신 상태만 유지
+	if err := patch.DropWorkspace(repoRoot, patchWorkspace); err != nil {
+		fmt.Printf("        Failed to clean patches: %v\n", err)
+	}
+	if relPath != "" {
+		os.RemoveAll(filepath.Join(patch.Dir(repoRoot), relPath))
+	}
 
 	// 2. Prepare today's backup directories (타임스탬프 기반 누적, 삭제 금지)
 	today := time.Now().Format("2006/01/02")
@@ -717,6 +752,7 @@ func processKeepFiles(repoRoot, workspacePath string, keepFiles []string, issues
 			currentBranch = "HEAD"
 			fmt.Printf("        Warning: failed to get branch, using HEAD: %v\n", branchErr)
 		}
+		headCommit, _ := git.GetCurrentCommit(workspacePath)
 
 		// 3a. Get modified files
 		var err error
@@ -736,12 +772,6 @@ func processKeepFiles(repoRoot, workspacePath string, keepFiles []string, issues
 
 		// 3b. Auto-populate Keep list if empty and there are modified files
 		if len(keepFiles) == 0 && len(modifiedFiles) > 0 {
-			// Load manifest to update it
-			m, loadErr := manifest.Load(repoRoot)
-			if loadErr != nil {
-				return fmt.Errorf("failed to load manifest: %w", loadErr)
-			}
-
 			// Update the keep list in manifest
 			if relPath == "" || relPath == "." {
 				// Mother repo
@@ -775,25 +805,47 @@ func processKeepFiles(repoRoot, workspacePath string, keepFiles []string, issues
 		for _, file := range modifiedFiles {
 			filePath := filepath.Join(workspacePath, file)
 
-			// Check if file exists
+			// Sensitive files are backed up and patched encrypted, or not at all
+			key, keyErr := sensitiveKey(m, relPath, file)
+			if keyErr != nil {
+				fmt.Printf("        Failed to backup %s: %v\n", file, keyErr)
+				*issues++
+				continue
+			}
+
+			// A deleted file is recorded as a tombstone (its deletion patch); HEAD still has the content
 			if _, statErr := os.Stat(filePath); os.IsNotExist(statErr) {
-				continue // Skip if file doesn't exist
+				patchPath := patch.StorePath(repoRoot, patchWorkspace, file)
+				if patchErr := patch.CreateWithKey(runCtx, workspacePath, file, patchPath, key); patchErr != nil {
+					fmt.Printf("        Failed to create patch for %s: %v\n", file, patchErr)
+					*issues++
+					continue
+				}
+				if recordErr := patch.RecordDeletion(repoRoot, patchWorkspace, file, headCommit, currentBranch); recordErr != nil {
+					fmt.Printf("        Failed to record deletion of %s: %v\n", file, recordErr)
+					*issues++
+				}
+				continue
 			}
 
 			// Backup original file to backup/modified/
-			if backupErr := backup.CreateFileBackup(filePath, backupDir, repoRoot, relPath, currentBranch); backupErr != nil {
+			if backupErr := backup.CreateFileBackupWithKey(filePath, backupDir, repoRoot, relPath, currentBranch, key); backupErr != nil {
 				fmt.Printf("        Failed to backup %s: %v\n", file, backupErr)
 				*issues++
 				continue
 			}
 
-			// Create patch (git diff HEAD file)
-			patchPath := filepath.Join(patchBaseDir, relPath, file+".patch")
-			if patchErr := patch.Create(workspacePath, file, patchPath); patchErr != nil {
+			// Create patch (git diff HEAD file) and record it in the patch index
+			patchPath := patch.StorePath(repoRoot, patchWorkspace, file)
+			if patchErr := patch.CreateWithKey(runCtx, workspacePath, file, patchPath, key); patchErr != nil {
 				fmt.Printf("        Failed to create patch for %s: %v\n", file, patchErr)
 				*issues++
 				continue
 			}
+			if recordErr := patch.Record(repoRoot, patchWorkspace, file, headCommit, currentBranch); recordErr != nil {
+				fmt.Printf("        Failed to record patch for %s: %v\n", file, recordErr)
+				*issues++
+			}
 
 			// Backup patch to backup/patched/
 			if patchBackupErr := backup.CreatePatchBackup(patchPath, backupDir, relPath, currentBranch); patchBackupErr != nil {
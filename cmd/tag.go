@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/git-multirepo/internal/common"
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/manifest"
+	"github.com/yejune/git-multirepo/internal/release"
+)
+
+var (
+	tagMessage string
+	tagSign    bool
+	tagPush    bool
+	tagParent  bool
+	tagGroups  []string
+	tagVerify  bool
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag <name> [path...]",
+	Short: "Tag a release across workspaces",
+	Long: `Create an annotated tag at HEAD in every selected workspace and
+record the tagged commits in .multirepos/releases/<name>.yaml.
+
+Nothing is tagged if the tag already exists in any selected workspace.
+Running tag again for a recorded release tags only the workspaces missing
+from the record, e.g., to retry after tagging failed in some of them.
+Use --parent to tag the parent repository too, and --push to push the
+tags to origin.
+
+--verify checks that every tag in the release record still points at
+the recorded commit (e.g., nobody moved or deleted a tag).
+
+Examples:
+  git multirepo tag v1.4.0 -m "Release 1.4.0"
+  git multirepo tag v1.4.0 --parent --push
+  git multirepo tag v1.4.0 --group backend --sign
+  git multirepo tag --verify v1.4.0`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runTag,
+}
+
+func init() {
+	tagCmd.Flags().StringVarP(&tagMessage, "message", "m", "", "Tag message (default: \"Release <name>\")")
+	tagCmd.Flags().BoolVarP(&tagSign, "sign", "s", false, "Create signed tags (git tag -s)")
+	tagCmd.Flags().BoolVar(&tagPush, "push", false, "Push the tags to origin")
+	tagCmd.Flags().BoolVar(&tagParent, "parent", false, "Tag the parent repository too")
+	tagCmd.Flags().StringSliceVarP(&tagGroups, "group", "g", nil, "Only tag workspaces in group (repeatable)")
+	tagCmd.Flags().BoolVar(&tagVerify, "verify", false, "Check that tags still point at the recorded commits")
+}
+
+func runTag(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if !git.IsValidTagName(name) {
+		return fmt.Errorf("invalid tag name: %s", name)
+	}
+
+	ctx, err := common.LoadWorkspaceContext()
+	if err != nil {
+		return err
+	}
+
+	if tagVerify {
+		return verifyRelease(ctx, name)
+	}
+
+	// A recorded release may be retried for the repositories it is missing
+	var previous *release.Record
+	recorded := make(map[string]bool)
+	if release.Exists(ctx.RepoRoot, name) {
+		if previous, err = release.Load(ctx.RepoRoot, name); err != nil {
+			return err
+		}
+		for _, e := range previous.Workspaces {
+			recorded[e.Path] = true
+		}
+	}
+
+	workspaces, err := ctx.SelectWorkspaces(args[1:], tagGroups)
+	if err != nil {
+		return err
+	}
+
+	// 1. Collect repositories to tag
+	var targets []release.Entry
+	if tagParent && !recorded[manifest.ParentPath] {
+		targets = append(targets, release.Entry{Path: manifest.ParentPath})
+	}
+	for _, ws := range workspaces {
+		if recorded[ws.Path] {
+			continue
+		}
+		if !git.IsRepo(filepath.Join(ctx.RepoRoot, ws.Path)) {
+			colorYellow.Fprintf(os.Stdout, "  ⚠ %s: not cloned, skipped\n", ws.Path)
+			continue
+		}
+		targets = append(targets, release.Entry{Path: ws.Path})
+	}
+	if len(targets) == 0 && previous != nil {
+		return fmt.Errorf("release %s is already recorded (use --verify to check it)", name)
+	}
+	if len(targets) == 0 {
+		fmt.Println("No workspaces to tag")
+		return nil
+	}
+
+	// 2. Refuse before tagging anything, so a release is never half-tagged by a name clash
+	var existing []string
+	for _, t := range targets {
+		if git.TagExists(releaseRepoPath(ctx.RepoRoot, t.Path), name) {
+			existing = append(existing, releaseDisplayPath(t.Path))
+		}
+	}
+	if len(existing) > 0 {
+		for _, p := range existing {
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: tag %s already exists\n", p, name)
+		}
+		return fmt.Errorf("tag %s already exists in %d repo(s)", name, len(existing))
+	}
+
+	message := tagMessage
+	if message == "" {
+		message = "Release " + name
+	}
+
+	// 3. Tag every repository and record the tagged commits
+	// A retry keeps the recorded message, so all tags of a release read the same
+	record := previous
+	if record != nil {
+		if record.Message != "" {
+			message = record.Message
+		}
+	} else {
+		record = &release.Record{
+			Name:       name,
+			Message:    message,
+			Created:    time.Now().Truncate(time.Second),
+			Signed:     tagSign,
+			Workspaces: []release.Entry{},
+		}
+	}
+	tagged := 0
+	runCtx := commandContext(cmd)
+	failed, skipped := 0, 0
+	for i, t := range targets {
+		if runCtx.Err() != nil {
+			skipped = len(targets) - i
+			break
+		}
+		fullPath := releaseRepoPath(ctx.RepoRoot, t.Path)
+		commit, err := createReleaseTag(fullPath, name, message)
+		if err != nil {
+			failed++
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: %v\n", releaseDisplayPath(t.Path), err)
+			continue
+		}
+
+		record.Workspaces = append(record.Workspaces, release.Entry{Path: t.Path, Commit: commit})
+		tagged++
+
+		status := "tagged " + shortHash(commit)
+		if tagPush {
+			if err := git.PushTag(runCtx, fullPath, name); err != nil {
+				failed++
+				colorYellow.Fprintf(os.Stdout, "  ✗ %s: %s, push failed: %v\n", releaseDisplayPath(t.Path), status, err)
+				continue
+			}
+			status += ", pushed"
+		}
+		printGreen("  ✓ %s: %s\n", releaseDisplayPath(t.Path), status)
+	}
+
+	// 4. Record what was tagged, even after partial failures, so --verify knows
+	// about it and a retry only tags the repositories still missing
+	if tagged > 0 {
+		if err := release.Save(ctx.RepoRoot, record); err != nil {
+			return fmt.Errorf("failed to write release record: %w", err)
+		}
+		relPath, _ := filepath.Rel(ctx.RepoRoot, release.Path(ctx.RepoRoot, name))
+		printFaint("Release record: %s\n", relPath)
+	}
+
+	if skipped > 0 {
+		return interruptedError(skipped)
+	}
+	if failed > 0 {
+		return fmt.Errorf("tagging failed in %d repo(s) (run tag %s again to retry)", failed, name)
+	}
+	return nil
+}
+
+// createReleaseTag tags HEAD and returns the tagged commit
+func createReleaseTag(fullPath, name, message string) (string, error) {
+	if err := git.CreateTag(fullPath, name, message, tagSign); err != nil {
+		return "", err
+	}
+	return git.GetTagCommit(fullPath, name)
+}
+
+// verifyRelease checks every tag in a release record against the recorded commit
+func verifyRelease(ctx *common.WorkspaceContext, name string) error {
+	record, err := release.Load(ctx.RepoRoot, name)
+	if err != nil {
+		return err
+	}
+
+	mismatched := 0
+	for _, e := range record.Workspaces {
+		fullPath := releaseRepoPath(ctx.RepoRoot, e.Path)
+		path := releaseDisplayPath(e.Path)
+
+		if !git.IsRepo(fullPath) {
+			mismatched++
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: not cloned\n", path)
+			continue
+		}
+		commit, err := git.GetTagCommit(fullPath, name)
+		switch {
+		case err != nil:
+			mismatched++
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: tag missing\n", path)
+		case commit != e.Commit:
+			mismatched++
+			colorYellow.Fprintf(os.Stdout, "  ✗ %s: tag points at %s, recorded %s\n", path, shortHash(commit), shortHash(e.Commit))
+		default:
+			printGreen("  ✓ %s: %s\n", path, shortHash(commit))
+		}
+	}
+
+	if mismatched > 0 {
+		return fmt.Errorf("%d of %d tag(s) do not match release %s", mismatched, len(record.Workspaces), name)
+	}
+	printGreen("✓ All %d tag(s) match release %s\n", len(record.Workspaces), name)
+	return nil
+}
+
+// releaseRepoPath returns the repository directory for a release entry path
+func releaseRepoPath(repoRoot, path string) string {
+	if path == manifest.ParentPath {
+		return repoRoot
+	}
+	return filepath.Join(repoRoot, path)
+}
+
+// releaseDisplayPath names the parent repository in output
+func releaseDisplayPath(path string) string {
+	if path == manifest.ParentPath {
+		return "(parent)"
+	}
+	return path
+}
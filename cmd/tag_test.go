@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yejune/git-multirepo/internal/git"
+	"github.com/yejune/git-multirepo/internal/manifest"
+	"github.com/yejune/git-multirepo/internal/release"
+)
+
+// resetTagFlags restores tag command flags to their defaults
+func resetTagFlags() {
+	tagMessage = ""
+	tagSign = false
+	tagPush = false
+	tagParent = false
+	tagGroups = nil
+	tagVerify = false
+}
+
+func TestRunTag(t *testing.T) {
+	dir, remote, paths := setupBranchWorkspaces(t)
+	defer resetTagFlags()
+	resetTagFlags()
+
+	// Every repository pushes to its own remote, as in a real release
+	parentRemote := filepath.Join(t.TempDir(), "parent.git")
+	exec.Command("git", "init", "-q", "--bare", parentRemote).Run()
+	exec.Command("git", "-C", dir, "remote", "add", "origin", parentRemote).Run()
+	webRemote := filepath.Join(t.TempDir(), "web.git")
+	exec.Command("git", "clone", "-q", "--bare", remote, webRemote).Run()
+	exec.Command("git", "-C", filepath.Join(dir, "apps/web"), "remote", "set-url", "origin", webRemote).Run()
+
+	tagParent = true
+	tagPush = true
+	tagMessage = "Release 1.0"
+	output := captureOutput(func() {
+		if err := runTag(tagCmd, []string{"v1.0"}); err != nil {
+			t.Errorf("runTag failed: %v", err)
+		}
+	})
+
+	record, err := release.Load(dir, "v1.0")
+	if err != nil {
+		t.Fatalf("release record not written: %v", err)
+	}
+	if record.Message != "Release 1.0" || len(record.Workspaces) != 3 || record.Workspaces[0].Path != manifest.ParentPath {
+		t.Errorf("unexpected record: %+v", record)
+	}
+	for _, p := range paths {
+		wsPath := filepath.Join(dir, p)
+		head, _ := git.GetCurrentCommit(wsPath)
+		if commit, err := git.GetTagCommit(wsPath, "v1.0"); err != nil || commit != head {
+			t.Errorf("%s: tag should point at HEAD, got %q, %v", p, commit, err)
+		}
+	}
+	if !git.TagExists(dir, "v1.0") {
+		t.Error("parent repository should be tagged")
+	}
+	for _, r := range []string{parentRemote, remote, webRemote} {
+		if !git.TagExists(r, "v1.0") {
+			t.Errorf("%s: tag should be pushed", r)
+		}
+	}
+	if !strings.Contains(output, "pushed") || !strings.Contains(output, ".multirepos/releases/v1.0.yaml") {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	t.Run("existing tag refuses everything", func(t *testing.T) {
+		resetTagFlags()
+		exec.Command("git", "-C", filepath.Join(dir, "apps/web"), "tag", "v2.0").Run()
+
+		var err error
+		output := captureOutput(func() {
+			err = runTag(tagCmd, []string{"v2.0"})
+		})
+		if err == nil {
+			t.Error("expected error for existing tag")
+		}
+		if !strings.Contains(output, "apps/web: tag v2.0 already exists") {
+			t.Errorf("output should name the workspace, got: %s", output)
+		}
+		if git.TagExists(filepath.Join(dir, "apps/api"), "v2.0") || release.Exists(dir, "v2.0") {
+			t.Error("nothing should be tagged or recorded")
+		}
+	})
+
+	t.Run("failed workspaces are retried", func(t *testing.T) {
+		resetTagFlags()
+		web := filepath.Join(dir, "apps/web")
+		lock := filepath.Join(web, ".git", "refs", "tags", "v3.0.lock")
+		os.MkdirAll(filepath.Dir(lock), 0755)
+		os.WriteFile(lock, nil, 0644)
+
+		captureOutput(func() {
+			if err := runTag(tagCmd, []string{"v3.0"}); err == nil {
+				t.Error("expected error when tagging fails in a workspace")
+			}
+		})
+		record, err := release.Load(dir, "v3.0")
+		if err != nil || len(record.Workspaces) != 1 || record.Workspaces[0].Path != "apps/api" {
+			t.Fatalf("record should hold the tagged workspace only: %+v, %v", record, err)
+		}
+
+		// The retry tags only the workspace missing from the record
+		os.Remove(lock)
+		captureOutput(func() {
+			if err := runTag(tagCmd, []string{"v3.0"}); err != nil {
+				t.Errorf("retry failed: %v", err)
+			}
+		})
+		record, _ = release.Load(dir, "v3.0")
+		if len(record.Workspaces) != 2 || record.Workspaces[1].Path != "apps/web" {
+			t.Errorf("retry should add the missing workspace: %+v", record)
+		}
+		head, _ := git.GetCurrentCommit(web)
+		if commit, err := git.GetTagCommit(web, "v3.0"); err != nil || commit != head {
+			t.Errorf("apps/web: tag should point at HEAD, got %q, %v", commit, err)
+		}
+	})
+
+	t.Run("recorded release is refused", func(t *testing.T) {
+		resetTagFlags()
+		if err := runTag(tagCmd, []string{"v1.0"}); err == nil {
+			t.Error("expected error for recorded release")
+		}
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		resetTagFlags()
+		if err := runTag(tagCmd, []string{"bad..name"}); err == nil {
+			t.Error("expected error for invalid tag name")
+		}
+	})
+}
+
+func TestRunTag_Verify(t *testing.T) {
+	dir, _, _ := setupBranchWorkspaces(t)
+	defer resetTagFlags()
+	resetTagFlags()
+	api := filepath.Join(dir, "apps/api")
+
+	captureOutput(func() {
+		runTag(tagCmd, []string{"release/1.0"})
+	})
+
+	tagVerify = true
+	output := captureOutput(func() {
+		if err := runTag(tagCmd, []string{"release/1.0"}); err != nil {
+			t.Errorf("verify failed: %v", err)
+		}
+	})
+	if !strings.Contains(output, "All 2 tag(s) match release release/1.0") {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	// Move the tag in one workspace and delete it in the other
+	commitFile(t, api, "later.txt", "later", "Later change")
+	exec.Command("git", "-C", api, "tag", "-f", "-a", "-m", "moved", "release/1.0").Run()
+	exec.Command("git", "-C", filepath.Join(dir, "apps/web"), "tag", "-d", "release/1.0").Run()
+
+	var err error
+	output = captureOutput(func() {
+		err = runTag(tagCmd, []string{"release/1.0"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "2 of 2 tag(s)") {
+		t.Errorf("expected mismatch error, got %v", err)
+	}
+	if !strings.Contains(output, "apps/api: tag points at") || !strings.Contains(output, "apps/web: tag missing") {
+		t.Errorf("output should explain mismatches, got: %s", output)
+	}
+
+	t.Run("missing record", func(t *testing.T) {
+		if err := runTag(tagCmd, []string{"v9.9"}); err == nil {
+			t.Error("expected error for missing release record")
+		}
+	})
+}
@@ -2,12 +2,16 @@ package backup
 
 import (
 	"archive/tar"
+	"bytes"
 	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+
+	"github.com/yejune/git-multirepo/internal/crypt"
 )
 
 // ArchiveOldBackups archives previous month backups to tar.gz and removes originals
@@ -15,7 +19,23 @@ import (
 //   - archived/{modified|patched}/workspace/YYYY-MM-{branch}.tar.gz
 //   - archived/{modified|patched}/multirepo/{workspace}/YYYY-MM-{branch}.tar.gz
 // Only previous months are archived, current month is preserved
+// Each archive holds the objects its index entries refer to once, under
+// objects/ as in the store; objects no loose backup refers to any more are then removed.
 func ArchiveOldBackups(backupDir string) error {
+	return ArchiveOldBackupsWithKey(backupDir, nil)
+}
+
+// ArchiveOldBackupsWithKey archives like ArchiveOldBackups, encrypting the archives with key
+// Encrypted archives are named YYYY-MM-{branch}.tar.gz.enc. A nil key writes plain archives.
+func ArchiveOldBackupsWithKey(backupDir string, key *crypt.Key) error {
+	return ArchiveOldBackupsFor(backupDir, nil, key)
+}
+
+// ArchiveOldBackupsFor archives like ArchiveOldBackupsWithKey
+// workspaces are the known workspace paths, used to tell workspace and branch
+// apart in backup paths (both may contain /); without a match the first
+// path segment is taken as the workspace.
+func ArchiveOldBackupsFor(backupDir string, workspaces []string, key *crypt.Key) error {
 	now := time.Now()
 	currentYear := now.Format("2006")
 	currentMonth := now.Format("01")
@@ -27,7 +47,7 @@ func ArchiveOldBackups(backupDir string) error {
 	totalSkipped := 0
 
 	// Process modified backups
-	archived, skipped, err := archiveBackupType(backupDir, "modified", currentYear, currentMonth)
+	archived, skipped, err := archiveBackupType(backupDir, "modified", currentYear, currentMonth, workspaces, key)
 	if err != nil {
 		return fmt.Errorf("failed to archive modified backups: %w", err)
 	}
@@ -35,13 +55,23 @@ func ArchiveOldBackups(backupDir string) error {
 	totalSkipped += skipped
 
 	// Process patched backups
-	archived, skipped, err = archiveBackupType(backupDir, "patched", currentYear, currentMonth)
+	archived, skipped, err = archiveBackupType(backupDir, "patched", currentYear, currentMonth, workspaces, key)
 	if err != nil {
 		return fmt.Errorf("failed to archive patched backups: %w", err)
 	}
 	totalArchived += archived
 	totalSkipped += skipped
 
+	if totalArchived > 0 {
+		removed, freed, err := CollectObjects(backupDir)
+		if err != nil {
+			return fmt.Errorf("failed to remove unreferenced objects: %w", err)
+		}
+		if removed > 0 {
+			fmt.Printf("  [Archive] ✓ Removed %d unreferenced object(s) (%s)\n", removed, FormatSize(freed))
+		}
+	}
+
 	fmt.Printf("[Archive] Completed: %d total archive(s) created, %d month(s) skipped (current)\n",
 		totalArchived, totalSkipped)
 	return nil
@@ -50,7 +80,7 @@ func ArchiveOldBackups(backupDir string) error {
 // archiveBackupType archives a specific backup type (modified or patched)
 // New structure: backup/{type}/{workspace|multirepo}/{path}/{branch}/{year}/{month}
 // Returns: (archived count, skipped count, error)
-func archiveBackupType(backupDir, backupType, currentYear, currentMonth string) (int, int, error) {
+func archiveBackupType(backupDir, backupType, currentYear, currentMonth string, workspaces []string, key *crypt.Key) (int, int, error) {
 	typeDir := filepath.Join(backupDir, backupType)
 
 	fmt.Printf("\n  [Archive] Processing '%s' backups...\n", backupType)
@@ -73,16 +103,7 @@ func archiveBackupType(backupDir, backupType, currentYear, currentMonth string)
 			continue // Skip if doesn't exist
 		}
 
-		var archived, skipped int
-		var err error
-		if topLevel == "workspace" {
-			// For workspace: directly access branch directories
-			archived, skipped, err = archiveWorkspaceBackups(topLevelDir, backupDir, backupType, currentYear, currentMonth)
-		} else {
-			// For multirepo: traverse singlerepo directories first
-			archived, skipped, err = archiveMultirepoBackups(topLevelDir, backupDir, backupType, currentYear, currentMonth)
-		}
-
+		archived, skipped, err := archiveMonths(topLevelDir, backupDir, backupType, topLevel, currentYear, currentMonth, workspaces, key)
 		if err != nil {
 			return totalArchived, totalSkipped, fmt.Errorf("failed to archive %s backups: %w", topLevel, err)
 		}
@@ -94,8 +115,8 @@ func archiveBackupType(backupDir, backupType, currentYear, currentMonth string)
 	return totalArchived, totalSkipped, nil
 }
 
-// formatSize formats byte size to human-readable format
-func formatSize(bytes int64) string {
+// FormatSize formats byte size to human-readable format
+func FormatSize(bytes int64) string {
 	const unit = 1024
 	if bytes < unit {
 		return fmt.Sprintf("%d B", bytes)
@@ -108,295 +129,146 @@ func formatSize(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
-// archiveWorkspaceBackups handles workspace (root repository) backups
-// Structure: backup/{type}/workspace/{branch}/{year}/{month}/
+// archiveMonths archives the month directories below a workspace or multirepo directory
+// Structure: backup/{type}/workspace/{branch}/{year}/{month}/ and
+// backup/{type}/multirepo/{workspace}/{branch}/{year}/{month}/, where the
+// workspace and the branch may both contain /. Month directories are found
+// by their YYYY/MM names, and workspaces tell workspace and branch apart.
 // Returns: (archived count, skipped count, error)
-func archiveWorkspaceBackups(workspaceDir, backupDir, backupType, currentYear, currentMonth string) (int, int, error) {
-	// Get all branch directories
-	branches, err := os.ReadDir(workspaceDir)
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to read workspace directory: %w", err)
-	}
-
+func archiveMonths(topLevelDir, backupDir, backupType, topLevel, currentYear, currentMonth string, workspaces []string, key *crypt.Key) (int, int, error) {
 	archivedCount := 0
 	skippedCount := 0
 
-	for _, branchEntry := range branches {
-		if !branchEntry.IsDir() {
-			continue
+	for _, monthPath := range findMonthDirs(topLevelDir) {
+		rel, err := filepath.Rel(topLevelDir, monthPath)
+		if err != nil {
+			return archivedCount, skippedCount, err
 		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		year, month := parts[len(parts)-2], parts[len(parts)-1]
+		prefix := strings.Join(parts[:len(parts)-2], "/")
 
-		branch := branchEntry.Name()
-		branchPath := filepath.Join(workspaceDir, branch)
+		if prefix == "" {
+			continue // No branch directory
+		}
 
-		// Get all year directories
-		years, err := os.ReadDir(branchPath)
-		if err != nil {
-			fmt.Printf("  [Archive] Warning: failed to read branch %s: %v\n", branch, err)
+		// Skip current month
+		if year == currentYear && month == currentMonth {
+			skippedCount++
 			continue
 		}
 
-		for _, yearEntry := range years {
-			if !yearEntry.IsDir() {
-				continue
-			}
-
-			year := yearEntry.Name()
-			yearPath := filepath.Join(branchPath, year)
-
-			// Get all month directories
-			months, err := os.ReadDir(yearPath)
-			if err != nil {
-				fmt.Printf("  [Archive] Warning: failed to read year %s/%s: %v\n", branch, year, err)
-				continue
+		// Create archive name: {year}-{month}-{branch}.tar.gz
+		// Replace / in workspace and branch names with _
+		var archivePath string
+		if topLevel == "workspace" {
+			archiveName := fmt.Sprintf("%s-%s-%s.tar.gz", year, month, sanitizePath(prefix))
+			archivePath = filepath.Join(backupDir, "archived", backupType, "workspace", archiveName)
+		} else {
+			workspace, branch := splitWorkspace(prefix, workspaces)
+			if branch == "" {
+				continue // Not {workspace}/{branch}/YYYY/MM
 			}
+			archiveName := fmt.Sprintf("%s-%s-%s.tar.gz", year, month, sanitizePath(branch))
+			archivePath = filepath.Join(backupDir, "archived", backupType, "multirepo", sanitizePath(workspace), archiveName)
+		}
+		archiveName := filepath.Base(archivePath)
 
-			for _, monthEntry := range months {
-				if !monthEntry.IsDir() {
-					continue
-				}
-
-				month := monthEntry.Name()
-				monthPath := filepath.Join(yearPath, month)
-
-				// Skip current month
-				if year == currentYear && month == currentMonth {
-					skippedCount++
-					continue
-				}
-
-				// Create archive name: {year}-{month}-{branch}.tar.gz
-				// Replace / in branch name with _
-				safeBranch := filepath.ToSlash(branch)
-				safeBranch = filepath.Base(safeBranch) // Use only the last part if it's a path
-				if branch != safeBranch {
-					safeBranch = branch
-				}
-				safeBranch = sanitizePath(safeBranch)
-
-				archiveName := fmt.Sprintf("%s-%s-%s.tar.gz", year, month, safeBranch)
-				archivePath := filepath.Join(backupDir, "archived", backupType, "workspace", archiveName)
-
-				// Check if archive already exists
-				if _, err := os.Stat(archivePath); err == nil {
-					fmt.Printf("  [Archive] Already exists: %s\n", archiveName)
-					continue
-				}
+		// Check if archive already exists (plain or encrypted)
+		if archiveExists(archivePath) {
+			fmt.Printf("  [Archive] Already exists: %s\n", archiveName)
+			continue
+		}
+		if key != nil {
+			archivePath += EncryptedSuffix
+		}
 
-				// Create archived directory structure if not exists
-				archiveDir := filepath.Dir(archivePath)
-				if err := os.MkdirAll(archiveDir, 0755); err != nil {
-					return 0, 0, fmt.Errorf("failed to create archive directory: %w", err)
-				}
+		// Create archived directory structure if not exists
+		if err := os.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
+			return 0, 0, fmt.Errorf("failed to create archive directory: %w", err)
+		}
 
-				// Create tar.gz archive from monthPath
-				if err := createTarGzFromDir(monthPath, archivePath); err != nil {
-					return 0, 0, fmt.Errorf("failed to create archive %s: %w", archiveName, err)
-				}
+		// Create tar.gz archive from monthPath
+		if err := createTarGzFromDir(monthPath, backupDir, archivePath, key); err != nil {
+			return 0, 0, fmt.Errorf("failed to create archive %s: %w", archiveName, err)
+		}
 
-				// Verify archive
-				if err := verifyTarGz(archivePath); err != nil {
-					os.Remove(archivePath)
-					return 0, 0, fmt.Errorf("archive verification failed for %s: %w", archiveName, err)
-				}
+		// Verify archive
+		if err := verifyTarGz(archivePath, key); err != nil {
+			os.Remove(archivePath)
+			return 0, 0, fmt.Errorf("archive verification failed for %s: %w", archiveName, err)
+		}
 
-				// Get archive file size
-				fileInfo, err := os.Stat(archivePath)
-				var size int64
-				if err == nil {
-					size = fileInfo.Size()
-				}
+		// Get archive file size
+		var size int64
+		if fileInfo, err := os.Stat(archivePath); err == nil {
+			size = fileInfo.Size()
+		}
 
-				// Get relative path for display
-				relPath, err := filepath.Rel(backupDir, archivePath)
-				if err != nil {
-					relPath = archivePath
-				}
+		// Get relative path for display
+		relPath, err := filepath.Rel(backupDir, archivePath)
+		if err != nil {
+			relPath = archivePath
+		}
 
-				fmt.Printf("  [Archive] ✓ Created: %s (size: %s)\n", relPath, formatSize(size))
+		fmt.Printf("  [Archive] ✓ Created: %s (size: %s)\n", relPath, FormatSize(size))
 
-				// Remove original directory
-				if err := os.RemoveAll(monthPath); err != nil {
-					return 0, 0, fmt.Errorf("failed to remove original directory %s: %w", monthPath, err)
-				}
+		// Remove original directory
+		if err := os.RemoveAll(monthPath); err != nil {
+			return 0, 0, fmt.Errorf("failed to remove original directory %s: %w", monthPath, err)
+		}
 
-				archivedCount++
+		archivedCount++
 
-				// Clean up empty year directory
-				remaining, err := os.ReadDir(yearPath)
-				if err == nil && len(remaining) == 0 {
-					os.Remove(yearPath)
-					// Clean up empty branch directory
-					remaining, err = os.ReadDir(branchPath)
-					if err == nil && len(remaining) == 0 {
-						os.Remove(branchPath)
-					}
-				}
+		// Clean up empty year, branch and workspace directories
+		for dir := filepath.Dir(monthPath); dir != topLevelDir; dir = filepath.Dir(dir) {
+			remaining, err := os.ReadDir(dir)
+			if err != nil || len(remaining) > 0 {
+				break
 			}
+			os.Remove(dir)
 		}
 	}
 
 	if archivedCount > 0 {
-		fmt.Printf("  [Archive] ✓ %d workspace archive(s) created for %s\n", archivedCount, backupType)
+		fmt.Printf("  [Archive] ✓ %d %s archive(s) created for %s\n", archivedCount, topLevel, backupType)
 	} else if skippedCount > 0 {
-		fmt.Printf("  [Archive] No old workspace backups to archive for %s (only current month)\n", backupType)
+		fmt.Printf("  [Archive] No old %s backups to archive for %s (only current month)\n", topLevel, backupType)
 	}
 
 	return archivedCount, skippedCount, nil
 }
 
-// archiveMultirepoBackups handles multirepo (singlerepo) backups
-// Structure: backup/{type}/multirepo/{workspace}/{branch}/{year}/{month}/
-// Returns: (archived count, skipped count, error)
-func archiveMultirepoBackups(multirepoDir, backupDir, backupType, currentYear, currentMonth string) (int, int, error) {
-	// Get all workspace directories
-	workspaces, err := os.ReadDir(multirepoDir)
+// findMonthDirs returns the YYYY/MM directories below dir
+// The year directory must be below at least one other directory (the branch).
+func findMonthDirs(dir string) []string {
+	var months []string
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to read multirepo directory: %w", err)
+		return nil
 	}
-
-	archivedCount := 0
-	skippedCount := 0
-
-	for _, workspaceEntry := range workspaces {
-		if !workspaceEntry.IsDir() {
-			continue
-		}
-
-		workspace := workspaceEntry.Name()
-		workspacePath := filepath.Join(multirepoDir, workspace)
-
-		// Get all branch directories
-		branches, err := os.ReadDir(workspacePath)
-		if err != nil {
-			fmt.Printf("  [Archive] Warning: failed to read workspace %s: %v\n", workspace, err)
+	for _, entry := range entries {
+		if !entry.IsDir() {
 			continue
 		}
-
-		for _, branchEntry := range branches {
-			if !branchEntry.IsDir() {
-				continue
-			}
-
-			branch := branchEntry.Name()
-			branchPath := filepath.Join(workspacePath, branch)
-
-			// Get all year directories
-			years, err := os.ReadDir(branchPath)
-			if err != nil {
-				fmt.Printf("  [Archive] Warning: failed to read branch %s/%s: %v\n", workspace, branch, err)
-				continue
-			}
-
-			for _, yearEntry := range years {
-				if !yearEntry.IsDir() {
-					continue
+		path := filepath.Join(dir, entry.Name())
+		if isDigits(entry.Name(), 4) {
+			if children, err := os.ReadDir(path); err == nil {
+				found := false
+				for _, child := range children {
+					if child.IsDir() && isDigits(child.Name(), 2) {
+						months = append(months, filepath.Join(path, child.Name()))
+						found = true
+					}
 				}
-
-				year := yearEntry.Name()
-				yearPath := filepath.Join(branchPath, year)
-
-				// Get all month directories
-				months, err := os.ReadDir(yearPath)
-				if err != nil {
-					fmt.Printf("  [Archive] Warning: failed to read year %s/%s/%s: %v\n", workspace, branch, year, err)
+				if found {
 					continue
 				}
-
-				for _, monthEntry := range months {
-					if !monthEntry.IsDir() {
-						continue
-					}
-
-					month := monthEntry.Name()
-					monthPath := filepath.Join(yearPath, month)
-
-					// Skip current month
-					if year == currentYear && month == currentMonth {
-						skippedCount++
-						continue
-					}
-
-					// Create archive name: {year}-{month}-{branch}.tar.gz
-					// Replace / in workspace and branch names with _
-					safeWorkspace := sanitizePath(workspace)
-					safeBranch := sanitizePath(branch)
-
-					archiveName := fmt.Sprintf("%s-%s-%s.tar.gz", year, month, safeBranch)
-					archivePath := filepath.Join(backupDir, "archived", backupType, "multirepo", safeWorkspace, archiveName)
-
-					// Check if archive already exists
-					if _, err := os.Stat(archivePath); err == nil {
-						fmt.Printf("  [Archive] Already exists: %s\n", archiveName)
-						continue
-					}
-
-					// Create archived directory structure if not exists
-					archiveDir := filepath.Dir(archivePath)
-					if err := os.MkdirAll(archiveDir, 0755); err != nil {
-						return 0, 0, fmt.Errorf("failed to create archive directory: %w", err)
-					}
-
-					// Create tar.gz archive from monthPath
-					if err := createTarGzFromDir(monthPath, archivePath); err != nil {
-						return 0, 0, fmt.Errorf("failed to create archive %s: %w", archiveName, err)
-					}
-
-					// Verify archive
-					if err := verifyTarGz(archivePath); err != nil {
-						os.Remove(archivePath)
-						return 0, 0, fmt.Errorf("archive verification failed for %s: %w", archiveName, err)
-					}
-
-					// Get archive file size
-					fileInfo, err := os.Stat(archivePath)
-					var size int64
-					if err == nil {
-						size = fileInfo.Size()
-					}
-
-					// Get relative path for display
-					relPath, err := filepath.Rel(backupDir, archivePath)
-					if err != nil {
-						relPath = archivePath
-					}
-
-					fmt.Printf("  [Archive] ✓ Created: %s (size: %s)\n", relPath, formatSize(size))
-
-					// Remove original directory
-					if err := os.RemoveAll(monthPath); err != nil {
-						return 0, 0, fmt.Errorf("failed to remove original directory %s: %w", monthPath, err)
-					}
-
-					archivedCount++
-
-					// Clean up empty year directory
-					remaining, err := os.ReadDir(yearPath)
-					if err == nil && len(remaining) == 0 {
-						os.Remove(yearPath)
-						// Clean up empty branch directory
-						remaining, err = os.ReadDir(branchPath)
-						if err == nil && len(remaining) == 0 {
-							os.Remove(branchPath)
-							// Clean up empty workspace directory
-							remaining, err = os.ReadDir(workspacePath)
-							if err == nil && len(remaining) == 0 {
-								os.Remove(workspacePath)
-							}
-						}
-					}
-				}
 			}
 		}
+		months = append(months, findMonthDirs(path)...)
 	}
-
-	if archivedCount > 0 {
-		fmt.Printf("  [Archive] ✓ %d multirepo archive(s) created for %s\n", archivedCount, backupType)
-	} else if skippedCount > 0 {
-		fmt.Printf("  [Archive] No old multirepo backups to archive for %s (only current month)\n", backupType)
-	}
-
-	return archivedCount, skippedCount, nil
+	return months
 }
 
 // sanitizePath replaces / with _ in path segments for safe filenames
@@ -434,9 +306,36 @@ func replaceAll(s, old, new string) string {
 	return result
 }
 
+// EncryptedSuffix is appended to the name of encrypted archives
+const EncryptedSuffix = ".enc"
+
+// archiveExists checks if an archive exists, plain or encrypted
+func archiveExists(archivePath string) bool {
+	for _, path := range []string{archivePath, archivePath + EncryptedSuffix} {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
 // createTarGzFromDir creates a tar.gz archive from a directory
-// Archives the entire contents of srcDir into archivePath
-func createTarGzFromDir(srcDir, archivePath string) error {
+// Archives the entire contents of srcDir into archivePath, encrypted with key if not nil,
+// with the objects of backupDir that index entries in srcDir refer to ("" for none)
+func createTarGzFromDir(srcDir, backupDir, archivePath string, key *crypt.Key) error {
+	if key != nil {
+		// AES-GCM seals the whole archive at once, so build it in memory
+		var buf bytes.Buffer
+		if err := writeTarGz(srcDir, backupDir, &buf); err != nil {
+			return err
+		}
+		sealed, err := key.Encrypt(buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to encrypt archive: %w", err)
+		}
+		return os.WriteFile(archivePath, sealed, 0600)
+	}
+
 	// Create archive file
 	archiveFile, err := os.Create(archivePath)
 	if err != nil {
@@ -444,8 +343,21 @@ func createTarGzFromDir(srcDir, archivePath string) error {
 	}
 	defer archiveFile.Close()
 
+	return writeTarGz(srcDir, backupDir, archiveFile)
+}
+
+// archiveObjectName returns the name of an object inside an archive
+// It matches the object store, so extracting into the backup directory restores it.
+func archiveObjectName(id string) string {
+	return objectsDirName + "/" + id[:2] + "/" + id[2:]
+}
+
+// writeTarGz writes the contents of srcDir as a tar.gz stream
+// Objects of backupDir referred to by index entries are added once each, as
+// they are stored (encrypted objects stay encrypted).
+func writeTarGz(srcDir, backupDir string, w io.Writer) error {
 	// Create gzip writer
-	gzipWriter := gzip.NewWriter(archiveFile)
+	gzipWriter := gzip.NewWriter(w)
 	defer gzipWriter.Close()
 
 	// Create tar writer
@@ -453,7 +365,7 @@ func createTarGzFromDir(srcDir, archivePath string) error {
 	defer tarWriter.Close()
 
 	// Walk directory and add files to tar
-	err = filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -503,6 +415,36 @@ func createTarGzFromDir(srcDir, archivePath string) error {
 		return fmt.Errorf("failed to create tar archive: %w", err)
 	}
 
+	if backupDir == "" {
+		return nil
+	}
+	ids, err := entryIDs(srcDir)
+	if err != nil {
+		return fmt.Errorf("failed to read backup entries: %w", err)
+	}
+	for _, id := range sortedIDs(ids) {
+		objPath := objectPath(backupDir, id)
+		info, err := os.Stat(objPath)
+		if err != nil {
+			continue // Dangling entry: nothing to keep
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return fmt.Errorf("failed to create tar header for %s: %w", objPath, err)
+		}
+		header.Name = archiveObjectName(id)
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write tar header: %w", err)
+		}
+		data, err := os.ReadFile(objPath)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", objPath, err)
+		}
+		if _, err := tarWriter.Write(data); err != nil {
+			return fmt.Errorf("failed to write file %s to tar: %w", objPath, err)
+		}
+	}
+
 	return nil
 }
 
@@ -575,24 +517,43 @@ func createTarGz(baseDir, archivePath, year, month string) error {
 	return nil
 }
 
+// OpenArchive opens a tar.gz archive for reading, decrypting it if it is encrypted
+// Close the returned gzip reader when done.
+func OpenArchive(archivePath string) (*tar.Reader, io.Closer, error) {
+	data, err := crypt.ReadFile(archivePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	return readTarGz(data)
+}
+
+// readTarGz returns a tar reader over tar.gz content
+func readTarGz(data []byte) (*tar.Reader, io.Closer, error) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	return tar.NewReader(gzipReader), gzipReader, nil
+}
+
 // verifyTarGz verifies the integrity of a tar.gz archive using native Go
-func verifyTarGz(archivePath string) error {
-	// Open archive file
-	archiveFile, err := os.Open(archivePath)
+// Encrypted archives are verified with the key they were written with.
+func verifyTarGz(archivePath string, key *crypt.Key) error {
+	data, err := os.ReadFile(archivePath)
 	if err != nil {
 		return fmt.Errorf("failed to open archive: %w", err)
 	}
-	defer archiveFile.Close()
+	if key != nil {
+		if data, err = key.Decrypt(data); err != nil {
+			return fmt.Errorf("failed to decrypt archive: %w", err)
+		}
+	}
 
-	// Create gzip reader
-	gzipReader, err := gzip.NewReader(archiveFile)
+	tarReader, closer, err := readTarGz(data)
 	if err != nil {
-		return fmt.Errorf("failed to create gzip reader: %w", err)
+		return err
 	}
-	defer gzipReader.Close()
-
-	// Create tar reader
-	tarReader := tar.NewReader(gzipReader)
+	defer closer.Close()
 
 	// Read all entries to verify archive structure
 	fileCount := 0
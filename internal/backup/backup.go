@@ -4,25 +4,26 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/yejune/git-multirepo/internal/crypt"
 )
 
 // CreatePatchBackup backs up a patch file with timestamp to the backup directory
-// Backup structure: backup/patched/{workspace|multirepo}/{workspace-path}/{branch}/yyyy/mm/dd/file.yyyymmdd_hhmmss.patch
+// Backup structure: backup/patched/{workspace|multirepo}/{workspace-path}/{branch}/yyyy/mm/dd/file.yyyymmdd_hhmmss.patch,
+// an index entry for the content in backup/objects (see storeObject)
 func CreatePatchBackup(patchPath, backupDir, workspace, branch string) error {
 	// Check if patch file exists
 	if _, err := os.Stat(patchPath); os.IsNotExist(err) {
 		return nil // No patch to backup
 	}
 
-	// Generate timestamp
+	// Backup time
 	now := time.Now()
-	timestamp := now.Format("20060102_150405")
 
 	// Extract relative path from .multirepos/patches/ (handle both absolute and relative paths)
 	relPath := patchPath
@@ -51,48 +52,26 @@ func CreatePatchBackup(patchPath, backupDir, workspace, branch string) error {
 			now.Format("2006"), now.Format("01"), now.Format("02"))
 	}
 
-	// NEW: Check if today's backup with identical content exists
-	latestBackup := findLatestBackup(typeDir, relPath)
-
-	// NEW: Compare with latest backup
-	if latestBackup != "" {
-		identical, err := filesIdentical(patchPath, latestBackup)
-		if err == nil && identical {
-			// Skip: content is identical to latest backup
-			return nil
-		}
-	}
-
-	// Build backup path
-	backupPath := filepath.Join(typeDir, relPath)
-
-	// Add timestamp to filename
-	dir := filepath.Dir(backupPath)
-	base := filepath.Base(backupPath)
-	ext := filepath.Ext(base)
-	name := strings.TrimSuffix(base, ext)
-	backupPath = filepath.Join(dir, fmt.Sprintf("%s.%s%s", name, timestamp, ext))
-
-	// Create directory if not exists
-	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
-		return fmt.Errorf("failed to create backup directory: %w", err)
-	}
-
-	// Copy patch file to backup location
-	return copyFile(patchPath, backupPath)
+	return storeBackup(patchPath, backupDir, typeDir, relPath, now, nil)
 }
 
 // CreateFileBackup backs up the entire file with timestamp
-// Backup structure: backup/modified/{workspace|multirepo}/{workspace-path}/{branch}/yyyy/mm/dd/file.yyyymmdd_hhmmss.ext
+// Backup structure: backup/modified/{workspace|multirepo}/{workspace-path}/{branch}/yyyy/mm/dd/file.yyyymmdd_hhmmss.ext,
+// an index entry for the content in backup/objects (see storeObject)
 func CreateFileBackup(filePath, backupDir, repoRoot, workspace, branch string) error {
+	return CreateFileBackupWithKey(filePath, backupDir, repoRoot, workspace, branch, nil)
+}
+
+// CreateFileBackupWithKey backs up the file like CreateFileBackup, encrypted with key
+// A nil key stores the content plain.
+func CreateFileBackupWithKey(filePath, backupDir, repoRoot, workspace, branch string, key *crypt.Key) error {
 	// Check if file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return nil // No file to backup
 	}
 
-	// Generate timestamp
+	// Backup time
 	now := time.Now()
-	timestamp := now.Format("20060102_150405")
 
 	// Extract relative path from appropriate base directory
 	var baseDir string
@@ -126,51 +105,66 @@ func CreateFileBackup(filePath, backupDir, repoRoot, workspace, branch string) e
 			now.Format("2006"), now.Format("01"), now.Format("02"))
 	}
 
-	// NEW: Check if today's backup with identical content exists
-	latestBackup := findLatestBackup(typeDir, relPath)
+	return storeBackup(filePath, backupDir, typeDir, relPath, now, key)
+}
 
-	// NEW: Compare with latest backup
-	if latestBackup != "" {
-		identical, err := filesIdentical(filePath, latestBackup)
-		if err == nil && identical {
-			// Skip: content is identical to latest backup
-			return nil
-		}
+// storeBackup adds src to the object store and writes its index entry
+// {typeDir}/{relPath without ext}.yyyymmdd_hhmmss.{ext}, unless the latest
+// backup of the day has the same content. The object is encrypted with key
+// if not nil.
+func storeBackup(src, backupDir, typeDir, relPath string, now time.Time, key *crypt.Key) error {
+	obj, err := newObject(src, key)
+	if err != nil {
+		return err
 	}
 
-	// Build backup path
-	backupPath := filepath.Join(typeDir, relPath)
+	// Skip if today's latest backup has identical content
+	if latestBackup := findLatestBackup(typeDir, relPath); latestBackup != "" {
+		if id, ok := readEntryID(latestBackup); ok {
+			if id == obj.id {
+				return nil
+			}
+		} else if identical, err := filesIdentical(src, latestBackup); err == nil && identical {
+			return nil // Full copy written before the object store
+		}
+	}
 
-	// Add timestamp to filename
-	dir := filepath.Dir(backupPath)
-	base := filepath.Base(backupPath)
+	// Build entry path with timestamp: name.yyyymmdd_hhmmss.ext
+	entryPath := filepath.Join(typeDir, relPath)
+	dir := filepath.Dir(entryPath)
+	base := filepath.Base(entryPath)
 	ext := filepath.Ext(base)
 	name := strings.TrimSuffix(base, ext)
-	backupPath = filepath.Join(dir, fmt.Sprintf("%s.%s%s", name, timestamp, ext))
+	entryPath = filepath.Join(dir, fmt.Sprintf("%s.%s%s", name, now.Format(TimestampFormat), ext))
 
-	// Create directory if not exists
-	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
+	if err := storeObject(backupDir, obj); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(entryPath), 0755); err != nil {
 		return fmt.Errorf("failed to create backup directory: %w", err)
 	}
-
-	// Copy file to backup location
-	return copyFile(filePath, backupPath)
+	return writeEntry(entryPath, obj)
 }
 
 // Cleanup removes backups older than specified days
+// Objects are removed once no remaining backup refers to them.
 func Cleanup(backupDir string, days int) error {
 	if days <= 0 {
 		return fmt.Errorf("days must be positive")
 	}
 
 	cutoffTime := time.Now().AddDate(0, 0, -days)
+	objectsDir := filepath.Join(backupDir, objectsDirName)
 
-	return filepath.Walk(backupDir, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(backupDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		// Skip directories
+		// Skip directories, and objects (their age says nothing about their use)
+		if path == objectsDir {
+			return filepath.SkipDir
+		}
 		if info.IsDir() {
 			return nil
 		}
@@ -184,51 +178,28 @@ func Cleanup(backupDir string, days int) error {
 
 		return nil
 	})
-}
-
-// copyFile copies a file from src to dst
-func copyFile(src, dst string) error {
-	sourceFile, err := os.Open(src)
-	if err != nil {
-		return fmt.Errorf("failed to open source file: %w", err)
-	}
-	defer sourceFile.Close()
-
-	destFile, err := os.Create(dst)
 	if err != nil {
-		return fmt.Errorf("failed to create destination file: %w", err)
+		return err
 	}
-	defer destFile.Close()
 
-	if _, err := io.Copy(destFile, sourceFile); err != nil {
-		return fmt.Errorf("failed to copy file: %w", err)
-	}
-
-	// Sync to ensure data is written to disk
-	if err := destFile.Sync(); err != nil {
-		return fmt.Errorf("failed to sync file: %w", err)
-	}
-
-	return nil
+	_, _, err = CollectObjects(backupDir)
+	return err
 }
 
 // sha256File calculates SHA256 hash of a file
+// Encrypted files are hashed by their decrypted content.
 func sha256File(filePath string) (string, error) {
-	f, err := os.Open(filePath)
+	data, err := crypt.ReadFile(filePath)
 	if err != nil {
 		return "", err
 	}
-	defer f.Close()
-
-	h := sha256.New()
-	if _, err := io.Copy(h, f); err != nil {
-		return "", err
-	}
 
-	return hex.EncodeToString(h.Sum(nil)), nil
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
 }
 
 // filesIdentical checks if two files have identical content using SHA256
+// Encrypted files are compared by their decrypted content.
 func filesIdentical(file1, file2 string) (bool, error) {
 	hash1, err := sha256File(file1)
 	if err != nil {
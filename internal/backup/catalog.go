@@ -0,0 +1,330 @@
+package backup
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yejune/git-multirepo/internal/crypt"
+)
+
+// TimestampFormat is the timestamp in backup file names
+const TimestampFormat = "20060102_150405"
+
+// Backup kinds: full copies of a file and copies of its keep patch
+const (
+	KindModified = "modified"
+	KindPatched  = "patched"
+)
+
+// Version is one backup of a file, loose or inside a monthly archive
+type Version struct {
+	Kind      string // KindModified or KindPatched
+	Workspace string // Workspace path, "" for the parent repository
+	Branch    string // Branch, with / replaced by _ for archived versions
+	File      string // File relative to the workspace (the keep file, also for patches)
+	Timestamp string // As in the file name (TimestampFormat)
+	Time      time.Time
+	Path      string // Loose backup, or the archive holding it
+	Member    string // Name inside the archive, "" for loose backups
+}
+
+// Archived checks if the version is inside an archive
+func (v *Version) Archived() bool {
+	return v.Member != ""
+}
+
+// Read returns the content of the version, decrypting it if it is encrypted
+// Index entries are resolved through the objects stored in the same archive,
+// or else the object store.
+func (v *Version) Read() ([]byte, error) {
+	if !v.Archived() {
+		return ReadFile(v.Path)
+	}
+
+	data, err := readArchiveMember(v.Path, v.Member)
+	if err != nil {
+		return nil, err
+	}
+	id, ok := parseEntry(data)
+	if !ok {
+		return crypt.Decrypt(data)
+	}
+	if data, err = readArchiveMember(v.Path, archiveObjectName(id)); err == nil {
+		return crypt.Decrypt(data)
+	}
+	backupDir, err := findBackupDir(v.Path)
+	if err != nil {
+		return nil, fmt.Errorf("object %s not found in %s", id, v.Path)
+	}
+	return readObject(backupDir, id)
+}
+
+// readArchiveMember returns the stored content of a file in an archive
+func readArchiveMember(archivePath, member string) ([]byte, error) {
+	tr, closer, err := OpenArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in %s", member, archivePath)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Name == member {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// backupName matches name.YYYYMMDD_HHMMSS.ext (see CreateFileBackup)
+var backupName = regexp.MustCompile(`^(.*)\.(\d{8}_\d{6})(\.[^.]*)?$`)
+
+// parseBackupName returns the original file name and the timestamp of a backup file name
+func parseBackupName(name string) (file, timestamp string, ok bool) {
+	m := backupName.FindStringSubmatch(name)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1] + m[3], m[2], true
+}
+
+// List returns every backup version in backupDir, oldest first
+// Loose backups and the contents of the monthly archives are both listed;
+// archives are decrypted with the key from the environment when needed, and
+// returned as unreadable if that fails. workspaces are the known workspace
+// paths, used to tell workspace and branch apart in backup paths (both may
+// contain /).
+func List(backupDir string, workspaces []string) (versions []Version, unreadable []string, err error) {
+	for _, kind := range []string{KindModified, KindPatched} {
+		loose, err := listLoose(backupDir, kind, workspaces)
+		if err != nil {
+			return nil, nil, err
+		}
+		versions = append(versions, loose...)
+
+		archived, err := listArchived(backupDir, kind, workspaces, &unreadable)
+		if err != nil {
+			return nil, nil, err
+		}
+		versions = append(versions, archived...)
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].Timestamp != versions[j].Timestamp {
+			return versions[i].Timestamp < versions[j].Timestamp
+		}
+		return versions[i].File < versions[j].File
+	})
+	return versions, unreadable, nil
+}
+
+// listLoose lists backup/{kind}/{workspace|multirepo}/...
+func listLoose(backupDir, kind string, workspaces []string) ([]Version, error) {
+	kindDir := filepath.Join(backupDir, kind)
+	var versions []Version
+
+	err := filepath.Walk(kindDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == kindDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(kindDir, p)
+		if err != nil {
+			return err
+		}
+
+		// {top}/{prefix...}/YYYY/MM/DD/{dir...}/{name}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		date := findDate(parts)
+		if date < 0 {
+			return nil
+		}
+		file, timestamp, ok := parseBackupName(parts[len(parts)-1])
+		if !ok {
+			return nil
+		}
+
+		v := Version{Kind: kind, Timestamp: timestamp, Path: p}
+		prefix := strings.Join(parts[1:date], "/")
+		switch parts[0] {
+		case "workspace":
+			v.Branch = prefix
+		case "multirepo":
+			v.Workspace, v.Branch = splitWorkspace(prefix, workspaces)
+		default:
+			return nil
+		}
+		v.File = versionFile(kind, path.Join(strings.Join(parts[date+3:len(parts)-1], "/"), file))
+		v.Time, _ = time.ParseInLocation(TimestampFormat, timestamp, time.Local)
+		versions = append(versions, v)
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	return versions, nil
+}
+
+// listArchived lists the contents of archived/{kind}/{workspace|multirepo}/...
+// Archives that cannot be opened (e.g. encrypted without a key) are added to unreadable.
+func listArchived(backupDir, kind string, workspaces []string, unreadable *[]string) ([]Version, error) {
+	kindDir := filepath.Join(backupDir, "archived", kind)
+	var versions []Version
+
+	err := filepath.Walk(kindDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == kindDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		name := strings.TrimSuffix(info.Name(), EncryptedSuffix)
+		if info.IsDir() || !strings.HasSuffix(name, ".tar.gz") {
+			return nil
+		}
+		rel, err := filepath.Rel(kindDir, p)
+		if err != nil {
+			return err
+		}
+
+		// {top}/[{workspace}/]YYYY-MM-{branch}.tar.gz[.enc]
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		name = strings.TrimSuffix(name, ".tar.gz")
+		if len(name) < 9 || name[7] != '-' {
+			return nil
+		}
+		v := Version{Kind: kind, Branch: name[8:], Path: p}
+		switch {
+		case parts[0] == "workspace" && len(parts) == 2:
+		case parts[0] == "multirepo" && len(parts) == 3:
+			v.Workspace = archivedWorkspace(parts[1], workspaces)
+		default:
+			return nil
+		}
+
+		members, err := archiveMembers(p)
+		if err != nil {
+			*unreadable = append(*unreadable, p)
+			return nil
+		}
+		for _, member := range members {
+			// DD/{dir...}/{name}; objects/{sha} are the contents of index entries
+			memberParts := strings.Split(member, "/")
+			file, timestamp, ok := parseBackupName(memberParts[len(memberParts)-1])
+			if !ok || len(memberParts) < 2 || memberParts[0] == objectsDirName {
+				continue
+			}
+			mv := v
+			mv.Member = member
+			mv.Timestamp = timestamp
+			mv.File = versionFile(kind, path.Join(strings.Join(memberParts[1:len(memberParts)-1], "/"), file))
+			mv.Time, _ = time.ParseInLocation(TimestampFormat, timestamp, time.Local)
+			versions = append(versions, mv)
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list archived backups: %w", err)
+	}
+	return versions, nil
+}
+
+// archiveMembers returns the names of the files in an archive
+func archiveMembers(archivePath string) ([]string, error) {
+	tr, closer, err := OpenArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return names, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			names = append(names, hdr.Name)
+		}
+	}
+}
+
+// findDate returns the index of the YYYY segment of a YYYY/MM/DD sequence in parts, or -1
+func findDate(parts []string) int {
+	for i := 1; i+3 < len(parts); i++ {
+		if isDigits(parts[i], 4) && isDigits(parts[i+1], 2) && isDigits(parts[i+2], 2) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isDigits(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// splitWorkspace splits {workspace}/{branch} of a loose backup path using the
+// known workspaces (the longest match wins). If none matches, the first
+// segment is taken as the workspace.
+func splitWorkspace(prefix string, workspaces []string) (workspace, branch string) {
+	best := ""
+	for _, ws := range workspaces {
+		if strings.HasPrefix(prefix, ws+"/") && len(ws) > len(best) {
+			best = ws
+		}
+	}
+	if best != "" {
+		return best, strings.TrimPrefix(prefix, best+"/")
+	}
+	if i := strings.IndexByte(prefix, '/'); i >= 0 {
+		return prefix[:i], prefix[i+1:]
+	}
+	return prefix, ""
+}
+
+// archivedWorkspace returns the workspace of an archive directory name
+// (the workspace path with / replaced by _)
+func archivedWorkspace(name string, workspaces []string) string {
+	for _, ws := range workspaces {
+		if sanitizePath(ws) == name {
+			return ws
+		}
+	}
+	return name
+}
+
+// versionFile returns the keep file a backup is of (patch backups are named file.patch)
+func versionFile(kind, name string) string {
+	if kind == KindPatched {
+		return strings.TrimSuffix(name, ".patch")
+	}
+	return name
+}
@@ -0,0 +1,121 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yejune/git-multirepo/internal/crypt"
+)
+
+// writeBackup writes a loose backup below backupDir
+func writeBackup(t *testing.T, backupDir, rel, content string) {
+	t.Helper()
+	p := filepath.Join(backupDir, filepath.FromSlash(rel))
+	os.MkdirAll(filepath.Dir(p), 0755)
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestList verifies loose and archived backups are listed with workspace, branch and file
+func TestList(t *testing.T) {
+	backupDir := filepath.Join(t.TempDir(), "backup")
+	workspaces := []string{"apps/api"}
+
+	// An old month, archived below
+	writeBackup(t, backupDir, "modified/multirepo/apps/api/feature/x/2025/11/03/config.20251103_101500.json", "old\n")
+	writeBackup(t, backupDir, "modified/workspace/main/2025/11/04/.env.20251104_090000", "PARENT=1\n")
+	// The current month stays loose, also below a nested workspace
+	now := time.Now()
+	rel := "modified/multirepo/apps/api/main/" + now.Format("2006/01/02") + "/config." + now.Format(TimestampFormat) + ".json"
+	writeBackup(t, backupDir, rel, "current\n")
+	current := filepath.Join(backupDir, filepath.FromSlash(rel))
+	if err := ArchiveOldBackupsFor(backupDir, workspaces, nil); err != nil {
+		t.Fatalf("ArchiveOldBackupsFor() error = %v", err)
+	}
+	if _, err := os.Stat(current); err != nil {
+		t.Fatalf("current month backup should not be archived: %v", err)
+	}
+	os.RemoveAll(filepath.Join(backupDir, "modified", "multirepo", "apps", "api", "main"))
+
+	// Loose backups
+	writeBackup(t, backupDir, "modified/multirepo/apps/api/feature/x/2026/01/09/config.20260109_143022.json", "new\n")
+	writeBackup(t, backupDir, "patched/multirepo/apps/api/main/2026/01/09/conf/app.yml.20260109_143023.patch", "diff\n")
+	writeBackup(t, backupDir, "modified/multirepo/apps/api/main/2026/01/09/README", "not a backup\n")
+
+	versions, unreadable, err := List(backupDir, workspaces)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(unreadable) != 0 {
+		t.Errorf("unexpected unreadable archives: %v", unreadable)
+	}
+	if len(versions) != 4 {
+		t.Fatalf("expected 4 versions, got %+v", versions)
+	}
+
+	want := []Version{
+		{Kind: KindModified, Workspace: "apps/api", Branch: "feature_x", File: "config.json", Timestamp: "20251103_101500"},
+		{Kind: KindModified, Workspace: "", Branch: "main", File: ".env", Timestamp: "20251104_090000"},
+		{Kind: KindModified, Workspace: "apps/api", Branch: "feature/x", File: "config.json", Timestamp: "20260109_143022"},
+		{Kind: KindPatched, Workspace: "apps/api", Branch: "main", File: "conf/app.yml", Timestamp: "20260109_143023"},
+	}
+	for i, w := range want {
+		v := versions[i]
+		if v.Kind != w.Kind || v.Workspace != w.Workspace || v.Branch != w.Branch || v.File != w.File || v.Timestamp != w.Timestamp {
+			t.Errorf("version %d = %+v, want %+v", i, v, w)
+		}
+	}
+	if !versions[0].Archived() || versions[2].Archived() {
+		t.Error("only the old versions should be archived")
+	}
+	if versions[2].Time.Format(TimestampFormat) != "20260109_143022" {
+		t.Errorf("unexpected time: %v", versions[2].Time)
+	}
+
+	for i, content := range map[int]string{0: "old\n", 1: "PARENT=1\n", 2: "new\n"} {
+		got, err := versions[i].Read()
+		if err != nil || string(got) != content {
+			t.Errorf("Read() of version %d = %q, %v; want %q", i, got, err, content)
+		}
+	}
+}
+
+// TestList_EncryptedArchiveWithoutKey verifies unreadable archives are reported, not fatal
+func TestList_EncryptedArchiveWithoutKey(t *testing.T) {
+	key := testKey(t)
+	backupDir := filepath.Join(t.TempDir(), "backup")
+	writeBackup(t, backupDir, "modified/workspace/main/2025/11/04/.env.20251104_090000", "PARENT=1\n")
+	if err := ArchiveOldBackupsWithKey(backupDir, key); err != nil {
+		t.Fatalf("ArchiveOldBackupsWithKey() error = %v", err)
+	}
+
+	t.Setenv(crypt.EnvPassphrase, "")
+	versions, unreadable, err := List(backupDir, nil)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(versions) != 0 || len(unreadable) != 1 {
+		t.Errorf("expected one unreadable archive, got %+v, %v", versions, unreadable)
+	}
+}
+
+func TestParseBackupName(t *testing.T) {
+	tests := []struct{ name, file, timestamp string }{
+		{"config.20260109_143022.json", "config.json", "20260109_143022"},
+		{".env.20260109_143022", ".env", "20260109_143022"},
+		{"Makefile.20260109_143022", "Makefile", "20260109_143022"},
+		{"config.json.20260109_143022.patch", "config.json.patch", "20260109_143022"},
+	}
+	for _, tt := range tests {
+		file, timestamp, ok := parseBackupName(tt.name)
+		if !ok || file != tt.file || timestamp != tt.timestamp {
+			t.Errorf("parseBackupName(%q) = %q, %q, %v", tt.name, file, timestamp, ok)
+		}
+	}
+	if _, _, ok := parseBackupName("config.json"); ok {
+		t.Error("a name without timestamp should not parse")
+	}
+}
@@ -0,0 +1,117 @@
+package backup
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yejune/git-multirepo/internal/crypt"
+)
+
+// testKey configures a passphrase in the environment and returns its key
+func testKey(t *testing.T) *crypt.Key {
+	t.Helper()
+	t.Setenv(crypt.EnvPassphrase, "test passphrase")
+	t.Setenv(crypt.EnvKeyfile, "")
+	key, err := crypt.LoadKey()
+	if err != nil {
+		t.Fatalf("LoadKey failed: %v", err)
+	}
+	return key
+}
+
+// TestCreateFileBackupWithKey verifies sensitive backups are encrypted and still deduplicated
+func TestCreateFileBackupWithKey(t *testing.T) {
+	key := testKey(t)
+	tmpDir := t.TempDir()
+	backupDir := filepath.Join(tmpDir, "backup")
+
+	sourceFile := filepath.Join(tmpDir, ".env")
+	os.WriteFile(sourceFile, []byte("API_KEY=secret\n"), 0644)
+
+	if err := CreateFileBackupWithKey(sourceFile, backupDir, tmpDir, "", "main", key); err != nil {
+		t.Fatalf("CreateFileBackupWithKey() error = %v", err)
+	}
+
+	now := time.Now()
+	todayDir := filepath.Join(backupDir, "modified", "workspace", "main",
+		now.Format("2006"), now.Format("01"), now.Format("02"))
+	entries, _ := os.ReadDir(todayDir)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 backup file, got %d", len(entries))
+	}
+	backupPath := filepath.Join(todayDir, entries[0].Name())
+
+	// The index entry points to an encrypted object
+	id, ok := readEntryID(backupPath)
+	if !ok {
+		t.Fatal("backup should be an index entry")
+	}
+	objPath := objectPath(backupDir, id)
+	raw, _ := os.ReadFile(objPath)
+	if !crypt.IsEncrypted(raw) || strings.Contains(string(raw), "secret") {
+		t.Error("backup object should be encrypted")
+	}
+	if info, _ := os.Stat(objPath); info.Mode().Perm() != 0600 {
+		t.Errorf("encrypted object mode = %v, want 0600", info.Mode().Perm())
+	}
+	if content, err := ReadFile(backupPath); err != nil || string(content) != "API_KEY=secret\n" {
+		t.Errorf("ReadFile = %q, %v", content, err)
+	}
+
+	// Identical content is compared after decryption
+	time.Sleep(time.Second)
+	CreateFileBackupWithKey(sourceFile, backupDir, tmpDir, "", "main", key)
+	if entries, _ := os.ReadDir(todayDir); len(entries) != 1 {
+		t.Errorf("expected 1 backup file (deduplication), got %d", len(entries))
+	}
+}
+
+// TestArchiveEncrypted verifies archives are encrypted with the key and readable with OpenArchive
+func TestArchiveEncrypted(t *testing.T) {
+	key := testKey(t)
+	tmpDir := t.TempDir()
+	srcDir := filepath.Join(tmpDir, "src")
+	os.MkdirAll(filepath.Join(srcDir, "09"), 0755)
+	os.WriteFile(filepath.Join(srcDir, "09", "config.json.20251209_143022"), []byte("{}\n"), 0644)
+
+	archivePath := filepath.Join(tmpDir, "2025-12-main.tar.gz"+EncryptedSuffix)
+	if err := createTarGzFromDir(srcDir, "", archivePath, key); err != nil {
+		t.Fatalf("createTarGzFromDir() error = %v", err)
+	}
+	if err := verifyTarGz(archivePath, key); err != nil {
+		t.Fatalf("verifyTarGz() error = %v", err)
+	}
+
+	raw, _ := os.ReadFile(archivePath)
+	if !crypt.IsEncrypted(raw) {
+		t.Fatal("archive should be encrypted")
+	}
+	if !archiveExists(strings.TrimSuffix(archivePath, EncryptedSuffix)) {
+		t.Error("archiveExists should find the encrypted archive")
+	}
+
+	tarReader, closer, err := OpenArchive(archivePath)
+	if err != nil {
+		t.Fatalf("OpenArchive() error = %v", err)
+	}
+	defer closer.Close()
+
+	var names []string
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read archive: %v", err)
+		}
+		names = append(names, header.Name)
+	}
+	if strings.Join(names, ",") != "09,09/config.json.20251209_143022" {
+		t.Errorf("archive entries = %v", names)
+	}
+}
@@ -0,0 +1,200 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retention limits how much backup history is kept
+// Zero values mean no limit.
+type Retention struct {
+	Days          int   // Loose backups older than this many days are removed
+	ArchiveMonths int   // Archives of months older than this many months are removed
+	MaxSize       int64 // Oldest backups and archives are removed until the total fits (bytes)
+}
+
+// IsZero checks if no limit is set
+func (r Retention) IsZero() bool {
+	return r.Days <= 0 && r.ArchiveMonths <= 0 && r.MaxSize <= 0
+}
+
+// Pruned is a backup or archive removed by Prune
+type Pruned struct {
+	Path   string // Relative to the backup directory
+	Size   int64
+	Reason string
+}
+
+// pruneItem is a loose backup or an archive considered by Prune
+type pruneItem struct {
+	path    string
+	size    int64
+	time    time.Time // Backup time, or the first day of an archive's month
+	archive bool
+	object  string // Object an index entry refers to
+}
+
+// Prune removes the backups and archives in backupDir that fall outside r
+// Loose backups older than r.Days and archives older than r.ArchiveMonths
+// (counted from the current month) go first; then the oldest remaining
+// items are removed until the total size is at most r.MaxSize. The size of
+// an object counts towards the newest backup referring to it, as removing
+// that one (after the older ones) frees it. With dryRun nothing is removed.
+// Returns what was (or would be) removed.
+func Prune(backupDir string, r Retention, now time.Time, dryRun bool) ([]Pruned, error) {
+	items, err := pruneItems(backupDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []Pruned
+	var kept []pruneItem
+	dayCutoff := now.AddDate(0, 0, -r.Days)
+	monthCutoff := time.Date(now.Year(), now.Month()-time.Month(r.ArchiveMonths), 1, 0, 0, 0, 0, now.Location())
+	for _, item := range items {
+		switch {
+		case !item.archive && r.Days > 0 && item.time.Before(dayCutoff):
+			pruned = append(pruned, item.pruned(backupDir, fmt.Sprintf("older than %d day(s)", r.Days)))
+		case item.archive && r.ArchiveMonths > 0 && item.time.Before(monthCutoff):
+			pruned = append(pruned, item.pruned(backupDir, fmt.Sprintf("older than %d month(s)", r.ArchiveMonths)))
+		default:
+			kept = append(kept, item)
+		}
+	}
+
+	if r.MaxSize > 0 {
+		var total int64
+		for _, item := range kept {
+			total += item.size
+		}
+		for len(kept) > 0 && total > r.MaxSize {
+			pruned = append(pruned, kept[0].pruned(backupDir, "over the size limit of "+FormatSize(r.MaxSize)))
+			total -= kept[0].size
+			kept = kept[1:]
+		}
+	}
+
+	if dryRun {
+		return pruned, nil
+	}
+	for _, p := range pruned {
+		path := filepath.Join(backupDir, p.Path)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return pruned, fmt.Errorf("failed to remove %s: %w", p.Path, err)
+		}
+		removeEmptyParents(filepath.Dir(path), backupDir)
+	}
+	if _, _, err := CollectObjects(backupDir); err != nil {
+		return pruned, fmt.Errorf("failed to remove unreferenced objects: %w", err)
+	}
+	return pruned, nil
+}
+
+func (item *pruneItem) pruned(backupDir, reason string) Pruned {
+	rel, err := filepath.Rel(backupDir, item.path)
+	if err != nil {
+		rel = item.path
+	}
+	return Pruned{Path: rel, Size: item.size, Reason: reason}
+}
+
+// pruneItems returns the loose backups and archives in backupDir, oldest first
+func pruneItems(backupDir string) ([]pruneItem, error) {
+	var items []pruneItem
+	for _, kind := range []string{KindModified, KindPatched} {
+		versions, err := listLoose(backupDir, kind, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range versions {
+			info, err := os.Stat(v.Path)
+			if err != nil {
+				continue
+			}
+			object, _ := readEntryID(v.Path)
+			items = append(items, pruneItem{path: v.Path, size: info.Size(), time: v.Time, object: object})
+		}
+	}
+
+	archivedDir := filepath.Join(backupDir, "archived")
+	err := filepath.Walk(archivedDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == archivedDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		name := strings.TrimSuffix(info.Name(), EncryptedSuffix)
+		if info.IsDir() || !strings.HasSuffix(name, ".tar.gz") {
+			return nil
+		}
+		// YYYY-MM-{branch}.tar.gz
+		month, err := time.ParseInLocation("2006-01", name[:min(7, len(name))], time.Local)
+		if err != nil {
+			return nil
+		}
+		items = append(items, pruneItem{path: p, size: info.Size(), time: month, archive: true})
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list archives: %w", err)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].time.Before(items[j].time)
+	})
+
+	newest := make(map[string]int)
+	for i, item := range items {
+		if item.object != "" {
+			newest[item.object] = i
+		}
+	}
+	for id, i := range newest {
+		if info, err := os.Stat(objectPath(backupDir, id)); err == nil {
+			items[i].size += info.Size()
+		}
+	}
+	return items, nil
+}
+
+// removeEmptyParents removes dir and its parents while they are empty, up to root
+func removeEmptyParents(dir, root string) {
+	for ; dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) > 0 {
+			return
+		}
+		os.Remove(dir)
+	}
+}
+
+// ParseSize parses a size like 500MB, 2G or 1048576 (bytes)
+// Units are powers of 1024; the trailing B (or iB) is optional.
+func ParseSize(s string) (int64, error) {
+	number := strings.ToUpper(strings.TrimSpace(s))
+	if trimmed := strings.TrimSuffix(number, "IB"); trimmed != number {
+		number = trimmed
+	} else {
+		number = strings.TrimSuffix(number, "B")
+	}
+
+	multiplier := 1.0
+	if n := len(number); n > 0 {
+		if i := strings.IndexByte("KMGT", number[n-1]); i >= 0 {
+			multiplier = float64(int64(1) << (10 * (i + 1)))
+			number = number[:n-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (e.g. 500MB)", s)
+	}
+	return int64(n * multiplier), nil
+}
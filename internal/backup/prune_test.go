@@ -0,0 +1,120 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPrune verifies the day, month and size limits
+func TestPrune(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.Local)
+	setup := func(t *testing.T) string {
+		backupDir := filepath.Join(t.TempDir(), "backup")
+		writeBackup(t, backupDir, "modified/multirepo/apps/api/main/2026/01/02/config.20260102_100000.json", strings.Repeat("a", 100))
+		writeBackup(t, backupDir, "modified/multirepo/apps/api/main/2026/03/14/config.20260314_100000.json", strings.Repeat("b", 100))
+		writeBackup(t, backupDir, "patched/workspace/main/2026/03/01/config.json.20260301_100000.patch", strings.Repeat("c", 100))
+		writeBackup(t, backupDir, "archived/modified/multirepo/apps_api/2025-01-main.tar.gz", strings.Repeat("d", 1000))
+		writeBackup(t, backupDir, "archived/modified/workspace/2025-12-main.tar.gz.enc", strings.Repeat("e", 1000))
+		return backupDir
+	}
+	paths := func(pruned []Pruned) string {
+		var names []string
+		for _, p := range pruned {
+			names = append(names, filepath.Base(p.Path))
+		}
+		return strings.Join(names, ",")
+	}
+
+	t.Run("days and months", func(t *testing.T) {
+		backupDir := setup(t)
+		pruned, err := Prune(backupDir, Retention{Days: 30, ArchiveMonths: 6}, now, false)
+		if err != nil {
+			t.Fatalf("Prune() error = %v", err)
+		}
+		if got := paths(pruned); got != "2025-01-main.tar.gz,config.20260102_100000.json" {
+			t.Errorf("pruned %s", got)
+		}
+		if pruned[0].Reason != "older than 6 month(s)" || pruned[1].Reason != "older than 30 day(s)" || pruned[1].Size != 100 {
+			t.Errorf("unexpected reasons: %+v", pruned)
+		}
+		if _, err := os.Stat(filepath.Join(backupDir, "modified/multirepo/apps/api/main/2026/01")); !os.IsNotExist(err) {
+			t.Error("empty directories should be removed")
+		}
+		if _, err := os.Stat(filepath.Join(backupDir, "archived/modified/workspace/2025-12-main.tar.gz.enc")); err != nil {
+			t.Error("recent archive should be kept")
+		}
+	})
+
+	t.Run("size", func(t *testing.T) {
+		backupDir := setup(t)
+		pruned, err := Prune(backupDir, Retention{MaxSize: 1200}, now, false)
+		if err != nil {
+			t.Fatalf("Prune() error = %v", err)
+		}
+		// 2300 bytes in total: the oldest archive goes, then the next oldest item
+		if got := paths(pruned); got != "2025-01-main.tar.gz,2025-12-main.tar.gz.enc" {
+			t.Errorf("pruned %s", got)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		backupDir := setup(t)
+		pruned, err := Prune(backupDir, Retention{Days: 1}, now, true)
+		if err != nil || len(pruned) != 3 {
+			t.Fatalf("Prune() = %+v, %v", pruned, err)
+		}
+		for _, p := range pruned {
+			if _, err := os.Stat(filepath.Join(backupDir, p.Path)); err != nil {
+				t.Errorf("dry run should not remove %s", p.Path)
+			}
+		}
+	})
+
+	t.Run("objects", func(t *testing.T) {
+		backupDir := filepath.Join(t.TempDir(), "backup")
+		content := strings.Repeat("f", 1000)
+		id := writeStoredBackup(t, backupDir, "modified/workspace/main/2026/01/02/config.20260102_100000.json", content)
+		writeStoredBackup(t, backupDir, "modified/workspace/main/2026/03/14/config.20260314_100000.json", content)
+
+		// The object stays while the newer backup refers to it
+		pruned, err := Prune(backupDir, Retention{Days: 30}, now, false)
+		if err != nil || len(pruned) != 1 || pruned[0].Size != int64(plainEntrySize) {
+			t.Fatalf("Prune() = %+v, %v", pruned, err)
+		}
+		if _, err := os.Stat(objectPath(backupDir, id)); err != nil {
+			t.Error("object still referred to should be kept")
+		}
+
+		// Removing the last backup referring to it frees the object
+		pruned, err = Prune(backupDir, Retention{MaxSize: 10}, now, false)
+		if err != nil || len(pruned) != 1 || pruned[0].Size != int64(plainEntrySize)+1000 {
+			t.Fatalf("Prune() = %+v, %v", pruned, err)
+		}
+		if _, err := os.Stat(objectPath(backupDir, id)); !os.IsNotExist(err) {
+			t.Error("unreferenced object should be removed")
+		}
+	})
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"1048576": 1048576,
+		"500MB":   500 << 20,
+		"2G":      2 << 30,
+		"1.5 GiB": 3 << 29,
+		"10kb":    10 << 10,
+	}
+	for s, want := range tests {
+		if got, err := ParseSize(s); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "MB", "-1", "ten"} {
+		if _, err := ParseSize(s); err == nil {
+			t.Errorf("ParseSize(%q) should fail", s)
+		}
+	}
+}
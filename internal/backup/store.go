@@ -0,0 +1,308 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yejune/git-multirepo/internal/crypt"
+)
+
+// Object store layout: backup/objects/{id[:2]}/{id[2:]}
+// Backups are index entries at their usual dated path (see CreateFileBackup)
+// holding only the id of the content, so identical content is stored once
+// across days, branches and workspaces.
+const objectsDirName = "objects"
+
+// objectTempSuffix ends the temporary files objects are written to before
+// they are renamed into place; object names are hex and never end with it
+const objectTempSuffix = ".tmp"
+
+// Index entries are a single line: object sha256:{hex} for plain content, or
+// object hmac-sha256:{hex} for encrypted content (see newObject)
+const (
+	entryPrefix      = "object sha256:"
+	keyedEntryPrefix = "object hmac-sha256:"
+)
+
+// maxEntrySize is the size of the largest index entry; larger files are full copies
+const maxEntrySize = len(keyedEntryPrefix) + sha256.Size*2 + 1
+
+// object is the content of a backup, ready for the store
+type object struct {
+	id    string
+	keyed bool       // id is a key.ID, not a plain sha256
+	data  []byte     // Content to store
+	key   *crypt.Key // Encrypts data when stored, if not nil
+}
+
+// newObject reads the content of src for the store
+// Plain content is keyed by its sha256, as sha256File computes it. Content
+// backed up with key, or already encrypted (patches of sensitive files), is
+// keyed by key.ID and stored encrypted, so that no plain hash of a secret is
+// written. Encrypted content that cannot be decrypted is stored as it is,
+// keyed by the sha256 of its ciphertext.
+func newObject(src string, key *crypt.Key) (*object, error) {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source file: %w", err)
+	}
+
+	if crypt.IsEncrypted(data) {
+		if key == nil {
+			key, _ = crypt.LoadKey()
+		}
+		plain, err := decryptWith(key, data)
+		if err != nil {
+			sum := sha256.Sum256(data)
+			return &object{id: hex.EncodeToString(sum[:]), data: data}, nil
+		}
+		data = plain
+	}
+
+	if key != nil {
+		id, err := key.ID(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to key content: %w", err)
+		}
+		return &object{id: id, keyed: true, data: data, key: key}, nil
+	}
+	sum := sha256.Sum256(data)
+	return &object{id: hex.EncodeToString(sum[:]), data: data}, nil
+}
+
+// decryptWith decrypts data with key, failing without one
+func decryptWith(key *crypt.Key, data []byte) ([]byte, error) {
+	if key == nil {
+		return nil, crypt.ErrNoKey
+	}
+	return key.Decrypt(data)
+}
+
+// objectPath returns the path of an object in the store
+func objectPath(backupDir, id string) string {
+	return filepath.Join(backupDir, objectsDirName, id[:2], id[2:])
+}
+
+// storeObject adds obj to the object store of backupDir, unless it is there
+func storeObject(backupDir string, obj *object) error {
+	objPath := objectPath(backupDir, obj.id)
+	if _, err := os.Stat(objPath); err == nil {
+		return nil
+	}
+
+	data, perm := obj.data, os.FileMode(0644)
+	if obj.key != nil {
+		var err error
+		if data, err = obj.key.Encrypt(obj.data); err != nil {
+			return fmt.Errorf("failed to encrypt file: %w", err)
+		}
+	}
+	if crypt.IsEncrypted(data) {
+		perm = 0600
+	}
+
+	if err := os.MkdirAll(filepath.Dir(objPath), 0755); err != nil {
+		return fmt.Errorf("failed to create object directory: %w", err)
+	}
+	// Write and rename so that a half-written object is never referenced
+	// Every writer gets its own temporary file, as workspaces backed up in
+	// parallel may store the same object at the same time.
+	f, err := os.CreateTemp(filepath.Dir(objPath), filepath.Base(objPath)+".*"+objectTempSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp, objPath)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	return nil
+}
+
+// writeEntry writes an index entry pointing to obj
+func writeEntry(entryPath string, obj *object) error {
+	prefix := entryPrefix
+	if obj.keyed {
+		prefix = keyedEntryPrefix
+	}
+	if err := os.WriteFile(entryPath, []byte(prefix+obj.id+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write backup entry: %w", err)
+	}
+	return nil
+}
+
+// parseEntry returns the object id of index entry content
+func parseEntry(data []byte) (string, bool) {
+	s := strings.TrimSuffix(string(data), "\n")
+	id, ok := strings.CutPrefix(s, entryPrefix)
+	if !ok {
+		if id, ok = strings.CutPrefix(s, keyedEntryPrefix); !ok {
+			return "", false
+		}
+	}
+	if len(id) != sha256.Size*2 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", false
+	}
+	return id, true
+}
+
+// readEntryID returns the object id if path is an index entry
+func readEntryID(path string) (string, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Size() > int64(maxEntrySize) {
+		return "", false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return parseEntry(data)
+}
+
+// ReadFile returns the content of a backup, decrypting it if it is encrypted
+// Index entries are resolved through the object store of the backup directory
+// they are in; other files (full copies of older backups, patches, archives)
+// are read as they are.
+func ReadFile(path string) ([]byte, error) {
+	id, ok := readEntryID(path)
+	if !ok {
+		return crypt.ReadFile(path)
+	}
+	backupDir, err := findBackupDir(path)
+	if err != nil {
+		return nil, err
+	}
+	return readObject(backupDir, id)
+}
+
+// readObject returns the decrypted content of an object
+func readObject(backupDir, id string) ([]byte, error) {
+	data, err := crypt.ReadFile(objectPath(backupDir, id))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("backup object %s not found", id)
+	}
+	return data, err
+}
+
+// findBackupDir returns the backup directory holding a backup or archive:
+// the closest parent with an objects directory next to the backup kinds
+func findBackupDir(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for dir := filepath.Dir(abs); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if !isDir(filepath.Join(dir, objectsDirName)) {
+			continue
+		}
+		for _, name := range []string{KindModified, KindPatched, "archived"} {
+			if isDir(filepath.Join(dir, name)) {
+				return dir, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no backup object store found for %s", path)
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// entryIDs returns the object ids referenced by the index entries below dir
+func entryIDs(dir string) (map[string]bool, error) {
+	ids := make(map[string]bool)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !info.IsDir() {
+			if id, ok := readEntryID(p); ok {
+				ids[id] = true
+			}
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// sortedIDs returns the ids of a set in order
+func sortedIDs(ids map[string]bool) []string {
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// CollectObjects removes the objects no loose backup refers to any more
+// Archives carry their own copy of the objects they refer to. Returns the
+// number of objects removed and the bytes freed.
+func CollectObjects(backupDir string) (int, int64, error) {
+	referenced := make(map[string]bool)
+	for _, kind := range []string{KindModified, KindPatched} {
+		ids, err := entryIDs(filepath.Join(backupDir, kind))
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to list backups: %w", err)
+		}
+		for id := range ids {
+			referenced[id] = true
+		}
+	}
+
+	objectsDir := filepath.Join(backupDir, objectsDirName)
+	removed := 0
+	var freed int64
+	err := filepath.Walk(objectsDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == objectsDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		// Temporary files belong to a backup being written, not to the store
+		if info.IsDir() || strings.HasSuffix(info.Name(), objectTempSuffix) {
+			return nil
+		}
+		id := filepath.Base(filepath.Dir(p)) + info.Name()
+		if referenced[id] {
+			return nil
+		}
+		if err := os.Remove(p); err != nil {
+			return fmt.Errorf("failed to remove object %s: %w", id, err)
+		}
+		removeEmptyParents(filepath.Dir(p), objectsDir)
+		removed++
+		freed += info.Size()
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return removed, freed, err
+	}
+	return removed, freed, nil
+}
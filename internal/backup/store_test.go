@@ -0,0 +1,235 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yejune/git-multirepo/internal/crypt"
+)
+
+// writeStoredBackup writes an index entry below backupDir with content in the object store
+func writeStoredBackup(t *testing.T, backupDir, rel, content string) string {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "src")
+	os.WriteFile(src, []byte(content), 0644)
+	obj, err := newObject(src, nil)
+	if err == nil {
+		err = storeObject(backupDir, obj)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(backupDir, filepath.FromSlash(rel))
+	os.MkdirAll(filepath.Dir(p), 0755)
+	if err := writeEntry(p, obj); err != nil {
+		t.Fatal(err)
+	}
+	return obj.id
+}
+
+// plainEntrySize is the size of an index entry of plain content
+const plainEntrySize = len(entryPrefix) + 64 + 1
+
+// countObjects returns the number of objects in the store
+func countObjects(t *testing.T, backupDir string) int {
+	t.Helper()
+	count := 0
+	filepath.Walk(filepath.Join(backupDir, objectsDirName), func(p string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+// TestCreateFileBackup_ContentAddressed verifies identical content is stored once
+// across workspaces and branches
+func TestCreateFileBackup_ContentAddressed(t *testing.T) {
+	tmpDir := t.TempDir()
+	backupDir := filepath.Join(tmpDir, "backup")
+	for _, ws := range []string{"apps/api", "apps/web"} {
+		os.MkdirAll(filepath.Join(tmpDir, ws), 0755)
+		os.WriteFile(filepath.Join(tmpDir, ws, "config.json"), []byte("{}\n"), 0644)
+	}
+
+	CreateFileBackup(filepath.Join(tmpDir, "apps/api/config.json"), backupDir, tmpDir, "apps/api", "main")
+	CreateFileBackup(filepath.Join(tmpDir, "apps/api/config.json"), backupDir, tmpDir, "apps/api", "feature/x")
+	CreateFileBackup(filepath.Join(tmpDir, "apps/web/config.json"), backupDir, tmpDir, "apps/web", "main")
+
+	versions, _, err := List(backupDir, []string{"apps/api", "apps/web"})
+	if err != nil || len(versions) != 3 {
+		t.Fatalf("List() = %d version(s), %v; want 3", len(versions), err)
+	}
+	if n := countObjects(t, backupDir); n != 1 {
+		t.Errorf("expected 1 object, got %d", n)
+	}
+	for _, v := range versions {
+		if info, _ := os.Stat(v.Path); info.Size() != int64(plainEntrySize) {
+			t.Errorf("%s should be an index entry, size %d", v.Path, info.Size())
+		}
+		if content, err := v.Read(); err != nil || string(content) != "{}\n" {
+			t.Errorf("Read() = %q, %v", content, err)
+		}
+	}
+
+	// Sensitive content is keyed by an HMAC and stored encrypted: no plain
+	// sha256 of it is written anywhere
+	key := testKey(t)
+	os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("API_KEY=secret\n"), 0644)
+	if err := CreateFileBackupWithKey(filepath.Join(tmpDir, ".env"), backupDir, tmpDir, "", "main", key); err != nil {
+		t.Fatalf("CreateFileBackupWithKey() error = %v", err)
+	}
+	sum := sha256.Sum256([]byte("API_KEY=secret\n"))
+	plainID := hex.EncodeToString(sum[:])
+	if _, err := os.Stat(objectPath(backupDir, plainID)); !os.IsNotExist(err) {
+		t.Error("sensitive content should not be stored under its sha256")
+	}
+	found := false
+	versions, _, _ = List(backupDir, nil)
+	for _, v := range versions {
+		if !strings.HasPrefix(v.File, ".env") {
+			continue
+		}
+		found = true
+		entry, _ := os.ReadFile(v.Path)
+		id, _ := parseEntry(entry)
+		if !strings.HasPrefix(string(entry), keyedEntryPrefix) || strings.Contains(string(entry), plainID) {
+			t.Errorf("entry should hold a keyed id, got %q", entry)
+		}
+		if raw, _ := os.ReadFile(objectPath(backupDir, id)); !crypt.IsEncrypted(raw) {
+			t.Error("sensitive object should be encrypted")
+		}
+		if content, err := v.Read(); err != nil || string(content) != "API_KEY=secret\n" {
+			t.Errorf("Read() = %q, %v", content, err)
+		}
+	}
+	if !found {
+		t.Error("sensitive backup not listed")
+	}
+}
+
+// TestStoreObject_Concurrent verifies writers of the same object do not share
+// a temporary file, and that CollectObjects leaves temporary files alone
+func TestStoreObject_Concurrent(t *testing.T) {
+	backupDir := filepath.Join(t.TempDir(), "backup")
+	src := filepath.Join(t.TempDir(), "src")
+	os.WriteFile(src, []byte(strings.Repeat("shared content\n", 4096)), 0644)
+	obj, err := newObject(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- storeObject(backupDir, obj)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("storeObject() error = %v", err)
+		}
+	}
+	if data, err := readObject(backupDir, obj.id); err != nil || string(data) != string(obj.data) {
+		t.Errorf("stored object differs from its content: %v", err)
+	}
+	if n := countObjects(t, backupDir); n != 1 {
+		t.Errorf("expected 1 object and no temporary files, got %d file(s)", n)
+	}
+
+	// A temporary file of a backup in progress is neither an object nor garbage
+	tmp := objectPath(backupDir, obj.id) + ".123" + objectTempSuffix
+	os.WriteFile(tmp, []byte("partial"), 0644)
+	removed, _, err := CollectObjects(backupDir)
+	if err != nil {
+		t.Fatalf("CollectObjects() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected only the unreferenced object to be removed, got %d", removed)
+	}
+	if _, err := os.Stat(tmp); err != nil {
+		t.Errorf("temporary file should be left alone: %v", err)
+	}
+}
+
+// TestArchiveObjects verifies archives hold each object once and the store is collected
+func TestArchiveObjects(t *testing.T) {
+	backupDir := filepath.Join(t.TempDir(), "backup")
+	id := writeStoredBackup(t, backupDir, "modified/workspace/main/2025/11/03/config.20251103_101500.json", "{}\n")
+	writeStoredBackup(t, backupDir, "modified/workspace/main/2025/11/04/config.20251104_101500.json", "{}\n")
+	writeStoredBackup(t, backupDir, "modified/workspace/main/2025/11/05/.env.20251105_101500", "A=1\n")
+	// Still referred to by a loose backup of the current month
+	now := time.Now()
+	current := filepath.Join("modified/workspace/main", now.Format("2006/01/02"), ".env."+now.Format(TimestampFormat))
+	writeStoredBackup(t, backupDir, current, "A=1\n")
+
+	if err := ArchiveOldBackups(backupDir); err != nil {
+		t.Fatalf("ArchiveOldBackups() error = %v", err)
+	}
+
+	archivePath := filepath.Join(backupDir, "archived/modified/workspace/2025-11-main.tar.gz")
+	members, err := archiveMembers(archivePath)
+	if err != nil {
+		t.Fatalf("archiveMembers() error = %v", err)
+	}
+	objects := 0
+	for _, m := range members {
+		if strings.HasPrefix(m, objectsDirName+"/") {
+			objects++
+		}
+	}
+	if objects != 2 {
+		t.Errorf("archive should hold 2 objects, got %v", members)
+	}
+	if _, err := os.Stat(objectPath(backupDir, id)); !os.IsNotExist(err) {
+		t.Error("object only referred to by the archive should be removed from the store")
+	}
+	if n := countObjects(t, backupDir); n != 1 {
+		t.Errorf("expected 1 object left in the store, got %d", n)
+	}
+
+	versions, _, _ := List(backupDir, nil)
+	if len(versions) != 4 {
+		t.Fatalf("expected 4 versions, got %+v", versions)
+	}
+	for _, v := range versions {
+		want := "{}\n"
+		if strings.HasPrefix(v.File, ".env") {
+			want = "A=1\n"
+		}
+		if content, err := v.Read(); err != nil || string(content) != want {
+			t.Errorf("%s Read() = %q, %v", v.Timestamp, content, err)
+		}
+	}
+}
+
+// TestReadFile verifies index entries are resolved and other files read as they are
+func TestReadFile(t *testing.T) {
+	backupDir := filepath.Join(t.TempDir(), "backup")
+	writeStoredBackup(t, backupDir, "modified/workspace/main/2026/01/09/a.20260109_143022.txt", "stored\n")
+	writeBackup(t, backupDir, "modified/workspace/main/2026/01/09/b.20260109_143022.txt", "full copy\n")
+
+	for name, want := range map[string]string{"a": "stored\n", "b": "full copy\n"} {
+		p := filepath.Join(backupDir, "modified/workspace/main/2026/01/09", name+".20260109_143022.txt")
+		if content, err := ReadFile(p); err != nil || string(content) != want {
+			t.Errorf("ReadFile(%s) = %q, %v", name, content, err)
+		}
+	}
+
+	os.RemoveAll(filepath.Join(backupDir, objectsDirName))
+	if _, err := ReadFile(filepath.Join(backupDir, "modified/workspace/main/2026/01/09/a.20260109_143022.txt")); err == nil {
+		t.Error("expected error without the object store")
+	}
+}
@@ -1,8 +1,12 @@
 package common
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/yejune/git-multirepo/internal/manifest"
 )
@@ -35,6 +39,64 @@ func (ctx *WorkspaceContext) ForEachWorkspaceWithContinue(handler WorkspaceHandl
 	}
 }
 
+// ParallelOptions controls ForEachWorkspaceParallel
+type ParallelOptions struct {
+	Workers  int             // Maximum number of concurrent handlers (minimum 1)
+	FailFast bool            // Stop starting new workspaces after the first error
+	Context  context.Context // Stop starting new workspaces once done (e.g., on Ctrl-C); nil never stops
+}
+
+// WorkspaceResult holds the outcome of a handler for a single workspace
+type WorkspaceResult struct {
+	Workspace manifest.WorkspaceEntry
+	Err       error
+	Skipped   bool // Handler was not run because of fail-fast or cancellation
+}
+
+// ForEachWorkspaceParallel applies the handler to the given workspaces concurrently
+// Results are returned in the same order as workspaces
+func (ctx *WorkspaceContext) ForEachWorkspaceParallel(workspaces []manifest.WorkspaceEntry, opts ParallelOptions, handler WorkspaceHandler) []WorkspaceResult {
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]WorkspaceResult, len(workspaces))
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+
+	// Semaphore for worker pool
+	sem := make(chan struct{}, workers)
+
+	for i := range workspaces {
+		results[i].Workspace = workspaces[i]
+
+		// Acquire worker before spawning so fail-fast stops scheduling promptly
+		sem <- struct{}{}
+		if (opts.FailFast && failed.Load()) || (opts.Context != nil && opts.Context.Err() != nil) {
+			<-sem
+			results[i].Skipped = true
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }() // Release worker
+
+			ws := &results[i].Workspace
+			fullPath := filepath.Join(ctx.RepoRoot, ws.Path)
+			if err := handler(ws, fullPath); err != nil {
+				results[i].Err = err
+				failed.Store(true)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
+
 // FilterWorkspaces returns workspaces filtered by command-line arguments
 // If no args provided, returns all workspaces
 // If args provided, returns only the matching workspace by path
@@ -52,3 +114,67 @@ func (ctx *WorkspaceContext) FilterWorkspaces(args []string) ([]manifest.Workspa
 
 	return nil, fmt.Errorf("workspace not found: %s", targetPath)
 }
+
+// SelectWorkspaces returns workspaces filtered by paths and groups
+// A path matches a workspace exactly or as a parent directory (e.g., "packages" matches "packages/lib")
+// A group matches workspaces listing it in their groups
+// If both are empty, returns all workspaces
+func (ctx *WorkspaceContext) SelectWorkspaces(paths, groups []string) ([]manifest.WorkspaceEntry, error) {
+	if len(paths) == 0 && len(groups) == 0 {
+		return ctx.Manifest.Workspaces, nil
+	}
+
+	// Validate every filter matches at least one workspace
+	for _, p := range paths {
+		if !ctx.anyWorkspace(func(ws *manifest.WorkspaceEntry) bool { return matchesPath(ws.Path, p) }) {
+			return nil, fmt.Errorf("workspace not found: %s", p)
+		}
+	}
+	for _, g := range groups {
+		if !ctx.anyWorkspace(func(ws *manifest.WorkspaceEntry) bool { return ws.InGroup(g) }) {
+			return nil, fmt.Errorf("no workspaces in group: %s", g)
+		}
+	}
+
+	var selected []manifest.WorkspaceEntry
+	for i := range ctx.Manifest.Workspaces {
+		ws := &ctx.Manifest.Workspaces[i]
+
+		matched := false
+		for _, p := range paths {
+			if matchesPath(ws.Path, p) {
+				matched = true
+				break
+			}
+		}
+		for _, g := range groups {
+			if matched {
+				break
+			}
+			matched = ws.InGroup(g)
+		}
+
+		if matched {
+			selected = append(selected, *ws)
+		}
+	}
+
+	return selected, nil
+}
+
+// anyWorkspace reports whether any workspace satisfies the predicate
+func (ctx *WorkspaceContext) anyWorkspace(pred func(ws *manifest.WorkspaceEntry) bool) bool {
+	for i := range ctx.Manifest.Workspaces {
+		if pred(&ctx.Manifest.Workspaces[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPath checks if a workspace path equals the filter or lives under it
+func matchesPath(wsPath, filter string) bool {
+	filter = strings.TrimSuffix(filepath.ToSlash(filepath.Clean(filter)), "/")
+	wsPath = filepath.ToSlash(wsPath)
+	return wsPath == filter || strings.HasPrefix(wsPath, filter+"/")
+}
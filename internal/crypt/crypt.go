@@ -0,0 +1,281 @@
+// Package crypt encrypts backups and patches of sensitive keep files at rest
+//
+// Files are sealed with AES-256-GCM. The key comes from a passphrase
+// (GIT_MULTIREPO_PASSPHRASE, stretched with PBKDF2-SHA256) or a keyfile
+// (GIT_MULTIREPO_KEYFILE, any secret content, expanded with HKDF-SHA256).
+// Every encrypted file starts with a header naming the key type and salt, so
+// readers can tell encrypted from plain content and decrypt transparently.
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+const (
+	// EnvPassphrase holds the passphrase to derive the key from
+	EnvPassphrase = "GIT_MULTIREPO_PASSPHRASE"
+	// EnvKeyfile names a file whose content is the key material
+	EnvKeyfile = "GIT_MULTIREPO_KEYFILE"
+)
+
+// Header layout: magic, version, key type, salt; followed by the GCM nonce and ciphertext
+const (
+	magic      = "GMRENC"
+	version    = 1
+	saltSize   = 16
+	headerSize = len(magic) + 2 + saltSize
+)
+
+// Key types recorded in the header
+const (
+	kdfPassphrase byte = 1
+	kdfKeyfile    byte = 2
+)
+
+// pbkdf2Iterations follows the OWASP recommendation for PBKDF2-HMAC-SHA256
+var pbkdf2Iterations = 600_000
+
+// ErrNoKey is returned when encrypted content is read or sensitive content is
+// written without a passphrase or keyfile configured
+var ErrNoKey = errors.New("no encryption key: set " + EnvPassphrase + " or " + EnvKeyfile)
+
+// Key encrypts and decrypts content with a passphrase or keyfile secret
+// A Key seals everything it encrypts with one salt, so the (slow) passphrase
+// derivation runs once per process; decryption derives per salt and caches.
+type Key struct {
+	kdf    byte
+	secret []byte
+	salt   []byte
+
+	mu      sync.Mutex
+	derived map[string]cipher.AEAD // by salt
+	idKey   []byte                 // HMAC key for ID, derived on first use
+}
+
+// idLabel separates the ID key from the encryption keys
+const idLabel = "git-multirepo object id"
+
+// NewPassphraseKey returns a key derived from a passphrase
+func NewPassphraseKey(passphrase string) (*Key, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+	return newKey(kdfPassphrase, []byte(passphrase))
+}
+
+// NewKeyfileKey returns a key derived from the content of a keyfile
+func NewKeyfileKey(path string) (*Key, error) {
+	secret, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyfile: %w", err)
+	}
+	secret = bytes.TrimSpace(secret)
+	if len(secret) < 16 {
+		return nil, fmt.Errorf("keyfile %s is too short (at least 16 bytes of secret)", path)
+	}
+	return newKey(kdfKeyfile, secret)
+}
+
+func newKey(kdf byte, secret []byte) (*Key, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return &Key{kdf: kdf, secret: secret, salt: salt, derived: make(map[string]cipher.AEAD)}, nil
+}
+
+var (
+	envKeyMu    sync.Mutex
+	envKeyValue string
+	envKey      *Key
+)
+
+// LoadKey returns the key configured in the environment, or nil if none is
+// The key is cached for the process as long as the environment is unchanged.
+func LoadKey() (*Key, error) {
+	passphrase, keyfile := os.Getenv(EnvPassphrase), os.Getenv(EnvKeyfile)
+	if passphrase == "" && keyfile == "" {
+		return nil, nil
+	}
+	if passphrase != "" && keyfile != "" {
+		return nil, fmt.Errorf("set only one of %s and %s", EnvPassphrase, EnvKeyfile)
+	}
+
+	envKeyMu.Lock()
+	defer envKeyMu.Unlock()
+	value := passphrase + "\x00" + keyfile
+	if envKey != nil && envKeyValue == value {
+		return envKey, nil
+	}
+
+	var key *Key
+	var err error
+	if passphrase != "" {
+		key, err = NewPassphraseKey(passphrase)
+	} else {
+		key, err = NewKeyfileKey(keyfile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	envKey, envKeyValue = key, value
+	return key, nil
+}
+
+// aead returns the cipher for a salt, deriving it on first use
+func (k *Key) aead(salt []byte) (cipher.AEAD, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if a, ok := k.derived[string(salt)]; ok {
+		return a, nil
+	}
+
+	var raw []byte
+	var err error
+	switch k.kdf {
+	case kdfPassphrase:
+		raw, err = pbkdf2.Key(sha256.New, string(k.secret), salt, pbkdf2Iterations, 32)
+	case kdfKeyfile:
+		raw, err = hkdf.Key(sha256.New, k.secret, salt, "git-multirepo backup", 32)
+	default:
+		err = fmt.Errorf("unknown key type %d", k.kdf)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	a, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	k.derived[string(salt)] = a
+	return a, nil
+}
+
+// ID returns a keyed identifier of content (hex HMAC-SHA256)
+// Equal content gets equal ids under the same secret, so encrypted content can
+// be deduplicated without storing a plain hash that would let short secrets
+// be confirmed offline.
+func (k *Key) ID(data []byte) (string, error) {
+	k.mu.Lock()
+	if k.idKey == nil {
+		var err error
+		switch k.kdf {
+		case kdfPassphrase:
+			k.idKey, err = pbkdf2.Key(sha256.New, string(k.secret), []byte(idLabel), pbkdf2Iterations, 32)
+		case kdfKeyfile:
+			k.idKey, err = hkdf.Key(sha256.New, k.secret, nil, idLabel, 32)
+		default:
+			err = fmt.Errorf("unknown key type %d", k.kdf)
+		}
+		if err != nil {
+			k.mu.Unlock()
+			return "", err
+		}
+	}
+	mac := hmac.New(sha256.New, k.idKey)
+	k.mu.Unlock()
+
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Encrypt seals plaintext, returning the header, nonce and ciphertext
+func (k *Key) Encrypt(plaintext []byte) ([]byte, error) {
+	a, err := k.aead(k.salt)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, headerSize+a.NonceSize())
+	header = append(header, magic...)
+	header = append(header, version, k.kdf)
+	header = append(header, k.salt...)
+
+	nonce := make([]byte, a.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(header, nonce...)
+	return a.Seal(out, nonce, plaintext, out[:headerSize]), nil
+}
+
+// Decrypt opens content sealed by Encrypt
+func (k *Key) Decrypt(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, errors.New("not encrypted")
+	}
+	if data[len(magic)] != version {
+		return nil, fmt.Errorf("unsupported encryption version %d", data[len(magic)])
+	}
+	if kdf := data[len(magic)+1]; kdf != k.kdf {
+		if kdf == kdfPassphrase {
+			return nil, fmt.Errorf("encrypted with a passphrase (set %s)", EnvPassphrase)
+		}
+		return nil, fmt.Errorf("encrypted with a keyfile (set %s)", EnvKeyfile)
+	}
+
+	a, err := k.aead(data[len(magic)+2 : headerSize])
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < headerSize+a.NonceSize() {
+		return nil, errors.New("encrypted content is truncated")
+	}
+	nonce := data[headerSize : headerSize+a.NonceSize()]
+	plaintext, err := a.Open(nil, nonce, data[headerSize+a.NonceSize():], data[:headerSize])
+	if err != nil {
+		return nil, errors.New("decryption failed: wrong key or corrupted content")
+	}
+	return plaintext, nil
+}
+
+// IsEncrypted checks if content starts with the encryption header
+func IsEncrypted(data []byte) bool {
+	return len(data) >= headerSize && string(data[:len(magic)]) == magic
+}
+
+// Decrypt returns plain content as-is and decrypts encrypted content with the
+// key from the environment
+func Decrypt(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	key, err := LoadKey()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrNoKey
+	}
+	return key.Decrypt(data)
+}
+
+// ReadFile reads a file, decrypting it if it is encrypted
+func ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := Decrypt(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,174 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func init() {
+	// Keep key derivation fast in tests
+	pbkdf2Iterations = 1000
+}
+
+func writeKeyfile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write keyfile: %v", err)
+	}
+	return path
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	plaintext := []byte("API_KEY=secret\n")
+
+	passphraseKey, _ := NewPassphraseKey("correct horse")
+	keyfileKey, err := NewKeyfileKey(writeKeyfile(t, "0123456789abcdef0123456789abcdef\n"))
+	if err != nil {
+		t.Fatalf("NewKeyfileKey failed: %v", err)
+	}
+
+	for name, key := range map[string]*Key{"passphrase": passphraseKey, "keyfile": keyfileKey} {
+		t.Run(name, func(t *testing.T) {
+			sealed, err := key.Encrypt(plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt failed: %v", err)
+			}
+			if !IsEncrypted(sealed) || bytes.Contains(sealed, plaintext) {
+				t.Fatal("sealed content should carry the header and hide the plaintext")
+			}
+
+			opened, err := key.Decrypt(sealed)
+			if err != nil || !bytes.Equal(opened, plaintext) {
+				t.Errorf("Decrypt = %q, %v", opened, err)
+			}
+
+			again, _ := key.Encrypt(plaintext)
+			if bytes.Equal(sealed, again) {
+				t.Error("every encryption should use a fresh nonce")
+			}
+		})
+	}
+
+	t.Run("same passphrase in another process", func(t *testing.T) {
+		sealed, _ := passphraseKey.Encrypt(plaintext)
+		other, _ := NewPassphraseKey("correct horse")
+		if opened, err := other.Decrypt(sealed); err != nil || !bytes.Equal(opened, plaintext) {
+			t.Errorf("Decrypt = %q, %v", opened, err)
+		}
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		sealed, _ := passphraseKey.Encrypt(plaintext)
+		wrong, _ := NewPassphraseKey("battery staple")
+		if _, err := wrong.Decrypt(sealed); err == nil {
+			t.Error("expected error for wrong passphrase")
+		}
+		if _, err := keyfileKey.Decrypt(sealed); err == nil || !strings.Contains(err.Error(), EnvPassphrase) {
+			t.Errorf("expected hint to set the passphrase, got %v", err)
+		}
+	})
+
+	t.Run("tampered content", func(t *testing.T) {
+		sealed, _ := passphraseKey.Encrypt(plaintext)
+		sealed[len(sealed)-1] ^= 1
+		if _, err := passphraseKey.Decrypt(sealed); err == nil {
+			t.Error("expected error for tampered content")
+		}
+	})
+}
+
+func TestID(t *testing.T) {
+	content := []byte("API_KEY=secret\n")
+	key, _ := NewPassphraseKey("correct horse")
+	other, _ := NewPassphraseKey("battery staple")
+	keyfileKey, _ := NewKeyfileKey(writeKeyfile(t, "0123456789abcdef0123456789abcdef\n"))
+
+	id, err := key.ID(content)
+	if err != nil || len(id) != 64 {
+		t.Fatalf("ID() = %q, %v", id, err)
+	}
+	// A new key from the same passphrase (with a new salt) gives the same id
+	again, _ := NewPassphraseKey("correct horse")
+	if id2, _ := again.ID(content); id2 != id {
+		t.Error("ID should not depend on the encryption salt")
+	}
+	if id2, _ := key.ID([]byte("API_KEY=other\n")); id2 == id {
+		t.Error("different content should get a different id")
+	}
+	for name, k := range map[string]*Key{"other passphrase": other, "keyfile": keyfileKey} {
+		if id2, _ := k.ID(content); id2 == id {
+			t.Errorf("%s should give a different id", name)
+		}
+	}
+	if sum := sha256.Sum256(content); hex.EncodeToString(sum[:]) == id {
+		t.Error("ID should not be the plain sha256")
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	t.Run("no key configured", func(t *testing.T) {
+		t.Setenv(EnvPassphrase, "")
+		t.Setenv(EnvKeyfile, "")
+		key, err := LoadKey()
+		if key != nil || err != nil {
+			t.Errorf("LoadKey = %v, %v; want nil, nil", key, err)
+		}
+
+		if _, err := Decrypt([]byte("plain")); err != nil {
+			t.Errorf("plain content should pass through, got %v", err)
+		}
+		sealed, _ := mustKey(t, "pw").Encrypt([]byte("secret"))
+		if _, err := Decrypt(sealed); !errors.Is(err, ErrNoKey) {
+			t.Errorf("expected ErrNoKey, got %v", err)
+		}
+	})
+
+	t.Run("passphrase", func(t *testing.T) {
+		t.Setenv(EnvPassphrase, "pw")
+		t.Setenv(EnvKeyfile, "")
+		key, err := LoadKey()
+		if err != nil || key == nil {
+			t.Fatalf("LoadKey = %v, %v", key, err)
+		}
+		if again, _ := LoadKey(); again != key {
+			t.Error("key should be cached")
+		}
+
+		sealed, _ := mustKey(t, "pw").Encrypt([]byte("secret"))
+		if opened, err := Decrypt(sealed); err != nil || string(opened) != "secret" {
+			t.Errorf("Decrypt = %q, %v", opened, err)
+		}
+	})
+
+	t.Run("both set", func(t *testing.T) {
+		t.Setenv(EnvPassphrase, "pw")
+		t.Setenv(EnvKeyfile, writeKeyfile(t, "0123456789abcdef"))
+		if _, err := LoadKey(); err == nil {
+			t.Error("expected error when both are set")
+		}
+	})
+
+	t.Run("short keyfile", func(t *testing.T) {
+		t.Setenv(EnvPassphrase, "")
+		t.Setenv(EnvKeyfile, writeKeyfile(t, "short"))
+		if _, err := LoadKey(); err == nil {
+			t.Error("expected error for a short keyfile")
+		}
+	})
+}
+
+func mustKey(t *testing.T, passphrase string) *Key {
+	t.Helper()
+	key, err := NewPassphraseKey(passphrase)
+	if err != nil {
+		t.Fatalf("NewPassphraseKey failed: %v", err)
+	}
+	return key
+}
@@ -0,0 +1,147 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BranchExists checks if a local branch exists
+func BranchExists(path, branch string) bool {
+	return run(path, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch) == nil
+}
+
+// CommitExists checks if commit is present in the local object store
+func CommitExists(path, commit string) bool {
+	return run(path, "rev-parse", "--verify", "--quiet", commit+"^{commit}") == nil
+}
+
+// GetBranchCommit returns the commit a local branch points to
+func GetBranchCommit(path, branch string) (string, error) {
+	out, err := output(path, "rev-parse", "--verify", "refs/heads/"+branch)
+	if err != nil {
+		return "", fmt.Errorf("branch not found: %s", branch)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// GetDefaultBranch returns the branch origin/HEAD points to (e.g., "main")
+func GetDefaultBranch(path string) (string, error) {
+	out, err := output(path, "symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+	if err != nil {
+		return "", fmt.Errorf("origin/HEAD is not set")
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(out)), "origin/"), nil
+}
+
+// HasTrackedChanges checks if tracked files have uncommitted changes
+// Skip-worktree files and untracked files are not reported
+func HasTrackedChanges(path string) (bool, error) {
+	out, err := output(path, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return false, err
+	}
+	return len(strings.TrimSpace(string(out))) > 0, nil
+}
+
+// savedFile is the content and mode of a file to write back later
+type savedFile struct {
+	content []byte
+	perm    os.FileMode
+}
+
+// CheckoutBranch switches to branch, carrying the local content of keepFiles over
+// If startPoint is not empty, the branch is created from it first.
+// Keep files are restored to HEAD before the checkout (so git does not refuse
+// to overwrite them) and written back with their mode afterwards, even if the
+// checkout fails. Call this within WithSkipWorktreeTransaction so the files are unskipped.
+func CheckoutBranch(path, branch, startPoint string, keepFiles []string) (err error) {
+	saved := make(map[string]savedFile)
+	for _, file := range keepFiles {
+		fullPath := filepath.Join(path, file)
+		info, statErr := os.Stat(fullPath)
+		content, readErr := os.ReadFile(fullPath)
+		if statErr != nil || readErr != nil {
+			continue // Missing keep files have nothing to preserve
+		}
+		saved[file] = savedFile{content: content, perm: info.Mode().Perm()}
+	}
+
+	defer func() {
+		for file, s := range saved {
+			fullPath := filepath.Join(path, file)
+			if mkErr := os.MkdirAll(filepath.Dir(fullPath), 0755); mkErr != nil && err == nil {
+				err = fmt.Errorf("failed to restore %s: %w", file, mkErr)
+				continue
+			}
+			if writeErr := WriteFileMode(fullPath, s.content, s.perm); writeErr != nil && err == nil {
+				err = fmt.Errorf("failed to restore %s: %w", file, writeErr)
+			}
+		}
+	}()
+
+	// Reset tracked keep files to HEAD and remove untracked ones
+	for file := range saved {
+		if isTracked(path, file) {
+			if out, err := combinedOutput(path, "checkout", "HEAD", "--", file); err != nil {
+				return fmt.Errorf("failed to reset %s: %s", file, strings.TrimSpace(string(out)))
+			}
+		} else {
+			os.Remove(filepath.Join(path, file))
+		}
+	}
+
+	args := []string{"checkout", "-q"}
+	if startPoint != "" {
+		args = append(args, "-b", branch, startPoint)
+	} else {
+		args = append(args, branch)
+	}
+	if out, err := combinedOutput(path, args...); err != nil {
+		return fmt.Errorf("git checkout failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// WriteFileMode writes content to a file and sets its mode
+// Unlike os.WriteFile, an existing file (e.g. recreated by git with the
+// default mode) gets perm too.
+func WriteFileMode(path string, content []byte, perm os.FileMode) error {
+	if err := os.WriteFile(path, content, perm); err != nil {
+		return err
+	}
+	return os.Chmod(path, perm)
+}
+
+// DeleteBranch deletes a local branch
+// Unmerged branches are refused unless force is set
+func DeleteBranch(path, branch string, force bool) error {
+	flag := "-d"
+	if force {
+		flag = "-D"
+	}
+	if out, err := combinedOutput(path, "branch", flag, branch); err != nil {
+		return fmt.Errorf("git branch %s failed: %s", flag, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// DeleteRemoteBranch deletes branch on origin
+// A branch already gone from the remote (e.g., deleted through another
+// clone of the same repository) only has its stale tracking ref removed
+func DeleteRemoteBranch(ctx context.Context, path, branch string) error {
+	if out, err := combinedOutputOp(ctx, OpPush, path, "push", "-q", "origin", "--delete", branch); err != nil {
+		if !strings.Contains(string(out), "remote ref does not exist") {
+			return opError(err, "git push --delete", string(out))
+		}
+		run(path, "update-ref", "-d", "refs/remotes/origin/"+branch)
+	}
+	return nil
+}
+
+// isTracked checks if file is in the index
+func isTracked(path, file string) bool {
+	return run(path, "ls-files", "--error-unmatch", "--", file) == nil
+}
@@ -0,0 +1,120 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckoutBranch_PreservesKeepFiles(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	base, _ := GetCurrentBranch(dir)
+	keep := []string{"config.json"}
+
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte("base\n"), 0644)
+	exec.Command("git", "-C", dir, "add", ".").Run()
+	exec.Command("git", "-C", dir, "commit", "-q", "-m", "Add config").Run()
+
+	// A branch where the keep file differs, so a plain checkout would be refused
+	exec.Command("git", "-C", dir, "checkout", "-q", "-b", "other").Run()
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte("other\n"), 0644)
+	exec.Command("git", "-C", dir, "commit", "-q", "-am", "Change config").Run()
+	exec.Command("git", "-C", dir, "checkout", "-q", base).Run()
+
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte("local\n"), 0644)
+	os.Chmod(filepath.Join(dir, "config.json"), 0600)
+	ApplySkipWorktree(dir, keep)
+
+	err := WithSkipWorktreeTransaction(dir, keep, func() error {
+		return CheckoutBranch(dir, "other", "", keep)
+	})
+	if err != nil {
+		t.Fatalf("CheckoutBranch failed: %v", err)
+	}
+
+	if branch, _ := GetCurrentBranch(dir); branch != "other" {
+		t.Errorf("expected branch other, got %s", branch)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "config.json")); string(content) != "local\n" {
+		t.Errorf("keep file content not preserved: %q", content)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "config.json")); info.Mode().Perm() != 0600 {
+		t.Errorf("keep file mode not preserved: %v", info.Mode().Perm())
+	}
+	if skipped, _ := ListSkipWorktree(dir); len(skipped) != 1 || skipped[0] != "config.json" {
+		t.Errorf("skip-worktree should be re-applied, got %v", skipped)
+	}
+}
+
+func TestCheckoutBranch_CreateAndFailure(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	base, _ := GetCurrentBranch(dir)
+
+	if err := CheckoutBranch(dir, "feature", base, nil); err != nil {
+		t.Fatalf("CheckoutBranch create failed: %v", err)
+	}
+	if !BranchExists(dir, "feature") {
+		t.Error("feature branch should exist")
+	}
+
+	if err := CheckoutBranch(dir, "missing", "", nil); err == nil {
+		t.Error("expected error for missing branch")
+	}
+}
+
+func TestDeleteBranch(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	base, _ := GetCurrentBranch(dir)
+
+	exec.Command("git", "-C", dir, "checkout", "-q", "-b", "unmerged").Run()
+	os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new"), 0644)
+	exec.Command("git", "-C", dir, "add", ".").Run()
+	exec.Command("git", "-C", dir, "commit", "-q", "-m", "New").Run()
+	exec.Command("git", "-C", dir, "checkout", "-q", base).Run()
+
+	if err := DeleteBranch(dir, "unmerged", false); err == nil {
+		t.Error("expected refusal for unmerged branch")
+	}
+	if err := DeleteBranch(dir, "unmerged", true); err != nil {
+		t.Errorf("forced delete failed: %v", err)
+	}
+	if BranchExists(dir, "unmerged") {
+		t.Error("branch should be deleted")
+	}
+}
+
+func TestHasTrackedChanges(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+
+	os.WriteFile(filepath.Join(dir, "untracked.txt"), []byte("x"), 0644)
+	if dirty, _ := HasTrackedChanges(dir); dirty {
+		t.Error("untracked files should not count")
+	}
+
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("changed"), 0644)
+	if dirty, _ := HasTrackedChanges(dir); !dirty {
+		t.Error("modified tracked file should count")
+	}
+}
+
+func TestCommitExistsAndGetBranchCommit(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	branch, _ := GetCurrentBranch(dir)
+	head, _ := GetCurrentCommit(dir)
+
+	if !CommitExists(dir, head) {
+		t.Errorf("HEAD commit %s should exist", head)
+	}
+	if CommitExists(dir, "0123456789abcdef0123456789abcdef01234567") {
+		t.Error("unknown commit should not exist")
+	}
+
+	commit, err := GetBranchCommit(dir, branch)
+	if err != nil || commit != head {
+		t.Errorf("GetBranchCommit = %q, %v; want %s", commit, err, head)
+	}
+	if _, err := GetBranchCommit(dir, "missing"); err == nil {
+		t.Error("expected error for missing branch")
+	}
+}
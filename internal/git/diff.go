@@ -0,0 +1,45 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffOptions controls the output of Diff
+type DiffOptions struct {
+	Stat     bool     // Show diffstat instead of patch
+	NameOnly bool     // Show only names of changed files
+	Prefix   string   // Path prefix added to a/ and b/ (e.g., workspace path)
+	Exclude  []string // Files excluded from the diff
+	Color    bool     // Force colored output
+}
+
+// Diff returns uncommitted changes (staged and unstaged) against HEAD
+func Diff(path string, opts DiffOptions) (string, error) {
+	args := []string{"diff", "HEAD"}
+	if opts.Color {
+		args = append(args, "--color=always")
+	}
+	switch {
+	case opts.NameOnly:
+		args = append(args, "--name-only")
+	case opts.Stat:
+		args = append(args, "--stat")
+	}
+	if opts.Prefix != "" {
+		prefix := strings.TrimSuffix(opts.Prefix, "/") + "/"
+		args = append(args, "--src-prefix=a/"+prefix, "--dst-prefix=b/"+prefix)
+	}
+	if len(opts.Exclude) > 0 {
+		args = append(args, "--", ".")
+		for _, file := range opts.Exclude {
+			args = append(args, ":(exclude)"+file)
+		}
+	}
+
+	out, err := output(path, args...)
+	if err != nil {
+		return "", fmt.Errorf("git diff failed: %s", stderrOf(err))
+	}
+	return string(out), nil
+}
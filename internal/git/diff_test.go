@@ -0,0 +1,46 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Changed"), 0644)
+	os.WriteFile(filepath.Join(dir, "local.txt"), []byte("local"), 0644)
+	exec.Command("git", "-C", dir, "add", "local.txt").Run()
+
+	t.Run("includes staged and unstaged changes", func(t *testing.T) {
+		out, err := Diff(dir, DiffOptions{NameOnly: true})
+		if err != nil {
+			t.Fatalf("Diff failed: %v", err)
+		}
+		if !strings.Contains(out, "README.md") || !strings.Contains(out, "local.txt") {
+			t.Errorf("unexpected output: %s", out)
+		}
+	})
+
+	t.Run("prefix", func(t *testing.T) {
+		out, err := Diff(dir, DiffOptions{Prefix: "apps/api"})
+		if err != nil {
+			t.Fatalf("Diff failed: %v", err)
+		}
+		if !strings.Contains(out, "--- a/apps/api/README.md") || !strings.Contains(out, "+++ b/apps/api/README.md") {
+			t.Errorf("paths should be prefixed, got: %s", out)
+		}
+	})
+
+	t.Run("exclude", func(t *testing.T) {
+		out, err := Diff(dir, DiffOptions{NameOnly: true, Exclude: []string{"local.txt"}})
+		if err != nil {
+			t.Fatalf("Diff failed: %v", err)
+		}
+		if strings.Contains(out, "local.txt") {
+			t.Errorf("excluded file should not appear, got: %s", out)
+		}
+	})
+}
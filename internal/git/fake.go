@@ -0,0 +1,141 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// FakeRunner is an in-memory Runner for tests
+// Commands are answered from registered responses, matched by directory and
+// leading arguments; the most recently registered match wins. Commands
+// without a response fail with exit status 128. Every command is recorded.
+//
+//	fake := git.NewFakeRunner()
+//	defer git.SetRunner(fake)()
+//	fake.On("/repo", "rev-parse", "--abbrev-ref", "HEAD").Return("main\n")
+type FakeRunner struct {
+	mu        sync.Mutex
+	responses []*FakeResponse
+	calls     []Cmd
+}
+
+// FakeResponse is the canned result of a matched command
+type FakeResponse struct {
+	dir      string
+	args     []string
+	stdout   string
+	stderr   string
+	exitCode int
+	effect   func(Cmd)
+}
+
+// NewFakeRunner returns a FakeRunner without responses
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{}
+}
+
+// On registers a response for commands run in dir whose arguments start with args
+// An empty dir matches every directory. The response succeeds with no output
+// until Return or Fail is called.
+func (f *FakeRunner) On(dir string, args ...string) *FakeResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := &FakeResponse{dir: dir, args: args}
+	f.responses = append(f.responses, r)
+	return r
+}
+
+// Return makes the command succeed with stdout
+func (r *FakeResponse) Return(stdout string) *FakeResponse {
+	r.stdout = stdout
+	return r
+}
+
+// Fail makes the command exit with code and print stderr
+func (r *FakeResponse) Fail(code int, stderr string) *FakeResponse {
+	r.exitCode = code
+	r.stderr = stderr
+	return r
+}
+
+// Do runs effect when the command is matched, e.g. to change what later commands return
+func (r *FakeResponse) Do(effect func(Cmd)) *FakeResponse {
+	r.effect = effect
+	return r
+}
+
+// Calls returns the commands run so far
+func (f *FakeRunner) Calls() []Cmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Cmd(nil), f.calls...)
+}
+
+// Called checks if a command in dir starting with args was run
+func (f *FakeRunner) Called(dir string, args ...string) bool {
+	for _, c := range f.Calls() {
+		if fakeMatches(dir, args, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run implements Runner
+func (f *FakeRunner) Run(ctx context.Context, c Cmd) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, c)
+	var match *FakeResponse
+	for i := len(f.responses) - 1; i >= 0; i-- {
+		if fakeMatches(f.responses[i].dir, f.responses[i].args, c) {
+			match = f.responses[i]
+			break
+		}
+	}
+	f.mu.Unlock()
+
+	if match == nil {
+		msg := fmt.Sprintf("fake: no response for git %s (in %s)", strings.Join(c.Args, " "), c.Dir)
+		if c.Stderr != nil {
+			io.WriteString(c.Stderr, msg)
+		}
+		return &ExitError{Code: 128}
+	}
+
+	if match.effect != nil {
+		match.effect(c)
+	}
+	if c.Stdout != nil {
+		io.WriteString(c.Stdout, match.stdout)
+	}
+	if c.Stderr != nil {
+		io.WriteString(c.Stderr, match.stderr)
+	}
+	if match.exitCode != 0 {
+		return &ExitError{Code: match.exitCode}
+	}
+	return nil
+}
+
+// fakeMatches checks if c runs in dir (any dir if empty) with arguments starting with args
+func fakeMatches(dir string, args []string, c Cmd) bool {
+	if dir != "" && dir != c.Dir {
+		return false
+	}
+	if len(args) > len(c.Args) {
+		return false
+	}
+	for i, arg := range args {
+		if c.Args[i] != arg {
+			return false
+		}
+	}
+	return true
+}
@@ -1,33 +1,32 @@
 // Package git provides git command wrappers
+//
+// Remote operations (clone, fetch, pull, push) take a context and run under
+// per-operation timeouts, see Timeout. Local commands only use the local timeout.
 package git
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
-	"time"
 )
 
 // Clone clones a repository to the specified path
-func Clone(repo, path, branch string) error {
+func Clone(ctx context.Context, repo, path, branch string) error {
 	args := []string{"clone"}
 	if branch != "" {
 		args = append(args, "-b", branch)
 	}
 	args = append(args, repo, path)
 
-	cmd := exec.Command("git", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return streamOp(ctx, OpClone, "", args...)
 }
 
 // InitRepo initializes a git repository in an existing directory with source files
 // This is used when source files are already tracked by parent but .git is missing
-func InitRepo(path, repo, branch string) error {
+func InitRepo(ctx context.Context, path, repo, branch string) error {
 	// Create a temporary directory for bare clone
 	tempDir, err := os.MkdirTemp("", "git-multirepo-*")
 	if err != nil {
@@ -43,8 +42,7 @@ func InitRepo(path, repo, branch string) error {
 	}
 	args = append(args, repo, tempGit)
 
-	cmd := exec.Command("git", args...)
-	if err := cmd.Run(); err != nil {
+	if err := runOp(ctx, OpClone, Cmd{Args: args}); err != nil {
 		return fmt.Errorf("failed to clone: %w", err)
 	}
 
@@ -55,16 +53,12 @@ func InitRepo(path, repo, branch string) error {
 	}
 
 	// Convert from bare to normal repository
-	cmd = exec.Command("git", "-C", path, "config", "--bool", "core.bare", "false")
-	if err := cmd.Run(); err != nil {
+	if err := run(path, "config", "--bool", "core.bare", "false"); err != nil {
 		return fmt.Errorf("failed to configure: %w", err)
 	}
 
 	// Reset index to match HEAD (don't touch working tree files)
-	cmd = exec.Command("git", "-C", path, "reset", "--mixed", "HEAD")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if err := stream(path, "reset", "--mixed", "HEAD"); err != nil {
 		return fmt.Errorf("failed to reset: %w", err)
 	}
 
@@ -72,19 +66,31 @@ func InitRepo(path, repo, branch string) error {
 }
 
 // Pull pulls the latest changes in the specified directory
-func Pull(path string) error {
-	cmd := exec.Command("git", "-C", path, "pull")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+func Pull(ctx context.Context, path string) error {
+	return streamOp(ctx, OpPull, path, "pull")
+}
+
+// PullWithStrategy pulls using the given strategy: "merge", "rebase" or "ff-only"
+// An empty strategy uses git's configured behavior (same as Pull)
+func PullWithStrategy(ctx context.Context, path, strategy string) error {
+	args := []string{"pull"}
+	switch strategy {
+	case "":
+	case "merge":
+		args = append(args, "--no-rebase")
+	case "rebase":
+		args = append(args, "--rebase")
+	case "ff-only":
+		args = append(args, "--ff-only")
+	default:
+		return fmt.Errorf("unknown pull strategy: %s", strategy)
+	}
+	return streamOp(ctx, OpPull, path, args...)
 }
 
 // Push pushes changes in the specified directory
-func Push(path string) error {
-	cmd := exec.Command("git", "-C", path, "push")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+func Push(ctx context.Context, path string) error {
+	return streamOp(ctx, OpPush, path, "push")
 }
 
 // IsRepo checks if the given path is a git repository
@@ -99,8 +105,7 @@ func IsRepo(path string) bool {
 
 // GetRepoRoot returns the root directory of the git repository
 func GetRepoRoot() (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-	out, err := cmd.Output()
+	out, err := output("", "rev-parse", "--show-toplevel")
 	if err != nil {
 		return "", fmt.Errorf("not a git repository")
 	}
@@ -283,8 +288,7 @@ func RemoveFromGitignore(repoRoot, path string) error {
 
 // HasChanges checks if there are uncommitted changes
 func HasChanges(path string) (bool, error) {
-	cmd := exec.Command("git", "-C", path, "status", "--porcelain")
-	out, err := cmd.Output()
+	out, err := output(path, "status", "--porcelain")
 	if err != nil {
 		return false, err
 	}
@@ -293,8 +297,7 @@ func HasChanges(path string) (bool, error) {
 
 // GetCurrentBranch returns the current branch name
 func GetCurrentBranch(path string) (string, error) {
-	cmd := exec.Command("git", "-C", path, "rev-parse", "--abbrev-ref", "HEAD")
-	out, err := cmd.Output()
+	out, err := output(path, "rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
 		return "", err
 	}
@@ -303,8 +306,7 @@ func GetCurrentBranch(path string) (string, error) {
 
 // GetCurrentCommit returns the current HEAD commit hash
 func GetCurrentCommit(path string) (string, error) {
-	cmd := exec.Command("git", "-C", path, "rev-parse", "HEAD")
-	out, err := cmd.Output()
+	out, err := output(path, "rev-parse", "HEAD")
 	if err != nil {
 		return "", err
 	}
@@ -314,23 +316,20 @@ func GetCurrentCommit(path string) (string, error) {
 // HasUnpushedCommits checks if there are commits not pushed to remote
 func HasUnpushedCommits(path string) (bool, error) {
 	// Get current branch
-	cmd := exec.Command("git", "-C", path, "rev-parse", "--abbrev-ref", "HEAD")
-	out, err := cmd.Output()
+	out, err := output(path, "rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
 		return false, err
 	}
 	branch := strings.TrimSpace(string(out))
 
 	// Check if branch has upstream
-	cmd = exec.Command("git", "-C", path, "rev-parse", "--abbrev-ref", branch+"@{upstream}")
-	if err := cmd.Run(); err != nil {
+	if err := run(path, "rev-parse", "--abbrev-ref", branch+"@{upstream}"); err != nil {
 		// No upstream configured - consider as unpushed
 		return true, nil
 	}
 
 	// Compare with upstream
-	cmd = exec.Command("git", "-C", path, "rev-list", "--count", branch+"@{upstream}.."+branch)
-	out, err = cmd.Output()
+	out, err = output(path, "rev-list", "--count", branch+"@{upstream}.."+branch)
 	if err != nil {
 		return false, err
 	}
@@ -341,14 +340,18 @@ func HasUnpushedCommits(path string) (bool, error) {
 
 // GetRemoteURL returns the remote origin URL
 func GetRemoteURL(path string) (string, error) {
-	cmd := exec.Command("git", "-C", path, "remote", "get-url", "origin")
-	out, err := cmd.Output()
+	out, err := output(path, "remote", "get-url", "origin")
 	if err != nil {
 		return "", err
 	}
 	return strings.TrimSpace(string(out)), nil
 }
 
+// AddRemote adds a remote to the repository
+func AddRemote(path, name, url string) error {
+	return run(path, "remote", "add", name, url)
+}
+
 // ApplySkipWorktree applies skip-worktree to files
 func ApplySkipWorktree(repoPath string, files []string) error {
 	if len(files) == 0 {
@@ -359,16 +362,14 @@ func ApplySkipWorktree(repoPath string, files []string) error {
 
 	for _, file := range files {
 		// Check if already skip-worktree
-		cmd := exec.Command("git", "-C", repoPath, "ls-files", "-v", file)
-		out, err := cmd.Output()
+		out, err := output(repoPath, "ls-files", "-v", file)
 		if err == nil && len(out) > 0 && out[0] == 'S' {
 			// Already skip-worktree, skip
 			continue
 		}
 
 		// Apply skip-worktree - let git tell us if file doesn't exist or isn't tracked
-		cmd = exec.Command("git", "-C", repoPath, "update-index", "--skip-worktree", file)
-		if err := cmd.Run(); err != nil {
+		if err := run(repoPath, "update-index", "--skip-worktree", file); err != nil {
 			failed = append(failed, fmt.Sprintf("%s (%v)", file, err))
 		}
 	}
@@ -389,8 +390,7 @@ func UnapplySkipWorktree(repoPath string, files []string) error {
 	var failed []string
 
 	for _, file := range files {
-		cmd := exec.Command("git", "-C", repoPath, "update-index", "--no-skip-worktree", file)
-		if err := cmd.Run(); err != nil {
+		if err := run(repoPath, "update-index", "--no-skip-worktree", file); err != nil {
 			failed = append(failed, fmt.Sprintf("%s (%v)", file, err))
 		}
 	}
@@ -404,8 +404,7 @@ func UnapplySkipWorktree(repoPath string, files []string) error {
 
 // ListSkipWorktree lists all files with skip-worktree set
 func ListSkipWorktree(repoPath string) ([]string, error) {
-	cmd := exec.Command("git", "-C", repoPath, "ls-files", "-v")
-	out, err := cmd.Output()
+	out, err := output(repoPath, "ls-files", "-v")
 	if err != nil {
 		return nil, err
 	}
@@ -424,8 +423,7 @@ func ListSkipWorktree(repoPath string) ([]string, error) {
 
 // HasLocalChanges checks if there are uncommitted changes (including untracked files)
 func HasLocalChanges(path string) (bool, error) {
-	cmd := exec.Command("git", "-C", path, "status", "--porcelain")
-	out, err := cmd.Output()
+	out, err := output(path, "status", "--porcelain")
 	if err != nil {
 		return false, err
 	}
@@ -434,8 +432,7 @@ func HasLocalChanges(path string) (bool, error) {
 
 // CountChangedFiles counts the number of changed files
 func CountChangedFiles(path string) (int, error) {
-	cmd := exec.Command("git", "-C", path, "status", "--porcelain")
-	out, err := cmd.Output()
+	out, err := output(path, "status", "--porcelain")
 	if err != nil {
 		return 0, err
 	}
@@ -446,22 +443,9 @@ func CountChangedFiles(path string) (int, error) {
 	return len(lines), nil
 }
 
-// Stash stashes all local changes
-func Stash(path string) error {
-	cmd := exec.Command("git", "-C", path, "stash", "push", "-m", "git-multirepo auto-stash")
-	return cmd.Run()
-}
-
-// StashPop applies and removes the most recent stash
-func StashPop(path string) error {
-	cmd := exec.Command("git", "-C", path, "stash", "pop")
-	return cmd.Run()
-}
-
 // GetModifiedFiles returns list of modified files
 func GetModifiedFiles(path string) ([]string, error) {
-	cmd := exec.Command("git", "-C", path, "diff", "--name-only", "HEAD")
-	out, err := cmd.Output()
+	out, err := output(path, "diff", "--name-only", "HEAD")
 	if err != nil {
 		return nil, err
 	}
@@ -474,8 +458,7 @@ func GetModifiedFiles(path string) ([]string, error) {
 
 // GetUntrackedFiles returns list of untracked files
 func GetUntrackedFiles(path string) ([]string, error) {
-	cmd := exec.Command("git", "-C", path, "ls-files", "--others", "--exclude-standard")
-	out, err := cmd.Output()
+	out, err := output(path, "ls-files", "--others", "--exclude-standard")
 	if err != nil {
 		return nil, err
 	}
@@ -488,8 +471,7 @@ func GetUntrackedFiles(path string) ([]string, error) {
 
 // GetStagedFiles returns list of staged files
 func GetStagedFiles(path string) ([]string, error) {
-	cmd := exec.Command("git", "-C", path, "diff", "--name-only", "--cached")
-	out, err := cmd.Output()
+	out, err := output(path, "diff", "--name-only", "--cached")
 	if err != nil {
 		return nil, err
 	}
@@ -500,37 +482,70 @@ func GetStagedFiles(path string) ([]string, error) {
 	return files, nil
 }
 
-// FetchTimeout is the timeout for fetch operations
-const FetchTimeout = 10 * time.Second
-
-// ErrFetchTimeout indicates fetch operation timed out
-var ErrFetchTimeout = fmt.Errorf("fetch timed out after %v", FetchTimeout)
+// StageTracked stages modifications and deletions of tracked files (git add -u)
+func StageTracked(path string) error {
+	if out, err := combinedOutput(path, "add", "-u"); err != nil {
+		return fmt.Errorf("git add failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
 
-// Fetch fetches from remote with timeout
-func Fetch(path string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), FetchTimeout)
-	defer cancel()
+// UnstageFiles removes files from the index, keeping working tree changes
+func UnstageFiles(path string, files []string) error {
+	if len(files) == 0 {
+		return nil
+	}
+	args := append([]string{"reset", "-q", "HEAD", "--"}, files...)
+	if out, err := combinedOutput(path, args...); err != nil {
+		return fmt.Errorf("git reset failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
 
-	cmd := exec.CommandContext(ctx, "git", "-C", path, "fetch", "origin")
-	cmd.Stderr = nil // Suppress stderr
-	err := cmd.Run()
+// CreateCommit creates a commit from staged changes with the given message
+// Hook output is included in the returned error
+func CreateCommit(ctx context.Context, path, message string) error {
+	var out bytes.Buffer
+	err := runOp(ctx, OpLocal, Cmd{
+		Dir:    path,
+		Args:   []string{"commit", "-q", "-F", "-"},
+		Stdin:  strings.NewReader(message),
+		Stdout: &out,
+		Stderr: &out,
+	})
+	if err != nil {
+		return opError(err, "git commit", out.String())
+	}
+	return nil
+}
 
-	if ctx.Err() == context.DeadlineExceeded {
-		return ErrFetchTimeout
+// ResetSoft moves HEAD to rev keeping index and working tree (git reset --soft)
+// If rev is empty, HEAD is removed so the repository has no commits again
+func ResetSoft(path, rev string) error {
+	args := []string{"reset", "-q", "--soft", rev}
+	if rev == "" {
+		args = []string{"update-ref", "-d", "HEAD"}
 	}
-	return err
+	if out, err := combinedOutput(path, args...); err != nil {
+		return fmt.Errorf("git reset --soft failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Fetch fetches from remote with the fetch timeout
+// A timeout is returned as *TimeoutError
+func Fetch(ctx context.Context, path string) error {
+	return runOp(ctx, OpFetch, Cmd{Dir: path, Args: []string{"fetch", "origin"}}) // Output suppressed
 }
 
 // GetBehindCount returns number of commits behind remote
 func GetBehindCount(path, branch string) (int, error) {
 	// Check if remote branch exists
-	cmd := exec.Command("git", "-C", path, "rev-parse", "--verify", "origin/"+branch)
-	if err := cmd.Run(); err != nil {
+	if err := run(path, "rev-parse", "--verify", "origin/"+branch); err != nil {
 		return 0, nil // Remote branch doesn't exist
 	}
 
-	cmd = exec.Command("git", "-C", path, "rev-list", "--count", branch+"..origin/"+branch)
-	out, err := cmd.Output()
+	out, err := output(path, "rev-list", "--count", branch+"..origin/"+branch)
 	if err != nil {
 		return 0, err
 	}
@@ -542,13 +557,11 @@ func GetBehindCount(path, branch string) (int, error) {
 // GetAheadCount returns number of commits ahead of remote
 func GetAheadCount(path, branch string) (int, error) {
 	// Check if remote branch exists
-	cmd := exec.Command("git", "-C", path, "rev-parse", "--verify", "origin/"+branch)
-	if err := cmd.Run(); err != nil {
+	if err := run(path, "rev-parse", "--verify", "origin/"+branch); err != nil {
 		return 0, nil // Remote branch doesn't exist
 	}
 
-	cmd = exec.Command("git", "-C", path, "rev-list", "--count", "origin/"+branch+".."+branch)
-	out, err := cmd.Output()
+	out, err := output(path, "rev-list", "--count", "origin/"+branch+".."+branch)
 	if err != nil {
 		return 0, err
 	}
@@ -564,8 +577,7 @@ func GetSkipFileRemoteChanges(path, file string) (string, error) {
 		return "", err
 	}
 
-	cmd := exec.Command("git", "-C", path, "diff", "HEAD", "origin/"+branch, "--", file)
-	out, err := cmd.Output()
+	out, err := output(path, "diff", "HEAD", "origin/"+branch, "--", file)
 	if err != nil {
 		return "", err
 	}
@@ -575,16 +587,14 @@ func GetSkipFileRemoteChanges(path, file string) (string, error) {
 // HasRemoteChanges checks if a file has changes between HEAD and remote
 func HasRemoteChanges(path, file, branch string) (bool, error) {
 	// Check if remote branch exists
-	cmd := exec.Command("git", "-C", path, "rev-parse", "--verify", "origin/"+branch)
-	if err := cmd.Run(); err != nil {
+	if err := run(path, "rev-parse", "--verify", "origin/"+branch); err != nil {
 		return false, nil // Remote branch doesn't exist
 	}
 
 	// Check for differences
-	cmd = exec.Command("git", "-C", path, "diff", "--quiet", "HEAD", "origin/"+branch, "--", file)
-	err := cmd.Run()
+	err := run(path, "diff", "--quiet", "HEAD", "origin/"+branch, "--", file)
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		if exitErr, ok := err.(*ExitError); ok && exitErr.ExitCode() == 1 {
 			return true, nil // Differences found
 		}
 		return false, err // Other error
@@ -594,18 +604,22 @@ func HasRemoteChanges(path, file, branch string) (bool, error) {
 
 // GetFileDiff returns the diff of a file between HEAD and remote
 func GetFileDiff(path, file, branch string) (string, error) {
-	cmd := exec.Command("git", "-C", path, "diff", "HEAD", "origin/"+branch, "--", file)
-	out, err := cmd.Output()
+	out, err := output(path, "diff", "HEAD", "origin/"+branch, "--", file)
 	if err != nil {
 		return "", err
 	}
 	return string(out), nil
 }
 
+// RestoreFileToHEAD discards working tree changes of a file
+func RestoreFileToHEAD(path, file string) error {
+	if out, err := combinedOutput(path, "checkout", "HEAD", "--", file); err != nil {
+		return fmt.Errorf("failed to restore %s: %s", file, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
 // ResetFile resets a file to match remote version
 func ResetFile(path, file, branch string) error {
-	cmd := exec.Command("git", "-C", path, "checkout", "origin/"+branch, "--", file)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return stream(path, "checkout", "origin/"+branch, "--", file)
 }
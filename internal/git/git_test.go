@@ -1,6 +1,7 @@
 package git
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -298,7 +299,7 @@ func TestClone(t *testing.T) {
 		// Clone to destination
 		dstDir := filepath.Join(t.TempDir(), "cloned")
 
-		err := Clone(srcDir, dstDir, "")
+		err := Clone(context.Background(), srcDir, dstDir, "")
 		if err != nil {
 			t.Fatalf("Clone failed: %v", err)
 		}
@@ -324,7 +325,7 @@ func TestClone(t *testing.T) {
 		// Clone to destination
 		dstDir := filepath.Join(t.TempDir(), "cloned")
 
-		err := Clone(srcDir, dstDir, "develop")
+		err := Clone(context.Background(), srcDir, dstDir, "develop")
 		if err != nil {
 			t.Fatalf("Clone failed: %v", err)
 		}
@@ -343,7 +344,7 @@ func TestPull(t *testing.T) {
 
 		// Clone to destination
 		dstDir := filepath.Join(t.TempDir(), "cloned")
-		Clone(srcDir, dstDir, "")
+		Clone(context.Background(), srcDir, dstDir, "")
 
 		// Add new commit to source
 		os.WriteFile(filepath.Join(srcDir, "new.txt"), []byte("new"), 0644)
@@ -351,7 +352,7 @@ func TestPull(t *testing.T) {
 		exec.Command("git", "-C", srcDir, "commit", "-m", "New commit").Run()
 
 		// Pull in destination
-		err := Pull(dstDir)
+		err := Pull(context.Background(), dstDir)
 		if err != nil {
 			t.Fatalf("Pull failed: %v", err)
 		}
@@ -375,7 +376,7 @@ func TestInitRepo(t *testing.T) {
 		// Create a test file in the target directory (simulating tracked files)
 		os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("# Test"), 0644)
 
-		err := InitRepo(repoDir, sourceDir, "")
+		err := InitRepo(context.Background(), repoDir, sourceDir, "")
 		if err != nil {
 			t.Fatalf("InitRepo failed: %v", err)
 		}
@@ -401,7 +402,7 @@ func TestInitRepo(t *testing.T) {
 		// Create a test file in the target directory
 		os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("# Test"), 0644)
 
-		err := InitRepo(repoDir, sourceDir, "")
+		err := InitRepo(context.Background(), repoDir, sourceDir, "")
 		if err != nil {
 			t.Fatalf("InitRepo with empty branch failed: %v", err)
 		}
@@ -413,7 +414,7 @@ func TestPush(t *testing.T) {
 		dir := setupTestRepoWithCommit(t)
 
 		// Push should fail because no remote configured
-		err := Push(dir)
+		err := Push(context.Background(), dir)
 		if err == nil {
 			t.Error("push without remote should fail")
 		}
@@ -433,7 +434,7 @@ func TestPush(t *testing.T) {
 		exec.Command("git", "-C", localDir, "push", "-u", "origin", "main").Run()
 
 		// Now Push should work
-		err := Push(localDir)
+		err := Push(context.Background(), localDir)
 		if err != nil {
 			t.Errorf("push to remote failed: %v", err)
 		}
@@ -520,7 +521,7 @@ func TestRemoveFromGitignore_ErrorCases(t *testing.T) {
 
 func TestInitRepo_ErrorCases(t *testing.T) {
 	t.Run("init fails on non-existent path", func(t *testing.T) {
-		err := InitRepo("/non/existent/path", "https://example.com/repo.git", "main")
+		err := InitRepo(context.Background(), "/non/existent/path", "https://example.com/repo.git", "main")
 		if err == nil {
 			t.Error("should error when path doesn't exist")
 		}
@@ -542,10 +543,10 @@ func TestInitRepo_ErrorCases(t *testing.T) {
 		exec.Command("git", "init", "--bare", remoteDir).Run()
 
 		// Init once
-		InitRepo(repoDir, remoteDir, "main")
+		InitRepo(context.Background(), repoDir, remoteDir, "main")
 
 		// Second init with same remote should fail on remote add
-		err := InitRepo(repoDir, "https://other.com/repo.git", "main")
+		err := InitRepo(context.Background(), repoDir, "https://other.com/repo.git", "main")
 		if err == nil {
 			t.Error("should error when remote already exists")
 		}
@@ -557,7 +558,7 @@ func TestInitRepo_ErrorCases(t *testing.T) {
 		os.MkdirAll(repoDir, 0755)
 
 		// Use a clearly invalid remote URL that git fetch will fail on
-		err := InitRepo(repoDir, "file:///non/existent/remote", "main")
+		err := InitRepo(context.Background(), repoDir, "file:///non/existent/remote", "main")
 		if err == nil {
 			t.Error("should error when fetch fails")
 		}
@@ -606,3 +607,33 @@ func TestGetCurrentBranch_ErrorCases(t *testing.T) {
 		}
 	})
 }
+
+func TestPullWithStrategy(t *testing.T) {
+	origin, clone := setupClonedRepo(t)
+
+	// Diverge: origin gets a new commit, clone gets a local one
+	other := filepath.Join(t.TempDir(), "other")
+	exec.Command("git", "clone", "-q", origin, other).Run()
+	exec.Command("git", "-C", other, "config", "user.email", "test@test.com").Run()
+	exec.Command("git", "-C", other, "config", "user.name", "Test User").Run()
+	commitTestFile(t, other, "remote.txt")
+	exec.Command("git", "-C", other, "push", "-q").Run()
+	commitTestFile(t, clone, "local.txt")
+
+	if err := PullWithStrategy(context.Background(), clone, "bogus"); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+	if err := PullWithStrategy(context.Background(), clone, "ff-only"); err == nil {
+		t.Error("ff-only should fail on diverged history")
+	}
+	if err := PullWithStrategy(context.Background(), clone, "rebase"); err != nil {
+		t.Fatalf("rebase pull failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(clone, "remote.txt")); err != nil {
+		t.Error("remote commit should be pulled")
+	}
+	out, _ := exec.Command("git", "-C", clone, "rev-list", "--merges", "--count", "HEAD").Output()
+	if strings.TrimSpace(string(out)) != "0" {
+		t.Error("rebase should not create merge commits")
+	}
+}
@@ -0,0 +1,94 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GrepOptions controls Grep
+type GrepOptions struct {
+	IgnoreCase bool     // Case-insensitive matching (-i)
+	FilesOnly  bool     // Only report matching file names (-l)
+	Ref        string   // Search the given revision instead of the working tree
+	Pathspecs  []string // Limit the search to these paths
+}
+
+// GrepMatch holds a single match returned by Grep
+// Line and Text are empty when FilesOnly is set
+type GrepMatch struct {
+	File string
+	Line int
+	Text string
+}
+
+// Grep searches tracked files for pattern using git grep
+// Returns an empty slice (not an error) when nothing matches
+func Grep(path, pattern string, opts GrepOptions) ([]GrepMatch, error) {
+	args := []string{"grep", "--null", "-I"}
+	if opts.IgnoreCase {
+		args = append(args, "-i")
+	}
+	if opts.FilesOnly {
+		args = append(args, "-l")
+	} else {
+		args = append(args, "-n")
+	}
+	args = append(args, "-e", pattern)
+	if opts.Ref != "" {
+		args = append(args, opts.Ref)
+	}
+	if len(opts.Pathspecs) > 0 {
+		args = append(args, "--")
+		args = append(args, opts.Pathspecs...)
+	}
+
+	out, err := output(path, args...)
+	if err != nil {
+		if exitErr, ok := err.(*ExitError); ok && exitErr.ExitCode() == 1 && exitErr.Stderr == "" {
+			return []GrepMatch{}, nil // No matches
+		}
+		return nil, fmt.Errorf("git grep failed: %s", stderrOf(err))
+	}
+
+	return parseGrep(string(out), opts), nil
+}
+
+// parseGrep parses git grep --null output
+// Format: "file\0line\0text\n" or "file\0" with -l
+func parseGrep(out string, opts GrepOptions) []GrepMatch {
+	// Matches in a revision are reported as "ref:file"
+	refPrefix := ""
+	if opts.Ref != "" {
+		refPrefix = opts.Ref + ":"
+	}
+
+	matches := []GrepMatch{}
+	if opts.FilesOnly {
+		for _, file := range strings.Split(out, "\x00") {
+			file = strings.TrimSpace(file)
+			if file == "" {
+				continue
+			}
+			matches = append(matches, GrepMatch{File: strings.TrimPrefix(file, refPrefix)})
+		}
+		return matches
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.SplitN(line, "\x00", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		lineNo, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		matches = append(matches, GrepMatch{
+			File: strings.TrimPrefix(parts[0], refPrefix),
+			Line: lineNo,
+			Text: parts[2],
+		})
+	}
+	return matches
+}
@@ -0,0 +1,51 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestGrep(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello\nHello world\n"), 0644)
+	exec.Command("git", "-C", dir, "add", ".").Run()
+	exec.Command("git", "-C", dir, "commit", "-m", "Add a").Run()
+
+	t.Run("line matches", func(t *testing.T) {
+		matches, err := Grep(dir, "world", GrepOptions{})
+		if err != nil {
+			t.Fatalf("Grep failed: %v", err)
+		}
+		if len(matches) != 1 || matches[0].File != "a.txt" || matches[0].Line != 2 || matches[0].Text != "Hello world" {
+			t.Errorf("unexpected matches: %+v", matches)
+		}
+	})
+
+	t.Run("files only with ref", func(t *testing.T) {
+		matches, err := Grep(dir, "HELLO", GrepOptions{IgnoreCase: true, FilesOnly: true, Ref: "HEAD"})
+		if err != nil {
+			t.Fatalf("Grep failed: %v", err)
+		}
+		if len(matches) != 1 || matches[0].File != "a.txt" {
+			t.Errorf("unexpected matches: %+v", matches)
+		}
+	})
+
+	t.Run("no matches", func(t *testing.T) {
+		matches, err := Grep(dir, "nothing-here", GrepOptions{})
+		if err != nil {
+			t.Fatalf("Grep failed: %v", err)
+		}
+		if len(matches) != 0 {
+			t.Errorf("expected no matches, got %+v", matches)
+		}
+	})
+
+	t.Run("unknown ref", func(t *testing.T) {
+		if _, err := Grep(dir, "hello", GrepOptions{Ref: "no-such-branch"}); err == nil {
+			t.Error("Grep should fail for unknown ref")
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package git
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// KeepExpansion is a keep list entry and the tracked files it stands for
+type KeepExpansion struct {
+	Entry   string
+	Files   []string
+	Invalid string // Why the entry was rejected and expanded to nothing, "" if valid
+}
+
+// IsKeepPattern checks if a keep entry is a glob pattern or a directory
+// ("config/*.local.yaml", "env/**", "env/") rather than a single file
+func IsKeepPattern(entry string) bool {
+	return strings.ContainsAny(entry, "*?[") || strings.HasSuffix(entry, "/")
+}
+
+// ExpandKeepEntries expands every keep entry against the tracked files (git ls-files)
+// Patterns match slash-separated paths; "*", "?" and "[...]" stay within one
+// directory and "**" matches any number of directories. A directory entry
+// ("env/" or "env") stands for every tracked file below it. A literal entry
+// that is not tracked is kept as-is, so git reports it when skip-worktree is applied.
+// Entries that stand for the whole repository (".", "a/..") or lie outside it
+// ("../x", "/x") are rejected (see KeepExpansion.Invalid).
+func ExpandKeepEntries(repoPath string, entries []string) ([]KeepExpansion, error) {
+	expansions := make([]KeepExpansion, 0, len(entries))
+	var tracked []string
+	loaded := false
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if reason := invalidKeepEntry(entry); reason != "" {
+			expansions = append(expansions, KeepExpansion{Entry: entry, Invalid: reason})
+			continue
+		}
+		if !loaded {
+			var err error
+			if tracked, err = ListTrackedFiles(repoPath); err != nil {
+				return nil, err
+			}
+			loaded = true
+		}
+		expansions = append(expansions, KeepExpansion{Entry: entry, Files: expandKeepEntry(entry, tracked)})
+	}
+	return expansions, nil
+}
+
+// ExpandKeep returns the files a keep list stands for, without duplicates, in list order
+func ExpandKeep(repoPath string, entries []string) ([]string, error) {
+	expansions, err := ExpandKeepEntries(repoPath, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, e := range expansions {
+		for _, f := range e.Files {
+			if !seen[f] {
+				seen[f] = true
+				files = append(files, f)
+			}
+		}
+	}
+	return files, nil
+}
+
+// ListTrackedFiles lists the files in the index, including skip-worktree files
+func ListTrackedFiles(repoPath string) ([]string, error) {
+	out, err := output(repoPath, "ls-files", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(out), "\x00") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// invalidKeepEntry returns why a keep entry is rejected, or "" if it is valid
+func invalidKeepEntry(entry string) string {
+	if path.IsAbs(entry) {
+		return "absolute path"
+	}
+	switch cleaned := path.Clean(entry); {
+	case cleaned == ".":
+		return "stands for the whole repository"
+	case cleaned == ".." || strings.HasPrefix(cleaned, "../"):
+		return "outside the repository"
+	}
+	return ""
+}
+
+// expandKeepEntry returns the tracked files matching one valid keep entry
+func expandKeepEntry(entry string, tracked []string) []string {
+	entry = path.Clean(entry)
+	if !IsKeepPattern(entry) {
+		for _, f := range tracked {
+			if f == entry {
+				return []string{entry}
+			}
+		}
+		if below := filesBelow(entry, tracked); len(below) > 0 {
+			return below
+		}
+		return []string{entry}
+	}
+
+	var files []string
+	for _, f := range tracked {
+		if MatchKeepPattern(entry, f) {
+			files = append(files, f)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// filesBelow returns the tracked files in a directory and its subdirectories
+func filesBelow(dir string, tracked []string) []string {
+	var files []string
+	for _, f := range tracked {
+		if strings.HasPrefix(f, dir+"/") {
+			files = append(files, f)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// MatchKeepPattern checks if a slash-separated file path matches a keep pattern
+func MatchKeepPattern(pattern, file string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(file, "/"))
+}
+
+func matchSegments(pattern, file []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// "**" matches zero or more directories
+			for i := 0; i <= len(file); i++ {
+				if matchSegments(pattern[1:], file[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(file) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], file[0]); err != nil || !ok {
+			return false
+		}
+		pattern, file = pattern[1:], file[1:]
+	}
+	return len(file) == 0
+}
@@ -0,0 +1,99 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatchKeepPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		file    string
+		want    bool
+	}{
+		{"config/*.local.yaml", "config/db.local.yaml", true},
+		{"config/*.local.yaml", "config/db.yaml", false},
+		{"config/*.local.yaml", "config/sub/db.local.yaml", false},
+		{"env/**", "env/a", true},
+		{"env/**", "env/a/b/c", true},
+		{"env/**", "environment/a", false},
+		{"**/.env", ".env", true},
+		{"**/.env", "apps/api/.env", true},
+		{"apps/**/config.json", "apps/config.json", true},
+		{"apps/**/config.json", "apps/api/v2/config.json", true},
+		{"?.json", "a.json", true},
+		{"[ab].json", "c.json", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchKeepPattern(tt.pattern, tt.file); got != tt.want {
+			t.Errorf("MatchKeepPattern(%q, %q) = %v, want %v", tt.pattern, tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestExpandKeep(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	for _, f := range []string{"config/db.local.yaml", "config/cache.local.yaml", "config/app.yaml", "env/dev", "env/prod/secrets", "settings.json"} {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(f)), 0755)
+		os.WriteFile(filepath.Join(dir, f), []byte(f), 0644)
+	}
+	exec.Command("git", "-C", dir, "add", ".").Run()
+	exec.Command("git", "-C", dir, "commit", "-q", "-m", "Add config").Run()
+
+	t.Run("entries", func(t *testing.T) {
+		expansions, err := ExpandKeepEntries(dir, []string{"settings.json", "config/*.local.yaml", "env/", "missing.txt", "logs/**"})
+		if err != nil {
+			t.Fatalf("ExpandKeepEntries failed: %v", err)
+		}
+		want := []KeepExpansion{
+			{Entry: "settings.json", Files: []string{"settings.json"}},
+			{Entry: "config/*.local.yaml", Files: []string{"config/cache.local.yaml", "config/db.local.yaml"}},
+			{Entry: "env/", Files: []string{"env/dev", "env/prod/secrets"}},
+			{Entry: "missing.txt", Files: []string{"missing.txt"}},
+			{Entry: "logs/**", Files: nil},
+		}
+		if !reflect.DeepEqual(expansions, want) {
+			t.Errorf("ExpandKeepEntries =\n%v\nwant\n%v", expansions, want)
+		}
+	})
+
+	t.Run("flattened without duplicates", func(t *testing.T) {
+		files, err := ExpandKeep(dir, []string{"env/**", "env/dev", "config"})
+		if err != nil {
+			t.Fatalf("ExpandKeep failed: %v", err)
+		}
+		want := []string{"env/dev", "env/prod/secrets", "config/app.yaml", "config/cache.local.yaml", "config/db.local.yaml"}
+		if !reflect.DeepEqual(files, want) {
+			t.Errorf("ExpandKeep = %v, want %v", files, want)
+		}
+	})
+
+	t.Run("entries outside the repository are rejected", func(t *testing.T) {
+		expansions, err := ExpandKeepEntries(dir, []string{".", "./", "env/..", "../settings.json", "/settings.json", "./settings.json"})
+		if err != nil {
+			t.Fatalf("ExpandKeepEntries failed: %v", err)
+		}
+		for _, e := range expansions[:5] {
+			if e.Invalid == "" || len(e.Files) != 0 {
+				t.Errorf("%q should be rejected, got %+v", e.Entry, e)
+			}
+		}
+		if last := expansions[5]; last.Invalid != "" || !reflect.DeepEqual(last.Files, []string{"settings.json"}) {
+			t.Errorf("./settings.json should expand to settings.json, got %+v", last)
+		}
+	})
+
+	t.Run("skip-worktree files are still tracked", func(t *testing.T) {
+		if err := ApplySkipWorktree(dir, []string{"config/db.local.yaml"}); err != nil {
+			t.Fatalf("ApplySkipWorktree failed: %v", err)
+		}
+		files, _ := ExpandKeep(dir, []string{"config/db.*"})
+		if !reflect.DeepEqual(files, []string{"config/db.local.yaml"}) {
+			t.Errorf("ExpandKeep = %v", files)
+		}
+	})
+}
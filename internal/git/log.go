@@ -0,0 +1,85 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LogOptions holds filters for Log
+type LogOptions struct {
+	Since    string // Passed to --since (e.g., "yesterday", "2024-01-01")
+	Until    string // Passed to --until
+	Author   string // Passed to --author
+	MaxCount int    // Maximum number of commits (0 = unlimited)
+}
+
+// Commit holds a single commit entry returned by Log
+type Commit struct {
+	Hash    string
+	Author  string
+	Email   string
+	Date    time.Time
+	Subject string
+}
+
+// Field and record separators used in the log format
+const (
+	logFieldSep  = "\x1f"
+	logRecordSep = "\x1e"
+)
+
+// Log returns commits reachable from HEAD that match the given options, newest first
+func Log(path string, opts LogOptions) ([]Commit, error) {
+	format := strings.Join([]string{"%H", "%an", "%ae", "%aI", "%s"}, logFieldSep) + logRecordSep
+	args := []string{"log", "--format=" + format}
+	if opts.Since != "" {
+		args = append(args, "--since="+opts.Since)
+	}
+	if opts.Until != "" {
+		args = append(args, "--until="+opts.Until)
+	}
+	if opts.Author != "" {
+		args = append(args, "--author="+opts.Author)
+	}
+	if opts.MaxCount > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", opts.MaxCount))
+	}
+
+	out, err := output(path, args...)
+	if err != nil {
+		return nil, fmt.Errorf("git log failed: %s", stderrOf(err))
+	}
+
+	return parseLog(string(out))
+}
+
+// parseLog parses output produced by the Log format
+func parseLog(out string) ([]Commit, error) {
+	var commits []Commit
+	for _, record := range strings.Split(out, logRecordSep) {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+
+		fields := strings.Split(record, logFieldSep)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected git log record: %q", record)
+		}
+
+		date, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid commit date %q: %w", fields[3], err)
+		}
+
+		commits = append(commits, Commit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Email:   fields[2],
+			Date:    date,
+			Subject: fields[4],
+		})
+	}
+	return commits, nil
+}
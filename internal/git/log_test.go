@@ -0,0 +1,67 @@
+package git
+
+import (
+	"testing"
+)
+
+func TestLog(t *testing.T) {
+	t.Run("returns commits", func(t *testing.T) {
+		dir := setupTestRepoWithCommit(t)
+
+		commits, err := Log(dir, LogOptions{})
+		if err != nil {
+			t.Fatalf("Log failed: %v", err)
+		}
+		if len(commits) != 1 {
+			t.Fatalf("expected 1 commit, got %d", len(commits))
+		}
+		if commits[0].Subject != "Initial commit" {
+			t.Errorf("Subject = %q, want %q", commits[0].Subject, "Initial commit")
+		}
+		if commits[0].Author != "Test User" || commits[0].Email != "test@test.com" {
+			t.Errorf("unexpected author: %s <%s>", commits[0].Author, commits[0].Email)
+		}
+		if len(commits[0].Hash) != 40 {
+			t.Errorf("Hash should be full SHA, got %q", commits[0].Hash)
+		}
+	})
+
+	t.Run("author filter", func(t *testing.T) {
+		dir := setupTestRepoWithCommit(t)
+
+		commits, err := Log(dir, LogOptions{Author: "nobody"})
+		if err != nil {
+			t.Fatalf("Log failed: %v", err)
+		}
+		if len(commits) != 0 {
+			t.Errorf("expected no commits, got %d", len(commits))
+		}
+	})
+
+	t.Run("repository without commits", func(t *testing.T) {
+		dir := setupTestRepo(t)
+
+		if _, err := Log(dir, LogOptions{}); err == nil {
+			t.Error("Log should fail for repository without commits")
+		}
+	})
+}
+
+func TestParseLog(t *testing.T) {
+	t.Run("subject containing separators", func(t *testing.T) {
+		out := "abc\x1fA\x1fa@x\x1f2024-01-02T03:04:05+09:00\x1ffix: a | b\x1e\n"
+		commits, err := parseLog(out)
+		if err != nil {
+			t.Fatalf("parseLog failed: %v", err)
+		}
+		if len(commits) != 1 || commits[0].Subject != "fix: a | b" {
+			t.Errorf("unexpected commits: %+v", commits)
+		}
+	})
+
+	t.Run("malformed record", func(t *testing.T) {
+		if _, err := parseLog("abc\x1fA\x1e"); err == nil {
+			t.Error("parseLog should fail on malformed record")
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MergeResult is the outcome of MergeFile
+type MergeResult struct {
+	Content   []byte // Merged content, with conflict markers if Conflicts > 0
+	Conflicts int    // Number of conflicting hunks (0 for a clean merge)
+}
+
+// GetFileAtRev returns the content of file at rev
+// Returns nil without error if the file does not exist at rev
+func GetFileAtRev(path, rev, file string) ([]byte, error) {
+	if run(path, "cat-file", "-e", rev+":"+filepath.ToSlash(file)) != nil {
+		return nil, nil
+	}
+	out, err := output(path, "cat-file", "blob", rev+":"+filepath.ToSlash(file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s at %s: %s", file, rev, stderrOf(err))
+	}
+	return out, nil
+}
+
+// MergeFile three-way merges the working tree version of file with its version at theirs
+// base is the common ancestor (usually HEAD, before a pull) and theirs the
+// incoming revision (e.g., origin/main). Non-overlapping changes merge
+// cleanly; overlapping ones are written as conflict markers labelled
+// "local", base and theirs. The working tree file is not modified.
+func MergeFile(path, file, base, theirs string) (*MergeResult, error) {
+	return MergeRenamedFile(path, file, file, base, theirs)
+}
+
+// MergeRenamedFile three-way merges the working tree version of file with
+// its version at theirs, where it was renamed to renamed
+// The merge base is file at base, as for MergeFile.
+func MergeRenamedFile(path, file, renamed, base, theirs string) (*MergeResult, error) {
+	baseContent, err := GetFileAtRev(path, base, file)
+	if err != nil {
+		return nil, err
+	}
+	theirsContent, err := GetFileAtRev(path, theirs, renamed)
+	if err != nil {
+		return nil, err
+	}
+
+	tempDir, err := os.MkdirTemp("", "git-multirepo-merge-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	basePath := filepath.Join(tempDir, "base")
+	theirsPath := filepath.Join(tempDir, "theirs")
+	if err := os.WriteFile(basePath, baseContent, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write merge base: %w", err)
+	}
+	if err := os.WriteFile(theirsPath, theirsContent, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write merge input: %w", err)
+	}
+
+	ours, err := filepath.Abs(filepath.Join(path, file))
+	if err != nil {
+		return nil, err
+	}
+
+	// git merge-file exits with the number of conflicts (capped at 127), or >127 on errors
+	out, err := output(path, "merge-file", "-p", "-L", "local", "-L", base, "-L", theirs, ours, basePath, theirsPath)
+	var exitErr *ExitError
+	switch {
+	case err == nil:
+		return &MergeResult{Content: out}, nil
+	case errors.As(err, &exitErr) && exitErr.Code > 0 && exitErr.Code < 128:
+		return &MergeResult{Content: out, Conflicts: exitErr.Code}, nil
+	default:
+		return nil, fmt.Errorf("git merge-file failed: %s", stderrOf(err))
+	}
+}
+
+// HasConflictMarkers checks if a working tree file still contains conflict markers
+// Keep files merged with conflicts need resolution until the markers are removed
+func HasConflictMarkers(path, file string) bool {
+	content, err := os.ReadFile(filepath.Join(path, file))
+	if err != nil {
+		return false
+	}
+
+	var start, middle bool
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		switch {
+		case bytes.HasPrefix(line, []byte("<<<<<<< ")):
+			start = true
+		case start && bytes.Equal(line, []byte("=======")):
+			middle = true
+		case middle && bytes.HasPrefix(line, []byte(">>>>>>> ")):
+			return true
+		}
+	}
+	return false
+}
+
+// FilesWithConflictMarkers returns the files that still contain conflict markers
+func FilesWithConflictMarkers(path string, files []string) []string {
+	var conflicted []string
+	for _, file := range files {
+		if HasConflictMarkers(path, file) {
+			conflicted = append(conflicted, file)
+		}
+	}
+	return conflicted
+}
+
+// IsBinary checks if content looks binary (a NUL byte in the first 8000 bytes, as git decides)
+func IsBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0
+}
+
+// RenamedFiles returns the files renamed between two revisions, old path to new path
+// Renames are detected like git diff -M does (at least 50% similar content).
+func RenamedFiles(path, from, to string) (map[string]string, error) {
+	out, err := output(path, "diff", "--name-status", "-M", "-z", from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect renames: %s", stderrOf(err))
+	}
+
+	renames := make(map[string]string)
+	fields := strings.Split(string(out), "\x00")
+	for i := 0; i < len(fields); i++ {
+		status := fields[i]
+		switch {
+		case strings.HasPrefix(status, "R") && i+2 < len(fields):
+			renames[fields[i+1]] = fields[i+2]
+			i += 2
+		case strings.HasPrefix(status, "C") && i+2 < len(fields):
+			i += 2
+		case status != "":
+			i++
+		}
+	}
+	return renames, nil
+}
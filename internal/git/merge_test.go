@@ -0,0 +1,150 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupMergeRepo commits base to config.txt and theirs on branch "upstream",
+// then leaves ours in the working tree on the original branch
+func setupMergeRepo(t *testing.T, base, theirs, ours string) string {
+	t.Helper()
+	dir := setupTestRepoWithCommit(t)
+	file := filepath.Join(dir, "config.txt")
+
+	os.WriteFile(file, []byte(base), 0644)
+	exec.Command("git", "-C", dir, "add", "config.txt").Run()
+	exec.Command("git", "-C", dir, "commit", "-q", "-m", "base").Run()
+
+	exec.Command("git", "-C", dir, "checkout", "-q", "-b", "upstream").Run()
+	os.WriteFile(file, []byte(theirs), 0644)
+	exec.Command("git", "-C", dir, "commit", "-q", "-am", "theirs").Run()
+	exec.Command("git", "-C", dir, "checkout", "-q", "-").Run()
+
+	os.WriteFile(file, []byte(ours), 0644)
+	return dir
+}
+
+func TestMergeFile(t *testing.T) {
+	base := "version: 1\na: 1\nb: 2\nc: 3\nlocal: false\n"
+
+	t.Run("non-overlapping changes merge cleanly", func(t *testing.T) {
+		dir := setupMergeRepo(t, base,
+			"version: 2\na: 1\nb: 2\nc: 3\nlocal: false\n",
+			"version: 1\na: 1\nb: 2\nc: 3\nlocal: true\n")
+
+		result, err := MergeFile(dir, "config.txt", "HEAD", "upstream")
+		if err != nil {
+			t.Fatalf("MergeFile failed: %v", err)
+		}
+		if result.Conflicts != 0 {
+			t.Errorf("expected clean merge, got %d conflict(s)", result.Conflicts)
+		}
+		if string(result.Content) != "version: 2\na: 1\nb: 2\nc: 3\nlocal: true\n" {
+			t.Errorf("unexpected merge result: %q", result.Content)
+		}
+
+		// The working tree is left alone
+		if content, _ := os.ReadFile(filepath.Join(dir, "config.txt")); strings.HasPrefix(string(content), "version: 2") {
+			t.Error("MergeFile should not write the file")
+		}
+	})
+
+	t.Run("overlapping changes write conflict markers", func(t *testing.T) {
+		dir := setupMergeRepo(t, base,
+			"version: 3\na: 1\nb: 2\nc: 3\nlocal: false\n",
+			"version: 2.5\na: 1\nb: 2\nc: 3\nlocal: true\n")
+
+		result, err := MergeFile(dir, "config.txt", "HEAD", "upstream")
+		if err != nil {
+			t.Fatalf("MergeFile failed: %v", err)
+		}
+		if result.Conflicts != 1 {
+			t.Errorf("expected 1 conflict, got %d", result.Conflicts)
+		}
+		content := string(result.Content)
+		for _, want := range []string{"<<<<<<< local", "version: 2.5", "=======", "version: 3", ">>>>>>> upstream", "local: true"} {
+			if !strings.Contains(content, want) {
+				t.Errorf("merge result should contain %q, got:\n%s", want, content)
+			}
+		}
+
+		os.WriteFile(filepath.Join(dir, "config.txt"), result.Content, 0644)
+		if !HasConflictMarkers(dir, "config.txt") {
+			t.Error("HasConflictMarkers should detect the markers")
+		}
+		if got := FilesWithConflictMarkers(dir, []string{"README.md", "config.txt"}); len(got) != 1 || got[0] != "config.txt" {
+			t.Errorf("FilesWithConflictMarkers = %v", got)
+		}
+	})
+
+	t.Run("file new upstream", func(t *testing.T) {
+		dir := setupTestRepoWithCommit(t)
+		exec.Command("git", "-C", dir, "checkout", "-q", "-b", "upstream").Run()
+		commitTestFile(t, dir, "new.txt")
+		exec.Command("git", "-C", dir, "checkout", "-q", "-").Run()
+		os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new.txt"), 0644)
+
+		result, err := MergeFile(dir, "new.txt", "HEAD", "upstream")
+		if err != nil {
+			t.Fatalf("MergeFile failed: %v", err)
+		}
+		if result.Conflicts != 0 || string(result.Content) != "new.txt" {
+			t.Errorf("identical additions should merge cleanly, got %d conflict(s): %q", result.Conflicts, result.Content)
+		}
+	})
+}
+
+func TestHasConflictMarkers(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "partial.txt"), []byte("<<<<<<< local\nonly a start\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "clean.txt"), []byte("a: 1\n"), 0644)
+
+	if HasConflictMarkers(dir, "partial.txt") || HasConflictMarkers(dir, "clean.txt") || HasConflictMarkers(dir, "missing.txt") {
+		t.Error("only complete conflict markers should be detected")
+	}
+}
+
+func TestMergeRenamedFile(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	base := "version: 1\na: 1\nb: 2\nc: 3\nlocal: false\n"
+	os.WriteFile(filepath.Join(dir, "config.txt"), []byte(base), 0644)
+	exec.Command("git", "-C", dir, "add", "config.txt").Run()
+	exec.Command("git", "-C", dir, "commit", "-q", "-m", "base").Run()
+
+	// Rename config.txt upstream and change it
+	exec.Command("git", "-C", dir, "checkout", "-q", "-b", "upstream").Run()
+	exec.Command("git", "-C", dir, "mv", "config.txt", "settings.txt").Run()
+	os.WriteFile(filepath.Join(dir, "settings.txt"), []byte(strings.Replace(base, "version: 1", "version: 2", 1)), 0644)
+	exec.Command("git", "-C", dir, "commit", "-q", "-am", "rename").Run()
+	exec.Command("git", "-C", dir, "checkout", "-q", "-").Run()
+	os.WriteFile(filepath.Join(dir, "config.txt"), []byte(strings.Replace(base, "local: false", "local: true", 1)), 0644)
+
+	renames, err := RenamedFiles(dir, "HEAD", "upstream")
+	if err != nil {
+		t.Fatalf("RenamedFiles failed: %v", err)
+	}
+	if renames["config.txt"] != "settings.txt" || len(renames) != 1 {
+		t.Fatalf("expected config.txt → settings.txt, got %v", renames)
+	}
+
+	result, err := MergeRenamedFile(dir, "config.txt", "settings.txt", "HEAD", "upstream")
+	if err != nil {
+		t.Fatalf("MergeRenamedFile failed: %v", err)
+	}
+	if result.Conflicts != 0 || string(result.Content) != "version: 2\na: 1\nb: 2\nc: 3\nlocal: true\n" {
+		t.Errorf("unexpected merge result (%d conflicts): %q", result.Conflicts, result.Content)
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	if IsBinary([]byte("plain text\n")) || IsBinary(nil) {
+		t.Error("text should not be binary")
+	}
+	if !IsBinary([]byte{0x89, 'P', 'N', 'G', 0, 1, 2}) {
+		t.Error("content with a NUL byte should be binary")
+	}
+}
@@ -0,0 +1,98 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PushResult holds the outcome of PushBranch
+type PushResult struct {
+	NewBranch bool   // The branch did not exist on the remote
+	Rejected  bool   // The remote refused the update
+	Status    string // "rejected" or "remote rejected" when Rejected
+	Reason    string // e.g. "non-fast-forward", "protected branch hook declined"
+	Output    string // Remote messages (stderr) for diagnostics
+}
+
+// HasUpstream checks if branch has an upstream configured
+func HasUpstream(path, branch string) bool {
+	return run(path, "rev-parse", "--abbrev-ref", branch+"@{upstream}") == nil
+}
+
+// GetUpstream returns the upstream of the current branch (e.g., "origin/main")
+func GetUpstream(path string) (string, error) {
+	out, err := output(path, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// RemoteBranchExists checks if origin/<branch> is known locally (as of the last fetch)
+func RemoteBranchExists(path, branch string) bool {
+	return run(path, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branch) == nil
+}
+
+// PushBranch pushes branch to origin, setting the upstream if requested
+// A rejected ref is reported in PushResult, not as an error; errors are
+// returned only when git could not push at all (e.g., network or auth failures)
+func PushBranch(ctx context.Context, path, branch string, setUpstream bool) (PushResult, error) {
+	args := []string{"push", "--porcelain"}
+	if setUpstream {
+		args = append(args, "-u")
+	}
+	args = append(args, "origin", branch)
+
+	var stdout, stderr bytes.Buffer
+	runErr := runOp(ctx, OpPush, Cmd{Dir: path, Args: args, Stdout: &stdout, Stderr: &stderr})
+
+	result, found := parsePushPorcelain(stdout.String())
+	result.Output = strings.TrimSpace(stderr.String())
+
+	if runErr != nil && !result.Rejected {
+		if IsInterrupted(runErr) {
+			return result, runErr
+		}
+		msg := result.Output
+		if msg == "" {
+			msg = runErr.Error()
+		}
+		return result, fmt.Errorf("git push failed: %s", msg)
+	}
+	if !found && runErr == nil {
+		return result, fmt.Errorf("git push reported no ref update")
+	}
+	return result, nil
+}
+
+// parsePushPorcelain parses the ref line of git push --porcelain output
+// Format: "<flag>\t<from>:<to>\t<summary> (<reason>)"
+func parsePushPorcelain(out string) (PushResult, bool) {
+	var result PushResult
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) != 3 || len(parts[0]) != 1 {
+			continue
+		}
+
+		summary := parts[2]
+		reason := ""
+		if open := strings.Index(summary, " ("); open >= 0 && strings.HasSuffix(summary, ")") {
+			reason = summary[open+2 : len(summary)-1]
+			summary = summary[:open]
+		}
+
+		switch parts[0] {
+		case "!":
+			result.Rejected = true
+			result.Status = strings.Trim(summary, "[]")
+			result.Reason = reason
+		case "*":
+			result.NewBranch = true
+		}
+		return result, true
+	}
+	return result, false
+}
@@ -0,0 +1,138 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// setupClonedRepo creates a bare origin and a clone of it with user config
+func setupClonedRepo(t *testing.T) (origin, clone string) {
+	t.Helper()
+	src := setupTestRepoWithCommit(t)
+	origin = filepath.Join(t.TempDir(), "origin.git")
+	if err := exec.Command("git", "clone", "-q", "--bare", src, origin).Run(); err != nil {
+		t.Fatalf("failed to create bare origin: %v", err)
+	}
+	clone = filepath.Join(t.TempDir(), "clone")
+	if err := exec.Command("git", "clone", "-q", origin, clone).Run(); err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+	exec.Command("git", "-C", clone, "config", "user.email", "test@test.com").Run()
+	exec.Command("git", "-C", clone, "config", "user.name", "Test User").Run()
+	return origin, clone
+}
+
+func commitTestFile(t *testing.T, repo, name string) {
+	t.Helper()
+	os.WriteFile(filepath.Join(repo, name), []byte(name), 0644)
+	exec.Command("git", "-C", repo, "add", name).Run()
+	if err := exec.Command("git", "-C", repo, "commit", "-q", "-m", "Add "+name).Run(); err != nil {
+		t.Fatalf("failed to commit %s: %v", name, err)
+	}
+}
+
+func TestPushBranch(t *testing.T) {
+	t.Run("new branch sets upstream", func(t *testing.T) {
+		_, clone := setupClonedRepo(t)
+		exec.Command("git", "-C", clone, "checkout", "-q", "-b", "feature").Run()
+		commitTestFile(t, clone, "feature.txt")
+
+		if HasUpstream(clone, "feature") || RemoteBranchExists(clone, "feature") {
+			t.Fatal("feature should not exist on the remote yet")
+		}
+
+		result, err := PushBranch(context.Background(), clone, "feature", true)
+		if err != nil {
+			t.Fatalf("PushBranch failed: %v", err)
+		}
+		if !result.NewBranch || result.Rejected {
+			t.Errorf("unexpected result: %+v", result)
+		}
+		if !HasUpstream(clone, "feature") {
+			t.Error("upstream should be set")
+		}
+	})
+
+	t.Run("diverged branch is rejected", func(t *testing.T) {
+		origin, clone := setupClonedRepo(t)
+		branch, _ := GetCurrentBranch(clone)
+
+		// Another clone pushes first
+		other := filepath.Join(t.TempDir(), "other")
+		exec.Command("git", "clone", "-q", origin, other).Run()
+		exec.Command("git", "-C", other, "config", "user.email", "test@test.com").Run()
+		exec.Command("git", "-C", other, "config", "user.name", "Test User").Run()
+		commitTestFile(t, other, "other.txt")
+		exec.Command("git", "-C", other, "push", "-q").Run()
+
+		commitTestFile(t, clone, "mine.txt")
+		result, err := PushBranch(context.Background(), clone, branch, false)
+		if err != nil {
+			t.Fatalf("rejection should not be an error: %v", err)
+		}
+		if !result.Rejected || result.Status != "rejected" {
+			t.Errorf("expected rejection, got %+v", result)
+		}
+		if result.Reason != "fetch first" && result.Reason != "non-fast-forward" {
+			t.Errorf("unexpected reason %q", result.Reason)
+		}
+	})
+
+	t.Run("missing remote is an error", func(t *testing.T) {
+		dir := setupTestRepoWithCommit(t)
+		branch, _ := GetCurrentBranch(dir)
+		if _, err := PushBranch(context.Background(), dir, branch, false); err == nil {
+			t.Error("expected error without origin")
+		}
+	})
+}
+
+func TestParsePushPorcelain(t *testing.T) {
+	tests := []struct {
+		name  string
+		out   string
+		want  PushResult
+		found bool
+	}{
+		{
+			name:  "fast-forward",
+			out:   "To /tmp/origin\n \trefs/heads/main:refs/heads/main\tabc123..def456\nDone\n",
+			want:  PushResult{},
+			found: true,
+		},
+		{
+			name:  "new branch",
+			out:   "To /tmp/origin\n*\trefs/heads/feat:refs/heads/feat\t[new branch]\nDone\n",
+			want:  PushResult{NewBranch: true},
+			found: true,
+		},
+		{
+			name:  "non-fast-forward",
+			out:   "To /tmp/origin\n!\trefs/heads/main:refs/heads/main\t[rejected] (non-fast-forward)\nDone\n",
+			want:  PushResult{Rejected: true, Status: "rejected", Reason: "non-fast-forward"},
+			found: true,
+		},
+		{
+			name:  "protected branch",
+			out:   "To github.com:org/repo.git\n!\trefs/heads/main:refs/heads/main\t[remote rejected] (protected branch hook declined)\nDone\n",
+			want:  PushResult{Rejected: true, Status: "remote rejected", Reason: "protected branch hook declined"},
+			found: true,
+		},
+		{
+			name: "no ref line",
+			out:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := parsePushPorcelain(tt.out)
+			if found != tt.found || got != tt.want {
+				t.Errorf("parsePushPorcelain() = %+v, %v; want %+v, %v", got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Cmd is a git invocation
+type Cmd struct {
+	Dir    string    // Repository directory (git -C); empty for the current directory
+	Args   []string  // Arguments after "git"
+	Stdin  io.Reader // nil for no input
+	Stdout io.Writer // nil discards output
+	Stderr io.Writer // nil discards output
+	Env    []string  // Extra environment variables ("KEY=value"), e.g. GIT_INDEX_FILE
+}
+
+// Runner executes git commands for this package
+// The default runs the git binary; SetRunner swaps in another backend,
+// e.g. FakeRunner in tests or a runner that caches read-only commands.
+type Runner interface {
+	// Run runs the command and waits for it to finish
+	// A command that ran but exited non-zero returns an *ExitError
+	Run(ctx context.Context, cmd Cmd) error
+}
+
+// ExitError reports a git command that exited with a non-zero status
+type ExitError struct {
+	Code   int
+	Stderr string // Captured stderr, when the caller did not stream it
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the exit status, like exec.ExitError
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// ExecRunner runs the git binary found in PATH
+type ExecRunner struct{}
+
+// Run implements Runner
+func (ExecRunner) Run(ctx context.Context, c Cmd) error {
+	args := c.Args
+	if c.Dir != "" {
+		args = append([]string{"-C", c.Dir}, c.Args...)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stdin = c.Stdin
+	cmd.Stdout = c.Stdout
+	cmd.Stderr = c.Stderr
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	// On cancellation, let git clean up (e.g., remove lock files) before killing it
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = cancelWaitDelay
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		return &ExitError{Code: exitErr.ExitCode()}
+	}
+	return err
+}
+
+// cancelWaitDelay is how long a cancelled git process may take to exit before it is killed
+const cancelWaitDelay = 5 * time.Second
+
+// runner is the backend used by every function in this package
+var runner Runner = ExecRunner{}
+
+// SetRunner replaces the git backend and returns a function restoring the previous one
+func SetRunner(r Runner) (restore func()) {
+	prev := runner
+	runner = r
+	return func() { runner = prev }
+}
+
+// Exec runs a local command through the current backend (for packages outside git)
+// It runs under ctx and the OpLocal timeout, like the commands of this package.
+func Exec(ctx context.Context, c Cmd) error {
+	return runOp(ctx, OpLocal, c)
+}
+
+// The helpers below run local commands under the OpLocal timeout only. They
+// are not cancelled by Ctrl-C, so cleanup (e.g., restoring skip-worktree
+// flags) still completes after an interrupt.
+
+// output runs git in dir and returns stdout
+// On failure the returned *ExitError carries stderr
+func output(dir string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	err := runOp(context.Background(), OpLocal, Cmd{Dir: dir, Args: args, Stdout: &stdout, Stderr: &stderr})
+	return stdout.Bytes(), withStderr(err, &stderr)
+}
+
+// combinedOutput runs git in dir and returns stdout and stderr interleaved
+func combinedOutput(dir string, args ...string) ([]byte, error) {
+	var out bytes.Buffer
+	err := runOp(context.Background(), OpLocal, Cmd{Dir: dir, Args: args, Stdout: &out, Stderr: &out})
+	return out.Bytes(), err
+}
+
+// combinedOutputOp is combinedOutput for remote operations
+func combinedOutputOp(ctx context.Context, op Operation, dir string, args ...string) ([]byte, error) {
+	var out bytes.Buffer
+	err := runOp(ctx, op, Cmd{Dir: dir, Args: args, Stdout: &out, Stderr: &out})
+	return out.Bytes(), err
+}
+
+// run runs git in dir, discarding output
+func run(dir string, args ...string) error {
+	return runOp(context.Background(), OpLocal, Cmd{Dir: dir, Args: args})
+}
+
+// stream runs git in dir with output shown to the user
+func stream(dir string, args ...string) error {
+	return streamOp(context.Background(), OpLocal, dir, args...)
+}
+
+// streamOp runs a remote operation with progress shown to the user (clone, pull, push)
+func streamOp(ctx context.Context, op Operation, dir string, args ...string) error {
+	return runOp(ctx, op, Cmd{Dir: dir, Args: args, Stdout: os.Stdout, Stderr: os.Stderr})
+}
+
+// withStderr attaches captured stderr to an *ExitError
+func withStderr(err error, stderr *bytes.Buffer) error {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) && exitErr.Stderr == "" {
+		exitErr.Stderr = strings.TrimSpace(stderr.String())
+	}
+	return err
+}
+
+// stderrOf returns the stderr carried by an *ExitError, or the error text
+func stderrOf(err error) string {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) && exitErr.Stderr != "" {
+		return exitErr.Stderr
+	}
+	return err.Error()
+}
@@ -0,0 +1,144 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExecRunner(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+
+	t.Run("output and exit error", func(t *testing.T) {
+		out, err := output(dir, "rev-parse", "--is-inside-work-tree")
+		if err != nil || strings.TrimSpace(string(out)) != "true" {
+			t.Errorf("output = %q, %v", out, err)
+		}
+
+		_, err = output(dir, "rev-parse", "--verify", "missing-ref")
+		var exitErr *ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("expected *ExitError, got %T: %v", err, err)
+		}
+		if exitErr.ExitCode() == 0 || !strings.HasPrefix(exitErr.Stderr, "fatal:") {
+			t.Errorf("exit error should carry code and stderr: %+v", exitErr)
+		}
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		var stdout bytes.Buffer
+		err := Exec(context.Background(), Cmd{
+			Dir:    dir,
+			Args:   []string{"hash-object", "--stdin"},
+			Stdin:  strings.NewReader("hello\n"),
+			Stdout: &stdout,
+		})
+		if err != nil || strings.TrimSpace(stdout.String()) != "ce013625030ba8dba906f756967f9e9ca394464a" {
+			t.Errorf("hash-object = %q, %v", stdout.String(), err)
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := Exec(ctx, Cmd{Dir: dir, Args: []string{"status"}}); err == nil {
+			t.Error("expected error for cancelled context")
+		}
+	})
+}
+
+func TestFakeRunner(t *testing.T) {
+	fake := NewFakeRunner()
+	defer SetRunner(fake)()
+
+	fake.On("", "rev-parse", "--abbrev-ref").Return("main\n")
+	fake.On("/repo", "rev-parse", "--abbrev-ref").Return("feature\n")
+	fake.On("/repo", "rev-parse", "--verify").Fail(1, "fatal: bad ref")
+
+	if branch, _ := GetCurrentBranch("/repo"); branch != "feature" {
+		t.Errorf("directory-specific response should win, got %q", branch)
+	}
+	if branch, _ := GetCurrentBranch("/other"); branch != "main" {
+		t.Errorf("any-directory response should match, got %q", branch)
+	}
+
+	_, err := output("/repo", "rev-parse", "--verify", "x")
+	if exitErr, ok := err.(*ExitError); !ok || exitErr.Code != 1 || exitErr.Stderr != "fatal: bad ref" {
+		t.Errorf("expected exit error with stderr, got %#v", err)
+	}
+
+	if _, err := GetCurrentCommit("/repo"); err == nil {
+		t.Error("unregistered command should fail")
+	}
+
+	if !fake.Called("/repo", "rev-parse", "HEAD") || fake.Called("/repo", "status") {
+		t.Error("Called reports wrong result")
+	}
+	want := []string{"rev-parse", "--abbrev-ref", "HEAD"}
+	if calls := fake.Calls(); len(calls) != 4 || !reflect.DeepEqual(calls[0].Args, want) {
+		t.Errorf("unexpected calls: %+v", calls)
+	}
+}
+
+func TestGetWorkspaceStatus_Fake(t *testing.T) {
+	fake := NewFakeRunner()
+	defer SetRunner(fake)()
+
+	// Keep files are unskipped while modified files are listed, then skipped again
+	fake.On("/ws", "update-index").Return("")
+	fake.On("/ws", "ls-files", "-v").Return("H config.json\n")
+	fake.On("/ws", "diff", "--name-only", "HEAD").Return("config.json\nmain.go\n")
+	fake.On("/ws", "ls-files", "--others").Return("new.txt\n")
+	fake.On("/ws", "diff", "--name-only", "--cached").Return("")
+
+	status, err := GetWorkspaceStatus("/ws", []string{"config.json"})
+	if err != nil {
+		t.Fatalf("GetWorkspaceStatus failed: %v", err)
+	}
+	if !reflect.DeepEqual(status.ModifiedFiles, []string{"config.json", "main.go"}) ||
+		!reflect.DeepEqual(status.UntrackedFiles, []string{"new.txt"}) ||
+		len(status.StagedFiles) != 0 || status.TotalUncommitted != 3 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if !fake.Called("/ws", "update-index", "--no-skip-worktree", "config.json") ||
+		!fake.Called("/ws", "update-index", "--skip-worktree", "config.json") {
+		t.Error("keep file should be unskipped and skipped again")
+	}
+}
+
+func TestPushBranch_FakeRejected(t *testing.T) {
+	fake := NewFakeRunner()
+	defer SetRunner(fake)()
+
+	fake.On("/ws", "push", "--porcelain").
+		Return("To origin\n!\trefs/heads/main:refs/heads/main\t[rejected] (non-fast-forward)\nDone\n").
+		Fail(1, "error: failed to push some refs")
+
+	result, err := PushBranch(context.Background(), "/ws", "main", false)
+	if err != nil {
+		t.Fatalf("a rejection should not be an error: %v", err)
+	}
+	if !result.Rejected || result.Reason != "non-fast-forward" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestHasRemoteChanges_Fake(t *testing.T) {
+	fake := NewFakeRunner()
+	defer SetRunner(fake)()
+
+	fake.On("/ws", "rev-parse", "--verify", "origin/main").Return("abc\n")
+	fake.On("/ws", "diff", "--quiet").Fail(1, "")
+
+	if changed, err := HasRemoteChanges("/ws", "config.json", "main"); err != nil || !changed {
+		t.Errorf("HasRemoteChanges = %v, %v; want true", changed, err)
+	}
+
+	fake.On("/ws", "diff", "--quiet").Return("")
+	if changed, err := HasRemoteChanges("/ws", "config.json", "main"); err != nil || changed {
+		t.Errorf("HasRemoteChanges = %v, %v; want false", changed, err)
+	}
+}
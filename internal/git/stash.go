@@ -0,0 +1,99 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StashMarker prefixes messages of stash entries created by git-multirepo
+// so they can be told apart from the user's own stashes. Entries of older
+// releases ("git-multirepo auto-stash") start with it too.
+const StashMarker = "git-multirepo"
+
+// StashEntry holds a single entry of git stash list
+type StashEntry struct {
+	Ref     string // e.g. "stash@{0}"
+	Commit  string
+	Date    time.Time
+	Subject string // e.g. "On main: git-multirepo: pull"
+}
+
+// IsMarked checks if the entry was created by git-multirepo
+func (e StashEntry) IsMarked() bool {
+	return strings.Contains(e.Subject, StashMarker)
+}
+
+// StashSave stashes tracked changes with a marked message ("git-multirepo: <reason>")
+// Skip-worktree files are not stashed. Returns the stash commit, or "" if
+// there was nothing to stash.
+func StashSave(path, reason string) (string, error) {
+	before := stashHead(path)
+
+	if out, err := combinedOutput(path, "stash", "push", "-m", StashMarker+": "+reason); err != nil {
+		return "", fmt.Errorf("git stash failed: %s", strings.TrimSpace(string(out)))
+	}
+
+	after := stashHead(path)
+	if after == before {
+		return "", nil // Nothing was stashed
+	}
+	return after, nil
+}
+
+// StashPopCommit applies and removes the stash entry with the given commit
+// If applying conflicts, git keeps the entry and an error naming it is returned
+func StashPopCommit(path, commit string) error {
+	entries, err := ListStashes(path)
+	if err != nil {
+		return err
+	}
+
+	ref := ""
+	for _, e := range entries {
+		if e.Commit == commit {
+			ref = e.Ref
+			break
+		}
+	}
+	if ref == "" {
+		return fmt.Errorf("stash entry %s not found", commit)
+	}
+
+	if out, err := combinedOutput(path, "stash", "pop", "-q", ref); err != nil {
+		return fmt.Errorf("git stash pop %s failed (entry kept): %s", ref, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ListStashes returns all stash entries, newest first
+func ListStashes(path string) ([]StashEntry, error) {
+	out, err := output(path, "stash", "list", "--format=%gd%x1f%H%x1f%ct%x1f%gs")
+	if err != nil {
+		return nil, fmt.Errorf("git stash list failed: %w", err)
+	}
+
+	entries := []StashEntry{}
+	for _, line := range strings.Split(string(out), "\n") {
+		parts := strings.SplitN(line, "\x1f", 4)
+		if len(parts) != 4 {
+			continue
+		}
+		entry := StashEntry{Ref: parts[0], Commit: parts[1], Subject: parts[3]}
+		if ts, err := strconv.ParseInt(parts[2], 10, 64); err == nil {
+			entry.Date = time.Unix(ts, 0)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// stashHead returns the commit of the latest stash entry, or "" if there is none
+func stashHead(path string) string {
+	out, err := output(path, "rev-parse", "--verify", "--quiet", "refs/stash")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,108 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStashSaveAndPop(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+
+	t.Run("nothing to stash", func(t *testing.T) {
+		stash, err := StashSave(dir, "test")
+		if err != nil {
+			t.Fatalf("StashSave failed: %v", err)
+		}
+		if stash != "" {
+			t.Errorf("expected no stash, got %s", stash)
+		}
+	})
+
+	t.Run("stash and pop", func(t *testing.T) {
+		// An unrelated user stash must not be popped
+		os.WriteFile(filepath.Join(dir, "README.md"), []byte("user"), 0644)
+		exec.Command("git", "-C", dir, "stash", "push", "-q", "-m", "user stash").Run()
+
+		os.WriteFile(filepath.Join(dir, "README.md"), []byte("changed"), 0644)
+		stash, err := StashSave(dir, "before pull")
+		if err != nil || stash == "" {
+			t.Fatalf("StashSave failed: %q, %v", stash, err)
+		}
+
+		entries, _ := ListStashes(dir)
+		if len(entries) != 2 {
+			t.Fatalf("expected 2 entries, got %+v", entries)
+		}
+		if !entries[0].IsMarked() || !strings.Contains(entries[0].Subject, "git-multirepo: before pull") {
+			t.Errorf("newest entry should be marked, got %+v", entries[0])
+		}
+		if entries[1].IsMarked() {
+			t.Errorf("user stash should not be marked, got %+v", entries[1])
+		}
+
+		if err := StashPopCommit(dir, stash); err != nil {
+			t.Fatalf("StashPopCommit failed: %v", err)
+		}
+		if content, _ := os.ReadFile(filepath.Join(dir, "README.md")); string(content) != "changed" {
+			t.Errorf("changes not restored: %q", content)
+		}
+		if entries, _ := ListStashes(dir); len(entries) != 1 || entries[0].IsMarked() {
+			t.Errorf("only the user stash should remain, got %+v", entries)
+		}
+	})
+
+	t.Run("entries of older releases are marked", func(t *testing.T) {
+		os.WriteFile(filepath.Join(dir, "README.md"), []byte("old"), 0644)
+		exec.Command("git", "-C", dir, "stash", "push", "-q", "-m", "git-multirepo auto-stash").Run()
+		defer exec.Command("git", "-C", dir, "stash", "drop", "-q").Run()
+
+		if entries, _ := ListStashes(dir); len(entries) != 2 || !entries[0].IsMarked() {
+			t.Errorf("auto-stash entry should be marked, got %+v", entries)
+		}
+	})
+
+	t.Run("conflicting pop keeps entry", func(t *testing.T) {
+		os.WriteFile(filepath.Join(dir, "README.md"), []byte("stashed"), 0644)
+		stash, _ := StashSave(dir, "conflict")
+		os.WriteFile(filepath.Join(dir, "README.md"), []byte("committed"), 0644)
+		exec.Command("git", "-C", dir, "commit", "-q", "-am", "Conflicting").Run()
+
+		if err := StashPopCommit(dir, stash); err == nil {
+			t.Fatal("expected conflict error")
+		}
+		found := false
+		entries, _ := ListStashes(dir)
+		for _, e := range entries {
+			if e.Commit == stash {
+				found = true
+			}
+		}
+		if !found {
+			t.Error("stash entry should be kept after a conflict")
+		}
+	})
+}
+
+func TestStashSave_SkipsKeepFiles(t *testing.T) {
+	dir := setupTestRepoWithCommit(t)
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte("{}"), 0644)
+	exec.Command("git", "-C", dir, "add", ".").Run()
+	exec.Command("git", "-C", dir, "commit", "-q", "-m", "Add config").Run()
+
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte("{\"local\": true}"), 0644)
+	ApplySkipWorktree(dir, []string{"config.json"})
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("changed"), 0644)
+
+	if _, err := StashSave(dir, "test"); err != nil {
+		t.Fatalf("StashSave failed: %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "config.json")); string(content) != "{\"local\": true}" {
+		t.Errorf("keep file should not be stashed: %q", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "README.md")); string(content) != "# Test" {
+		t.Errorf("README change should be stashed: %q", content)
+	}
+}
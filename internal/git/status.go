@@ -5,6 +5,7 @@ type WorkspaceStatus struct {
 	ModifiedFiles    []string
 	UntrackedFiles   []string
 	StagedFiles      []string
+	UnresolvedFiles  []string // Keep files with conflict markers left by a three-way merge
 	TotalUncommitted int
 }
 
@@ -37,6 +38,9 @@ func GetWorkspaceStatus(workspacePath string, keepFiles []string) (*WorkspaceSta
 	}
 	status.StagedFiles = staged
 
+	// Keep files are merged with remote changes on pull; conflicts need resolution
+	status.UnresolvedFiles = FilesWithConflictMarkers(workspacePath, keepFiles)
+
 	// Calculate total uncommitted changes
 	status.TotalUncommitted = len(status.ModifiedFiles) + len(status.UntrackedFiles) + len(status.StagedFiles)
 
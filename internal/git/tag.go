@@ -0,0 +1,47 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// IsValidTagName checks if name can be used as a tag (git check-ref-format)
+func IsValidTagName(name string) bool {
+	return run("", "check-ref-format", "refs/tags/"+name) == nil
+}
+
+// TagExists checks if a local tag exists
+func TagExists(path, tag string) bool {
+	return run(path, "rev-parse", "--verify", "--quiet", "refs/tags/"+tag) == nil
+}
+
+// CreateTag creates an annotated tag at HEAD
+// With sign, the tag is GPG/SSH signed (git tag -s) using the user's signing key
+func CreateTag(path, tag, message string, sign bool) error {
+	flag := "-a"
+	if sign {
+		flag = "-s"
+	}
+	if out, err := combinedOutput(path, "tag", flag, "-m", message, tag); err != nil {
+		return fmt.Errorf("git tag failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// GetTagCommit returns the commit a tag points to (peeling annotated tags)
+func GetTagCommit(path, tag string) (string, error) {
+	out, err := output(path, "rev-parse", "--verify", "--quiet", "refs/tags/"+tag+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("tag not found: %s", tag)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// PushTag pushes a tag to origin
+func PushTag(ctx context.Context, path, tag string) error {
+	if out, err := combinedOutputOp(ctx, OpPush, path, "push", "-q", "origin", "refs/tags/"+tag); err != nil {
+		return opError(err, "git push", string(out))
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package git
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestIsValidTagName(t *testing.T) {
+	for _, name := range []string{"v1.0.0", "release/2026.10"} {
+		if !IsValidTagName(name) {
+			t.Errorf("%q should be valid", name)
+		}
+	}
+	for _, name := range []string{"", "bad..name", "bad name", "../escape"} {
+		if IsValidTagName(name) {
+			t.Errorf("%q should be invalid", name)
+		}
+	}
+}
+
+func TestCreateAndPushTag(t *testing.T) {
+	origin, clone := setupClonedRepo(t)
+	head, _ := GetCurrentCommit(clone)
+
+	if TagExists(clone, "v1.0.0") {
+		t.Fatal("tag should not exist yet")
+	}
+	if err := CreateTag(clone, "v1.0.0", "Release 1.0.0", false); err != nil {
+		t.Fatalf("CreateTag failed: %v", err)
+	}
+	if !TagExists(clone, "v1.0.0") {
+		t.Error("tag should exist")
+	}
+
+	out, _ := exec.Command("git", "-C", clone, "cat-file", "-t", "refs/tags/v1.0.0").Output()
+	if strings.TrimSpace(string(out)) != "tag" {
+		t.Errorf("expected an annotated tag, got %q", out)
+	}
+
+	// The tag object is peeled to the commit
+	commit, err := GetTagCommit(clone, "v1.0.0")
+	if err != nil || commit != head {
+		t.Errorf("GetTagCommit = %q, %v; want %s", commit, err, head)
+	}
+	if _, err := GetTagCommit(clone, "missing"); err == nil {
+		t.Error("expected error for missing tag")
+	}
+
+	if err := CreateTag(clone, "v1.0.0", "again", false); err == nil {
+		t.Error("expected error for existing tag")
+	}
+
+	if err := PushTag(context.Background(), clone, "v1.0.0"); err != nil {
+		t.Fatalf("PushTag failed: %v", err)
+	}
+	if commit, err := GetTagCommit(origin, "v1.0.0"); err != nil || commit != head {
+		t.Errorf("tag not pushed: %q, %v", commit, err)
+	}
+}
@@ -0,0 +1,104 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Operation is a kind of git operation with its own timeout
+type Operation string
+
+const (
+	OpClone Operation = "clone"
+	OpFetch Operation = "fetch"
+	OpPull  Operation = "pull"
+	OpPush  Operation = "push"
+	OpLocal Operation = "local" // Commands that do not contact a remote (status, rev-list, ...)
+)
+
+// FetchTimeout is the default timeout for fetch operations
+const FetchTimeout = 10 * time.Second
+
+// defaultTimeouts are used unless GIT_MULTIREPO_TIMEOUT_<OP> is set
+var defaultTimeouts = map[Operation]time.Duration{
+	OpClone: 30 * time.Minute,
+	OpFetch: FetchTimeout,
+	OpPull:  10 * time.Minute,
+	OpPush:  10 * time.Minute,
+	OpLocal: 5 * time.Minute,
+}
+
+// TimeoutEnv returns the environment variable overriding the timeout for op
+func TimeoutEnv(op Operation) string {
+	return "GIT_MULTIREPO_TIMEOUT_" + strings.ToUpper(string(op))
+}
+
+// Timeout returns the timeout for op
+// GIT_MULTIREPO_TIMEOUT_<OP> (e.g., GIT_MULTIREPO_TIMEOUT_FETCH=30s) overrides
+// the default; "0" disables the timeout. Invalid values are ignored.
+func Timeout(op Operation) time.Duration {
+	if value := os.Getenv(TimeoutEnv(op)); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return defaultTimeouts[op]
+}
+
+// TimeoutError reports an operation that did not finish within its timeout
+type TimeoutError struct {
+	Op    Operation
+	After time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %v (set %s to change)", e.Op, e.After, TimeoutEnv(e.Op))
+}
+
+// runOp runs c under ctx with the timeout for op
+// Cancellation of ctx (e.g., Ctrl-C) is returned as ctx.Err(); an expired
+// timeout as *TimeoutError
+func runOp(ctx context.Context, op Operation, c Cmd) error {
+	timeout := Timeout(op)
+	opCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		opCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err := runner.Run(opCtx, c)
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if errors.Is(opCtx.Err(), context.DeadlineExceeded) {
+		return &TimeoutError{Op: op, After: timeout}
+	}
+	return err
+}
+
+// IsInterrupted checks if err is a timeout or a cancellation (e.g., Ctrl-C)
+func IsInterrupted(err error) bool {
+	var timeoutErr *TimeoutError
+	return errors.As(err, &timeoutErr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// opError describes a failed remote operation
+// Timeouts and cancellation are returned as is; git's own failures as
+// "<what> failed: <output>"
+func opError(err error, what, out string) error {
+	if IsInterrupted(err) {
+		return err
+	}
+	if msg := strings.TrimSpace(out); msg != "" {
+		return fmt.Errorf("%s failed: %s", what, msg)
+	}
+	return fmt.Errorf("%s failed: %w", what, err)
+}
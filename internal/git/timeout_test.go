@@ -0,0 +1,102 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingRunner blocks every command until its context is done
+type blockingRunner struct{}
+
+func (blockingRunner) Run(ctx context.Context, c Cmd) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"default", "", FetchTimeout},
+		{"override", "45s", 45 * time.Second},
+		{"disabled", "0", 0},
+		{"invalid", "soon", FetchTimeout},
+		{"negative", "-1s", FetchTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GIT_MULTIREPO_TIMEOUT_FETCH", tt.value)
+			if got := Timeout(OpFetch); got != tt.want {
+				t.Errorf("Timeout(OpFetch) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := TimeoutEnv(OpClone); got != "GIT_MULTIREPO_TIMEOUT_CLONE" {
+		t.Errorf("TimeoutEnv(OpClone) = %q", got)
+	}
+}
+
+func TestRunOp(t *testing.T) {
+	defer SetRunner(blockingRunner{})()
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Setenv("GIT_MULTIREPO_TIMEOUT_FETCH", "10ms")
+
+		err := Fetch(context.Background(), "/repo")
+		var timeoutErr *TimeoutError
+		if !errors.As(err, &timeoutErr) {
+			t.Fatalf("expected *TimeoutError, got %T: %v", err, err)
+		}
+		if timeoutErr.Op != OpFetch || timeoutErr.After != 10*time.Millisecond {
+			t.Errorf("unexpected timeout error: %+v", timeoutErr)
+		}
+		if !strings.Contains(err.Error(), "GIT_MULTIREPO_TIMEOUT_FETCH") {
+			t.Errorf("error should name the variable to change: %v", err)
+		}
+		if !IsInterrupted(err) {
+			t.Error("timeout should count as interrupted")
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		err := PushTag(ctx, "/repo", "v1.0.0")
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("local commands ignore cancellation", func(t *testing.T) {
+		fake := NewFakeRunner()
+		defer SetRunner(fake)()
+		fake.On("/repo", "update-index").Return("")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := UnapplySkipWorktree("/repo", []string{"config.json"}); err != nil {
+			t.Errorf("local command failed after cancellation: %v", err)
+		}
+		if err := Fetch(ctx, "/repo"); !errors.Is(err, context.Canceled) {
+			t.Errorf("remote command should stop after cancellation, got %v", err)
+		}
+	})
+}
+
+func TestOpError(t *testing.T) {
+	if err := opError(&ExitError{Code: 1}, "git push", "remote: denied\n"); err.Error() != "git push failed: remote: denied" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	timeoutErr := &TimeoutError{Op: OpPush, After: time.Second}
+	if err := opError(timeoutErr, "git push", ""); err != timeoutErr {
+		t.Errorf("timeouts should be returned as is, got %v", err)
+	}
+}
@@ -8,6 +8,8 @@ import (
 // WithSkipWorktreeTransaction executes workFunc within a skip-worktree transaction.
 // Pattern: UnapplySkipWorktree → work → ApplySkipWorktree (defer)
 // This ensures skip-worktree is always re-applied, even on error.
+// The flags are local git commands, which are not cancelled by Ctrl-C, so the
+// re-apply also completes when workFunc was interrupted.
 func WithSkipWorktreeTransaction(
 	workspacePath string,
 	keepFiles []string,
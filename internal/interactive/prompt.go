@@ -135,3 +135,12 @@ func ConfirmYN(message string) (bool, error) {
 	err := survey.AskOne(prompt, &result)
 	return result, err
 }
+
+// IsTerminal checks if the given file is attached to a terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -12,24 +12,56 @@ import (
 
 const FileName = ".git.multirepos"
 
+// ParentPath is the path used for the parent (mother) repository where
+// repositories are listed by workspace path
+const ParentPath = "."
+
 // marshalFunc is the function used to marshal YAML (allows testing)
 var marshalFunc = yaml.Marshal
 
 // WorkspaceEntry represents a single workspace entry
 type WorkspaceEntry struct {
-	Path   string   `yaml:"path"`
-	Repo   string   `yaml:"repo"`
-	Branch string   `yaml:"branch,omitempty"`
-	Keep   []string `yaml:"keep,omitempty"`
-	Commit string   `yaml:"commit,omitempty"` // Deprecated: kept for backward compatibility, no longer used
+	Path      string   `yaml:"path"`
+	Repo      string   `yaml:"repo"`
+	Branch    string   `yaml:"branch,omitempty"`
+	Keep      []string `yaml:"keep,omitempty"`
+	Sensitive []string `yaml:"sensitive,omitempty"` // Keep files (or patterns) whose backups and patches are encrypted
+	Groups    []string `yaml:"groups,omitempty"`    // Named groups for selecting workspaces (e.g., --group backend)
+	Commit    string   `yaml:"commit,omitempty"`    // Deprecated: kept for backward compatibility, no longer used
+}
+
+// InGroup checks if the workspace belongs to the given group
+func (ws *WorkspaceEntry) InGroup(group string) bool {
+	for _, g := range ws.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// KeepProfile lists the keep files a keep profile overrides, by workspace path
+// ("." for the parent repository)
+type KeepProfile map[string][]string
+
+// RetentionConfig limits the backup history kept in .multirepos/backup
+// Zero values mean no limit.
+type RetentionConfig struct {
+	Days          int    `yaml:"days,omitempty"`           // Keep loose backups this many days
+	ArchiveMonths int    `yaml:"archive_months,omitempty"` // Keep monthly archives this many months
+	MaxSize       string `yaml:"max_size,omitempty"`       // Maximum total size of backups and archives, e.g. 500MB
 }
 
 // Manifest represents the .git.multirepos file structure
 type Manifest struct {
-	Language   string           `yaml:"language,omitempty"`
-	Keep       []string         `yaml:"keep,omitempty"`   // Mother repo: files to keep
-	Ignore     []string         `yaml:"ignore,omitempty"` // Mother repo: files to ignore (gitignore-style)
-	Workspaces []WorkspaceEntry `yaml:"workspaces,omitempty"`
+	Language     string                 `yaml:"language,omitempty"`
+	Keep         []string               `yaml:"keep,omitempty"`          // Mother repo: files to keep
+	Sensitive    []string               `yaml:"sensitive,omitempty"`     // Mother repo: keep files whose backups and patches are encrypted
+	Ignore       []string               `yaml:"ignore,omitempty"`        // Mother repo: files to ignore (gitignore-style)
+	Autostash    bool                   `yaml:"autostash,omitempty"`     // Stash uncommitted changes around pull and branch switching
+	KeepProfiles map[string]KeepProfile `yaml:"keep_profiles,omitempty"` // Named sets of keep file overrides (profile use)
+	Retention    *RetentionConfig       `yaml:"retention,omitempty"`     // Backup retention (backup prune)
+	Workspaces   []WorkspaceEntry       `yaml:"workspaces,omitempty"`
 }
 
 // Load reads the manifest from the given directory
@@ -128,6 +160,15 @@ func (m *Manifest) Exists(path string) bool {
 	return m.Find(path) != nil
 }
 
+// Paths returns the paths of all workspaces
+func (m *Manifest) Paths() []string {
+	paths := make([]string, 0, len(m.Workspaces))
+	for _, ws := range m.Workspaces {
+		paths = append(paths, ws.Path)
+	}
+	return paths
+}
+
 // GetLanguage returns the configured language, defaults to "en"
 func (m *Manifest) GetLanguage() string {
 	if m.Language == "" {
@@ -181,3 +181,14 @@ func TestSaveMarshalError(t *testing.T) {
 		t.Error("Save should fail when marshal fails")
 	}
 }
+
+func TestInGroup(t *testing.T) {
+	ws := WorkspaceEntry{Path: "apps/api", Groups: []string{"backend", "core"}}
+
+	if !ws.InGroup("backend") {
+		t.Error("InGroup(backend) = false, want true")
+	}
+	if ws.InGroup("frontend") {
+		t.Error("InGroup(frontend) = true, want false")
+	}
+}
@@ -0,0 +1,443 @@
+package patch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/yejune/git-multirepo/internal/crypt"
+)
+
+// MaxFuzz is the number of context lines a hunk may ignore at its start and
+// end when it does not match exactly (as patch does by default)
+const MaxFuzz = 2
+
+// FileDiff is the unified diff of one file
+type FileDiff struct {
+	OldPath string // Path before the change, without the a/ prefix
+	NewPath string // Path after the change, without the b/ prefix
+	Hunks   []*Hunk
+}
+
+// Hunk is one @@ section of a unified diff
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Section            string   // Text after the closing @@, if any
+	Lines              []string // Diff lines, each starting with ' ', '-' or '+', with their line endings
+}
+
+// HunkResult is the outcome of applying one hunk
+type HunkResult struct {
+	Index    int  // 1-based position of the hunk in the diff
+	OldStart int  // Line the hunk header expects it at
+	Line     int  // Line it was applied at in the result, 0 if it failed
+	Offset   int  // Lines away from where it was expected
+	Fuzz     int  // Context lines ignored at each end to make it match
+	Applied  bool // False if no position matched; the hunk is in the reject file
+}
+
+// FileResult is the outcome of applying the diff of one file
+type FileResult struct {
+	Path     string // File relative to the repository
+	Hunks    []HunkResult
+	Rejected []byte // Failed hunks, in reject file format
+	Reject   string // Reject file written for failed hunks (<file>.rej), relative to the repository; "" for encrypted patches
+}
+
+// Failed returns the number of hunks that did not apply
+func (r *FileResult) Failed() int {
+	failed := 0
+	for _, h := range r.Hunks {
+		if !h.Applied {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Result is the outcome of ApplyWithResult
+type Result struct {
+	Files []FileResult
+}
+
+// Failed returns the number of hunks that did not apply, in all files
+func (r *Result) Failed() int {
+	failed := 0
+	for i := range r.Files {
+		failed += r.Files[i].Failed()
+	}
+	return failed
+}
+
+// Hunks returns the number of hunks in all files
+func (r *Result) Hunks() int {
+	n := 0
+	for i := range r.Files {
+		n += len(r.Files[i].Hunks)
+	}
+	return n
+}
+
+// ApplyWithResult applies a patch file to the working tree and reports every hunk
+// Hunks that do not match where the header says are searched for nearby
+// (offset) and then with up to MaxFuzz context lines ignored at each end.
+// Hunks that match nowhere are skipped and written to <file>.rej (with the
+// file's mode at most); the other hunks are still applied. Rejects of
+// encrypted patches are only reported in the result, as they hold the
+// lines of a sensitive file. Patches that need git apply (see NeedsGitApply)
+// are applied as a whole, and their result lists no hunks.
+func ApplyWithResult(ctx context.Context, repoPath, patchPath string) (*Result, error) {
+	if repoPath == "" {
+		return nil, fmt.Errorf("repoPath cannot be empty")
+	}
+	if patchPath == "" {
+		return nil, fmt.Errorf("patchPath cannot be empty")
+	}
+
+	// Check if patch file exists
+	if _, err := os.Stat(patchPath); err != nil {
+		return nil, fmt.Errorf("patch file not found: %w", err)
+	}
+
+	// Read (and decrypt) patch file
+	raw, err := os.ReadFile(patchPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patch: %w", err)
+	}
+	content, err := crypt.Decrypt(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patch: %s: %w", patchPath, err)
+	}
+	encrypted := crypt.IsEncrypted(raw)
+	if NeedsGitApply(content) {
+		return &Result{}, gitApply(ctx, repoPath, content)
+	}
+
+	diffs, err := Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for _, d := range diffs {
+		file := d.NewPath
+		if !validBundlePath(file, false) {
+			return result, fmt.Errorf("patch touches a path outside the repository: %s", file)
+		}
+		fullPath := filepath.Join(repoPath, filepath.FromSlash(file))
+		info, err := os.Stat(fullPath)
+		if err != nil {
+			return result, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		original, err := os.ReadFile(fullPath)
+		if err != nil {
+			return result, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		patched, hunks, rejected := ApplyDiff(original, d)
+		fr := FileResult{Path: file, Hunks: hunks}
+		if !bytes.Equal(patched, original) {
+			if err := os.WriteFile(fullPath, patched, info.Mode().Perm()); err != nil {
+				return result, fmt.Errorf("failed to write %s: %w", file, err)
+			}
+		}
+		if len(rejected) > 0 {
+			fr.Rejected = FormatReject(d, rejected)
+		}
+		if len(rejected) > 0 && !encrypted {
+			fr.Reject = file + ".rej"
+			perm := info.Mode().Perm() & 0666
+			if err := os.WriteFile(fullPath+".rej", fr.Rejected, perm); err != nil {
+				return result, fmt.Errorf("failed to write %s: %w", fr.Reject, err)
+			}
+			// WriteFile keeps the mode of an existing reject file
+			if err := os.Chmod(fullPath+".rej", perm); err != nil {
+				return result, fmt.Errorf("failed to write %s: %w", fr.Reject, err)
+			}
+		}
+		result.Files = append(result.Files, fr)
+	}
+	return result, nil
+}
+
+// Parse reads the file diffs of a unified diff (git diff or diff -u output)
+func Parse(content []byte) ([]*FileDiff, error) {
+	lines := splitLines(content)
+	var diffs []*FileDiff
+	var cur *FileDiff
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r\n")
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			cur = &FileDiff{}
+			diffs = append(diffs, cur)
+
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if cur == nil || cur.OldPath != "" || len(cur.Hunks) > 0 {
+				cur = &FileDiff{}
+				diffs = append(diffs, cur)
+			}
+			cur.OldPath = diffPath(line[4:])
+			cur.NewPath = diffPath(strings.TrimRight(lines[i+1], "\r\n")[4:])
+			if cur.NewPath == "" {
+				cur.NewPath = cur.OldPath
+			}
+			i++
+
+		case strings.HasPrefix(line, "@@ "):
+			if cur == nil || cur.NewPath == "" {
+				return nil, fmt.Errorf("line %d: hunk without file header", i+1)
+			}
+			h, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			cur.Hunks = append(cur.Hunks, h)
+			i = next - 1
+		}
+	}
+
+	// Drop headers without hunks (e.g. mode changes)
+	var withHunks []*FileDiff
+	for _, d := range diffs {
+		if len(d.Hunks) > 0 {
+			withHunks = append(withHunks, d)
+		}
+	}
+	return withHunks, nil
+}
+
+// parseHunk reads the hunk starting at lines[start] and returns the index of the line after it
+func parseHunk(lines []string, start int) (*Hunk, int, error) {
+	header := strings.TrimRight(lines[start], "\r\n")
+	end := strings.Index(header[3:], " @@")
+	if end < 0 {
+		return nil, 0, fmt.Errorf("line %d: invalid hunk header: %s", start+1, header)
+	}
+	ranges := strings.Fields(header[3 : 3+end])
+	if len(ranges) != 2 || !strings.HasPrefix(ranges[0], "-") || !strings.HasPrefix(ranges[1], "+") {
+		return nil, 0, fmt.Errorf("line %d: invalid hunk header: %s", start+1, header)
+	}
+
+	h := &Hunk{Section: strings.TrimPrefix(header[3+end+3:], " ")}
+	var err1, err2 error
+	h.OldStart, h.OldLines, err1 = parseRange(ranges[0][1:])
+	h.NewStart, h.NewLines, err2 = parseRange(ranges[1][1:])
+	if err1 != nil || err2 != nil {
+		return nil, 0, fmt.Errorf("line %d: invalid hunk header: %s", start+1, header)
+	}
+
+	// Read lines until both sides are complete
+	oldLeft, newLeft := h.OldLines, h.NewLines
+	i := start + 1
+	for ; i < len(lines) && (oldLeft > 0 || newLeft > 0); i++ {
+		line := lines[i]
+		if line == "\n" || line == "\r\n" {
+			line = " " + line // Empty context line with its space stripped
+		}
+		switch line[0] {
+		case ' ':
+			oldLeft--
+			newLeft--
+		case '-':
+			oldLeft--
+		case '+':
+			newLeft--
+		case '\\':
+			h.markNoNewline()
+			continue
+		default:
+			return nil, 0, fmt.Errorf("line %d: hunk ends early (expected %d more old and %d more new lines)", i+1, oldLeft, newLeft)
+		}
+		if oldLeft < 0 || newLeft < 0 {
+			return nil, 0, fmt.Errorf("line %d: hunk is longer than its header says", i+1)
+		}
+		h.Lines = append(h.Lines, line)
+	}
+	if oldLeft > 0 || newLeft > 0 {
+		return nil, 0, fmt.Errorf("hunk at line %d is truncated", start+1)
+	}
+	// "\ No newline at end of file" after the last line
+	if i < len(lines) && strings.HasPrefix(lines[i], `\`) {
+		h.markNoNewline()
+		i++
+	}
+	return h, i, nil
+}
+
+// markNoNewline removes the line ending of the last hunk line
+func (h *Hunk) markNoNewline() {
+	if n := len(h.Lines); n > 0 {
+		h.Lines[n-1] = strings.TrimRight(h.Lines[n-1], "\r\n")
+	}
+}
+
+func parseRange(s string) (start, count int, err error) {
+	count = 1
+	if comma := strings.IndexByte(s, ','); comma >= 0 {
+		if count, err = strconv.Atoi(s[comma+1:]); err != nil {
+			return 0, 0, err
+		}
+		s = s[:comma]
+	}
+	start, err = strconv.Atoi(s)
+	return start, count, err
+}
+
+// diffPath strips the a/ or b/ prefix and any trailing timestamp from a --- or +++ path
+// /dev/null is returned as "".
+func diffPath(p string) string {
+	if tab := strings.IndexByte(p, '\t'); tab >= 0 {
+		p = p[:tab]
+	}
+	if p == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(p, "a/") || strings.HasPrefix(p, "b/") {
+		p = p[2:]
+	}
+	return path.Clean(p)
+}
+
+// ApplyDiff applies the hunks of a file diff to content
+// Returns the patched content, the result of every hunk and the hunks that
+// did not apply.
+func ApplyDiff(content []byte, d *FileDiff) ([]byte, []HunkResult, []*Hunk) {
+	cur := splitLines(content)
+	results := make([]HunkResult, 0, len(d.Hunks))
+	var rejected []*Hunk
+
+	delta, minPos := 0, 0
+	for i, h := range d.Hunks {
+		r := HunkResult{Index: i + 1, OldStart: h.OldStart}
+		old, new := h.sides()
+
+		// Where the header puts the hunk, adjusted for the hunks applied before it
+		expected := h.OldStart - 1 + delta
+		if h.OldLines == 0 {
+			expected = h.OldStart + delta
+		}
+
+		applied := false
+		for fuzz := 0; fuzz <= MaxFuzz && !applied; fuzz++ {
+			top, bottom := h.contextTrim(fuzz)
+			if fuzz > 0 && top == 0 && bottom == 0 {
+				break // Nothing left to ignore
+			}
+			o, n := old[top:len(old)-bottom], new[top:len(new)-bottom]
+			pos, ok := findLines(cur, o, expected+top, minPos)
+			if !ok {
+				continue
+			}
+
+			cur = append(cur[:pos], append(append([]string(nil), n...), cur[pos+len(o):]...)...)
+			r.Applied, r.Fuzz = true, fuzz
+			r.Line = pos - top + 1
+			r.Offset = pos - top - expected
+			delta += len(n) - len(o)
+			minPos = pos + len(n)
+			applied = true
+		}
+		if !applied {
+			rejected = append(rejected, h)
+		}
+		results = append(results, r)
+	}
+	return []byte(strings.Join(cur, "")), results, rejected
+}
+
+// sides returns the lines the hunk expects (context and removed) and produces (context and added)
+func (h *Hunk) sides() (old, new []string) {
+	for _, line := range h.Lines {
+		switch line[0] {
+		case ' ':
+			old = append(old, line[1:])
+			new = append(new, line[1:])
+		case '-':
+			old = append(old, line[1:])
+		case '+':
+			new = append(new, line[1:])
+		}
+	}
+	return old, new
+}
+
+// contextTrim returns how many leading and trailing context lines to ignore
+// for a fuzz factor (at most the context lines the hunk has at each end)
+func (h *Hunk) contextTrim(fuzz int) (top, bottom int) {
+	for top < fuzz && top < len(h.Lines) && h.Lines[top][0] == ' ' {
+		top++
+	}
+	for bottom < fuzz && bottom < len(h.Lines)-top && h.Lines[len(h.Lines)-1-bottom][0] == ' ' {
+		bottom++
+	}
+	return top, bottom
+}
+
+// findLines finds want in lines at or after minPos, trying expected first and
+// then positions further and further away from it
+func findLines(lines, want []string, expected, minPos int) (int, bool) {
+	maxPos := len(lines) - len(want)
+	expected = min(max(expected, minPos), max(maxPos, minPos))
+	for dist := 0; expected-dist >= minPos || expected+dist <= maxPos; dist++ {
+		if p := expected - dist; p >= minPos && p <= maxPos && linesEqual(lines[p:p+len(want)], want) {
+			return p, true
+		}
+		if p := expected + dist; dist > 0 && p >= minPos && p <= maxPos && linesEqual(lines[p:p+len(want)], want) {
+			return p, true
+		}
+	}
+	return 0, false
+}
+
+func linesEqual(a, b []string) bool {
+	for i := range b {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitLines splits content into lines that keep their line endings
+func splitLines(content []byte) []string {
+	var lines []string
+	s := string(content)
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			lines = append(lines, s)
+			break
+		}
+		lines = append(lines, s[:i+1])
+		s = s[i+1:]
+	}
+	return lines
+}
+
+// FormatReject writes hunks of a file diff in unified diff format, as a .rej file
+func FormatReject(d *FileDiff, hunks []*Hunk) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- a/%s\n+++ b/%s\n", d.OldPath, d.NewPath)
+	for _, h := range hunks {
+		fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+		if h.Section != "" {
+			buf.WriteString(" " + h.Section)
+		}
+		buf.WriteString("\n")
+		for _, line := range h.Lines {
+			buf.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,201 @@
+package patch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yejune/git-multirepo/internal/crypt"
+)
+
+const configDiff = `diff --git a/config.txt b/config.txt
+index 1111111..2222222 100644
+--- a/config.txt
++++ b/config.txt
+@@ -2,3 +2,3 @@ header
+ b
+-c
++C
+ d
+@@ -7,3 +7,4 @@
+ g
+ h
++h2
+ i
+`
+
+func TestParse(t *testing.T) {
+	diffs, err := Parse([]byte(configDiff))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(diffs) != 1 || diffs[0].OldPath != "config.txt" || diffs[0].NewPath != "config.txt" {
+		t.Fatalf("unexpected diffs: %+v", diffs)
+	}
+	h := diffs[0].Hunks
+	if len(h) != 2 || h[0].OldStart != 2 || h[0].Section != "header" || h[1].NewLines != 4 || len(h[1].Lines) != 4 {
+		t.Errorf("unexpected hunks: %+v %+v", h[0], h[1])
+	}
+
+	// A hunk line starting with "--- " is content, not a file header
+	tricky := "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n--- x\n+++ y\n a\n"
+	diffs, err = Parse([]byte(tricky))
+	if err != nil || len(diffs) != 1 || len(diffs[0].Hunks[0].Lines) != 3 {
+		t.Errorf("unexpected parse of tricky diff: %+v, %v", diffs, err)
+	}
+
+	for _, bad := range []string{
+		"@@ -1 +1 @@\n-a\n+b\n",                   // no file header
+		"--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n", // truncated
+		"--- a/f\n+++ b/f\n@@ -x +1 @@\n-a\n+b\n", // bad range
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestApplyDiff(t *testing.T) {
+	original := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+
+	tests := []struct {
+		name     string
+		content  string
+		want     string
+		offsets  []int
+		fuzz     []int
+		rejected int
+	}{
+		{
+			name:    "exact",
+			content: original,
+			want:    "a\nb\nC\nd\ne\nf\ng\nh\nh2\ni\nj\n",
+			offsets: []int{0, 0},
+			fuzz:    []int{0, 0},
+		},
+		{
+			name:    "offset",
+			content: "x\ny\n" + original,
+			want:    "x\ny\na\nb\nC\nd\ne\nf\ng\nh\nh2\ni\nj\n",
+			offsets: []int{2, 2},
+			fuzz:    []int{0, 0},
+		},
+		{
+			name:    "fuzz",
+			content: "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\n",
+			want:    "a\nB\nC\nd\ne\nf\ng\nh\nh2\ni\nj\n",
+			offsets: []int{0, 0},
+			fuzz:    []int{1, 0},
+		},
+		{
+			name:     "reject",
+			content:  "a\nb\nX\nd\ne\nf\ng\nh\ni\nj\n",
+			want:     "a\nb\nX\nd\ne\nf\ng\nh\nh2\ni\nj\n",
+			rejected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs, _ := Parse([]byte(configDiff))
+			got, results, rejected := ApplyDiff([]byte(tt.content), diffs[0])
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if len(rejected) != tt.rejected {
+				t.Fatalf("expected %d rejected hunk(s), got %d", tt.rejected, len(rejected))
+			}
+			if tt.rejected > 0 {
+				if results[0].Applied || !results[1].Applied || results[0].Line != 0 {
+					t.Errorf("unexpected results: %+v", results)
+				}
+				return
+			}
+			for i, r := range results {
+				if !r.Applied || r.Offset != tt.offsets[i] || r.Fuzz != tt.fuzz[i] {
+					t.Errorf("hunk %d: unexpected result %+v", i+1, r)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyDiff_NoNewlineAtEnd(t *testing.T) {
+	diff := "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n"
+	diffs, err := Parse([]byte(diff))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	got, _, rejected := ApplyDiff([]byte("a\nb"), diffs[0])
+	if len(rejected) != 0 || string(got) != "a\nc" {
+		t.Errorf("got %q (%d rejected)", got, len(rejected))
+	}
+
+	// The hunk expects no newline after b, so it does not match one that has it
+	if _, _, rejected := ApplyDiff([]byte("a\nb\n"), diffs[0]); len(rejected) != 1 {
+		t.Error("expected the hunk to be rejected")
+	}
+	if reject := string(FormatReject(diffs[0], diffs[0].Hunks)); !strings.HasSuffix(reject, "+c\n\\ No newline at end of file\n") {
+		t.Errorf("unexpected reject: %q", reject)
+	}
+}
+
+func TestApplyWithResult(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "config.txt"), []byte("a\nb\nX\nd\ne\nf\ng\nh\ni\nj\n"), 0640)
+	patchPath := filepath.Join(t.TempDir(), "config.patch")
+	os.WriteFile(patchPath, []byte(configDiff), 0644)
+
+	result, err := ApplyWithResult(context.Background(), dir, patchPath)
+	if err != nil {
+		t.Fatalf("ApplyWithResult failed: %v", err)
+	}
+	if len(result.Files) != 1 || result.Failed() != 1 || result.Hunks() != 2 || result.Files[0].Reject != "config.txt.rej" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "config.txt"))
+	if !strings.Contains(string(content), "h2\n") {
+		t.Errorf("second hunk should be applied, got %q", content)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "config.txt")); info.Mode().Perm() != 0640 {
+		t.Errorf("file mode should be kept, got %v", info.Mode().Perm())
+	}
+	reject, _ := os.ReadFile(filepath.Join(dir, "config.txt.rej"))
+	if !strings.HasPrefix(string(reject), "--- a/config.txt\n+++ b/config.txt\n@@ -2,3 +2,3 @@ header\n b\n-c\n+C\n d\n") || strings.Contains(string(reject), "h2") {
+		t.Errorf("unexpected reject file: %q", reject)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "config.txt.rej")); info.Mode().Perm() != 0640 {
+		t.Errorf("reject file should not be more open than the file, got %v", info.Mode().Perm())
+	}
+
+	err = Apply(context.Background(), dir, patchPath)
+	if err == nil || !strings.Contains(err.Error(), "config.txt: 1 of 2 hunk(s) failed (#1 at line 2), rejects in config.txt.rej") {
+		t.Errorf("expected reject error, got %v", err)
+	}
+
+	// Rejects of an encrypted patch hold sensitive lines: only in the result
+	t.Setenv(crypt.EnvPassphrase, "test passphrase")
+	t.Setenv(crypt.EnvKeyfile, "")
+	key, _ := crypt.LoadKey()
+	sealed, _ := key.Encrypt([]byte(configDiff))
+	os.WriteFile(patchPath, sealed, 0600)
+	os.Remove(filepath.Join(dir, "config.txt.rej"))
+	os.WriteFile(filepath.Join(dir, "config.txt"), []byte("a\nb\nX\nd\ne\nf\ng\nh\ni\nj\n"), 0640)
+	result, err = ApplyWithResult(context.Background(), dir, patchPath)
+	if err != nil || result.Failed() != 1 || result.Files[0].Reject != "" || !strings.Contains(string(result.Files[0].Rejected), "+C\n") {
+		t.Fatalf("unexpected result: %+v, %v", result, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.txt.rej")); !os.IsNotExist(err) {
+		t.Error("no reject file should be written for an encrypted patch")
+	}
+
+	escape := filepath.Join(t.TempDir(), "escape.patch")
+	os.WriteFile(escape, []byte("--- a/../x\n+++ b/../x\n@@ -1 +1 @@\n-a\n+b\n"), 0644)
+	if _, err := ApplyWithResult(context.Background(), dir, escape); err == nil {
+		t.Error("expected error for a path outside the repository")
+	}
+}
@@ -0,0 +1,179 @@
+package patch
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/yejune/git-multirepo/internal/manifest"
+)
+
+// BundleVersion is the version of the bundle format written by WriteBundle
+const BundleVersion = 1
+
+// bundleIndexName is the index inside a bundle
+const bundleIndexName = "overrides.json"
+
+// Bundle is a portable set of keep file patches (overrides export/import)
+// It is a tar archive holding overrides.json and the patches under
+// patches/<workspace>/<file>.patch, laid out like the patch store.
+type Bundle struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Patches []Entry   `json:"patches"` // Base and branch the patches were made against
+
+	content map[string][]byte // Patch content by ID
+}
+
+// Content returns the patch of an entry in the bundle
+func (b *Bundle) Content(e *Entry) []byte {
+	return b.content[e.ID()]
+}
+
+// bundlePath returns the name of a patch inside a bundle
+func bundlePath(e *Entry) string {
+	return path.Join("patches", e.Workspace, e.File+".patch")
+}
+
+// WriteBundle writes the patches of entries to w as a bundle
+// content returns the (plain or encrypted) patch of an entry, as stored.
+func WriteBundle(w io.Writer, entries []Entry, content func(e *Entry) ([]byte, error)) error {
+	b := Bundle{Version: BundleVersion, Created: time.Now().Truncate(time.Second), Patches: []Entry{}}
+	patches := make([][]byte, 0, len(entries))
+	for _, e := range entries {
+		data, err := content(&e)
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.ID(), err)
+		}
+		sum := sha256.Sum256(data)
+		e.Hash = hex.EncodeToString(sum[:])
+		b.Patches = append(b.Patches, e)
+		patches = append(patches, data)
+	}
+
+	index, err := json.MarshalIndent(&b, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+	if err := writeTarFile(tw, bundleIndexName, append(index, '\n'), b.Created); err != nil {
+		return err
+	}
+	for i := range b.Patches {
+		if err := writeTarFile(tw, bundlePath(&b.Patches[i]), patches[i], b.Created); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// ReadBundle reads a bundle written by WriteBundle
+// Every patch listed in the index must be present and match its hash, and
+// workspaces and files must be relative paths inside the repository.
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	files := make(map[string][]byte)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("not an overrides bundle: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[hdr.Name] = data
+	}
+
+	index, ok := files[bundleIndexName]
+	if !ok {
+		return nil, errors.New("not an overrides bundle: " + bundleIndexName + " missing")
+	}
+	var b Bundle
+	if err := json.Unmarshal(index, &b); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", bundleIndexName, err)
+	}
+	if b.Version != BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+
+	b.content = make(map[string][]byte, len(b.Patches))
+	for i := range b.Patches {
+		e := &b.Patches[i]
+		if !validBundlePath(e.Workspace, true) || !validBundlePath(e.File, false) {
+			return nil, fmt.Errorf("invalid patch path in bundle: %s", e.ID())
+		}
+		data, ok := files[bundlePath(e)]
+		if !ok {
+			return nil, fmt.Errorf("%s: patch missing from bundle", e.ID())
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != e.Hash {
+			return nil, fmt.Errorf("%s: patch does not match its hash", e.ID())
+		}
+		b.content[e.ID()] = data
+	}
+	return &b, nil
+}
+
+// validBundlePath checks that p is a clean relative path that stays inside the repository
+func validBundlePath(p string, allowParent bool) bool {
+	if allowParent && p == manifest.ParentPath {
+		return true
+	}
+	return p != "" && p != "." && !strings.HasPrefix(p, "/") && path.Clean(p) == p &&
+		p != ".." && !strings.HasPrefix(p, "../") && !strings.Contains(p, `\`)
+}
+
+// TouchesOnly checks that a git diff only changes file
+// A patch from a bundle is applied to the repository, so it must not reach
+// beyond the keep file it is listed for. Only the file headers of each diff
+// are inspected, hunk content may contain anything.
+func TouchesOnly(content []byte, file string) bool {
+	found, inHeader := false, false
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			if line != "diff --git a/"+file+" b/"+file {
+				return false
+			}
+			found, inHeader = true, true
+		case strings.HasPrefix(line, "@@"):
+			inHeader = false
+		case inHeader && (strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ ")):
+			name := line[4:]
+			if name != "/dev/null" && name != "a/"+file && name != "b/"+file {
+				return false
+			}
+		case inHeader && (strings.HasPrefix(line, "rename ") || strings.HasPrefix(line, "copy ")):
+			return false
+		case !found && strings.TrimSpace(line) != "":
+			return false // Content before the first diff (e.g. a plain unified diff)
+		}
+	}
+	return found
+}
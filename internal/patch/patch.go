@@ -1,15 +1,30 @@
 package patch
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strings"
+
+	"github.com/yejune/git-multirepo/internal/crypt"
+	"github.com/yejune/git-multirepo/internal/git"
 )
 
 // Create creates a patch file from the diff between HEAD and working tree
 // in unified diff format. If file is empty, diffs all changes.
-func Create(repoPath, file, patchPath string) error {
+// Binary files are included as git binary patches, and a deleted file as a
+// deletion; Apply uses git apply for those.
+func Create(ctx context.Context, repoPath, file, patchPath string) error {
+	return CreateWithKey(ctx, repoPath, file, patchPath, nil)
+}
+
+// CreateWithKey creates a patch file like Create, encrypted with key
+// A nil key writes a plain patch. The other functions in this package read
+// encrypted patches transparently.
+func CreateWithKey(ctx context.Context, repoPath, file, patchPath string, key *crypt.Key) error {
 	if repoPath == "" {
 		return fmt.Errorf("repoPath cannot be empty")
 	}
@@ -24,70 +39,111 @@ func Create(repoPath, file, patchPath string) error {
 	}
 
 	// Build git diff command
-	args := []string{"diff", "HEAD"}
+	args := []string{"diff", "--binary", "HEAD"}
 	if file != "" {
 		args = append(args, "--", file)
 	}
 
-	cmd := exec.Command("git", args...)
-	cmd.Dir = repoPath
-
 	// Capture output
-	output, err := cmd.Output()
+	var stdout, stderr bytes.Buffer
+	err := git.Exec(ctx, git.Cmd{Dir: repoPath, Args: args, Stdout: &stdout, Stderr: &stderr})
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return fmt.Errorf("git diff failed: %w\nstderr: %s", err, exitErr.Stderr)
+		var exitErr *git.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("git diff failed: %w\nstderr: %s", err, stderr.Bytes())
 		}
 		return fmt.Errorf("git diff failed: %w", err)
 	}
 
 	// Write patch file
-	if err := os.WriteFile(patchPath, output, 0644); err != nil {
+	content, perm := stdout.Bytes(), os.FileMode(0644)
+	if key != nil {
+		if content, err = key.Encrypt(content); err != nil {
+			return fmt.Errorf("failed to encrypt patch: %w", err)
+		}
+		perm = 0600
+	}
+	if err := os.WriteFile(patchPath, content, perm); err != nil {
 		return fmt.Errorf("failed to write patch file: %w", err)
 	}
 
 	return nil
 }
 
-// Apply applies a patch file to the working tree using the patch command.
-// The patch must be in unified diff format.
-func Apply(repoPath, patchPath string) error {
-	if repoPath == "" {
-		return fmt.Errorf("repoPath cannot be empty")
+// Apply applies a patch file to the working tree (see ApplyWithResult)
+// Returns an error listing the failed hunks if any hunk did not apply; the
+// other hunks are applied and the failed ones written to <file>.rej.
+func Apply(ctx context.Context, repoPath, patchPath string) error {
+	result, err := ApplyWithResult(ctx, repoPath, patchPath)
+	if err != nil {
+		return err
 	}
-	if patchPath == "" {
-		return fmt.Errorf("patchPath cannot be empty")
+	if result.Failed() > 0 {
+		return &RejectError{Result: result}
 	}
+	return nil
+}
 
-	// Check if patch file exists
-	if _, err := os.Stat(patchPath); err != nil {
-		return fmt.Errorf("patch file not found: %w", err)
-	}
+// RejectError is returned by Apply when hunks did not apply
+type RejectError struct {
+	Result *Result
+}
 
-	// Open patch file
-	patchFile, err := os.Open(patchPath)
-	if err != nil {
-		return fmt.Errorf("failed to open patch file: %w", err)
+func (e *RejectError) Error() string {
+	var parts []string
+	for i := range e.Result.Files {
+		f := &e.Result.Files[i]
+		if f.Failed() == 0 {
+			continue
+		}
+		var lines []string
+		for _, h := range f.Hunks {
+			if !h.Applied {
+				lines = append(lines, fmt.Sprintf("#%d at line %d", h.Index, h.OldStart))
+			}
+		}
+		rejects := "rejects in " + f.Reject
+		if f.Reject == "" {
+			rejects = "rejects not saved (encrypted patch)"
+		}
+		parts = append(parts, fmt.Sprintf("%s: %d of %d hunk(s) failed (%s), %s",
+			f.Path, f.Failed(), len(f.Hunks), strings.Join(lines, ", "), rejects))
 	}
-	defer patchFile.Close()
-
-	// Apply patch with -p1 to strip leading path component
-	cmd := exec.Command("patch", "-p1")
-	cmd.Dir = repoPath
-	cmd.Stdin = patchFile
+	return strings.Join(parts, "; ")
+}
 
-	// Capture both stdout and stderr
-	output, err := cmd.CombinedOutput()
+// gitApply applies patch content with git apply
+func gitApply(ctx context.Context, repoPath string, content []byte) error {
+	var stderr bytes.Buffer
+	err := git.Exec(ctx, git.Cmd{Dir: repoPath, Args: []string{"apply", "-"}, Stdin: bytes.NewReader(content), Stderr: &stderr})
 	if err != nil {
-		return fmt.Errorf("patch apply failed: %w\noutput: %s", err, output)
+		return fmt.Errorf("git apply failed: %w\noutput: %s", err, stderr.Bytes())
 	}
-
 	return nil
 }
 
-// Check checks if applying the patch would cause conflicts using git apply --check.
+// NeedsGitApply checks if a patch has parts only git apply understands:
+// binary patches, file deletions or file creations
+func NeedsGitApply(content []byte) bool {
+	for _, line := range strings.Split(string(content), "\n") {
+		if line == "GIT binary patch" || strings.HasPrefix(line, "Binary files ") ||
+			strings.HasPrefix(line, "deleted file mode ") || strings.HasPrefix(line, "new file mode ") {
+			return true
+		}
+	}
+	return false
+}
+
+// Check checks if the patch applies to the files at HEAD (git apply --check)
 // Returns true if conflicts are detected, false otherwise.
-func Check(repoPath, patchPath string) (hasConflicts bool, err error) {
+// The working tree is not involved, so a patch can be checked while its
+// changes are already in the file.
+func Check(ctx context.Context, repoPath, patchPath string) (hasConflicts bool, err error) {
+	return CheckAt(ctx, repoPath, patchPath, "HEAD")
+}
+
+// CheckAt checks if the patch applies to the files at rev (e.g., origin/main before a pull)
+func CheckAt(ctx context.Context, repoPath, patchPath, rev string) (hasConflicts bool, err error) {
 	if repoPath == "" {
 		return false, fmt.Errorf("repoPath cannot be empty")
 	}
@@ -99,13 +155,60 @@ func Check(repoPath, patchPath string) (hasConflicts bool, err error) {
 	if _, err := os.Stat(patchPath); err != nil {
 		return false, fmt.Errorf("patch file not found: %w", err)
 	}
+	content, err := Read(patchPath)
+	if err != nil {
+		return false, err
+	}
 
-	// Use git apply --check to validate patch
-	cmd := exec.Command("git", "-C", repoPath, "apply", "--check", patchPath)
-	if err := cmd.Run(); err != nil {
+	// Load rev into a temporary index and check the patch against it
+	tempDir, err := os.MkdirTemp("", "git-multirepo-check-*")
+	if err != nil {
+		return false, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(tempDir, "index")}
+
+	var stderr bytes.Buffer
+	err = git.Exec(ctx, git.Cmd{Dir: repoPath, Args: []string{"read-tree", rev}, Env: env, Stderr: &stderr})
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %s", rev, strings.TrimSpace(stderr.String()))
+	}
+
+	err = git.Exec(ctx, git.Cmd{Dir: repoPath, Args: []string{"apply", "--check", "--cached", "-"}, Stdin: bytes.NewReader(content), Env: env})
+	if err != nil {
 		// Non-zero exit means patch cannot be applied (conflicts or errors)
 		return true, nil
 	}
 
 	return false, nil
 }
+
+// Applies checks if the patch applies to the working tree
+func Applies(ctx context.Context, repoPath, patchPath string) bool {
+	return applyCheck(ctx, repoPath, patchPath)
+}
+
+// IsApplied checks if the changes in the patch are already in the working tree
+// (the patch applies in reverse)
+func IsApplied(ctx context.Context, repoPath, patchPath string) bool {
+	return applyCheck(ctx, repoPath, patchPath, "--reverse")
+}
+
+// applyCheck runs git apply --check with the patch content on stdin
+func applyCheck(ctx context.Context, repoPath, patchPath string, flags ...string) bool {
+	content, err := Read(patchPath)
+	if err != nil {
+		return false
+	}
+	args := append([]string{"apply", "--check"}, flags...)
+	return git.Exec(ctx, git.Cmd{Dir: repoPath, Args: append(args, "-"), Stdin: bytes.NewReader(content)}) == nil
+}
+
+// Read returns the content of a patch file, decrypting it if it is encrypted
+func Read(patchPath string) ([]byte, error) {
+	content, err := crypt.ReadFile(patchPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patch: %w", err)
+	}
+	return content, nil
+}
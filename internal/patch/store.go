@@ -0,0 +1,261 @@
+package patch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yejune/git-multirepo/internal/manifest"
+)
+
+// Entry is the index record of one stored patch
+type Entry struct {
+	Workspace string    `json:"workspace"`         // Workspace path, "." for the parent repository
+	File      string    `json:"file"`              // Keep file, relative to the workspace
+	Base      string    `json:"base"`              // Commit the patch was made against (HEAD at the time)
+	Branch    string    `json:"branch"`            // Branch checked out at the time
+	Hash      string    `json:"hash"`              // sha256 of the patch content
+	Deleted   bool      `json:"deleted,omitempty"` // Tombstone: the keep file is deleted locally
+	Created   time.Time `json:"created"`
+}
+
+// ID returns the name used for the patch on the command line: <workspace>/<file>
+func (e *Entry) ID() string {
+	if e.Workspace == manifest.ParentPath {
+		return e.File
+	}
+	return e.Workspace + "/" + e.File
+}
+
+// Index lists the patches in .multirepos/patches
+type Index struct {
+	Patches []Entry `json:"patches"`
+}
+
+// Dir returns the patch store directory: .multirepos/patches
+func Dir(repoRoot string) string {
+	return filepath.Join(repoRoot, ".multirepos", "patches")
+}
+
+// IndexPath returns the index file: .multirepos/patches/index.json
+func IndexPath(repoRoot string) string {
+	return filepath.Join(Dir(repoRoot), "index.json")
+}
+
+// StorePath returns the patch file for a keep file: .multirepos/patches/<workspace>/<file>.patch
+func StorePath(repoRoot, workspace, file string) string {
+	return filepath.Join(Dir(repoRoot), workspace, file+".patch")
+}
+
+// LoadIndex reads the patch index
+// A missing index (no patches recorded yet) is returned empty
+func LoadIndex(repoRoot string) (*Index, error) {
+	data, err := os.ReadFile(IndexPath(repoRoot))
+	if os.IsNotExist(err) {
+		return &Index{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("invalid patch index %s: %w", IndexPath(repoRoot), err)
+	}
+	return &idx, nil
+}
+
+// SaveIndex writes the patch index, sorted by ID
+func SaveIndex(repoRoot string, idx *Index) error {
+	sort.Slice(idx.Patches, func(i, j int) bool { return idx.Patches[i].ID() < idx.Patches[j].ID() })
+	if idx.Patches == nil {
+		idx.Patches = []Entry{}
+	}
+
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(Dir(repoRoot), 0755); err != nil {
+		return fmt.Errorf("failed to create patch directory: %w", err)
+	}
+	return os.WriteFile(IndexPath(repoRoot), append(data, '\n'), 0644)
+}
+
+// Find returns the entry for a keep file, or nil
+func (idx *Index) Find(workspace, file string) *Entry {
+	for i := range idx.Patches {
+		if idx.Patches[i].Workspace == workspace && idx.Patches[i].File == file {
+			return &idx.Patches[i]
+		}
+	}
+	return nil
+}
+
+// Put adds an entry, replacing the entry for the same keep file
+func (idx *Index) Put(e Entry) {
+	if existing := idx.Find(e.Workspace, e.File); existing != nil {
+		*existing = e
+		return
+	}
+	idx.Patches = append(idx.Patches, e)
+}
+
+// Remove removes the entry for a keep file and reports whether it existed
+func (idx *Index) Remove(workspace, file string) bool {
+	for i := range idx.Patches {
+		if idx.Patches[i].Workspace == workspace && idx.Patches[i].File == file {
+			idx.Patches = append(idx.Patches[:i], idx.Patches[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the entries matching any filter, in index order
+// A filter matches a patch ID exactly, or a workspace (and every patch in it).
+// Without filters all entries are returned. Every filter must match.
+func (idx *Index) Select(filters []string) ([]Entry, error) {
+	if len(filters) == 0 {
+		return idx.Patches, nil
+	}
+
+	for _, f := range filters {
+		matched := false
+		for i := range idx.Patches {
+			if matchesFilter(&idx.Patches[i], f) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no patch matches: %s", f)
+		}
+	}
+
+	var selected []Entry
+	for i := range idx.Patches {
+		for _, f := range filters {
+			if matchesFilter(&idx.Patches[i], f) {
+				selected = append(selected, idx.Patches[i])
+				break
+			}
+		}
+	}
+	return selected, nil
+}
+
+// matchesFilter checks if a filter names the patch or its workspace
+func matchesFilter(e *Entry, filter string) bool {
+	filter = strings.TrimSuffix(filepath.ToSlash(filepath.Clean(filter)), "/")
+	return e.ID() == filter || e.Workspace == filter
+}
+
+// Hash returns the content hash recorded for a patch file
+func Hash(patchPath string) (string, error) {
+	data, err := os.ReadFile(patchPath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Record adds the patch stored for a keep file to the index
+// base and branch describe the HEAD the patch was created against
+func Record(repoRoot, workspace, file, base, branch string) error {
+	return record(repoRoot, workspace, file, base, branch, false)
+}
+
+// RecordDeletion adds the patch of a locally deleted keep file to the index
+// as a tombstone, so the deletion is kept like any other local change
+func RecordDeletion(repoRoot, workspace, file, base, branch string) error {
+	return record(repoRoot, workspace, file, base, branch, true)
+}
+
+func record(repoRoot, workspace, file, base, branch string, deleted bool) error {
+	hash, err := Hash(StorePath(repoRoot, workspace, file))
+	if err != nil {
+		return fmt.Errorf("failed to hash patch: %w", err)
+	}
+
+	idx, err := LoadIndex(repoRoot)
+	if err != nil {
+		return err
+	}
+	idx.Put(Entry{
+		Workspace: workspace,
+		File:      file,
+		Base:      base,
+		Branch:    branch,
+		Hash:      hash,
+		Deleted:   deleted,
+		Created:   time.Now().Truncate(time.Second),
+	})
+	return SaveIndex(repoRoot, idx)
+}
+
+// Drop removes the patch stored for a keep file and its index entry
+// Directories left empty are removed with it.
+func Drop(repoRoot, workspace, file string) error {
+	if err := removePatch(repoRoot, workspace, file); err != nil {
+		return err
+	}
+
+	idx, err := LoadIndex(repoRoot)
+	if err != nil {
+		return err
+	}
+	if !idx.Remove(workspace, file) {
+		return nil
+	}
+	return SaveIndex(repoRoot, idx)
+}
+
+// DropWorkspace removes every patch recorded for a workspace
+func DropWorkspace(repoRoot, workspace string) error {
+	idx, err := LoadIndex(repoRoot)
+	if err != nil {
+		return err
+	}
+
+	kept := idx.Patches[:0]
+	removed := false
+	for _, e := range idx.Patches {
+		if e.Workspace != workspace {
+			kept = append(kept, e)
+			continue
+		}
+		if err := removePatch(repoRoot, e.Workspace, e.File); err != nil {
+			return err
+		}
+		removed = true
+	}
+	if !removed {
+		return nil
+	}
+	idx.Patches = kept
+	return SaveIndex(repoRoot, idx)
+}
+
+// removePatch removes a patch file and the directories it leaves empty,
+// up to the patch store directory
+func removePatch(repoRoot, workspace, file string) error {
+	patchPath := StorePath(repoRoot, workspace, file)
+	if err := os.Remove(patchPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	root := Dir(repoRoot)
+	for dir := filepath.Dir(patchPath); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			break // Not empty, or already gone
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package patch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/yejune/git-multirepo/internal/manifest"
+)
+
+// writeStoredPatch writes a patch file to the store and records it
+func writeStoredPatch(t *testing.T, repoRoot, workspace, file, content string) {
+	t.Helper()
+	p := StorePath(repoRoot, workspace, file)
+	os.MkdirAll(filepath.Dir(p), 0755)
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Record(repoRoot, workspace, file, "abc123", "main"); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+}
+
+// entryIDs returns the IDs of entries
+func entryIDs(entries []Entry) []string {
+	ids := []string{}
+	for _, e := range entries {
+		ids = append(ids, e.ID())
+	}
+	return ids
+}
+
+func TestLoadIndex_Missing(t *testing.T) {
+	idx, err := LoadIndex(t.TempDir())
+	if err != nil || len(idx.Patches) != 0 {
+		t.Errorf("LoadIndex() = %+v, %v; want an empty index", idx, err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeStoredPatch(t, repoRoot, "apps/api", "config.json", "a")
+	writeStoredPatch(t, repoRoot, "apps/api", "env/db.json", "b")
+	writeStoredPatch(t, repoRoot, "apps/api-v2", "config.json", "c")
+	writeStoredPatch(t, repoRoot, manifest.ParentPath, ".env", "d")
+
+	idx, err := LoadIndex(repoRoot)
+	if err != nil {
+		t.Fatalf("LoadIndex() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		filters []string
+		want    []string
+	}{
+		{"all", nil, []string{".env", "apps/api-v2/config.json", "apps/api/config.json", "apps/api/env/db.json"}},
+		{"workspace", []string{"apps/api"}, []string{"apps/api/config.json", "apps/api/env/db.json"}},
+		{"workspace with trailing slash", []string{"apps/api/"}, []string{"apps/api/config.json", "apps/api/env/db.json"}},
+		{"patch", []string{"apps/api/env/db.json"}, []string{"apps/api/env/db.json"}},
+		{"parent repository", []string{".env"}, []string{".env"}},
+		{"several", []string{"apps/api-v2", ".env"}, []string{".env", "apps/api-v2/config.json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := idx.Select(tt.filters)
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			if got := entryIDs(entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Select(%v) = %v, want %v", tt.filters, got, tt.want)
+			}
+		})
+	}
+
+	// A workspace name is not a prefix of longer paths
+	for _, filters := range [][]string{{"apps"}, {"apps/ap"}, {"apps/api", "missing"}} {
+		if _, err := idx.Select(filters); err == nil {
+			t.Errorf("Select(%v) should fail", filters)
+		}
+	}
+}
+
+func TestRecord_Replaces(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeStoredPatch(t, repoRoot, "apps/api", "config.json", "first")
+	first, _ := LoadIndex(repoRoot)
+	firstHash := first.Find("apps/api", "config.json").Hash
+
+	// Recorded again after the keep file changed, e.g. by the next sync
+	os.WriteFile(StorePath(repoRoot, "apps/api", "config.json"), []byte("second"), 0644)
+	if err := Record(repoRoot, "apps/api", "config.json", "def456", "feature"); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	idx, _ := LoadIndex(repoRoot)
+	if len(idx.Patches) != 1 {
+		t.Fatalf("expected a single entry, got %+v", idx.Patches)
+	}
+	e := idx.Patches[0]
+	if e.Hash == firstHash || e.Base != "def456" || e.Branch != "feature" || e.Deleted {
+		t.Errorf("entry not replaced: %+v", e)
+	}
+	if hash, _ := Hash(StorePath(repoRoot, "apps/api", "config.json")); hash != e.Hash {
+		t.Errorf("hash %s does not match the patch file (%s)", e.Hash, hash)
+	}
+
+	// A deletion replaces the entry with a tombstone
+	if err := RecordDeletion(repoRoot, "apps/api", "config.json", "def456", "feature"); err != nil {
+		t.Fatalf("RecordDeletion() error = %v", err)
+	}
+	if idx, _ := LoadIndex(repoRoot); len(idx.Patches) != 1 || !idx.Patches[0].Deleted {
+		t.Errorf("expected a single tombstone, got %+v", idx.Patches)
+	}
+}
+
+func TestDrop(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeStoredPatch(t, repoRoot, "apps/api", "config.json", "a")
+	writeStoredPatch(t, repoRoot, "apps/api", "env/db.json", "b")
+	writeStoredPatch(t, repoRoot, "apps/web", "config.json", "c")
+
+	if err := Drop(repoRoot, "apps/api", "env/db.json"); err != nil {
+		t.Fatalf("Drop() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(Dir(repoRoot), "apps/api/env")); !os.IsNotExist(err) {
+		t.Error("empty directory of the dropped patch should be removed")
+	}
+
+	// Dropping the last patch of a workspace leaves nothing of it behind
+	if err := Drop(repoRoot, "apps/api", "config.json"); err != nil {
+		t.Fatalf("Drop() error = %v", err)
+	}
+	idx, _ := LoadIndex(repoRoot)
+	if got := entryIDs(idx.Patches); !reflect.DeepEqual(got, []string{"apps/web/config.json"}) {
+		t.Errorf("index = %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(Dir(repoRoot), "apps/api")); !os.IsNotExist(err) {
+		t.Error("workspace directory should be removed with its last patch")
+	}
+	if _, err := os.Stat(StorePath(repoRoot, "apps/web", "config.json")); err != nil {
+		t.Errorf("other workspaces should be untouched: %v", err)
+	}
+
+	// Dropping an unknown patch is not an error
+	if err := Drop(repoRoot, "apps/api", "config.json"); err != nil {
+		t.Errorf("Drop() of a missing patch error = %v", err)
+	}
+}
+
+func TestDropWorkspace(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeStoredPatch(t, repoRoot, "apps/api", "config.json", "a")
+	writeStoredPatch(t, repoRoot, "apps/api", "env/db.json", "b")
+	writeStoredPatch(t, repoRoot, "apps/api-v2", "config.json", "c")
+
+	if err := DropWorkspace(repoRoot, "apps/api"); err != nil {
+		t.Fatalf("DropWorkspace() error = %v", err)
+	}
+	idx, _ := LoadIndex(repoRoot)
+	if got := entryIDs(idx.Patches); !reflect.DeepEqual(got, []string{"apps/api-v2/config.json"}) {
+		t.Errorf("index = %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(Dir(repoRoot), "apps/api")); !os.IsNotExist(err) {
+		t.Error("workspace directory should be removed")
+	}
+	if _, err := os.Stat(StorePath(repoRoot, "apps/api-v2", "config.json")); err != nil {
+		t.Errorf("a workspace sharing the prefix should be untouched: %v", err)
+	}
+
+	// The last workspace leaves an empty index, not a missing one
+	if err := DropWorkspace(repoRoot, "apps/api-v2"); err != nil {
+		t.Fatalf("DropWorkspace() error = %v", err)
+	}
+	if idx, err := LoadIndex(repoRoot); err != nil || len(idx.Patches) != 0 {
+		t.Errorf("LoadIndex() = %+v, %v; want an empty index", idx, err)
+	}
+	if _, err := os.Stat(IndexPath(repoRoot)); err != nil {
+		t.Errorf("index should stay: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+// Package profile stores named sets of keep file overrides (keep profiles)
+//
+// A profile holds, for every keep file it overrides, the file content and
+// its patch against HEAD at the time it was saved:
+//
+//	.multirepos/profiles/<name>/<workspace>/<file>        content
+//	.multirepos/profiles/<name>/<workspace>/<file>.patch  patch
+//
+// The files a profile covers are listed in the manifest (keep_profiles); the
+// profile that is currently applied is recorded in .multirepos/profiles/.active.
+package profile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dir returns the profile directory: .multirepos/profiles
+func Dir(repoRoot string) string {
+	return filepath.Join(repoRoot, ".multirepos", "profiles")
+}
+
+// FilePath returns where a profile stores the content of a keep file
+func FilePath(repoRoot, name, workspace, file string) string {
+	return filepath.Join(Dir(repoRoot), name, workspace, file)
+}
+
+// PatchPath returns where a profile stores the patch of a keep file
+func PatchPath(repoRoot, name, workspace, file string) string {
+	return FilePath(repoRoot, name, workspace, file) + ".patch"
+}
+
+// activePath returns the file recording the active profile
+func activePath(repoRoot string) string {
+	return filepath.Join(Dir(repoRoot), ".active")
+}
+
+// ValidateName rejects names that cannot be used as a directory name
+func ValidateName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid profile name: %q", name)
+	}
+	return nil
+}
+
+// Active returns the name of the active profile, or "" if none is
+func Active(repoRoot string) string {
+	data, err := os.ReadFile(activePath(repoRoot))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// SetActive records the active profile; an empty name clears it
+func SetActive(repoRoot, name string) error {
+	if name == "" {
+		if err := os.Remove(activePath(repoRoot)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(Dir(repoRoot), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(activePath(repoRoot), []byte(name+"\n"), 0644)
+}
+
+// Remove deletes the stored files of a profile
+func Remove(repoRoot, name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(Dir(repoRoot), name))
+}
+
+// RemoveFiles deletes the stored content and patch of keep files of a profile
+func RemoveFiles(repoRoot, name, workspace string, files []string) error {
+	for _, f := range files {
+		for _, p := range []string{FilePath(repoRoot, name, workspace, f), PatchPath(repoRoot, name, workspace, f)} {
+			if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yejune/git-multirepo/internal/manifest"
+)
+
+func TestActive(t *testing.T) {
+	dir := t.TempDir()
+
+	if active := Active(dir); active != "" {
+		t.Errorf("expected no active profile, got %q", active)
+	}
+	if err := SetActive(dir, "staging"); err != nil {
+		t.Fatalf("SetActive failed: %v", err)
+	}
+	if active := Active(dir); active != "staging" {
+		t.Errorf("expected staging, got %q", active)
+	}
+	if err := SetActive(dir, ""); err != nil {
+		t.Fatalf("SetActive failed: %v", err)
+	}
+	if active := Active(dir); active != "" {
+		t.Errorf("expected active profile to be cleared, got %q", active)
+	}
+	if err := SetActive(dir, ""); err != nil {
+		t.Errorf("clearing twice should succeed: %v", err)
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"dev", "staging-local", "v2.1"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("%q should be valid: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", ".active", "..", "a/b", `a\b`} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("%q should be invalid", name)
+		}
+	}
+}
+
+func TestRemoveFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, p := range []string{
+		FilePath(dir, "dev", manifest.ParentPath, "config.json"),
+		PatchPath(dir, "dev", manifest.ParentPath, "config.json"),
+		FilePath(dir, "dev", "apps/api", "config.json"),
+	} {
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+	}
+
+	// Removing the parent's files leaves the workspaces stored below it alone
+	if err := RemoveFiles(dir, "dev", manifest.ParentPath, []string{"config.json", "missing.json"}); err != nil {
+		t.Fatalf("RemoveFiles failed: %v", err)
+	}
+	if _, err := os.Stat(FilePath(dir, "dev", manifest.ParentPath, "config.json")); !os.IsNotExist(err) {
+		t.Error("content should be removed")
+	}
+	if _, err := os.Stat(PatchPath(dir, "dev", manifest.ParentPath, "config.json")); !os.IsNotExist(err) {
+		t.Error("patch should be removed")
+	}
+	if _, err := os.Stat(FilePath(dir, "dev", "apps/api", "config.json")); err != nil {
+		t.Error("workspace files should be kept")
+	}
+
+	if err := Remove(dir, "dev"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(Dir(dir), "dev")); !os.IsNotExist(err) {
+		t.Error("profile directory should be removed")
+	}
+}
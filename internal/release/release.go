@@ -0,0 +1,73 @@
+// Package release records which commit every workspace was tagged at for a release
+package release
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Entry is one tagged repository
+type Entry struct {
+	Path   string `yaml:"path"`
+	Commit string `yaml:"commit"`
+}
+
+// Record is the release record written when a release is tagged
+type Record struct {
+	Name       string    `yaml:"name"`
+	Message    string    `yaml:"message,omitempty"`
+	Created    time.Time `yaml:"created"`
+	Signed     bool      `yaml:"signed,omitempty"`
+	Workspaces []Entry   `yaml:"workspaces"` // The parent is included as "." when tagged
+}
+
+// Dir returns the release record directory: .multirepos/releases
+func Dir(repoRoot string) string {
+	return filepath.Join(repoRoot, ".multirepos", "releases")
+}
+
+// Path returns the file a release record is stored in
+// Tag names may contain slashes (e.g., release/1.0), which become subdirectories
+func Path(repoRoot, name string) string {
+	return filepath.Join(Dir(repoRoot), filepath.FromSlash(name)+".yaml")
+}
+
+// Exists checks if a release record exists
+func Exists(repoRoot, name string) bool {
+	_, err := os.Stat(Path(repoRoot, name))
+	return err == nil
+}
+
+// Save writes the release record
+func Save(repoRoot string, r *Record) error {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+	path := Path(repoRoot, r.Name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create release directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Load reads a release record by name
+func Load(repoRoot, name string) (*Record, error) {
+	data, err := os.ReadFile(Path(repoRoot, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no release record for %s", name)
+		}
+		return nil, err
+	}
+
+	var r Record
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid release record %s: %w", name, err)
+	}
+	return &r, nil
+}
@@ -0,0 +1,64 @@
+package release
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yejune/git-multirepo/internal/manifest"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	r := &Record{
+		Name:    "release/1.0",
+		Message: "Release 1.0",
+		Created: created,
+		Signed:  true,
+		Workspaces: []Entry{
+			{Path: manifest.ParentPath, Commit: "p1"},
+			{Path: "apps/api", Commit: "a1"},
+		},
+	}
+	if Exists(dir, r.Name) {
+		t.Fatal("record should not exist yet")
+	}
+	if err := Save(dir, r); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Slashes in tag names become subdirectories
+	if _, err := os.Stat(filepath.Join(dir, ".multirepos", "releases", "release", "1.0.yaml")); err != nil {
+		t.Errorf("record not stored where expected: %v", err)
+	}
+	if !Exists(dir, r.Name) {
+		t.Error("record should exist")
+	}
+
+	loaded, err := Load(dir, r.Name)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Name != r.Name || loaded.Message != r.Message || !loaded.Signed || !loaded.Created.Equal(created) {
+		t.Errorf("loaded record differs: %+v", loaded)
+	}
+	if len(loaded.Workspaces) != 2 || loaded.Workspaces[1] != r.Workspaces[1] {
+		t.Errorf("workspaces differ: %+v", loaded.Workspaces)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Load(dir, "v1"); err == nil {
+		t.Error("expected error for missing record")
+	}
+
+	os.MkdirAll(Dir(dir), 0755)
+	os.WriteFile(Path(dir, "broken"), []byte("workspaces: [\n"), 0644)
+	if _, err := Load(dir, "broken"); err == nil {
+		t.Error("expected error for invalid YAML")
+	}
+}
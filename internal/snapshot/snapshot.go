@@ -0,0 +1,158 @@
+// Package snapshot records the HEAD of every workspace so it can be restored later
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yejune/git-multirepo/internal/manifest"
+	"gopkg.in/yaml.v3"
+)
+
+// Entry is the recorded state of one repository
+type Entry struct {
+	Path   string `yaml:"path"`
+	Branch string `yaml:"branch,omitempty"` // Empty when HEAD was detached
+	Commit string `yaml:"commit"`
+	Dirty  bool   `yaml:"dirty,omitempty"` // Had uncommitted changes (not recorded)
+}
+
+// Snapshot is the state of the parent repository and all workspaces at one point in time
+type Snapshot struct {
+	Name       string    `yaml:"name"`
+	Created    time.Time `yaml:"created"`
+	Parent     Entry     `yaml:"parent"`
+	Workspaces []Entry   `yaml:"workspaces"`
+}
+
+// Find returns the entry for path ("." for the parent), or nil
+func (s *Snapshot) Find(path string) *Entry {
+	if path == manifest.ParentPath {
+		return &s.Parent
+	}
+	for i := range s.Workspaces {
+		if s.Workspaces[i].Path == path {
+			return &s.Workspaces[i]
+		}
+	}
+	return nil
+}
+
+// Dir returns the snapshot directory: .multirepos/snapshots
+func Dir(repoRoot string) string {
+	return filepath.Join(repoRoot, ".multirepos", "snapshots")
+}
+
+// Path returns the file a snapshot is stored in
+func Path(repoRoot, name string) string {
+	return filepath.Join(Dir(repoRoot), name+".yaml")
+}
+
+// ValidateName rejects names that cannot be used as a file name
+func ValidateName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid snapshot name: %q", name)
+	}
+	return nil
+}
+
+// Exists checks if a snapshot with the given name exists
+func Exists(repoRoot, name string) bool {
+	_, err := os.Stat(Path(repoRoot, name))
+	return err == nil
+}
+
+// Save writes the snapshot, replacing any snapshot with the same name
+func Save(repoRoot string, s *Snapshot) error {
+	if err := ValidateName(s.Name); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(Dir(repoRoot), 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	return os.WriteFile(Path(repoRoot, s.Name), data, 0644)
+}
+
+// Load reads a snapshot by name
+func Load(repoRoot, name string) (*Snapshot, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(Path(repoRoot, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("snapshot not found: %s", name)
+		}
+		return nil, err
+	}
+
+	var s Snapshot
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", name, err)
+	}
+	s.Name = name // The file name wins if the two disagree
+	return &s, nil
+}
+
+// List returns all snapshots, oldest first
+func List(repoRoot string) ([]*Snapshot, error) {
+	files, err := filepath.Glob(filepath.Join(Dir(repoRoot), "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []*Snapshot
+	for _, file := range files {
+		s, err := Load(repoRoot, strings.TrimSuffix(filepath.Base(file), ".yaml"))
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].Created.Before(snapshots[j].Created)
+	})
+	return snapshots, nil
+}
+
+// Change is a repository whose state differs between two snapshots
+// Old is nil for repositories added since, New is nil for removed ones
+type Change struct {
+	Path string
+	Old  *Entry
+	New  *Entry
+}
+
+// Compare returns the repositories that differ between before and after, parent first
+// Entries differ when their branch, commit or dirty state changed
+func Compare(before, after *Snapshot) []Change {
+	var changes []Change
+	if before.Parent != after.Parent {
+		o, n := before.Parent, after.Parent
+		changes = append(changes, Change{Path: manifest.ParentPath, Old: &o, New: &n})
+	}
+
+	for i := range before.Workspaces {
+		o := &before.Workspaces[i]
+		n := after.Find(o.Path)
+		if n == nil || *n != *o {
+			changes = append(changes, Change{Path: o.Path, Old: o, New: n})
+		}
+	}
+	for i := range after.Workspaces {
+		n := &after.Workspaces[i]
+		if before.Find(n.Path) == nil {
+			changes = append(changes, Change{Path: n.Path, New: n})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,144 @@
+package snapshot
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/yejune/git-multirepo/internal/manifest"
+)
+
+func TestSaveLoadAndList(t *testing.T) {
+	dir := t.TempDir()
+
+	if snapshots, err := List(dir); err != nil || len(snapshots) != 0 {
+		t.Fatalf("expected no snapshots, got %v, %v", snapshots, err)
+	}
+
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	newer := &Snapshot{
+		Name:    "newer",
+		Created: created.Add(time.Hour),
+		Parent:  Entry{Path: manifest.ParentPath, Branch: "main", Commit: "p2"},
+	}
+	older := &Snapshot{
+		Name:    "older",
+		Created: created,
+		Parent:  Entry{Path: manifest.ParentPath, Branch: "main", Commit: "p1"},
+		Workspaces: []Entry{
+			{Path: "apps/api", Branch: "main", Commit: "a1", Dirty: true},
+			{Path: "apps/web", Commit: "w1"},
+		},
+	}
+	for _, s := range []*Snapshot{newer, older} {
+		if err := Save(dir, s); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	if !Exists(dir, "older") || Exists(dir, "missing") {
+		t.Error("Exists reports wrong result")
+	}
+
+	loaded, err := Load(dir, "older")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !loaded.Created.Equal(created) || loaded.Parent.Commit != "p1" || len(loaded.Workspaces) != 2 {
+		t.Errorf("loaded snapshot differs: %+v", loaded)
+	}
+	if e := loaded.Find("apps/api"); e == nil || !e.Dirty || e.Branch != "main" {
+		t.Errorf("apps/api entry differs: %+v", e)
+	}
+	if e := loaded.Find("apps/web"); e == nil || e.Branch != "" {
+		t.Errorf("detached entry should have no branch: %+v", e)
+	}
+	if loaded.Find(manifest.ParentPath) != &loaded.Parent {
+		t.Error("Find(.) should return the parent entry")
+	}
+
+	snapshots, err := List(dir)
+	if err != nil || len(snapshots) != 2 {
+		t.Fatalf("List = %v, %v", snapshots, err)
+	}
+	if snapshots[0].Name != "older" || snapshots[1].Name != "newer" {
+		t.Errorf("expected oldest first, got %s, %s", snapshots[0].Name, snapshots[1].Name)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := Load(dir, "missing"); err == nil {
+		t.Error("expected error for missing snapshot")
+	}
+
+	os.MkdirAll(Dir(dir), 0755)
+	os.WriteFile(Path(dir, "broken"), []byte("workspaces: [\n"), 0644)
+	if _, err := Load(dir, "broken"); err == nil {
+		t.Error("expected error for invalid YAML")
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"before-upgrade", "v1.2", "nightly_2026"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("%q should be valid: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", ".", "..", "a/b", `a\b`} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("%q should be invalid", name)
+		}
+	}
+	if err := Save(t.TempDir(), &Snapshot{Name: "../escape"}); err == nil {
+		t.Error("Save should reject invalid names")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	before := &Snapshot{
+		Parent: Entry{Path: manifest.ParentPath, Branch: "main", Commit: "p1"},
+		Workspaces: []Entry{
+			{Path: "same", Branch: "main", Commit: "s1"},
+			{Path: "moved", Branch: "main", Commit: "m1"},
+			{Path: "dirty", Branch: "main", Commit: "d1"},
+			{Path: "removed", Branch: "main", Commit: "r1"},
+		},
+	}
+	after := &Snapshot{
+		Parent: Entry{Path: manifest.ParentPath, Branch: "main", Commit: "p1"},
+		Workspaces: []Entry{
+			{Path: "same", Branch: "main", Commit: "s1"},
+			{Path: "moved", Branch: "feat/x", Commit: "m2"},
+			{Path: "dirty", Branch: "main", Commit: "d1", Dirty: true},
+			{Path: "added", Branch: "main", Commit: "n1"},
+		},
+	}
+
+	changes := Compare(before, after)
+	got := make(map[string]Change)
+	for _, c := range changes {
+		got[c.Path] = c
+	}
+	if len(changes) != 4 {
+		t.Fatalf("expected 4 changes, got %+v", changes)
+	}
+	if c := got["moved"]; c.Old.Commit != "m1" || c.New.Commit != "m2" {
+		t.Errorf("moved: %+v", c)
+	}
+	if c := got["dirty"]; c.Old == nil || c.New == nil || !c.New.Dirty {
+		t.Errorf("dirty: %+v", c)
+	}
+	if c := got["removed"]; c.Old == nil || c.New != nil {
+		t.Errorf("removed: %+v", c)
+	}
+	if c := got["added"]; c.Old != nil || c.New == nil {
+		t.Errorf("added: %+v", c)
+	}
+
+	after.Parent.Commit = "p2"
+	if changes := Compare(before, after); changes[0].Path != manifest.ParentPath {
+		t.Errorf("parent change should come first, got %+v", changes[0])
+	}
+}